@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// runStripAnnotations implements the strip-annotations subcommand: it removes
+// the review markers written by --annotate from a translated file.
+func runStripAnnotations(args []string) {
+	fs := flag.NewFlagSet("strip-annotations", flag.ExitOnError)
+	fs.String("config", "", configUsage)
+	inputFile := fs.String("input", "", "Annotated translation (required)")
+	outputFile := fs.String("output", "", "File for the plain translation (default: overwrite the input)")
+	fs.Parse(args)
+	if err := applySettings(fs); err != nil {
+		fmt.Printf("Error reading settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *inputFile == "" {
+		fmt.Println("Error: --input is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *outputFile == "" {
+		*outputFile = *inputFile
+	}
+
+	data, err := os.ReadFile(*inputFile)
+	if err != nil {
+		fmt.Printf("Error reading input file: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*outputFile, []byte(translator.StripAnnotations(string(data))), 0644); err != nil {
+		fmt.Printf("Error writing output file: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// runAuditVerify implements the audit-verify subcommand: it checks the hash
+// chain of an audit log written with --audit.
+func runAuditVerify(args []string) {
+	fs := flag.NewFlagSet("audit-verify", flag.ExitOnError)
+	fs.String("config", "", configUsage)
+	auditPath := fs.String("audit", "", "Audit log to verify (required)")
+	fs.Parse(args)
+	if err := applySettings(fs); err != nil {
+		fmt.Printf("Error reading settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *auditPath == "" {
+		fmt.Println("Error: --audit is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	key, err := storageKey()
+	if err != nil {
+		fmt.Printf("Error reading storage key: %v\n", err)
+		os.Exit(1)
+	}
+
+	records, err := translator.VerifyAuditLog(*auditPath, key)
+	if err != nil {
+		fmt.Printf("Audit log %s is invalid: %v\n", *auditPath, err)
+		os.Exit(1)
+	}
+
+	if len(records) == 0 {
+		fmt.Printf("Audit log %s is empty\n", *auditPath)
+		return
+	}
+	first, last := records[0], records[len(records)-1]
+	fmt.Printf("Audit log %s is intact: %d requests from %s to %s, last hash %s\n",
+		*auditPath, len(records), first.Time.Format("2006-01-02 15:04:05"), last.Time.Format("2006-01-02 15:04:05"), last.Hash)
+}
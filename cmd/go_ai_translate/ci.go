@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// printCIAnnotations reports validation warnings as GitHub Actions workflow
+// commands so they show up as annotations on the output file.
+func printCIAnnotations(result translator.Result) {
+	for _, w := range result.Warnings {
+		fmt.Printf("::warning file=%s,line=%d,title=%s::%s\n",
+			escapeCIProperty(result.Output), w.Line, escapeCIProperty(w.Kind),
+			escapeCIData(fmt.Sprintf("chunk %d: %s", w.Chunk, w.Message)))
+	}
+}
+
+// writeCIJobSummary appends a Markdown summary table to the file named by
+// GITHUB_STEP_SUMMARY. It does nothing outside GitHub Actions.
+func writeCIJobSummary(result translator.Result, elapsed time.Duration, runErr error) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+
+	status := "✅ translated"
+	if runErr != nil {
+		status = "❌ " + runErr.Error()
+	} else if len(result.Warnings) > 0 {
+		status = fmt.Sprintf("⚠️ %d warnings", len(result.Warnings))
+	}
+
+	var b strings.Builder
+	b.WriteString("### Translation\n\n")
+	if result.RunID != "" {
+		fmt.Fprintf(&b, "Run `%s`\n\n", result.RunID)
+	}
+	b.WriteString("| Input | Output | Chunks | Tokens | Cost | Time | Status |\n")
+	b.WriteString("|---|---|---|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| `%s` | `%s` | %d/%d | %d | $%.4f | %v | %s |\n",
+		result.Input, result.Output, result.ChunksTranslated, result.Chunks,
+		result.PromptTokens+result.CompletionTokens, result.Cost,
+		elapsed.Round(time.Second), strings.ReplaceAll(status, "|", "\\|"))
+
+	if len(result.Warnings) > 0 {
+		b.WriteString("\n| Chunk | Line | Kind | Message |\n|---|---|---|---|\n")
+		for _, w := range result.Warnings {
+			fmt.Fprintf(&b, "| %d | %d | %s | %s |\n", w.Chunk, w.Line, w.Kind,
+				strings.ReplaceAll(w.Message, "|", "\\|"))
+		}
+	}
+	b.WriteString("\n")
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(b.String())
+	return err
+}
+
+func escapeCIData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeCIProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(escapeCIData(s))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultConfigPath is the config file read when --config is not given.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go_ai_translate", "config.yaml")
+}
+
+// loadConfig reads the config file at path, flag values in the form of a
+// preset. A missing file is no error unless it was given with --config.
+func loadConfig(path string, explicit bool) (preset, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return nil, nil
+		}
+		return nil, err
+	}
+	config, err := parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// parseConfig reads a config file, a YAML map of flag names to values with
+// the entries of repeatable flags in a list:
+//
+//	api-key: sk-or-...
+//	model: openai/gpt-4o-mini
+//	chunk-size: 2000
+//	glossary: /home/me/glossary.csv
+//	protect-regex:
+//	  - 'ACME-[0-9]+'
+func parseConfig(data []byte) (preset, error) {
+	config := preset{}
+	list := ""
+	for n, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' && strings.HasPrefix(trimmed, "- ") {
+			return nil, fmt.Errorf("line %d: list items must be indented", n+1)
+		}
+		if err := config.parseLine(trimmed, &list); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+	}
+	return config, nil
+}
+
+// configUsage is the help of the --config flag of the subcommands.
+const configUsage = "YAML file of default flag values; flags win over it, it wins over environment variables (default: config.yaml in the user config directory's go_ai_translate folder)"
+
+// applySettings fills the flags of a subcommand's fs that are still unset
+// from the config file and then from the environment, as the main command
+// does. Options of the config file that fs lacks belong to other commands
+// and are skipped.
+func applySettings(fs *flag.FlagSet) error {
+	if err := applyEnv(fs, "config"); err != nil {
+		return err
+	}
+	path := fs.Lookup("config").Value.String()
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	config, err := loadConfig(path, explicit)
+	if err != nil {
+		return err
+	}
+	for key := range config {
+		if key != "config" && fs.Lookup(key) == nil {
+			delete(config, key)
+		}
+	}
+	if err := applyConfig(fs, config); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return applyEnv(fs)
+}
+
+// applyConfig sets the flags of fs that are still unset from config, or only
+// the flags named in only when given. Flags given on the command line or by
+// a preset win; environment variables are applied after it and only fill
+// what it leaves unset, including flags whose default is read from one, such
+// as --api-key from OPENROUTER_API_KEY.
+func applyConfig(fs *flag.FlagSet, config preset, only ...string) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	wanted := map[string]bool{}
+	for _, name := range only {
+		wanted[name] = true
+	}
+
+	var keys []string
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f := fs.Lookup(key)
+		if f == nil || key == "config" {
+			return fmt.Errorf("unknown option %q, use the name of a flag without --", key)
+		}
+		if set[key] || (len(only) > 0 && !wanted[key]) {
+			continue
+		}
+		for _, value := range config[key] {
+			if err := fs.Set(key, value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
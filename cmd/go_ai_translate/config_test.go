@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigFileWinsOverEnvironment(t *testing.T) {
+	for name, value := range map[string]string{
+		"GO_AI_TRANSLATE_MODEL":      "env/model",
+		"GO_AI_TRANSLATE_CHUNK_SIZE": "900",
+	} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	model := fs.String("model", "default/model", "")
+	chunkSize := fs.Int("chunk-size", 500, "")
+	to := fs.String("to", "russian", "")
+	apiKey := fs.String("api-key", "env-key", "")
+	if err := fs.Parse([]string{"--to", "german"}); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := parseConfig([]byte("model: config/model\nto: french\napi-key: config-key\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig(fs, config); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnv(fs); err != nil {
+		t.Fatal(err)
+	}
+
+	if *model != "config/model" || *apiKey != "config-key" {
+		t.Errorf("Expected the config file to win over the environment, got model %q and API key %q", *model, *apiKey)
+	}
+	if *chunkSize != 900 {
+		t.Errorf("Expected the environment to fill what the config file leaves unset, got %d", *chunkSize)
+	}
+	if *to != "german" {
+		t.Errorf("Expected the command line to win, got %q", *to)
+	}
+}
+
+func TestSubcommandsReadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("api-key: config-key\nmodel: config/model\nchunk-size: 2000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("GO_AI_TRANSLATE_CONFIG", path)
+	defer os.Unsetenv("GO_AI_TRANSLATE_CONFIG")
+
+	fs := flag.NewFlagSet("flush", flag.ContinueOnError)
+	fs.String("config", "", configUsage)
+	apiKey := fs.String("api-key", "", "")
+	model := fs.String("model", "default/model", "")
+	if err := fs.Parse([]string{"--model", "flag/model"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applySettings(fs); err != nil {
+		t.Fatalf("Expected options of other commands to be skipped, got %v", err)
+	}
+	if *apiKey != "config-key" {
+		t.Errorf("Expected the API key from the config file, got %q", *apiKey)
+	}
+	if *model != "flag/model" {
+		t.Errorf("Expected the command line to win, got %q", *model)
+	}
+}
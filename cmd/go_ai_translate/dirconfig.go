@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+const dirConfigName = ".ai-translate.yaml"
+
+// dirConfig holds the overrides of a .ai-translate.yaml file in a sync source
+// tree. They apply to the files of its directory and below, a deeper file
+// overriding a shallower one key by key:
+//
+//	to: german
+//	model: openai/gpt-4o-mini
+//	format: markdown
+//	glossary:
+//	  pull request: Pull-Request
+//	skip:
+//	  - "*.generated.md"
+//	  - drafts/
+type dirConfig struct {
+	To       string
+	Model    string
+	Format   string
+	Glossary map[string]string
+	// Skip holds file name patterns; a pattern ending in / matches a
+	// directory. They are relative to the directory of the file.
+	Skip []string
+}
+
+// parseDirConfig reads the small YAML subset dirConfig needs: scalar keys, a
+// list under skip and a map under glossary.
+func parseDirConfig(data []byte) (*dirConfig, error) {
+	c := &dirConfig{}
+	section := ""
+	for n, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			switch {
+			case section == "skip" && strings.HasPrefix(trimmed, "- "):
+				c.Skip = append(c.Skip, unquoteYAML(strings.TrimSpace(trimmed[2:])))
+			case section == "glossary" && strings.Contains(trimmed, ":"):
+				i := strings.LastIndex(trimmed, ":")
+				if c.Glossary == nil {
+					c.Glossary = map[string]string{}
+				}
+				c.Glossary[unquoteYAML(strings.TrimSpace(trimmed[:i]))] = unquoteYAML(strings.TrimSpace(trimmed[i+1:]))
+			default:
+				return nil, fmt.Errorf("line %d: expected a \"- pattern\" under skip or a \"term: translation\" under glossary", n+1)
+			}
+			continue
+		}
+
+		i := strings.Index(trimmed, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", n+1)
+		}
+		key, value := trimmed[:i], unquoteYAML(strings.TrimSpace(trimmed[i+1:]))
+		section = ""
+		switch key {
+		case "to":
+			c.To = value
+		case "model":
+			c.Model = value
+		case "format":
+			c.Format = value
+		case "glossary", "skip":
+			if value != "" {
+				return nil, fmt.Errorf("line %d: put the entries of %s on the following lines", n+1, key)
+			}
+			section = key
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q, use to, model, format, glossary or skip", n+1, key)
+		}
+	}
+	return c, nil
+}
+
+func unquoteYAML(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// dirConfigs loads the .ai-translate.yaml files of a source tree on demand.
+type dirConfigs struct {
+	sourceDir string
+	loaded    map[string]*dirConfig
+}
+
+func newDirConfigs(sourceDir string) *dirConfigs {
+	return &dirConfigs{sourceDir: sourceDir, loaded: map[string]*dirConfig{}}
+}
+
+// load returns the overrides of dir, relative to the source tree, or nil
+// when it has no .ai-translate.yaml.
+func (d *dirConfigs) load(dir string) (*dirConfig, error) {
+	if c, ok := d.loaded[dir]; ok {
+		return c, nil
+	}
+
+	path := filepath.Join(d.sourceDir, dir, dirConfigName)
+	data, err := os.ReadFile(path)
+	var c *dirConfig
+	if err == nil {
+		if c, err = parseDirConfig(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	d.loaded[dir] = c
+	return c, nil
+}
+
+// forFile applies the overrides on the way from the root of the tree to the
+// file rel onto config. It reports whether any applied and whether a skip
+// rule excludes the file.
+func (d *dirConfigs) forFile(config translator.Config, rel string) (translator.Config, bool, bool, error) {
+	var dirs []string
+	for dir := filepath.Dir(rel); ; dir = filepath.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+		if dir == "." || dir == string(filepath.Separator) {
+			break
+		}
+	}
+
+	overridden := false
+	for _, dir := range dirs {
+		c, err := d.load(dir)
+		if err != nil {
+			return config, false, false, err
+		}
+		if c == nil {
+			continue
+		}
+
+		inDir, err := filepath.Rel(dir, rel)
+		if err != nil {
+			return config, false, false, err
+		}
+		if skipped(c.Skip, filepath.ToSlash(inDir)) {
+			return config, overridden, true, nil
+		}
+
+		overridden = true
+		if c.To != "" {
+			config.ToLang = c.To
+		}
+		if c.Model != "" {
+			config.Model, config.FallbackModels = c.Model, nil
+		}
+		if c.Format != "" {
+			config.Format = c.Format
+		}
+		if len(c.Glossary) > 0 {
+			glossary := map[string]string{}
+			for term, translation := range config.Glossary {
+				glossary[term] = translation
+			}
+			for term, translation := range c.Glossary {
+				glossary[term] = translation
+			}
+			config.Glossary = glossary
+		}
+	}
+	return config, overridden, false, nil
+}
+
+// skipped reports whether path, relative to the directory of the rules,
+// matches one of the skip patterns.
+func skipped(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") {
+			if strings.HasPrefix(path+"/", pattern) || strings.Contains("/"+path, "/"+pattern) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, pathBase(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func pathBase(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix starts the environment variables that set flags, e.g.
+// GO_AI_TRANSLATE_CHUNK_SIZE for --chunk-size.
+const envPrefix = "GO_AI_TRANSLATE_"
+
+// envName is the environment variable of the flag name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnv sets the flags of fs that are still unset from their environment
+// variables, or only the flags named in only when given. Flags given on the
+// command line, by a preset or by the config file win. A repeatable flag
+// takes one value per line of its variable.
+func applyEnv(fs *flag.FlagSet, only ...string) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	wanted := map[string]bool{}
+	for _, name := range only {
+		wanted[name] = true
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || set[f.Name] || (len(only) > 0 && !wanted[f.Name]) {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		values := []string{value}
+		if _, repeatable := f.Value.(*stringList); repeatable {
+			values = strings.Split(strings.TrimRight(value, "\n"), "\n")
+		}
+		for _, v := range values {
+			if e := fs.Set(f.Name, v); e != nil {
+				err = fmt.Errorf("%s: %w", envName(f.Name), e)
+				return
+			}
+		}
+	})
+	return err
+}
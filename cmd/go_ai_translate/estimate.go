@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+func defaultEstimatesPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "go_ai_translate", "estimates.json")
+}
+
+func defaultCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "go_ai_translate", "chunks.jsonl")
+}
+
+// printEstimate implements --dry-run: it chunks inputPath and prints the
+// expected token usage and cost without translating anything.
+func printEstimate(t *translator.Translator, inputPath string, jsonMode bool) {
+	prepared, err := t.Prepare(inputPath)
+	if err != nil {
+		fail(jsonMode, "Error preparing file", err)
+	}
+	est, err := t.EstimatePrepared(context.Background(), prepared)
+	if err != nil {
+		fail(jsonMode, "Error estimating", err)
+	}
+
+	if jsonMode {
+		out, _ := json.MarshalIndent(est, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	basis := "rough guess, no earlier runs of this model and language"
+	if est.Runs > 0 {
+		basis = fmt.Sprintf("corrected with %d earlier runs", est.Runs)
+	}
+	fmt.Printf("%d chunks, ~%d prompt and ~%d completion tokens (%s)\n",
+		len(prepared.Chunks), est.PromptTokens, est.CompletionTokens, basis)
+	fmt.Printf("Chunks of up to %s; each request adds ~%d tokens of instructions\n", est.Sizing, est.Sizing.PromptOverhead)
+	if est.Cost > 0 {
+		fmt.Printf("Estimated cost: $%.4f\n", est.Cost)
+	}
+}
+
+// confirmBudget asks whether to translate a file over --max-cost or
+// --max-chunks anyway. Without a terminal to ask on the file is not
+// translated.
+func confirmBudget(est *translator.Estimate, problem error) bool {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	fmt.Printf("Over budget: %v. Translate anyway? [y/N] ", problem)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		fmt.Println()
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// exportSegments writes segments in the format implied by the extension of
+// path, for import into Crowdin, Weblate or another TMS.
+func exportSegments(path, original, sourceLang, targetLang string, segments []translator.Segment) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xliff", ".xlf":
+		err = translator.WriteXLIFF(f, filepath.Base(original), sourceLang, targetLang, segments)
+	case ".csv":
+		err = translator.WriteCSV(f, segments)
+	default:
+		err = fmt.Errorf("unsupported export format %q, use .xliff, .xlf or .csv", filepath.Ext(path))
+	}
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// tempFiles are removed by exit, since os.Exit skips deferred calls.
+var tempFiles []string
+
+// exit removes the temporary files, which may hold document text, and exits
+// with code.
+func exit(code int) {
+	for _, path := range tempFiles {
+		os.Remove(path)
+	}
+	os.Exit(code)
+}
+
+// writeGitLog dumps the commit log for rangeSpec into a temporary file so it
+// can be translated like any other input. The file is listed in tempFiles;
+// the caller removes it.
+func writeGitLog(rangeSpec string) (string, error) {
+	// A range starting with a dash would be taken for an option of git log,
+	// such as --output.
+	if strings.HasPrefix(rangeSpec, "-") {
+		return "", fmt.Errorf("invalid revision range %q", rangeSpec)
+	}
+
+	cmd := exec.Command("git", "log", "--no-color", "--date=short", rangeSpec)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git log %s failed: %w", rangeSpec, err)
+	}
+
+	f, err := os.CreateTemp("", "go_ai_translate-gitlog-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer f.Close()
+	tempFiles = append(tempFiles, f.Name())
+
+	if _, err := f.Write(out); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write git log: %w", err)
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteGitLogRejectsOptions(t *testing.T) {
+	written := filepath.Join(t.TempDir(), "written")
+	if _, err := writeGitLog("--output=" + written); err == nil {
+		t.Error("Expected a range starting with a dash to be rejected")
+	}
+	if _, err := os.Stat(written); !os.IsNotExist(err) {
+		t.Errorf("Expected git log not to write %s", written)
+	}
+}
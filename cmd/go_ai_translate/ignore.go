@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const ignoreFileName = ".aitranslateignore"
+
+// ignoreRule is one pattern of a .aitranslateignore file, with gitignore
+// semantics: a pattern without a slash matches at any depth below the file's
+// directory, one with a slash is anchored there, a trailing slash matches
+// directories only and a leading ! re-includes what earlier rules ignored.
+type ignoreRule struct {
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	r := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate, line = true, line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			if end := strings.IndexByte(glob[i:], ']'); end > 0 {
+				class := glob[i+1 : i+end]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				b.WriteString("[" + class + "]")
+				i += end
+				continue
+			}
+			b.WriteString(regexp.QuoteMeta("["))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// matches reports whether the rule applies to rel, a slash-separated path
+// relative to the source tree, or to one of its parent directories.
+func (r ignoreRule) matches(rel string) bool {
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+
+	parts := strings.Split(rel, "/")
+	for i := 1; i <= len(parts); i++ {
+		if i == len(parts) && r.dirOnly {
+			break
+		}
+		if r.re.MatchString(strings.Join(parts[:i], "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadIgnoreRules reads every .aitranslateignore file of the source tree,
+// parents before their subdirectories so deeper rules win.
+func loadIgnoreRules(sourceDir string) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != sourceDir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() != ignoreFileName {
+			return nil
+		}
+
+		base, err := filepath.Rel(sourceDir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if base = filepath.ToSlash(base); base == "." {
+			base = ""
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if r, ok := parseIgnoreRule(base, scanner.Text()); ok {
+				rules = append(rules, r)
+			}
+		}
+		return scanner.Err()
+	})
+	return rules, err
+}
+
+// filterIgnored drops the files, relative to sourceDir, that the
+// .aitranslateignore files of the tree exclude, and the ignore files
+// themselves.
+func filterIgnored(sourceDir string, files []string) ([]string, error) {
+	rules, err := loadIgnoreRules(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []string
+	for _, rel := range files {
+		if filepath.Base(rel) == ignoreFileName {
+			continue
+		}
+		ignored := false
+		for _, r := range rules {
+			if r.matches(filepath.ToSlash(rel)) {
+				ignored = !r.negate
+			}
+		}
+		if !ignored {
+			kept = append(kept, rel)
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// langPlaceholder in --output or --sync-target stands for the target
+// language when --to lists several.
+const langPlaceholder = "{lang}"
+
+func outputForLang(template, lang string) string {
+	return strings.Replace(template, langPlaceholder, lang, -1)
+}
+
+// checkLanguages validates config once for every target language, since
+// settings such as the DeepL language code depend on it.
+func checkLanguages(config translator.Config, languages []string) error {
+	for _, lang := range languages {
+		config.ToLang = lang
+		if err := config.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translateLanguages implements --to with several languages for one input
+// file: it is read and split once and translated into each language, the
+// output path following the {lang} template.
+func translateLanguages(t *translator.Translator, languages []string, inputPath, outputTemplate string, jsonMode bool) {
+	if !strings.Contains(outputTemplate, langPlaceholder) || inputPath == "-" {
+		fail(jsonMode, "Error", errors.New("several --to languages need an input file and an --output containing {lang}, e.g. docs/manual.{lang}.md"))
+	}
+	for _, lang := range languages {
+		if dir := filepath.Dir(outputForLang(outputTemplate, lang)); dir != "" && dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				fail(jsonMode, "Error creating output directory", err)
+			}
+		}
+	}
+
+	startTime := time.Now()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	results, err := t.TranslateFileLanguages(ctx, inputPath, languages, func(lang string) string {
+		return outputForLang(outputTemplate, lang)
+	})
+	stop()
+	elapsedTime := time.Since(startTime)
+
+	if jsonMode {
+		printLanguagesReport(results, elapsedTime, err)
+		if err != nil {
+			exit(1)
+		}
+		return
+	}
+
+	for i, r := range results {
+		if i < len(results)-1 || err == nil {
+			fmt.Printf("Translated to %s: %s\n", languages[i], r.Output)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error translating file: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("Translation into %d languages completed in %v\n", len(languages), elapsedTime.Round(time.Second))
+}
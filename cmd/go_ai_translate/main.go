@@ -0,0 +1,678 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "verify":
+			runVerify(os.Args[2:])
+			return
+		case "flush":
+			runFlush(os.Args[2:])
+			return
+		case "export-state":
+			runExportState(os.Args[2:])
+			return
+		case "import-state":
+			runImportState(os.Args[2:])
+			return
+		case "scrub":
+			runScrub(os.Args[2:])
+			return
+		case "audit-verify":
+			runAuditVerify(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "strip-annotations":
+			runStripAnnotations(os.Args[2:])
+			return
+		case "prompt-test":
+			runPromptTest(os.Args[2:])
+			return
+		}
+	}
+
+	inputFile := flag.String("input", "", "Input file to translate (required)")
+	outputFile := flag.String("output", "", "Output file for translation (required)")
+	toLang := flag.String("to", translator.DefaultToLang, "Target language (default: russian); a comma-separated list translates into each, with {lang} in --output or --sync-target naming the outputs")
+	fromLang := flag.String("from", "", "Source language named in the prompt (default: detected from the first chunks)")
+	apiKey := flag.String("api-key", os.Getenv("OPENROUTER_API_KEY"), "OpenRouter API key (default from env OPENROUTER_API_KEY)")
+	chunkSize := flag.Int("chunk-size", translator.DefaultChunkSize, "Size of text chunks in tokens (default: 500)")
+	maxCost := flag.Float64("max-cost", 0, "Stop before translating a file whose estimated cost in USD is higher, or ask when run in a terminal")
+	maxChunks := flag.Int("max-chunks", 0, "Stop before translating a file with more chunks, or ask when run in a terminal")
+	chunkMargin := flag.Float64("chunk-margin", translator.DefaultChunkMargin, "Share of --chunk-size held back when filling chunks, for token counts that come out low; --dry-run shows the resulting size")
+	chunking := flag.String("chunking", "", "How text is split into chunks: paragraph, markdown (default: markdown for Markdown files, paragraph otherwise)")
+	model := flag.String("model", translator.DefaultModel, "Model to use for translation (default: deepseek/deepseek-chat); a comma-separated list adds fallback models")
+	backend := flag.String("provider", "openrouter", "Backend to send chunks to: openrouter, ollama (a local Ollama server, no API key needed), deepl (key from --api-key or DEEPL_AUTH_KEY) (default: openrouter)")
+	concurrency := flag.Int("concurrency", 1, "Number of chunks translated at the same time (default: 1)")
+	schedule := flag.String("schedule", "fifo", "Order chunks are handed to concurrent workers: fifo, largest-first (default: fifo)")
+	baseURL := flag.String("base-url", "", "OpenRouter compatible API base URL (default: https://openrouter.ai/api/v1)")
+	raceModel := flag.String("race-model", "", "Send every chunk to this model as well and keep the first valid answer (costs more, finishes sooner)")
+	delay := flag.Duration("delay", 0, "Pause between chunk requests, e.g. 500ms (default: grows with chunk size, up to 1.5s)")
+	noDelay := flag.Bool("no-delay", false, "Send chunk requests without any pause, e.g. for local models")
+	rateLimit := flag.String("rate-limit", "", "Most chunk requests to send, retries included, e.g. 20/min or 2/s, spaced evenly across --concurrency workers; replaces the pause that grows with chunk size (default: none)")
+	runUntil := flag.String("run-until", "", "Pause at the next chunk boundary after this local time, e.g. 23:00, and queue the rest for flush")
+	maxDuration := flag.Duration("max-duration", 0, "Pause at the next chunk boundary after running this long, e.g. 2h, and queue the rest for flush")
+	batchAPI := flag.String("batch-api", "", "Submit all chunks through the provider's batch API (openai, anthropic); cheaper but can take hours. Use the provider's API key")
+	batchPoll := flag.Duration("batch-poll-interval", 30*time.Second, "How often to check a submitted batch (default: 30s)")
+	hedgeDelay := flag.Duration("hedge-delay", 0, "Send a chunk request again if no response arrived within this time, e.g. 20s, and use the first answer")
+	warmUp := flag.Bool("warm-up", false, "Open the API connection before the first chunk is sent")
+	preferFree := flag.Bool("prefer-free", false, "Pick a free model from OpenRouter and fall back to --model on repeated rate limits")
+	modelProfiles := flag.String("model-profiles", "", "JSON file with per-model request profiles overriding the built-in ones")
+	tmPath := flag.String("tm", "", "Translation memory file (JSON lines); similar earlier translations are added to prompts as references")
+	tmThreshold := flag.Float64("tm-threshold", 0.85, "Minimum embedding similarity for translation memory references (default: 0.85)")
+	embeddingsModel := flag.String("embeddings-model", "openai/text-embedding-3-small", "Embeddings model used for the translation memory")
+	verbose := flag.Bool("verbose", false, "Enable verbose logging")
+	maxRetries := flag.Int("max-retries", 3, "Default number of retries per chunk for each kind of failure, network, HTTP and extraction, so a chunk may be retried up to three times as often in all (default: 3)")
+	networkRetries := flag.Int("network-retries", 0, "Retries per chunk after connection failures; -1 disables (default: --max-retries)")
+	httpRetries := flag.Int("http-retries", 0, "Retries per chunk after HTTP and API errors; -1 disables (default: --max-retries)")
+	extractionRetries := flag.Int("extraction-retries", 0, "Retries per chunk when the answer has no <result> tag; -1 disables (default: --max-retries)")
+	retryBackoff := flag.Duration("retry-backoff", 2*time.Second, "Pause before the first retry, doubled after each retry with random jitter (default: 2s)")
+	maxBackoff := flag.Duration("max-backoff", time.Minute, "Longest pause between retries; a Retry-After of a rate limit is waited for in full (default: 1m)")
+	format := flag.String("format", "auto", "Input format: auto, text, typst, quarto, yaml, changelog, markdown (default: auto, by file extension)")
+	progressFD := flag.Int("progress-fd", 0, "Write newline-delimited JSON progress events to this file descriptor")
+	progressFile := flag.String("progress-file", "", "Write newline-delimited JSON progress events to this file or named pipe")
+	gitLog := flag.String("git-log", "", "Translate the git commit log for this revision range instead of an input file")
+	jsonOutput := flag.Bool("json", false, "Print a single JSON object describing the result instead of human-readable output")
+	ciMode := flag.Bool("ci", false, "Emit GitHub Actions annotations and a job summary, and exit with status 2 on validation warnings")
+	syncSource := flag.String("sync-source", "", "Source docs directory; regenerate localized copies of its files under --sync-target")
+	syncTarget := flag.String("sync-target", "", "Target directory for localized copies in sync mode")
+	changedSince := flag.String("changed-since", "", "In sync mode, only translate files changed since this git revision")
+	mergeSmall := flag.Bool("merge-small", false, "In sync mode, join small text and Markdown files into requests of up to a chunk and split the translation back into their outputs")
+	structureReport := flag.String("structure-report", "", "Write an HTML report comparing the headings, lists, code blocks and links of the input and the translation to this file")
+	exportFile := flag.String("export", "", "Write translated and untranslated segments for post-editing to this .xliff/.xlf or .csv file")
+	exportSourceLang := flag.String("export-source-lang", "en", "Source language code written to XLIFF exports (default: en)")
+	dedupe := flag.Bool("dedupe", false, "In sync mode, translate near-duplicate paragraphs across files once and reuse the translation")
+	dedupeThreshold := flag.Float64("dedupe-threshold", 0.97, "Minimum embedding similarity for paragraphs to count as duplicates (default: 0.97)")
+	queue := flag.Bool("queue", false, "Chunk the input and queue it locally instead of translating; send queued jobs later with the flush subcommand")
+	queueDir := flag.String("queue-dir", defaultQueueDir(), "Directory holding queued translation jobs")
+	encrypt := flag.Bool("encrypt", false, "Encrypt the translation memory and queued jobs at rest; the passphrase comes from GO_AI_TRANSLATE_STORAGE_KEY or the system keyring")
+	manifest := flag.Bool("manifest", false, "Write a manifest of every chunk's offsets, hashes and status next to the output file, named after it with .chunks.json; a rerun keeps the chunks it lists as done")
+	auditPath := flag.String("audit", "", "Append a tamper-evident hash chain of every API request to this file; check it with audit-verify")
+	noPersist := flag.Bool("no-persist", false, "Never store document text locally: the translation memory is only read, and queueing and the dedupe report are disabled")
+	glossary := flag.String("glossary", "", "CSV file of source terms and the translations the model must use (source,target per row); missing terms are reported as warnings")
+	systemPrompt := flag.String("system-prompt", "", "Text opening the system message of every request, e.g. the audience or tone; {to} stands for the target language")
+	temperature := flag.Float64("temperature", 0, "Sampling temperature of every request (default: the model profile's, else the provider's)")
+	topP := flag.Float64("top-p", 0, "Nucleus sampling top_p of every request (default: the provider's)")
+	maxTokens := flag.Int("max-tokens", 0, "Maximum tokens of each answer (default: the provider's)")
+	seed := flag.Int("seed", 0, "Sampling seed of every request, for providers that honor it (default: none)")
+	annotate := flag.Bool("annotate", false, "Wrap every translated chunk in <!-- seg:N conf:X --> review markers with its confidence; remove them with strip-annotations")
+	stream := flag.Bool("stream", false, "Stream answers from OpenRouter and report them in chunk_delta progress events (printed as they arrive with --verbose) and write them into the output file, replaced by the chunk once it is complete and validated")
+	deterministic := flag.Bool("deterministic", false, "Reproducible output: temperature 0, a fixed seed, one chunk at a time, and the run's inputs recorded in the JSON result and sync manifest")
+	dryRun := flag.Bool("dry-run", false, "Only print the expected token usage and cost of translating the input")
+	showRequest := flag.Bool("show-request", false, "Only print the API request of every chunk exactly as it would be sent, with the API key redacted")
+	noExternalMetadata := flag.Bool("no-external-metadata", false, "Send nothing but the translation request: no HTTP-Referer and X-Title headers, run and request IDs or batch metadata")
+	estimatesPath := flag.String("estimates", defaultEstimatesPath(), "File where estimates are compared with actual usage to correct future estimates")
+	cachePath := flag.String("cache", defaultCachePath(), "File of translated chunks reused by later runs with the same model, target language and instructions")
+	noCache := flag.Bool("no-cache", false, "Translate every chunk again instead of reusing cached translations")
+	selectSection := flag.String("select", "", "Translate only the sections under a matching heading, e.g. \"heading:Installation\"; the rest is written through unchanged")
+	selectRegex := flag.String("select-regex", "", "Translate only the sections whose heading matches this regular expression")
+	icu := flag.Bool("icu", false, "Treat the input as ICU MessageFormat strings: retry answers with broken syntax or renamed arguments, and keep the source when retries fail")
+	contextSentences := flag.Int("context-sentences", 0, "Give the model the last N sentences of the previous chunk and of its translation as context (0: none)")
+	summary := flag.Bool("summary", false, "Keep a running summary of the document in the prompts so names and pronouns stay consistent (one extra request per chunk)")
+	rewrap := flag.Bool("rewrap", false, "Join hard-wrapped lines, e.g. of books wrapped at 72 columns, into paragraphs before chunking")
+	imageText := flag.String("image-text", "", "Send the images of a Markdown or HTML document to a vision model: alt (translated alt texts), appendix (translated image text at the end)")
+	visionModel := flag.String("vision-model", "", "Model that reads the images for --image-text (default: --model)")
+	splitModel := flag.String("split-model", "", "Model asked for clause boundaries to cut paragraphs without sentence breaks that exceed a chunk (default: cut by length)")
+	ocr := flag.String("ocr", "", "Read PDF and image inputs with OCR before translating them as Markdown: tesseract (needs tesseract and pdftoppm), vision (uses --vision-model)")
+	via := flag.String("via", "", "Convert the input to Markdown for translation and back to the output's format: pandoc")
+	viaTo := flag.String("via-to-md", "", "Command converting {input} to Markdown {output} for --via (default: pandoc {input} -t gfm --wrap=none -o {output})")
+	viaFrom := flag.String("via-from-md", "", "Command converting Markdown {input} to {output} for --via (default: pandoc {input} -f gfm -o {output})")
+	finalNewline := flag.Bool("final-newline", false, "End output files with exactly one newline")
+	var postCommands stringList
+	flag.Var(&postCommands, "post-command", "Command run on the finished output file, e.g. \"prettier --write {output}\"; repeatable")
+	speechModel := flag.String("speech-model", "", "Read the translation aloud with this text-to-speech model, e.g. tts-1, into an MP3 per chapter")
+	speechVoice := flag.String("speech-voice", "alloy", "Voice for --speech-model")
+	speechURL := flag.String("speech-url", "", "OpenAI compatible speech endpoint (default: --base-url + /audio/speech, or OpenAI's)")
+	speechKey := flag.String("speech-key", os.Getenv("OPENAI_API_KEY"), "API key of the speech endpoint (default from env OPENAI_API_KEY, else --api-key)")
+	ocrLangs := flag.String("ocr-lang", "", "Languages tesseract reads, e.g. eng+deu (default: tesseract's)")
+	maskCode := flag.Bool("mask-code", false, "Keep fenced code blocks and inline code away from the model in every format")
+	var protectRegex stringList
+	flag.Var(&protectRegex, "protect-regex", "Regular expression for text copied verbatim, e.g. URLs or ticket IDs; repeatable")
+	typography := flag.Bool("typography", false, "Set quotation marks, ellipses and punctuation spacing the way the target language does")
+	noSanityCheck := flag.Bool("no-sanity-check", false, "Do not re-request chunks whose translation is empty, far off the source's length or left untranslated")
+	noStructuredOutput := flag.Bool("no-structured-output", false, "Ask models with JSON mode for the <result> tag rather than a JSON object")
+	pipelineFile := flag.String("pipeline", "", "YAML file declaring the stages of the translation: mask, mt-draft, llm-postedit, validate, typography")
+	abbreviationsFile := flag.String("abbreviations-file", "", "JSON file adding abbreviations per language that do not end a sentence, e.g. {\"de\": [\"Abs.\"]}")
+	typographyFile := flag.String("typography-file", "", "JSON file overriding the typography tables per language; implies --typography")
+	frontMatterKeys := flag.String("front-matter-keys", "", "Comma-separated front matter keys of Markdown and Quarto files whose values are translated, e.g. title,description (default: none)")
+	yamlKeys := flag.String("yaml-keys", "", "Comma-separated YAML keys whose values are translated along with comments (default: description,summary,message)")
+
+	presetName := flag.String("preset", "", "Named bundle of settings, e.g. book-ru, docs-de-cheap or subtitles; flags given on the command line win")
+	presetsFile := flag.String("presets", "", "YAML file with more presets (default: presets.yaml in the user config directory's go_ai_translate folder)")
+	configFile := flag.String("config", "", "YAML file of default flag values such as api-key, model or glossary; flags and presets win over it, it wins over environment variables (default: config.yaml in the user config directory's go_ai_translate folder)")
+
+	flag.Parse()
+	reserveStdout(*outputFile)
+
+	// Flags on the command line win over a preset, which wins over the
+	// config file, which wins over environment variables.
+	if err := applyEnv(flag.CommandLine, "config"); err != nil {
+		fail(*jsonOutput, "Error reading environment", err)
+	}
+	configPath := *configFile
+	if configPath == "" {
+		configPath = defaultConfigPath()
+	}
+	fileConfig, err := loadConfig(configPath, *configFile != "")
+	if err != nil {
+		fail(*jsonOutput, "Error reading config file", err)
+	}
+	if err := applyConfig(flag.CommandLine, fileConfig, "preset", "presets"); err != nil {
+		fail(*jsonOutput, "Error reading config file", fmt.Errorf("%s: %w", configPath, err))
+	}
+	if err := applyEnv(flag.CommandLine, "preset", "presets"); err != nil {
+		fail(*jsonOutput, "Error reading environment", err)
+	}
+	if *presetName != "" {
+		path := *presetsFile
+		if path == "" {
+			path = defaultPresetsPath()
+		}
+		if err := applyPreset(*presetName, path, *presetsFile != ""); err != nil {
+			fail(*jsonOutput, "Error applying preset", err)
+		}
+	}
+	if err := applyConfig(flag.CommandLine, fileConfig); err != nil {
+		fail(*jsonOutput, "Error reading config file", fmt.Errorf("%s: %w", configPath, err))
+	}
+	if err := applyEnv(flag.CommandLine); err != nil {
+		fail(*jsonOutput, "Error reading environment", err)
+	}
+	reserveStdout(*outputFile)
+	// DEEPL_AUTH_KEY wins over OPENROUTER_API_KEY, not over an API key
+	// given by a flag, preset, the config file or GO_AI_TRANSLATE_API_KEY.
+	apiKeyGiven := isFlagSet("api-key")
+
+	if *gitLog != "" {
+		logFile, err := writeGitLog(*gitLog)
+		if err != nil {
+			fail(*jsonOutput, "Error reading git log", err)
+		}
+		defer os.Remove(logFile)
+
+		*inputFile = logFile
+		if *format == "auto" {
+			*format = "changelog"
+		}
+	}
+
+	missingPaths := *inputFile == "" || *outputFile == ""
+	if *syncSource != "" {
+		missingPaths = *syncTarget == ""
+	} else if *dryRun || *showRequest {
+		missingPaths = *inputFile == ""
+	}
+
+	switch *backend {
+	case translator.BackendOpenRouter, translator.BackendOllama:
+	case translator.BackendDeepL:
+		if key := os.Getenv("DEEPL_AUTH_KEY"); key != "" && !apiKeyGiven {
+			*apiKey = key
+		}
+	default:
+		fail(*jsonOutput, "Error", fmt.Errorf("unknown provider %q", *backend))
+	}
+
+	if missingPaths || (*apiKey == "" && !*queue && !*dryRun && !*showRequest && *backend != translator.BackendOllama && *pipelineFile == "") {
+		if *jsonOutput {
+			fail(true, "", errors.New("input file, output file, and API key are required"))
+		}
+		fmt.Println("Error: input file, output file, and API key are required")
+		flag.Usage()
+		exit(1)
+	}
+
+	languages := splitList(*toLang)
+	if len(languages) == 0 {
+		languages = []string{""}
+	}
+
+	config := translator.Config{
+		APIKey:             *apiKey,
+		ToLang:             languages[0],
+		FromLang:           *fromLang,
+		ChunkSize:          *chunkSize,
+		Model:              *model,
+		Verbose:            *verbose,
+		MaxRetries:         *maxRetries,
+		Format:             *format,
+		PreferFree:         *preferFree,
+		RaceModel:          *raceModel,
+		HedgeDelay:         *hedgeDelay,
+		BatchAPI:           *batchAPI,
+		WarmUp:             *warmUp,
+		Concurrency:        *concurrency,
+		Schedule:           *schedule,
+		Delay:              *delay,
+		NoDelay:            *noDelay,
+		BaseURL:            *baseURL,
+		TMPath:             *tmPath,
+		TMThreshold:        *tmThreshold,
+		EmbeddingsModel:    *embeddingsModel,
+		YAMLKeys:           splitList(*yamlKeys),
+		FrontMatterKeys:    splitList(*frontMatterKeys),
+		MaskCode:           *maskCode,
+		ProtectPatterns:    protectRegex,
+		NoSanityCheck:      *noSanityCheck,
+		NoStructuredOutput: *noStructuredOutput,
+		NoExternalMetadata: *noExternalMetadata,
+		Typography:         *typography || *typographyFile != "",
+		Select:             *selectSection,
+		SelectRegex:        *selectRegex,
+		Chunking:           *chunking,
+		Rewrap:             *rewrap,
+		ContextSentences:   *contextSentences,
+		Summary:            *summary,
+		ImageText:          *imageText,
+		VisionModel:        *visionModel,
+		SplitModel:         *splitModel,
+		OCR:                *ocr,
+		OCRLanguages:       *ocrLangs,
+		Via:                *via,
+		ViaToMarkdown:      *viaTo,
+		ViaFromMarkdown:    *viaFrom,
+		FinalNewline:       *finalNewline,
+		PostCommands:       postCommands,
+		SpeechModel:        *speechModel,
+		SpeechVoice:        *speechVoice,
+		SpeechURL:          *speechURL,
+		SpeechAPIKey:       *speechKey,
+	}
+	if models := splitList(*model); len(models) > 1 {
+		config.Model, config.FallbackModels = models[0], models[1:]
+	}
+	config.BatchPollInterval = *batchPoll
+	config.Backend = *backend
+	config.Retry = translator.RetryPolicy{
+		Network:    *networkRetries,
+		HTTP:       *httpRetries,
+		Extraction: *extractionRetries,
+		Backoff:    *retryBackoff,
+		MaxBackoff: *maxBackoff,
+	}
+
+	config.NoPersist = *noPersist
+	if config.RateLimit, err = parseRateLimit(*rateLimit); err != nil {
+		fail(*jsonOutput, "Error", err)
+	}
+	until, err := runDeadline(*runUntil, *maxDuration, time.Now())
+	if err != nil {
+		fail(*jsonOutput, "Error", err)
+	}
+	config.Until = until
+	config.AuditPath = *auditPath
+	config.Manifest = *manifest
+	config.EstimatesPath = *estimatesPath
+	config.Deterministic = *deterministic
+	config.Stream = *stream
+	config.Annotate = *annotate
+	config.ICU = *icu
+	config.SystemPrompt = *systemPrompt
+	config.MaxCost = *maxCost
+	config.MaxChunks = *maxChunks
+	if !*jsonOutput && *inputFile != "-" {
+		config.ConfirmBudget = confirmBudget
+	}
+	if isFlagSet("chunk-margin") {
+		config.ChunkMargin = chunkMargin
+	}
+	if isFlagSet("temperature") {
+		config.Temperature = temperature
+	}
+	if isFlagSet("top-p") {
+		config.TopP = topP
+	}
+	if isFlagSet("seed") {
+		config.Seed = seed
+	}
+	config.MaxTokens = *maxTokens
+	if *stream && *verbose {
+		config.Progress = func(ev translator.ProgressEvent) {
+			if ev.Event == "chunk_delta" {
+				fmt.Print(ev.Text)
+			}
+		}
+	}
+
+	// Without --encrypt the key from the environment only reads what an
+	// encrypted run stored; new records stay in the clear.
+	readKey := config.StorageKey
+	if *encrypt {
+		key, err := requireStorageKey()
+		if err != nil {
+			fail(*jsonOutput, "Error reading storage key", err)
+		}
+		config.StorageKey, readKey = key, key
+	} else if passphrase := os.Getenv(translator.StorageKeyEnv); passphrase != "" {
+		readKey = translator.StorageKey(passphrase)
+		config.ReadKey = readKey
+	}
+
+	if !*noCache && !*noPersist && *cachePath != "" {
+		cache, err := translator.OpenFileCacheKeys(*cachePath, readKey, config.StorageKey)
+		if err != nil {
+			fail(*jsonOutput, "Error opening cache", err)
+		}
+		config.Cache = cache
+	}
+
+	if *glossary != "" {
+		terms, err := translator.LoadGlossary(*glossary)
+		if err != nil {
+			fail(*jsonOutput, "Error loading glossary", err)
+		}
+		config.Glossary = terms
+	}
+
+	if *typographyFile != "" {
+		overrides, err := translator.LoadTypography(*typographyFile)
+		if err != nil {
+			fail(*jsonOutput, "Error loading typography", err)
+		}
+		config.TypographyOverrides = overrides
+	}
+
+	if *abbreviationsFile != "" {
+		abbreviations, err := translator.LoadAbbreviations(*abbreviationsFile)
+		if err != nil {
+			fail(*jsonOutput, "Error loading abbreviations", err)
+		}
+		config.Abbreviations = abbreviations
+	}
+
+	if *modelProfiles != "" {
+		profiles, err := translator.LoadModelProfiles(*modelProfiles)
+		if err != nil {
+			fail(*jsonOutput, "Error loading model profiles", err)
+		}
+		config.ModelProfiles = profiles
+	}
+
+	if *pipelineFile != "" {
+		pipeline, err := translator.LoadPipeline(*pipelineFile)
+		if err == nil {
+			config, err = pipeline.Configure(config)
+		}
+		if err != nil {
+			fail(*jsonOutput, "Error loading pipeline", err)
+		}
+	}
+
+	if err := checkLanguages(config, languages); err != nil {
+		fail(*jsonOutput, "Error", err)
+	}
+	if len(languages) > 1 {
+		switch {
+		case *queue, *dryRun, *showRequest, *exportFile != "", *structureReport != "", *ciMode:
+			fail(*jsonOutput, "Error", errors.New("--queue, --dry-run, --show-request, --export, --structure-report and --ci need a single --to language"))
+		case *syncSource != "" && !strings.Contains(*syncTarget, langPlaceholder):
+			fail(*jsonOutput, "Error", errors.New("several --to languages need a --sync-target containing {lang}, e.g. docs/{lang}"))
+		}
+	}
+
+	if *progressFD > 0 {
+		config.ProgressOutput = os.NewFile(uintptr(*progressFD), "progress")
+	} else if *progressFile != "" {
+		f, err := os.OpenFile(*progressFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			fail(*jsonOutput, "Error opening progress file", err)
+		}
+		defer f.Close()
+		config.ProgressOutput = f
+	}
+
+	if *verbose {
+		fmt.Printf("Configuration:\n")
+		fmt.Printf("  To language: %s\n", strings.Join(languages, ", "))
+		fmt.Printf("  Chunk size: %d tokens\n", *chunkSize)
+		fmt.Printf("  Model: %s\n", *model)
+		fmt.Printf("  Max retries: %d\n", *maxRetries)
+		fmt.Printf("  Format: %s\n", *format)
+	}
+
+	t := translator.NewTranslator(config)
+
+	if *dryRun {
+		printEstimate(t, *inputFile, *jsonOutput)
+		return
+	}
+	if *showRequest {
+		printRequests(t, *inputFile, *jsonOutput)
+		return
+	}
+
+	if *queue {
+		if *noPersist {
+			fail(*jsonOutput, "Error queueing file", errors.New("--queue stores document text and cannot be used with --no-persist"))
+		}
+		path, err := enqueueFile(t, config, *queueDir, *inputFile, *outputFile)
+		if err != nil {
+			fail(*jsonOutput, "Error queueing file", err)
+		}
+		fmt.Printf("Queued %s as %s\n", *inputFile, path)
+		return
+	}
+
+	if *syncSource != "" {
+		threshold := 0.0
+		if *dedupe {
+			threshold = *dedupeThreshold
+		}
+		for _, lang := range languages {
+			langConfig := config
+			langConfig.ToLang = lang
+			runSync(t.Derive(langConfig), langConfig, *syncSource, outputForLang(*syncTarget, lang), *changedSince, threshold, *mergeSmall, *jsonOutput)
+		}
+		return
+	}
+
+	if len(languages) > 1 {
+		translateLanguages(t, languages, *inputFile, *outputFile, *jsonOutput)
+		return
+	}
+
+	outputDir := filepath.Dir(*outputFile)
+	if *outputFile != "-" && outputDir != "" && outputDir != "." {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			fail(*jsonOutput, "Error creating output directory", err)
+		}
+	}
+
+	if *verbose {
+		fmt.Printf("Starting translation of %s to %s...\n", *inputFile, *outputFile)
+	}
+
+	startTime := time.Now()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if *inputFile == "-" || *outputFile == "-" {
+		err = translateStdio(ctx, t, *inputFile, *outputFile)
+	} else {
+		err = t.TranslateFileContext(ctx, *inputFile, *outputFile)
+	}
+	stop()
+	elapsedTime := time.Since(startTime)
+
+	var exportErr error
+	if *exportFile != "" {
+		exportErr = exportSegments(*exportFile, *inputFile, *exportSourceLang, *toLang, t.Result().Segments)
+		if exportErr != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting segments: %v\n", exportErr)
+		}
+	}
+
+	if *structureReport != "" && err == nil && *inputFile != "-" && *outputFile != "-" {
+		report, reportErr := writeStructureReport(*structureReport, *inputFile, *outputFile)
+		if reportErr != nil {
+			fmt.Fprintf(os.Stderr, "Error writing structure report: %v\n", reportErr)
+			exportErr = reportErr
+		} else if !*jsonOutput {
+			fmt.Printf("Structure report with %d differences written to %s\n", report.Problems(), *structureReport)
+		}
+	}
+
+	if *ciMode {
+		printCIAnnotations(t.Result())
+		if summaryErr := writeCIJobSummary(t.Result(), elapsedTime, err); summaryErr != nil {
+			fmt.Fprintf(os.Stderr, "Error writing job summary: %v\n", summaryErr)
+		}
+	}
+
+	if *jsonOutput {
+		printJSONReport(t.Result(), elapsedTime, err)
+	} else if err != nil && translator.ErrorClass(err) != translator.ErrorClassPaused {
+		fmt.Printf("Error translating file: %v\n", err)
+	}
+
+	if translator.ErrorClass(err) == translator.ErrorClassPaused {
+		checkpoint := t.Result().Checkpoint
+		if checkpoint == nil || *outputFile == "-" || *noPersist {
+			fmt.Fprintln(os.Stderr, "The paused run cannot be resumed: it has no output file or --no-persist is set")
+			exit(1)
+		}
+		if checkpoint.Input != "" {
+			checkpoint.Input, _ = filepath.Abs(checkpoint.Input)
+		}
+		path, saveErr := queuePrepared(config, *queueDir, checkpoint, *outputFile)
+		if saveErr != nil {
+			fmt.Fprintf(os.Stderr, "Error saving checkpoint: %v\n", saveErr)
+			exit(1)
+		}
+		if !*jsonOutput {
+			fmt.Printf("Paused after %d of %d chunks; the rest is queued as %s, resume with flush\n",
+				checkpoint.Done, len(checkpoint.Chunks), path)
+		}
+		exit(3)
+	}
+
+	if err != nil || exportErr != nil {
+		exit(1)
+	}
+
+	if !*jsonOutput && *outputFile != "-" {
+		fmt.Printf("Translation completed successfully in %v. Output written to %s\n",
+			elapsedTime.Round(time.Second), *outputFile)
+		if r := t.Result(); r.Estimate != nil && r.PromptTokens > 0 {
+			fmt.Printf("Used %d prompt and %d completion tokens, estimated %d and %d\n",
+				r.PromptTokens, r.CompletionTokens, r.Estimate.PromptTokens, r.Estimate.CompletionTokens)
+		}
+	}
+
+	if *ciMode && len(t.Result().Warnings) > 0 {
+		exit(2)
+	}
+}
+
+// translationOut is the standard output, where --output - writes the
+// translation.
+var translationOut = os.Stdout
+
+// reserveStdout leaves the standard output to the translation when
+// outputPath is "-": status lines, verbose output, the JSON report and
+// errors go to stderr instead.
+func reserveStdout(outputPath string) {
+	if outputPath == "-" {
+		os.Stdout = os.Stderr
+	}
+}
+
+// translateStdio handles "-" as input or output path, meaning standard input
+// and standard output.
+func translateStdio(ctx context.Context, t *translator.Translator, inputPath, outputPath string) error {
+	var in io.Reader = os.Stdin
+	if inputPath != "-" {
+		f, err := os.Open(inputPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var out io.Writer = translationOut
+	if outputPath != "-" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	return t.Translate(ctx, in, out)
+}
+
+// parseRateLimit reads a --rate-limit such as 20/min, 2/s or 600/h as
+// requests a minute.
+func parseRateLimit(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	count, unit := s, "min"
+	if i := strings.Index(s, "/"); i >= 0 {
+		count, unit = s[:i], s[i+1:]
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(count), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid --rate-limit %q, use e.g. 20/min", s)
+	}
+	switch strings.TrimSpace(unit) {
+	case "s", "sec":
+		return n * 60, nil
+	case "m", "min":
+		return n, nil
+	case "h":
+		return n / 60, nil
+	}
+	return 0, fmt.Errorf("invalid --rate-limit %q, the unit is s, min or h", s)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// stringList is a flag that may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the command instead of the tests when the test binary is
+// started by runCommand.
+func TestMain(m *testing.M) {
+	if args := os.Getenv("GO_AI_TRANSLATE_TEST_ARGS"); args != "" {
+		os.Args = append([]string{"go_ai_translate"}, strings.Split(args, "\n")...)
+		main()
+		exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCommand runs the command with args and stdin in a child process and
+// returns its standard output, stderr and exit error.
+func runCommand(t *testing.T, stdin string, args ...string) (string, string, error) {
+	home := t.TempDir()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "GO_AI_TRANSLATE_TEST_ARGS="+strings.Join(args, "\n"),
+		"HOME="+home, "XDG_CONFIG_HOME="+home, "XDG_CACHE_HOME="+home)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	return stdout.String(), stderr.String(), err
+}
+
+func TestStdoutHoldsOnlyTheTranslation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"<result>Hallo Welt</result>"}}],"usage":{"prompt_tokens":10,"completion_tokens":3}}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	args := []string{"--input", "-", "--output", "-", "--format", "text",
+		"--api-key", "test-key", "--base-url", server.URL, "--to", "german", "--no-delay",
+		"--cache", filepath.Join(dir, "cache"), "--estimates", filepath.Join(dir, "estimates")}
+	stdout, stderr, err := runCommand(t, "Hello world\n", append(args, "--verbose", "--json")...)
+	if err != nil {
+		t.Fatalf("Command failed: %v\n%s", err, stderr)
+	}
+	if stdout != "Hallo Welt\n" {
+		t.Errorf("Expected the translation alone on standard output, got %q", stdout)
+	}
+	if !strings.Contains(stderr, `"ok": true`) {
+		t.Errorf("Expected the JSON report on stderr, got %q", stderr)
+	}
+
+	// Errors stay off standard output too.
+	refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid API key"}}`, http.StatusUnauthorized)
+	}))
+	defer refusing.Close()
+	stdout, stderr, err = runCommand(t, "Hello world\n", append(args, "--no-cache", "--base-url", refusing.URL, "--max-retries", "1", "--retry-backoff", "1ms")...)
+	if err == nil || stdout != "" || stderr == "" {
+		t.Errorf("Expected the error on stderr alone, got %v, %q and %q", err, stdout, stderr)
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	for value, want := range map[string]float64{"": 0, "20/min": 20, "20": 20, "2/s": 120, "600/h": 10} {
+		if got, err := parseRateLimit(value); err != nil || got != want {
+			t.Errorf("Expected %q to be %v a minute, got %v: %v", value, want, got, err)
+		}
+	}
+	for _, value := range []string{"fast", "0/min", "-1/s", "5/day"} {
+		if _, err := parseRateLimit(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
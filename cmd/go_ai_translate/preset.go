@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// preset holds flag values by flag name; repeatable flags take several.
+type preset map[string][]string
+
+// builtinPresets are the presets --preset knows without a presets file.
+var builtinPresets = map[string]preset{
+	// Fiction into Russian: a strong model that keeps names and voice
+	// consistent over the whole book.
+	"book-ru": {
+		"to":                {"russian"},
+		"model":             {"anthropic/claude-3.5-sonnet"},
+		"rewrap":            {"true"},
+		"summary":           {"true"},
+		"context-sentences": {"3"},
+		"typography":        {"true"},
+		"system-prompt":     {"You translate fiction into {to}. Keep the author's voice, register and rhythm, and render dialogue naturally."},
+	},
+	// Technical documentation into German with a cheap model and the
+	// checks that keep a docs build green.
+	"docs-de-cheap": {
+		"to":                {"german"},
+		"model":             {"deepseek/deepseek-chat"},
+		"chunking":          {"markdown"},
+		"mask-code":         {"true"},
+		"typography":        {"true"},
+		"final-newline":     {"true"},
+		"max-retries":       {"2"},
+		"protect-regex":     {`https?://[^\s)\]>]+`},
+		"system-prompt":     {"You translate software documentation into {to}. Use the formal Sie and the usual German terms of the field, keeping English product names."},
+		"context-sentences": {"1"},
+	},
+	// SRT subtitles: cue numbers and timings are protected, cues stay
+	// short and keep their line breaks.
+	"subtitles": {
+		"chunking":      {"paragraph"},
+		"protect-regex": {`(?m)^\d+\r?$`, `\d{2}:\d{2}:\d{2}[,.]\d{3} --> \d{2}:\d{2}:\d{2}[,.]\d{3}`},
+		"system-prompt": {"You translate subtitles into {to}. Keep every cue as short as the original and keep its line breaks, so it fits on screen and in time."},
+		"final-newline": {"true"},
+	},
+}
+
+// defaultPresetsPath is the presets file read when --presets is not given.
+func defaultPresetsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go_ai_translate", "presets.yaml")
+}
+
+// applyPreset sets the flags of preset name that the command line leaves
+// unset. Presets come from the file at path, when it exists, and the
+// built-in ones; a preset in the file replaces a built-in one of the same
+// name.
+func applyPreset(name, path string, explicit bool) error {
+	presets := map[string]preset{}
+	for n, p := range builtinPresets {
+		presets[n] = p
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			fromFile, err := parsePresets(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			for n, p := range fromFile {
+				presets[n] = p
+			}
+		case !os.IsNotExist(err) || explicit:
+			return err
+		}
+	}
+
+	p, ok := presets[name]
+	if !ok {
+		var names []string
+		for n := range presets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown preset %q, use %s", name, strings.Join(names, ", "))
+	}
+
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	var keys []string
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if flag.Lookup(key) == nil || key == "preset" || key == "presets" {
+			return fmt.Errorf("preset %s: unknown option %q, use the name of a flag without --", name, key)
+		}
+		if set[key] {
+			continue
+		}
+		for _, value := range p[key] {
+			if err := flag.Set(key, value); err != nil {
+				return fmt.Errorf("preset %s: %s: %w", name, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parsePresets reads a presets file, a YAML map of preset names to flag
+// values, with the entries of repeatable flags in a list:
+//
+//	docs-fr:
+//	  to: french
+//	  model: openai/gpt-4o-mini
+//	  typography: true
+//	  protect-regex:
+//	    - 'ACME-[0-9]+'
+func parsePresets(data []byte) (map[string]preset, error) {
+	presets := map[string]preset{}
+	var current preset
+	list := ""
+	for n, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if line[0] != ' ' && line[0] != '\t' {
+			if !strings.HasSuffix(trimmed, ":") {
+				return nil, fmt.Errorf("line %d: expected \"preset-name:\"", n+1)
+			}
+			current, list = preset{}, ""
+			presets[unquoteYAML(strings.TrimSuffix(trimmed, ":"))] = current
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: expected \"preset-name:\"", n+1)
+		}
+
+		if err := current.parseLine(trimmed, &list); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+	}
+	return presets, nil
+}
+
+// parseLine adds the trimmed line "flag: value" to p, or a list item of
+// the repeatable flag in list, which it sets to the flag of a line without
+// a value.
+func (p preset) parseLine(trimmed string, list *string) error {
+	if strings.HasPrefix(trimmed, "- ") {
+		if *list == "" {
+			return errors.New("list item without a flag")
+		}
+		p[*list] = append(p[*list], unquoteYAML(strings.TrimSpace(trimmed[2:])))
+		return nil
+	}
+	i := strings.Index(trimmed, ":")
+	if i < 0 {
+		return errors.New("expected \"flag: value\"")
+	}
+	key, value := strings.TrimPrefix(trimmed[:i], "--"), unquoteYAML(strings.TrimSpace(trimmed[i+1:]))
+	*list = ""
+	if value == "" {
+		*list = key
+		return nil
+	}
+	p[key] = []string{value}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// runPromptTest implements the prompt-test subcommand: it sends the built-in
+// suite of tricky snippets through the current prompt and model and exits
+// non-zero when a translation breaks their structure.
+func runPromptTest(args []string) {
+	fs := flag.NewFlagSet("prompt-test", flag.ExitOnError)
+	fs.String("config", "", configUsage)
+	apiKey := fs.String("api-key", os.Getenv("OPENROUTER_API_KEY"), "OpenRouter API key (default from env OPENROUTER_API_KEY)")
+	toLang := fs.String("to", translator.DefaultToLang, "Target language")
+	fromLang := fs.String("from", "", "Source language named in the prompt (default: detected)")
+	model := fs.String("model", translator.DefaultModel, "Model to test")
+	backend := fs.String("provider", translator.BackendOpenRouter, "Backend to send snippets to: openrouter, ollama, deepl")
+	baseURL := fs.String("base-url", "", "OpenRouter compatible API base URL")
+	systemPrompt := fs.String("system-prompt", "", "Text opening the system message of every request; {to} stands for the target language")
+	temperature := fs.Float64("temperature", 0, "Sampling temperature of every request (default: the model profile's)")
+	show := fs.Bool("show", false, "Print every translation, not only the problems")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	fs.Parse(args)
+	if err := applySettings(fs); err != nil {
+		fmt.Printf("Error reading settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *apiKey == "" && *backend != translator.BackendOllama {
+		fmt.Println("Error: API key is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	config := translator.Config{
+		APIKey:       *apiKey,
+		ToLang:       *toLang,
+		FromLang:     *fromLang,
+		ChunkSize:    translator.DefaultChunkSize,
+		Model:        *model,
+		Backend:      *backend,
+		BaseURL:      *baseURL,
+		SystemPrompt: *systemPrompt,
+		Verbose:      *verbose,
+		NoDelay:      true,
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "temperature" {
+			config.Temperature = temperature
+		}
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	failed := 0
+	results := translator.RunPromptTests(ctx, config, translator.PromptTests)
+	for _, r := range results {
+		if r.Passed() {
+			fmt.Printf("PASS %s\n", r.Name)
+		} else {
+			failed++
+			fmt.Printf("FAIL %s\n", r.Name)
+		}
+		if r.Err != nil {
+			fmt.Printf("  error: %v\n", r.Err)
+		}
+		for _, p := range r.Problems {
+			fmt.Printf("  %s\n", p)
+		}
+		if *show || !r.Passed() {
+			fmt.Printf("  translation:\n%s\n", r.Translation)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d snippets failed with %s\n", failed, len(results), *model)
+		os.Exit(1)
+	}
+	fmt.Printf("All %d snippets passed with %s\n", len(results), *model)
+}
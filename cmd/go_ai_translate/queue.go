@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// queuedJob is a file that was chunked while offline and is waiting to be
+// sent to the API. The API key is never written to disk.
+type queuedJob struct {
+	ID       string                   `json:"id"`
+	Output   string                   `json:"output"`
+	Config   translator.Config        `json:"config"`
+	Prepared *translator.PreparedFile `json:"prepared"`
+}
+
+func defaultQueueDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "go_ai_translate", "queue")
+}
+
+// enqueueFile chunks inputPath locally and stores the job in queueDir so it
+// can be translated later by the flush subcommand.
+func enqueueFile(t *translator.Translator, config translator.Config, queueDir, inputPath, outputPath string) (string, error) {
+	prepared, err := t.Prepare(inputPath)
+	if err != nil {
+		return "", err
+	}
+	prepared.Input, _ = filepath.Abs(prepared.Input)
+	return queuePrepared(config, queueDir, prepared, outputPath)
+}
+
+// queuePrepared stores prepared, e.g. the checkpoint of a paused run, in
+// queueDir as a new job.
+func queuePrepared(config translator.Config, queueDir string, prepared *translator.PreparedFile, outputPath string) (string, error) {
+	output, err := filepath.Abs(outputPath)
+	if err != nil {
+		return "", err
+	}
+
+	input := prepared.Input
+	job := queuedJob{
+		ID:       fmt.Sprintf("%d-%s", time.Now().UnixNano(), strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))),
+		Output:   output,
+		Config:   config,
+		Prepared: prepared,
+	}
+
+	path := filepath.Join(queueDir, job.ID+".json")
+	return path, writeQueuedJob(path, job, config.StorageKey)
+}
+
+// writeQueuedJob writes job to path, encrypted with key if one is set. The
+// API key and the storage key are never written.
+func writeQueuedJob(path string, job queuedJob, key []byte) error {
+	job.Config.APIKey = ""
+	job.Config.StorageKey = nil
+	job.Config.Until = time.Time{}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		if data, err = translator.SealRecord(key, data); err != nil {
+			return err
+		}
+	}
+	return translator.WriteFileAtomic(path, data, 0600)
+}
+
+// decodeQueuedJob parses a job written by writeQueuedJob once it is
+// decrypted.
+func decodeQueuedJob(data []byte) (queuedJob, error) {
+	var job queuedJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return job, err
+	}
+	if job.Prepared == nil {
+		return job, errors.New("the job has no prepared chunks")
+	}
+	return job, nil
+}
+
+// runFlush implements the flush subcommand: it waits for the API to become
+// reachable and translates every queued job in the order it was queued.
+func runFlush(args []string) {
+	fs := flag.NewFlagSet("flush", flag.ExitOnError)
+	fs.String("config", "", configUsage)
+	queueDir := fs.String("queue-dir", defaultQueueDir(), "Directory holding queued translation jobs")
+	apiKey := fs.String("api-key", os.Getenv("OPENROUTER_API_KEY"), "OpenRouter API key (default from env OPENROUTER_API_KEY)")
+	wait := fs.Bool("wait", false, "Keep checking connectivity until the API is reachable instead of exiting")
+	interval := fs.Duration("interval", 30*time.Second, "Time between connectivity checks with --wait")
+	runUntil := fs.String("run-until", "", "Pause at the next chunk boundary after this local time, e.g. 23:00, and keep the job queued")
+	maxDuration := fs.Duration("max-duration", 0, "Pause at the next chunk boundary after running this long, e.g. 2h, and keep the job queued")
+	fs.Parse(args)
+	if err := applySettings(fs); err != nil {
+		fmt.Printf("Error reading settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	until, err := runDeadline(*runUntil, *maxDuration, time.Now())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *apiKey == "" {
+		fmt.Println("Error: API key is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(*queueDir, "*.json"))
+	if err != nil {
+		fmt.Printf("Error reading queue: %v\n", err)
+		os.Exit(1)
+	}
+	sort.Strings(paths)
+	if len(paths) == 0 {
+		fmt.Println("Queue is empty")
+		return
+	}
+
+	failed := 0
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		var key []byte
+		if translator.IsSealed(data) {
+			if key, err = requireStorageKey(); err == nil {
+				data, err = translator.OpenRecord(key, data)
+			}
+			if err != nil {
+				fmt.Printf("Error reading %s: %v\n", path, err)
+				failed++
+				continue
+			}
+		}
+
+		job, err := decodeQueuedJob(data)
+		if err != nil {
+			fmt.Printf("Error reading %s: invalid job: %v\n", path, err)
+			failed++
+			continue
+		}
+
+		job.Config.APIKey = *apiKey
+		job.Config.StorageKey = key
+		job.Config.Until = until
+		t := translator.NewTranslator(job.Config)
+		for {
+			err := t.CheckOnline()
+			if err == nil {
+				break
+			}
+			if !*wait {
+				fmt.Printf("API is not reachable, %d jobs stay queued: %v\n", len(paths)-i, err)
+				os.Exit(1)
+			}
+			time.Sleep(*interval)
+		}
+
+		if dir := filepath.Dir(job.Output); dir != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				fmt.Printf("Error creating output directory: %v\n", err)
+				failed++
+				continue
+			}
+		}
+
+		if err := t.TranslatePrepared(job.Prepared, job.Output); translator.ErrorClass(err) == translator.ErrorClassPaused {
+			job.Prepared = t.Result().Checkpoint
+			if err := writeQueuedJob(path, job, key); err != nil {
+				fmt.Printf("Error saving checkpoint of %s: %v\n", job.Prepared.Input, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Paused %s after %d of %d chunks, %d jobs stay queued\n",
+				job.Prepared.Input, job.Prepared.Done, len(job.Prepared.Chunks), len(paths)-i)
+			os.Exit(3)
+		} else if err != nil {
+			fmt.Printf("Error translating %s: %v\n", job.Prepared.Input, err)
+			failed++
+			continue
+		}
+		os.Remove(path)
+		fmt.Printf("Translated %s -> %s\n", job.Prepared.Input, job.Output)
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d queued jobs failed and stay queued\n", failed, len(paths))
+		os.Exit(1)
+	}
+}
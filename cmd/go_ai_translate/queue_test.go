@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+func TestQueuedJobWithCacheReadsBack(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := translator.OpenFileCache(filepath.Join(dir, "cache.jsonl"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := queuedJob{
+		ID:     "1-book",
+		Output: filepath.Join(dir, "book.ru.txt"),
+		Config: translator.Config{
+			ToLang:         "russian",
+			APIKey:         "secret",
+			Cache:          cache,
+			ProgressOutput: os.Stderr,
+			Progress:       func(ev translator.ProgressEvent) {},
+		},
+		Prepared: &translator.PreparedFile{Input: filepath.Join(dir, "book.txt")},
+	}
+	path := filepath.Join(dir, "queue", job.ID+".json")
+	if err := writeQueuedJob(path, job, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeQueuedJob(data)
+	if err != nil {
+		t.Fatalf("Expected the job to read back, got %v", err)
+	}
+	if got.Config.ToLang != "russian" || got.Config.APIKey != "" || got.Config.Cache != nil || got.Prepared.Input != job.Prepared.Input {
+		t.Errorf("Unexpected job %+v", got)
+	}
+}
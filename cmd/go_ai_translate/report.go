@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+type jsonReport struct {
+	OK bool `json:"ok"`
+	translator.Result
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+	ErrorClass      string  `json:"error_class,omitempty"`
+}
+
+func printJSONReport(result translator.Result, elapsed time.Duration, err error) {
+	report := jsonReport{
+		OK:              err == nil,
+		Result:          result,
+		DurationSeconds: elapsed.Seconds(),
+	}
+	if err != nil {
+		report.Error = err.Error()
+		report.ErrorClass = translator.ErrorClass(err)
+	}
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+}
+
+// languagesReport is the --json result of a run with several --to
+// languages: one entry per language, in the order they were translated.
+type languagesReport struct {
+	OK              bool             `json:"ok"`
+	Languages       []languageReport `json:"languages"`
+	DurationSeconds float64          `json:"duration_seconds"`
+	Error           string           `json:"error,omitempty"`
+	ErrorClass      string           `json:"error_class,omitempty"`
+}
+
+type languageReport struct {
+	OK bool `json:"ok"`
+	translator.Result
+	Error      string `json:"error,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"`
+}
+
+// printLanguagesReport prints the results of a run into several languages as
+// a single JSON object. err, if any, belongs to the last of them.
+func printLanguagesReport(results []translator.Result, elapsed time.Duration, err error) {
+	report := languagesReport{
+		OK:              err == nil,
+		Languages:       []languageReport{},
+		DurationSeconds: elapsed.Seconds(),
+	}
+	for i, r := range results {
+		entry := languageReport{OK: true, Result: r}
+		if i == len(results)-1 && err != nil {
+			entry.OK, entry.Error, entry.ErrorClass = false, err.Error(), translator.ErrorClass(err)
+		}
+		report.Languages = append(report.Languages, entry)
+	}
+	if err != nil {
+		report.Error = err.Error()
+		report.ErrorClass = translator.ErrorClass(err)
+	}
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+}
+
+// fail reports err either as a human-readable message or, in JSON mode, as a
+// failed report, and exits.
+func fail(jsonMode bool, message string, err error) {
+	if jsonMode {
+		printJSONReport(translator.Result{}, 0, err)
+	} else {
+		fmt.Printf("%s: %v\n", message, err)
+	}
+	exit(1)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// captureStdout returns what f prints to standard output.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	f()
+	w.Close()
+	return string(<-done)
+}
+
+func TestLanguagesReportIsOneObject(t *testing.T) {
+	results := []translator.Result{{Output: "doc.de.md"}, {Output: "doc.fr.md"}}
+	out := captureStdout(t, func() {
+		printLanguagesReport(results, 2*time.Second, errors.New("french failed"))
+	})
+
+	var report languagesReport
+	decoder := json.NewDecoder(strings.NewReader(out))
+	if err := decoder.Decode(&report); err != nil {
+		t.Fatalf("Expected a JSON object, got %q: %v", out, err)
+	}
+	if decoder.More() {
+		t.Errorf("Expected a single JSON document, got %q", out)
+	}
+	if report.OK || len(report.Languages) != 2 || !report.Languages[0].OK || report.Languages[1].OK || report.Languages[1].Error != "french failed" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
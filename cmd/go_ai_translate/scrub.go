@@ -0,0 +1,204 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// runScrub implements the scrub subcommand: it deletes stored source and
+// target text that is older than a number of days or belongs to matching
+// documents, from the translation memory, the chunk cache, chunk manifests
+// and the offline queue.
+func runScrub(args []string) {
+	fs := flag.NewFlagSet("scrub", flag.ExitOnError)
+	fs.String("config", "", configUsage)
+	olderThan := fs.Int("older-than", 0, "Remove entries older than this many days")
+	pathPattern := fs.String("path", "", "Remove entries of documents matching this glob or under this directory")
+	tmPath := fs.String("tm", "", "Translation memory file to scrub")
+	cachePath := fs.String("cache", defaultCachePath(), "Chunk cache file to scrub; its entries are shared across documents, so only --older-than applies")
+	manifestDir := fs.String("manifest-dir", "", "Directory searched for chunk manifests (*"+translator.ManifestSuffix+") to scrub")
+	queueDir := fs.String("queue-dir", defaultQueueDir(), "Directory holding queued translation jobs")
+	dryRun := fs.Bool("dry-run", false, "Only report what would be removed")
+	fs.Parse(args)
+	if err := applySettings(fs); err != nil {
+		fmt.Printf("Error reading settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *olderThan <= 0 && *pathPattern == "" {
+		fmt.Println("Error: --older-than or --path is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var cutoff time.Time
+	if *olderThan > 0 {
+		cutoff = time.Now().AddDate(0, 0, -*olderThan)
+	}
+	matches := func(document string, created time.Time) bool {
+		if !cutoff.IsZero() && created.Before(cutoff) {
+			return true
+		}
+		return *pathPattern != "" && document != "" && pathMatches(*pathPattern, document)
+	}
+
+	key, err := storageKey()
+	if err != nil {
+		fmt.Printf("Error reading storage key: %v\n", err)
+		os.Exit(1)
+	}
+
+	verb := "Removed"
+	if *dryRun {
+		verb = "Would remove"
+	}
+
+	if *tmPath != "" {
+		found := 0
+		removed, err := translator.ScrubTranslationMemory(*tmPath, key, func(document string, created time.Time) bool {
+			if !matches(document, created) {
+				return false
+			}
+			found++
+			return !*dryRun
+		})
+		if err != nil {
+			fmt.Printf("Error scrubbing translation memory: %v\n", err)
+			os.Exit(1)
+		}
+		if !*dryRun {
+			found = removed
+		}
+		fmt.Printf("%s %d translation memory entries from %s\n", verb, found, *tmPath)
+	}
+
+	if *olderThan > 0 {
+		found := 0
+		removed, err := translator.ScrubCache(*cachePath, key, func(created time.Time) bool {
+			if !created.Before(cutoff) {
+				return false
+			}
+			found++
+			return !*dryRun
+		})
+		if err != nil {
+			fmt.Printf("Error scrubbing cache: %v\n", err)
+			os.Exit(1)
+		}
+		if !*dryRun {
+			found = removed
+		}
+		fmt.Printf("%s %d cached chunks from %s\n", verb, found, *cachePath)
+	}
+
+	if *manifestDir != "" {
+		manifests, err := scrubManifests(*manifestDir, matches, *dryRun)
+		if err != nil {
+			fmt.Printf("Error scrubbing chunk manifests: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s %d chunk manifests from %s\n", verb, manifests, *manifestDir)
+	}
+
+	jobs, err := scrubQueue(*queueDir, key, matches, *dryRun)
+	if err != nil {
+		fmt.Printf("Error scrubbing queue: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s %d queued jobs from %s\n", verb, jobs, *queueDir)
+}
+
+// scrubQueue removes queued jobs whose input matches. A job's age is the
+// modification time of its file.
+func scrubQueue(queueDir string, key []byte, matches func(string, time.Time) bool, dryRun bool) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(queueDir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return removed, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return removed, err
+		}
+		if data, err = translator.OpenRecord(key, data); err != nil {
+			return removed, fmt.Errorf("%s: %w", path, err)
+		}
+
+		job, err := decodeQueuedJob(data)
+		if err != nil {
+			return removed, fmt.Errorf("%s: invalid job: %w", path, err)
+		}
+		if !matches(job.Prepared.Input, info.ModTime()) {
+			continue
+		}
+
+		removed++
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return removed, err
+			}
+		}
+	}
+	return removed, nil
+}
+
+// scrubManifests removes the chunk manifests under dir whose input matches.
+// A manifest's age is the modification time of its file.
+func scrubManifests(dir string, matches func(string, time.Time) bool, dryRun bool) (int, error) {
+	removed := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, translator.ManifestSuffix) {
+			return nil
+		}
+		manifest, err := translator.ReadChunkManifest(path)
+		if err != nil {
+			return err
+		}
+		if !matches(manifest.Input, info.ModTime()) {
+			return nil
+		}
+
+		removed++
+		if dryRun {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	return removed, err
+}
+
+// pathMatches reports whether document matches the glob pattern or lies
+// under the directory pattern.
+func pathMatches(pattern, document string) bool {
+	if ok, _ := filepath.Match(pattern, document); ok {
+		return true
+	}
+	if ok, _ := filepath.Match(pattern, filepath.Base(document)); ok {
+		return true
+	}
+
+	dir, err := filepath.Abs(pattern)
+	if err != nil {
+		return false
+	}
+	doc, err := filepath.Abs(document)
+	if err != nil {
+		return false
+	}
+	return doc == dir || strings.HasPrefix(doc, dir+string(filepath.Separator))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+func TestScrubManifests(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, input string) string {
+		t.Helper()
+		path := filepath.Join(dir, "out", name+translator.ManifestSuffix)
+		data, _ := json.Marshal(translator.ChunkManifest{Input: input, Output: filepath.Join(dir, "out", name)})
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	private := write("a.md", filepath.Join(dir, "private", "a.md"))
+	public := write("b.md", filepath.Join(dir, "docs", "b.md"))
+
+	matches := func(document string, created time.Time) bool {
+		return pathMatches(filepath.Join(dir, "private"), document)
+	}
+	if n, err := scrubManifests(dir, matches, true); err != nil || n != 1 {
+		t.Fatalf("Expected one manifest found on a dry run, got %d, %v", n, err)
+	}
+	if _, err := os.Stat(private); err != nil {
+		t.Fatal("Expected a dry run to keep the manifest")
+	}
+
+	if n, err := scrubManifests(dir, matches, false); err != nil || n != 1 {
+		t.Fatalf("Expected one manifest removed, got %d, %v", n, err)
+	}
+	if _, err := os.Stat(private); !os.IsNotExist(err) {
+		t.Error("Expected the manifest of the private document to be removed")
+	}
+	if _, err := os.Stat(public); err != nil {
+		t.Error("Expected the other manifest to remain")
+	}
+}
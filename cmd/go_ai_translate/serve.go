@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// runServe implements the serve subcommand: an HTTP server translating
+// snippets synchronously and files as background jobs. All requests share a
+// translator.PriorityGate, so snippets go ahead of file jobs at the next
+// chunk boundary.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.String("config", "", configUsage)
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on")
+	apiKey := fs.String("api-key", os.Getenv("OPENROUTER_API_KEY"), "OpenRouter API key (default from env OPENROUTER_API_KEY)")
+	toLang := fs.String("to", translator.DefaultToLang, "Default target language, overridden by the to query parameter")
+	model := fs.String("model", translator.DefaultModel, "Model to use for translation")
+	chunkSize := fs.Int("chunk-size", translator.DefaultChunkSize, "Size of text chunks in tokens")
+	baseURL := fs.String("base-url", "", "OpenRouter compatible API base URL")
+	slots := fs.Int("slots", 1, "Number of chunk requests sent to the API at the same time across all jobs")
+	maxBody := fs.Int64("max-body", 10<<20, "Largest request body accepted, in bytes")
+	jobTTL := fs.Duration("job-ttl", time.Hour, "How long a finished job is kept when its output is not fetched")
+	fs.Parse(args)
+	if err := applySettings(fs); err != nil {
+		fmt.Printf("Error reading settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *apiKey == "" {
+		fmt.Println("Error: API key is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	s := &server{
+		config: translator.Config{
+			APIKey:     *apiKey,
+			ToLang:     *toLang,
+			ChunkSize:  *chunkSize,
+			Model:      *model,
+			MaxRetries: 3,
+			Format:     "auto",
+			BaseURL:    *baseURL,
+			Gate:       translator.NewPriorityGate(*slots),
+		},
+		jobs:    make(map[string]*serverJob),
+		maxBody: *maxBody,
+		jobTTL:  *jobTTL,
+	}
+
+	fmt.Printf("Listening on %s\n", *addr)
+	if err := http.ListenAndServe(*addr, s.handler()); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+type server struct {
+	config  translator.Config
+	maxBody int64
+	// jobTTL is how long finished jobs are kept. A job whose output was
+	// fetched is dropped at once.
+	jobTTL time.Duration
+
+	mu     sync.Mutex
+	nextID int
+	jobs   map[string]*serverJob
+}
+
+type serverJob struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name,omitempty"`
+	Status   string     `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	output []byte
+}
+
+// namedReader gives the request body a file name, which Translate uses to
+// detect the input format.
+type namedReader struct {
+	io.Reader
+	name string
+}
+
+func (r namedReader) Name() string { return r.name }
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/translate", s.handleTranslate)
+	mux.HandleFunc("/jobs", s.handleSubmit)
+	mux.HandleFunc("/jobs/", s.handleJob)
+	return mux
+}
+
+func (s *server) translatorFor(r *http.Request, priority translator.Priority) *translator.Translator {
+	config := s.config
+	config.Priority = priority
+	if to := r.URL.Query().Get("to"); to != "" {
+		config.ToLang = to
+	}
+	if from := r.URL.Query().Get("from"); from != "" {
+		config.FromLang = from
+	}
+	if format := r.URL.Query().Get("format"); format != "" {
+		config.Format = format
+	}
+	return translator.NewTranslator(config)
+}
+
+// handleTranslate translates the request body as an interactive snippet and
+// answers with the translation.
+func (s *server) handleTranslate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	var out bytes.Buffer
+	t := s.translatorFor(r, translator.PriorityInteractive)
+	in := namedReader{bytes.NewReader(content), r.URL.Query().Get("name")}
+	if err := t.Translate(r.Context(), in, &out); err != nil {
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(out.Bytes())
+}
+
+// handleSubmit starts a background job for the request body and answers with
+// the job, whose status is then polled at /jobs/<id>.
+func (s *server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	t := s.translatorFor(r, translator.PriorityBackground)
+	s.mu.Lock()
+	s.evictJobs(time.Now())
+	s.nextID++
+	job := &serverJob{
+		ID:      strconv.Itoa(s.nextID),
+		Name:    r.URL.Query().Get("name"),
+		Status:  "running",
+		Created: time.Now().UTC(),
+	}
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	go func() {
+		var out bytes.Buffer
+		err := t.Translate(context.Background(), namedReader{bytes.NewReader(content), job.Name}, &out)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		finished := time.Now().UTC()
+		job.Finished = &finished
+		if err != nil {
+			job.Status = "failed"
+			job.Error = err.Error()
+			return
+		}
+		job.Status = "done"
+		job.output = out.Bytes()
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+	s.writeJob(w, job)
+}
+
+// handleJob answers /jobs/<id> with the job status and /jobs/<id>/output
+// with the translation of a finished job.
+func (s *server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	output := strings.HasSuffix(id, "/output")
+	id = strings.TrimSuffix(id, "/output")
+
+	s.mu.Lock()
+	s.evictJobs(time.Now())
+	job, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !output {
+		s.writeJob(w, job)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job.Status != "done" {
+		http.Error(w, "job is "+job.Status, http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(job.output)
+	delete(s.jobs, id)
+}
+
+// readBody reads the request body, answering 413 when it is larger than
+// s.maxBody.
+func (s *server) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBody))
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.Contains(err.Error(), "request body too large") {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return nil, false
+	}
+	return content, true
+}
+
+// evictJobs drops the jobs that finished longer than s.jobTTL before now.
+// The caller holds s.mu.
+func (s *server) evictJobs(now time.Time) {
+	for id, job := range s.jobs {
+		if job.Finished != nil && now.Sub(*job.Finished) > s.jobTTL {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+func (s *server) writeJob(w http.ResponseWriter, job *serverJob) {
+	s.mu.Lock()
+	data, err := json.Marshal(job)
+	s.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+func statusFor(err error) int {
+	switch translator.ErrorClass(err) {
+	case translator.ErrorClassInput:
+		return http.StatusBadRequest
+	case translator.ErrorClassRateLimit:
+		return http.StatusTooManyRequests
+	case translator.ErrorClassNetwork, translator.ErrorClassAPI:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
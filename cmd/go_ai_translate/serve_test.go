@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeRefusesLargeBodies(t *testing.T) {
+	s := &server{jobs: map[string]*serverJob{}, maxBody: 16, jobTTL: time.Hour}
+	for _, path := range []string{"/translate", "/jobs"} {
+		w := httptest.NewRecorder()
+		s.handler().ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(strings.Repeat("a", 17))))
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected %s to refuse the body with 413, got %d", path, w.Code)
+		}
+	}
+	if len(s.jobs) != 0 {
+		t.Errorf("Expected no job for a refused body, got %v", s.jobs)
+	}
+}
+
+func TestServeDropsFinishedJobs(t *testing.T) {
+	s := &server{jobs: map[string]*serverJob{}, maxBody: 16, jobTTL: time.Hour}
+	old := time.Now().Add(-2 * time.Hour)
+	recent := time.Now()
+	s.jobs["1"] = &serverJob{ID: "1", Status: "done", Finished: &old, output: []byte("OLD")}
+	s.jobs["2"] = &serverJob{ID: "2", Status: "done", Finished: &recent, output: []byte("NEW")}
+	s.jobs["3"] = &serverJob{ID: "3", Status: "running"}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handler().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	if w := get("/jobs/1"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected the expired job to be gone, got %d", w.Code)
+	}
+	if w := get("/jobs/2/output"); w.Code != http.StatusOK || w.Body.String() != "NEW" {
+		t.Errorf("Expected the output of the recent job, got %d %q", w.Code, w.Body.String())
+	}
+	if w := get("/jobs/2"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected the job to be dropped once its output was fetched, got %d", w.Code)
+	}
+	if w := get("/jobs/3"); w.Code != http.StatusOK {
+		t.Errorf("Expected the running job to stay, got %d", w.Code)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// printRequests implements --show-request: it chunks inputPath and prints
+// the request of every chunk as it would be sent, without sending it.
+func printRequests(t *translator.Translator, inputPath string, jsonMode bool) {
+	prepared, err := t.Prepare(inputPath)
+	if err != nil {
+		fail(jsonMode, "Error preparing file", err)
+	}
+	previews, err := t.PreviewRequests(context.Background(), prepared)
+	if err != nil {
+		fail(jsonMode, "Error building requests", err)
+	}
+
+	if jsonMode {
+		out, _ := json.MarshalIndent(previews, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	for n, p := range previews {
+		if n > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("# chunk %d\n%s %s\n", p.Chunk, p.Method, p.URL)
+		var names []string
+		for name := range p.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, v := range p.Header[name] {
+				fmt.Printf("%s: %s\n", name, v)
+			}
+		}
+		fmt.Printf("\n%s\n", p.Body)
+	}
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+const stateHeaderName = "state.json"
+
+// stateHeader describes a state bundle. It is the first entry of the archive.
+type stateHeader struct {
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	Queue    []string  `json:"queue,omitempty"`
+	TM       bool      `json:"tm,omitempty"`
+	Cache    bool      `json:"cache,omitempty"`
+	Manifest bool      `json:"manifest,omitempty"`
+	// Chunks lists the chunk manifests by their path under --manifest-dir.
+	Chunks []string `json:"chunks,omitempty"`
+}
+
+// runExportState implements the export-state subcommand: it packs queued
+// jobs, the translation memory, the chunk cache, chunk manifests and the sync
+// manifest into one .tar.gz so work can be moved to another machine and
+// resumed there with import-state.
+func runExportState(args []string) {
+	fs := flag.NewFlagSet("export-state", flag.ExitOnError)
+	fs.String("config", "", configUsage)
+	output := fs.String("output", "", "State bundle to write, e.g. state.tar.gz (required)")
+	queueDir := fs.String("queue-dir", defaultQueueDir(), "Directory holding queued translation jobs")
+	tmPath := fs.String("tm", "", "Translation memory file to include")
+	cachePath := fs.String("cache", defaultCachePath(), "Chunk cache file to include; empty leaves it out")
+	manifestDir := fs.String("manifest-dir", "", "Directory searched for chunk manifests (*"+translator.ManifestSuffix+") to include")
+	syncTarget := fs.String("sync-target", "", "Localized docs directory whose manifest is included")
+	fs.Parse(args)
+	if err := applySettings(fs); err != nil {
+		fmt.Printf("Error reading settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		fmt.Println("Error: --output is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	header, err := exportState(*output, *queueDir, *tmPath, *cachePath, *manifestDir, *syncTarget)
+	if err != nil {
+		fmt.Printf("Error exporting state: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %d queued jobs to %s\n", len(header.Queue), *output)
+}
+
+// runImportState implements the import-state subcommand.
+func runImportState(args []string) {
+	fs := flag.NewFlagSet("import-state", flag.ExitOnError)
+	fs.String("config", "", configUsage)
+	input := fs.String("input", "", "State bundle written by export-state (required)")
+	queueDir := fs.String("queue-dir", defaultQueueDir(), "Directory to add queued translation jobs to")
+	outputDir := fs.String("output-dir", "", "Write the output of imported jobs into this directory instead of their original paths")
+	tmPath := fs.String("tm", "", "Translation memory file to merge the bundled entries into")
+	cachePath := fs.String("cache", defaultCachePath(), "Chunk cache file to merge the bundled chunks into")
+	manifestDir := fs.String("manifest-dir", "", "Directory to restore the bundled chunk manifests into")
+	syncTarget := fs.String("sync-target", "", "Localized docs directory to restore the manifest into")
+	fs.Parse(args)
+	if err := applySettings(fs); err != nil {
+		fmt.Printf("Error reading settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *input == "" {
+		fmt.Println("Error: --input is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	header, err := importState(*input, *queueDir, *outputDir, *tmPath, *cachePath, *manifestDir, *syncTarget)
+	if err != nil {
+		fmt.Printf("Error importing state: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Imported %d queued jobs from %s; run flush to translate them\n", len(header.Queue), *input)
+}
+
+func exportState(output, queueDir, tmPath, cachePath, manifestDir, syncTarget string) (stateHeader, error) {
+	header := stateHeader{Version: 1, Created: time.Now().UTC()}
+	files := map[string][]byte{}
+
+	jobs, err := filepath.Glob(filepath.Join(queueDir, "*.json"))
+	if err != nil {
+		return header, err
+	}
+	sort.Strings(jobs)
+	for _, job := range jobs {
+		data, err := os.ReadFile(job)
+		if err != nil {
+			return header, err
+		}
+		name := filepath.Base(job)
+		header.Queue = append(header.Queue, name)
+		files["queue/"+name] = data
+	}
+
+	if tmPath != "" {
+		data, err := os.ReadFile(tmPath)
+		if err != nil && !os.IsNotExist(err) {
+			return header, err
+		}
+		if err == nil {
+			header.TM = true
+			files["tm.jsonl"] = data
+		}
+	}
+
+	if cachePath != "" {
+		data, err := os.ReadFile(cachePath)
+		if err != nil && !os.IsNotExist(err) {
+			return header, err
+		}
+		if err == nil {
+			header.Cache = true
+			files["cache.jsonl"] = data
+		}
+	}
+
+	if manifestDir != "" {
+		err := filepath.Walk(manifestDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(path, translator.ManifestSuffix) {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(manifestDir, path)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(rel)
+			header.Chunks = append(header.Chunks, name)
+			files["chunks/"+name] = data
+			return nil
+		})
+		if err != nil {
+			return header, err
+		}
+	}
+
+	if syncTarget != "" {
+		data, err := os.ReadFile(filepath.Join(syncTarget, syncManifestName))
+		if err != nil && !os.IsNotExist(err) {
+			return header, err
+		}
+		if err == nil {
+			header.Manifest = true
+			files["manifest.json"] = data
+		}
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return header, err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	headerData, _ := json.MarshalIndent(header, "", "  ")
+	names := []string{stateHeaderName}
+	files[stateHeaderName] = headerData
+	for name := range files {
+		if name != stateHeaderName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+
+	for _, name := range names {
+		data := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: header.Created}); err != nil {
+			return header, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return header, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return header, err
+	}
+	if err := gz.Close(); err != nil {
+		return header, err
+	}
+	return header, f.Close()
+}
+
+func importState(input, queueDir, outputDir, tmPath, cachePath, manifestDir, syncTarget string) (stateHeader, error) {
+	var header stateHeader
+
+	files, err := readStateBundle(input)
+	if err != nil {
+		return header, err
+	}
+	if err := json.Unmarshal(files[stateHeaderName], &header); err != nil {
+		return header, fmt.Errorf("%s is not a state bundle: %w", input, err)
+	}
+	if header.Version != 1 {
+		return header, fmt.Errorf("unsupported state bundle version %d", header.Version)
+	}
+
+	if len(header.Queue) > 0 {
+		if err := os.MkdirAll(queueDir, 0755); err != nil {
+			return header, err
+		}
+	}
+	for _, name := range header.Queue {
+		data := files["queue/"+name]
+		if outputDir != "" {
+			if data, err = redirectJob(data, outputDir); err != nil {
+				return header, fmt.Errorf("invalid queued job %s: %w", name, err)
+			}
+		}
+
+		dst := filepath.Join(queueDir, name)
+		if _, err := os.Stat(dst); err == nil {
+			fmt.Printf("Skipping %s, already queued\n", name)
+			continue
+		}
+		if err := translator.WriteFileAtomic(dst, data, 0600); err != nil {
+			return header, err
+		}
+	}
+
+	if header.TM {
+		if tmPath == "" {
+			fmt.Println("Bundle contains a translation memory, pass --tm to import it")
+		} else if err := mergeRecordFile(tmPath, files["tm.jsonl"], 0644); err != nil {
+			return header, err
+		}
+	}
+
+	if header.Cache {
+		if cachePath == "" {
+			fmt.Println("Bundle contains a chunk cache, pass --cache to import it")
+		} else {
+			if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+				return header, err
+			}
+			if err := mergeRecordFile(cachePath, files["cache.jsonl"], 0600); err != nil {
+				return header, err
+			}
+		}
+	}
+
+	if len(header.Chunks) > 0 && manifestDir == "" {
+		fmt.Println("Bundle contains chunk manifests, pass --manifest-dir to import them")
+	} else {
+		for _, name := range header.Chunks {
+			data, ok := files["chunks/"+name]
+			if !ok || strings.HasPrefix(path.Clean(name), "../") || path.IsAbs(name) {
+				return header, fmt.Errorf("invalid chunk manifest %q in state bundle", name)
+			}
+			dst := filepath.Join(manifestDir, filepath.FromSlash(path.Clean(name)))
+			if _, err := os.Stat(dst); err == nil {
+				fmt.Printf("Keeping existing chunk manifest %s\n", dst)
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return header, err
+			}
+			if err := translator.WriteFileAtomic(dst, data, 0644); err != nil {
+				return header, err
+			}
+		}
+	}
+
+	if header.Manifest {
+		if syncTarget == "" {
+			fmt.Println("Bundle contains a sync manifest, pass --sync-target to import it")
+		} else {
+			dst := filepath.Join(syncTarget, syncManifestName)
+			if _, err := os.Stat(dst); err == nil {
+				fmt.Printf("Keeping existing manifest %s\n", dst)
+			} else {
+				if err := os.MkdirAll(syncTarget, 0755); err != nil {
+					return header, err
+				}
+				if err := translator.WriteFileAtomic(dst, files["manifest.json"], 0644); err != nil {
+					return header, err
+				}
+			}
+		}
+	}
+
+	return header, nil
+}
+
+// redirectJob points the output of a queued job into outputDir, keeping the
+// job encrypted if it was.
+func redirectJob(data []byte, outputDir string) ([]byte, error) {
+	var key []byte
+	var err error
+	if translator.IsSealed(data) {
+		if key, err = requireStorageKey(); err != nil {
+			return nil, err
+		}
+		if data, err = translator.OpenRecord(key, data); err != nil {
+			return nil, err
+		}
+	}
+
+	var job queuedJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	if job.Output, err = filepath.Abs(filepath.Join(outputDir, filepath.Base(job.Output))); err != nil {
+		return nil, err
+	}
+
+	data, _ = json.MarshalIndent(job, "", "  ")
+	if key != nil {
+		return translator.SealRecord(key, data)
+	}
+	return data, nil
+}
+
+func readStateBundle(input string) (map[string][]byte, error) {
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a state bundle: %w", input, err)
+	}
+	tr := tar.NewReader(gz)
+
+	files := map[string][]byte{}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := path.Clean(h.Name)
+		if strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			return nil, fmt.Errorf("invalid entry %q in state bundle", h.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
+// mergeRecordFile appends the JSON lines in data that the translation memory
+// or chunk cache file does not contain yet.
+func mergeRecordFile(file string, data []byte, perm os.FileMode) error {
+	existing := map[string]bool{}
+	if current, err := os.ReadFile(file); err == nil {
+		for _, line := range strings.Split(string(current), "\n") {
+			existing[line] = true
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || existing[line] {
+			continue
+		}
+		existing[line] = true
+		w.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
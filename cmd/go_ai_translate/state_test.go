@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+func TestStateBundleCarriesCacheAndChunkManifests(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	manifest := filepath.Join("book", "book.ru.txt"+translator.ManifestSuffix)
+	os.MkdirAll(filepath.Join(src, "out", "book"), 0755)
+	os.WriteFile(filepath.Join(src, "out", manifest), []byte(`{"run_id":"1"}`+"\n"), 0644)
+	os.WriteFile(filepath.Join(src, "chunks.jsonl"), []byte(`{"key":"a","translation":"A"}`+"\n"+`{"key":"b","translation":"B"}`+"\n"), 0600)
+	os.WriteFile(filepath.Join(dst, "chunks.jsonl"), []byte(`{"key":"a","translation":"A"}`+"\n"), 0600)
+
+	bundle := filepath.Join(src, "state.tar.gz")
+	header, err := exportState(bundle, filepath.Join(src, "queue"), "", filepath.Join(src, "chunks.jsonl"), filepath.Join(src, "out"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !header.Cache || len(header.Chunks) != 1 {
+		t.Fatalf("Expected the cache and one chunk manifest in the bundle, got %+v", header)
+	}
+
+	if _, err := importState(bundle, filepath.Join(dst, "queue"), "", "", filepath.Join(dst, "chunks.jsonl"), filepath.Join(dst, "out"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "out", manifest)); err != nil || string(data) != `{"run_id":"1"}`+"\n" {
+		t.Errorf("Expected the chunk manifest restored, got %q, %v", data, err)
+	}
+	cache, err := translator.OpenFileCache(filepath.Join(dst, "chunks.jsonl"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := cache.Get("a"); a != "A" {
+		t.Errorf("Expected the existing chunk kept, got %q", a)
+	}
+	if b, _ := cache.Get("b"); b != "B" {
+		t.Errorf("Expected the bundled chunk merged in, got %q", b)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "chunks.jsonl")); len(data) != len(`{"key":"a","translation":"A"}`+"\n"+`{"key":"b","translation":"B"}`+"\n") {
+		t.Errorf("Expected each chunk once in the merged cache, got %q", data)
+	}
+}
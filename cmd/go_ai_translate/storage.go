@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+const keyringService = "go_ai_translate"
+
+// storageKey returns the key for encrypted local storage, taken from the
+// environment or, failing that, from the system keyring. It returns nil when
+// neither holds a passphrase.
+func storageKey() ([]byte, error) {
+	if passphrase := os.Getenv(translator.StorageKeyEnv); passphrase != "" {
+		return translator.StorageKey(passphrase), nil
+	}
+
+	passphrase, err := keyringPassphrase()
+	if err != nil || passphrase == "" {
+		return nil, err
+	}
+	return translator.StorageKey(passphrase), nil
+}
+
+// keyringPassphrase reads the passphrase stored for keyringService with the
+// platform's keyring tool: security on macOS, secret-tool elsewhere.
+func keyringPassphrase() (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService)
+	}
+
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.Is(err, exec.ErrNotFound) || errors.As(err, &exitErr) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read keyring: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// requireStorageKey is storageKey for --encrypt, where a missing key is an
+// error.
+func requireStorageKey() ([]byte, error) {
+	key, err := storageKey()
+	if err == nil && key == nil {
+		err = fmt.Errorf("no storage key: set %s or store a passphrase in the keyring under service %q", translator.StorageKeyEnv, keyringService)
+	}
+	return key, err
+}
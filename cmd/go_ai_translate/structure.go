@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// writeStructureReport compares the structure of the input file with its
+// translation and writes the HTML report to path.
+func writeStructureReport(path, inputPath, outputPath string) (*translator.StructureReport, error) {
+	source, err := os.ReadFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	translation, err := os.ReadFile(outputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	report := translator.CompareStructure(string(source), translator.StripAnnotations(string(translation)))
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := report.WriteHTML(f, filepath.Base(inputPath)); err != nil {
+		return nil, err
+	}
+	return report, f.Close()
+}
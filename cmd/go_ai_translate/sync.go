@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+const syncManifestName = ".translation-manifest.json"
+
+type syncEntry struct {
+	Source       string `json:"source"`
+	Output       string `json:"output"`
+	SourceSHA256 string `json:"source_sha256,omitempty"`
+	Status       string `json:"status"`
+	Language     string `json:"language,omitempty"`
+	Chunks       int    `json:"chunks,omitempty"`
+	// Batch is the merged batch of small files the file went out in, see
+	// --merge-small.
+	Batch    int                  `json:"batch,omitempty"`
+	Warnings []translator.Warning `json:"warnings,omitempty"`
+	Error    string               `json:"error,omitempty"`
+}
+
+type syncManifest struct {
+	SourceDir string      `json:"source_dir"`
+	TargetDir string      `json:"target_dir"`
+	Language  string      `json:"language"`
+	Model     string      `json:"model"`
+	Files     []syncEntry `json:"files"`
+	// Inputs records the settings of a deterministic run; the input hashes
+	// are in Files.
+	Inputs *translator.RunInputs `json:"inputs,omitempty"`
+}
+
+// changedFiles lists files under sourceDir that differ from ref according to
+// git. Paths are returned relative to sourceDir.
+func changedFiles(sourceDir, ref string) ([]string, error) {
+	cmd := exec.Command("git", "diff", "--name-only", "--relative", ref, "--", ".")
+	cmd.Dir = sourceDir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff against %s failed: %w", ref, err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, filepath.FromSlash(line))
+		}
+	}
+	return files, nil
+}
+
+func allFiles(sourceDir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != sourceDir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	return files, err
+}
+
+// sourceFiles lists the files of the source tree to translate: those that
+// changed since changedSince, or all of them, minus what .aitranslateignore
+// files exclude.
+func sourceFiles(sourceDir, changedSince string) ([]string, error) {
+	var files []string
+	var err error
+	if changedSince != "" {
+		files, err = changedFiles(sourceDir, changedSince)
+	} else {
+		files, err = allFiles(sourceDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filterIgnored(sourceDir, files)
+}
+
+func readSyncManifest(targetDir string) (syncManifest, error) {
+	var manifest syncManifest
+
+	data, err := os.ReadFile(filepath.Join(targetDir, syncManifestName))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// syncTree regenerates the localized copy of every listed source file under
+// targetDir and updates the manifest describing the tree. Files are processed
+// in sorted order and the manifest carries no timestamps, so identical inputs
+// produce identical manifests. With merge, files without overrides go to
+// TranslateMerged together after the others.
+func syncTree(t *translator.Translator, config translator.Config, sourceDir, targetDir string, files []string, merge bool) (syncManifest, error) {
+	sort.Strings(files)
+
+	previous, err := readSyncManifest(targetDir)
+	if err != nil {
+		return previous, err
+	}
+
+	manifest := syncManifest{
+		SourceDir: filepath.ToSlash(sourceDir),
+		TargetDir: filepath.ToSlash(targetDir),
+		Language:  config.ToLang,
+		Model:     config.Model,
+	}
+
+	entries := map[string]syncEntry{}
+	for _, e := range previous.Files {
+		entries[e.Source] = e
+	}
+	var run []syncEntry
+	var merged []translator.MergedFile
+	var mergedEntries []syncEntry
+
+	overrides := newDirConfigs(sourceDir)
+
+	var failed int
+	for _, rel := range files {
+		if filepath.Base(rel) == dirConfigName {
+			continue
+		}
+		sourcePath := filepath.Join(sourceDir, rel)
+		outputPath := filepath.Join(targetDir, rel)
+		entry := syncEntry{Source: filepath.ToSlash(sourcePath), Output: filepath.ToSlash(outputPath)}
+
+		fileConfig, overridden, skip, err := overrides.forFile(config, rel)
+		if err != nil {
+			return manifest, err
+		}
+		if skip {
+			entry.Status = "skipped"
+			run = append(run, entry)
+			continue
+		}
+		ft := t
+		if overridden {
+			if err := fileConfig.Validate(); err != nil {
+				return manifest, fmt.Errorf("overrides for %s: %w", sourcePath, err)
+			}
+			ft = t.Derive(fileConfig)
+			if fileConfig.ToLang != config.ToLang {
+				entry.Language = fileConfig.ToLang
+			}
+		}
+
+		content, err := os.ReadFile(sourcePath)
+		if os.IsNotExist(err) {
+			if err := os.Remove(outputPath); err != nil && !os.IsNotExist(err) {
+				return manifest, fmt.Errorf("failed to remove %s: %w", outputPath, err)
+			}
+			entry.Status = "deleted"
+			delete(entries, entry.Source)
+			run = append(run, entry)
+			continue
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("failed to read %s: %w", sourcePath, err)
+		}
+
+		sum := sha256.Sum256(content)
+		entry.SourceSHA256 = hex.EncodeToString(sum[:])
+
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return manifest, fmt.Errorf("failed to create output directory: %w", err)
+		}
+
+		if merge && !overridden {
+			merged = append(merged, translator.MergedFile{Input: sourcePath, Output: outputPath})
+			mergedEntries = append(mergedEntries, entry)
+			continue
+		}
+
+		if config.Verbose {
+			fmt.Printf("Translating %s -> %s\n", sourcePath, outputPath)
+		}
+
+		err = ft.TranslateFile(sourcePath, outputPath)
+		result := ft.Result()
+		entry.Chunks = result.Chunks
+		entry.Warnings = result.Warnings
+		if result.Inputs != nil {
+			inputs := *result.Inputs
+			inputs.InputSHA256 = ""
+			manifest.Inputs = &inputs
+		}
+		if err != nil {
+			entry.Status = "failed"
+			entry.Error = err.Error()
+			failed++
+		} else {
+			entry.Status = "translated"
+		}
+		entries[entry.Source] = entry
+		run = append(run, entry)
+	}
+
+	if len(merged) > 0 {
+		results, _ := t.TranslateMerged(context.Background(), merged)
+		for i, r := range results {
+			entry := mergedEntries[i]
+			entry.Chunks, entry.Batch, entry.Warnings = r.Chunks, r.Batch, r.Warnings
+			if r.Err != nil {
+				entry.Status = "failed"
+				entry.Error = r.Err.Error()
+				failed++
+			} else {
+				entry.Status = "translated"
+			}
+			entries[entry.Source] = entry
+			run = append(run, entry)
+		}
+	}
+
+	for _, e := range entries {
+		manifest.Files = append(manifest.Files, e)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Source < manifest.Files[j].Source
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err := translator.WriteFileAtomic(filepath.Join(targetDir, syncManifestName), append(data, '\n'), 0644); err != nil {
+		return manifest, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	// The caller reports on this run only, the file keeps the whole tree.
+	manifest.Files = run
+
+	if failed > 0 {
+		return manifest, fmt.Errorf("%d of %d files failed to translate", failed, len(files))
+	}
+	return manifest, nil
+}
+
+func runSync(t *translator.Translator, config translator.Config, sourceDir, targetDir, changedSince string, dedupeThreshold float64, merge, jsonMode bool) {
+	files, err := sourceFiles(sourceDir, changedSince)
+	if err != nil {
+		fail(jsonMode, "Error listing source files", err)
+	}
+
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		fail(jsonMode, "Error creating target directory", err)
+	}
+
+	if dedupeThreshold > 0 {
+		paths := make([]string, len(files))
+		for i, rel := range files {
+			paths[i] = filepath.Join(sourceDir, rel)
+		}
+		sort.Strings(paths)
+		if err := t.PrepareDedupe(paths, dedupeThreshold); err != nil {
+			fail(jsonMode, "Error preparing deduplication", err)
+		}
+	}
+
+	manifest, err := syncTree(t, config, sourceDir, targetDir, files, merge)
+
+	if dedupeThreshold > 0 {
+		report := t.DedupeReport()
+		if !config.NoPersist {
+			data, _ := json.MarshalIndent(report, "", "  ")
+			if writeErr := os.WriteFile(filepath.Join(targetDir, ".dedupe-report.json"), append(data, '\n'), 0644); writeErr != nil {
+				fmt.Fprintf(os.Stderr, "Error writing deduplication report: %v\n", writeErr)
+			}
+		}
+		if !jsonMode {
+			fmt.Printf("Reused translations for %d near-duplicate paragraphs\n", len(report))
+		}
+	}
+
+	if jsonMode {
+		out, _ := json.MarshalIndent(manifest, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		for _, f := range manifest.Files {
+			fmt.Printf("%-10s %s\n", f.Status, f.Output)
+		}
+	}
+
+	if err != nil {
+		if !jsonMode {
+			fmt.Printf("Error syncing translations: %v\n", err)
+		}
+		exit(1)
+	}
+}
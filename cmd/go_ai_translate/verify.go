@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hightemp/go_ai_translate/translator"
+)
+
+// runVerify implements the verify subcommand: it checks that the localized
+// tree is up to date with the source tree using only local state, without
+// any API calls, and exits non-zero when it is stale.
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	fs.String("config", "", configUsage)
+	cachedOnly := fs.Bool("cached-only", false, "Check that every chunk of the files is in the cache or translation memory, instead of the sync manifest")
+	toLang := fs.String("to", translator.DefaultToLang, "Target language of the cached translations (default: russian)")
+	model := fs.String("model", translator.DefaultModel, "Model of the cached translations (default: deepseek/deepseek-chat)")
+	chunkSize := fs.Int("chunk-size", translator.DefaultChunkSize, "Size of text chunks in tokens (default: 500)")
+	systemPrompt := fs.String("system-prompt", "", "Text opening the system message the translations were made with")
+	cachePath := fs.String("cache", defaultCachePath(), "File of translated chunks")
+	tmPath := fs.String("tm", "", "Translation memory file whose exact matches count as translated")
+	sourceDir := fs.String("sync-source", "", "Source docs directory (required)")
+	targetDir := fs.String("sync-target", "", "Localized docs directory holding the manifest (required)")
+	changedSince := fs.String("changed-since", "", "Only verify files changed since this git revision")
+	fs.Parse(args)
+	if err := applySettings(fs); err != nil {
+		fmt.Printf("Error reading settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *sourceDir == "" || *targetDir == "" {
+		fmt.Println("Error: --sync-source and --sync-target are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+	files, err := sourceFiles(*sourceDir, *changedSince)
+	if err != nil {
+		fmt.Printf("Error listing source files: %v\n", err)
+		os.Exit(1)
+	}
+
+	var stale []string
+	if *cachedOnly {
+		// The cache and memory are only read; a key from the environment or
+		// keyring opens encrypted records.
+		key, _ := storageKey()
+		config := translator.Config{
+			ToLang:       *toLang,
+			Model:        *model,
+			ChunkSize:    *chunkSize,
+			SystemPrompt: *systemPrompt,
+			TMPath:       *tmPath,
+			ReadKey:      key,
+		}
+		cache, cacheErr := translator.OpenFileCacheKeys(*cachePath, key, nil)
+		if cacheErr != nil {
+			fmt.Printf("Error opening cache: %v\n", cacheErr)
+			os.Exit(1)
+		}
+		config.Cache = cache
+		if models := splitList(*model); len(models) > 0 {
+			config.Model = models[0]
+		}
+		stale, err = verifyCached(config, *sourceDir, *targetDir, files)
+	} else {
+		stale, err = verifyTree(*sourceDir, *targetDir, files)
+	}
+	if err != nil {
+		fmt.Printf("Error verifying translations: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, s := range stale {
+		fmt.Printf("stale: %s\n", s)
+	}
+	if len(stale) > 0 {
+		fmt.Printf("%d of %d files need translation\n", len(stale), len(files))
+		os.Exit(1)
+	}
+	fmt.Printf("All %d files are translated and up to date\n", len(files))
+}
+
+// verifyTree returns the source files whose localized copy is missing or was
+// produced from different content than the current source.
+func verifyTree(sourceDir, targetDir string, files []string) ([]string, error) {
+	manifest, err := readSyncManifest(targetDir)
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]syncEntry{}
+	for _, e := range manifest.Files {
+		known[e.Source] = e
+	}
+
+	overrides := newDirConfigs(sourceDir)
+
+	var stale []string
+	for _, rel := range files {
+		if filepath.Base(rel) == dirConfigName {
+			continue
+		}
+		if _, _, skip, err := overrides.forFile(translator.Config{}, rel); err != nil {
+			return nil, err
+		} else if skip {
+			continue
+		}
+
+		sourcePath := filepath.Join(sourceDir, rel)
+		content, err := os.ReadFile(sourcePath)
+		if os.IsNotExist(err) {
+			if _, err := os.Stat(filepath.Join(targetDir, rel)); err == nil {
+				stale = append(stale, filepath.ToSlash(sourcePath))
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(content)
+		entry, ok := known[filepath.ToSlash(sourcePath)]
+		if !ok || entry.Status != "translated" || entry.SourceSHA256 != hex.EncodeToString(sum[:]) {
+			stale = append(stale, filepath.ToSlash(sourcePath))
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(targetDir, rel)); err != nil {
+			stale = append(stale, filepath.ToSlash(sourcePath))
+		}
+	}
+
+	return stale, nil
+}
+
+// verifyCached returns the source files whose localized copy is missing or
+// that have chunks in neither the cache nor the translation memory of
+// config, with the overrides of .ai-translate.yaml files applied.
+func verifyCached(config translator.Config, sourceDir, targetDir string, files []string) ([]string, error) {
+	overrides := newDirConfigs(sourceDir)
+	t := translator.NewTranslator(config)
+
+	var stale []string
+	for _, rel := range files {
+		if filepath.Base(rel) == dirConfigName {
+			continue
+		}
+		fileConfig, overridden, skip, err := overrides.forFile(config, rel)
+		if err != nil {
+			return nil, err
+		} else if skip {
+			continue
+		}
+
+		sourcePath := filepath.Join(sourceDir, rel)
+		if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+			if _, err := os.Stat(filepath.Join(targetDir, rel)); err == nil {
+				stale = append(stale, filepath.ToSlash(sourcePath))
+			}
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(targetDir, rel)); err != nil {
+			stale = append(stale, filepath.ToSlash(sourcePath))
+			continue
+		}
+
+		ft := t
+		if overridden {
+			ft = t.Derive(fileConfig)
+		}
+		missing, err := ft.UncachedChunks(context.Background(), sourcePath)
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) > 0 {
+			stale = append(stale, fmt.Sprintf("%s (%d of its chunks are not cached)", filepath.ToSlash(sourcePath), len(missing)))
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// runDeadline turns --run-until and --max-duration into the time a run
+// pauses, whichever comes first. until is a local time of day; a time that
+// has already passed today means tomorrow. The zero time means no limit.
+func runDeadline(until string, maxDuration time.Duration, now time.Time) (time.Time, error) {
+	var deadline time.Time
+	if until != "" {
+		clock, err := time.Parse("15:04", until)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --run-until %q, expected HH:MM", until)
+		}
+		deadline = time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if !deadline.After(now) {
+			deadline = deadline.AddDate(0, 0, 1)
+		}
+	}
+	if maxDuration > 0 {
+		if end := now.Add(maxDuration); deadline.IsZero() || end.Before(deadline) {
+			deadline = end
+		}
+	}
+	return deadline, nil
+}
@@ -0,0 +1,55 @@
+package translator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// annotationRe matches the markers Config.Annotate adds around chunks.
+var annotationRe = regexp.MustCompile(`<!-- seg:\d+ conf:[0-9.]+ -->\n|<!-- /seg:\d+ -->`)
+
+// StripAnnotations removes the markers written by Config.Annotate, giving
+// back the plain translation.
+func StripAnnotations(text string) string {
+	return annotationRe.ReplaceAllString(text, "")
+}
+
+// annotate wraps chunk n of the output in review markers. The opening marker
+// takes a line of its own and the closing one follows the text directly, so
+// StripAnnotations restores the output byte for byte.
+func annotate(n int, confidence float64, translation string) string {
+	return fmt.Sprintf("<!-- seg:%d conf:%.2f -->\n%s<!-- /seg:%d -->", n, confidence, translation, n)
+}
+
+// chunkConfidence estimates how much a reviewer can trust chunk n, from 1
+// down to 0: every retry, fallback and validation warning on the chunk
+// lowers it, as does a translation much shorter or longer than its source.
+func (t *Translator) chunkConfidence(n int, source, translation string) float64 {
+	t.mu.Lock()
+	confidence := 1 - 0.05*float64(t.retries[n])
+	for _, w := range t.result.Warnings {
+		if w.Chunk != n {
+			continue
+		}
+		switch w.Kind {
+		case WarningGlossaryTerm:
+			confidence -= 0.1
+		case WarningModelFallback:
+			confidence -= 0.2
+		default:
+			confidence -= 0.3
+		}
+	}
+	t.mu.Unlock()
+
+	if src := len(strings.TrimSpace(source)); src > 0 {
+		if ratio := float64(len(strings.TrimSpace(translation))) / float64(src); ratio < 0.5 || ratio > 2 {
+			confidence -= 0.2
+		}
+	}
+	if confidence < 0 {
+		return 0
+	}
+	return confidence
+}
@@ -0,0 +1,47 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnnotate(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.md")
+	out := filepath.Join(dir, "out.md")
+	first := strings.Repeat("The first paragraph. ", 12)
+	second := strings.Repeat("The second paragraph. ", 12)
+	os.WriteFile(in, []byte(first+"\n\n"+second+"\n"), 0644)
+
+	provider := &upperProvider{}
+	tr := NewTranslator(Config{
+		Provider:   provider,
+		ChunkSize:  100,
+		NoDelay:    true,
+		Annotate:   true,
+		Retry:      RetryPolicy{Backoff: 1},
+		MaxRetries: 2,
+	})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(out)
+	if !strings.HasPrefix(string(got), "<!-- seg:1 conf:0.95 -->\n") || !strings.Contains(string(got), "<!-- seg:2 conf:1.00 -->\n") {
+		t.Errorf("Expected each chunk wrapped with its confidence, lowered by a retry, got %q", got)
+	}
+	if !strings.HasSuffix(string(got), "<!-- /seg:2 -->") {
+		t.Errorf("Expected the last chunk closed, got %q", got)
+	}
+
+	plain := filepath.Join(dir, "plain.md")
+	tr = NewTranslator(Config{Provider: &upperProvider{calls: 1}, ChunkSize: 100, NoDelay: true})
+	if err := tr.TranslateFile(in, plain); err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := os.ReadFile(plain); StripAnnotations(string(got)) != string(want) {
+		t.Errorf("Expected stripping to restore %q, got %q", want, StripAnnotations(string(got)))
+	}
+}
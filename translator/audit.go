@@ -0,0 +1,200 @@
+package translator
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditRecord is one API request in the audit log. Records form a hash
+// chain: Hash covers the record including Prev, the hash of the record
+// before it, so editing, removing or reordering records breaks the chain.
+// Only content hashes are stored, never the content itself.
+type AuditRecord struct {
+	Seq            int       `json:"seq"`
+	Time           time.Time `json:"time"`
+	URL            string    `json:"url"`
+	Model          string    `json:"model"`
+	RunID          string    `json:"run_id,omitempty"`
+	ChunkID        string    `json:"chunk_id,omitempty"`
+	Status         int       `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	RequestSHA256  string    `json:"request_sha256"`
+	ResponseSHA256 string    `json:"response_sha256,omitempty"`
+	Prev           string    `json:"prev"`
+	Hash           string    `json:"hash"`
+}
+
+// auditLog appends AuditRecords to a JSON lines file.
+type auditLog struct {
+	mu   sync.Mutex
+	path string
+	key  []byte
+	seq  int
+	prev string
+}
+
+func openAuditLog(path string, key []byte) (*auditLog, error) {
+	log := &auditLog{path: path, key: key}
+
+	records, err := readAuditLog(path, key)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(records); n > 0 {
+		log.seq = records[n-1].Seq
+		log.prev = records[n-1].Hash
+	}
+	return log, nil
+}
+
+func (l *auditLog) append(rec AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	rec.Seq = l.seq
+	rec.Prev = l.prev
+	rec.Hash = auditHash(rec)
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if l.key != nil {
+		if line, err = SealRecord(l.key, line); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	l.prev = rec.Hash
+	return nil
+}
+
+func auditHash(rec AuditRecord) string {
+	rec.Hash = ""
+	data, _ := json.Marshal(rec)
+	return sha256Hex(data)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func readAuditLog(path string, key []byte) ([]AuditRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		data, err := OpenRecord(key, scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("audit log %s line %d: %w", path, line, err)
+		}
+		var rec AuditRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("audit log %s line %d: %w", path, line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return records, nil
+}
+
+// VerifyAuditLog checks the hash chain of the audit log at path and returns
+// its records. The error names the first record that does not fit the chain.
+func VerifyAuditLog(path string, key []byte) ([]AuditRecord, error) {
+	records, err := readAuditLog(path, key)
+	if err != nil {
+		return nil, err
+	}
+
+	prev := ""
+	for i, rec := range records {
+		if rec.Seq != i+1 {
+			return records, fmt.Errorf("record %d: expected sequence number %d, found %d", i+1, i+1, rec.Seq)
+		}
+		if rec.Prev != prev {
+			return records, fmt.Errorf("record %d: does not follow record %d", rec.Seq, rec.Seq-1)
+		}
+		if auditHash(rec) != rec.Hash {
+			return records, fmt.Errorf("record %d: hash mismatch, the record was modified", rec.Seq)
+		}
+		prev = rec.Hash
+	}
+	return records, nil
+}
+
+// openAudit opens the audit log once per Translator, so the chain continues
+// across files.
+func (t *Translator) openAudit() error {
+	if t.config.AuditPath == "" || t.audit != nil {
+		return nil
+	}
+
+	audit, err := openAuditLog(t.config.AuditPath, t.readKey())
+	if err != nil {
+		return classify(ErrorClassConfig, err)
+	}
+	audit.key = t.config.StorageKey
+	t.audit = audit
+	return nil
+}
+
+// recordRequest adds a request to the audit log, if one is configured.
+// Failing to write the log fails the request: an incomplete audit trail is
+// worse than a failed run for the users who ask for one.
+func (t *Translator) recordRequest(url, model, chunkID string, request []byte, status int, response []byte, requestErr error) error {
+	if t.audit == nil {
+		return nil
+	}
+
+	rec := AuditRecord{
+		Time:          time.Now().UTC(),
+		URL:           url,
+		Model:         model,
+		RunID:         t.runID,
+		ChunkID:       chunkID,
+		Status:        status,
+		RequestSHA256: sha256Hex(request),
+	}
+	if response != nil {
+		rec.ResponseSHA256 = sha256Hex(response)
+	}
+	if requestErr != nil {
+		rec.Error = requestErr.Error()
+	}
+
+	if err := t.audit.append(rec); err != nil {
+		return classify(ErrorClassOutput, err)
+	}
+	return nil
+}
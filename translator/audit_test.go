@@ -0,0 +1,52 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(input, []byte("First paragraph.\n\nSecond paragraph.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	audit := filepath.Join(dir, "audit.jsonl")
+
+	for i := 0; i < 2; i++ {
+		translator := NewTranslator(Config{BaseURL: server.URL, ChunkSize: 5, NoDelay: true, AuditPath: audit})
+		if err := translator.TranslateFile(input, filepath.Join(dir, "out.txt")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := VerifyAuditLog(audit, nil)
+	if err != nil {
+		t.Fatalf("Expected a valid chain, got %v", err)
+	}
+	if len(records) < 4 || records[0].Status != 200 || records[0].RequestSHA256 == "" || records[0].ResponseSHA256 == "" {
+		t.Fatalf("Unexpected audit records: %+v", records)
+	}
+
+	data, _ := os.ReadFile(audit)
+	if strings.Contains(string(data), "paragraph") {
+		t.Error("Expected the audit log to hold hashes, not content")
+	}
+
+	tampered := strings.Replace(string(data), `"status":200`, `"status":500`, 1)
+	os.WriteFile(audit, []byte(tampered), 0600)
+	if _, err := VerifyAuditLog(audit, nil); err == nil || !strings.Contains(err.Error(), "record 1") {
+		t.Errorf("Expected tampering with record 1 to be detected, got %v", err)
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	os.WriteFile(audit, []byte(lines[0]+strings.Join(lines[2:], "")), 0600)
+	if _, err := VerifyAuditLog(audit, nil); err == nil {
+		t.Error("Expected a removed record to be detected")
+	}
+}
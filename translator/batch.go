@@ -0,0 +1,496 @@
+package translator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Batch APIs accepted by Config.BatchAPI.
+const (
+	BatchAPIOpenAI    = "openai"
+	BatchAPIAnthropic = "anthropic"
+)
+
+const (
+	openAIBaseURL         = "https://api.openai.com/v1"
+	anthropicBaseURL      = "https://api.anthropic.com/v1"
+	anthropicVersion      = "2023-06-01"
+	anthropicMaxTokens    = 8192
+	defaultBatchPollEvery = 30 * time.Second
+)
+
+// batchResult is the outcome of one chunk in a finished batch.
+type batchResult struct {
+	text string
+	err  error
+}
+
+// translateBatch submits every chunk of job to the provider's asynchronous
+// batch endpoint, waits for the batch to finish and writes the output in
+// order. Chunks the batch could not translate are retried with regular
+// requests.
+func (t *Translator) translateBatch(ctx context.Context, job *fileJob) error {
+	model := t.activeModel()
+	prompts := make([][]Message, len(job.chunks)-job.first)
+	for n := range prompts {
+		i := job.first + n
+		prompts[n] = t.messages(job.chunks[i], promptContext{references: t.tmReferences(ctx, job.source(i)), hint: t.chunkHint(i)})
+	}
+
+	var results map[int]batchResult
+	var err error
+	switch t.config.BatchAPI {
+	case BatchAPIOpenAI:
+		results, err = t.runOpenAIBatch(ctx, model, prompts)
+	case BatchAPIAnthropic:
+		results, err = t.runAnthropicBatch(ctx, model, prompts)
+	default:
+		return classify(ErrorClassConfig, fmt.Errorf("unknown batch API %q, expected %s or %s", t.config.BatchAPI, BatchAPIOpenAI, BatchAPIAnthropic))
+	}
+	if err != nil {
+		return err
+	}
+
+	for n := range prompts {
+		i := job.first + n
+		r, ok := results[n]
+		if !ok {
+			r.err = classify(ErrorClassAPI, fmt.Errorf("missing from batch results"))
+		}
+		translated := r.text
+		if r.err == nil {
+			translated, r.err = t.extractTranslation(model, r.text)
+		}
+		if r.err != nil {
+			if t.config.Verbose {
+				fmt.Printf("Batch failed chunk %d (%v), translating it directly\n", i+1, r.err)
+			}
+			if translated, err = t.translateChunkWithRetries(ctx, i, len(job.chunks), job.chunks[i], job.source(i)); err != nil {
+				t.markUntranslated(job, i)
+				return err
+			}
+		} else if t.postEditor != nil {
+			translated = t.postEdit(ctx, i, job.chunks[i], translated)
+		}
+
+		if err := t.writeChunk(job, i, translated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Translator) batchBaseURL(fallback string) string {
+	if t.config.BaseURL != "" {
+		return strings.TrimSuffix(t.config.BaseURL, "/")
+	}
+	return fallback
+}
+
+// nativeModel strips the OpenRouter vendor prefix, e.g. "openai/gpt-4o-mini"
+// becomes "gpt-4o-mini".
+func nativeModel(model, vendor string) string {
+	return strings.TrimPrefix(model, vendor+"/")
+}
+
+func batchCustomID(i int) string {
+	return fmt.Sprintf("chunk-%d", i+1)
+}
+
+func parseBatchCustomID(id string) (int, bool) {
+	var n int
+	if _, err := fmt.Sscanf(id, "chunk-%d", &n); err != nil || n < 1 {
+		return 0, false
+	}
+	return n - 1, true
+}
+
+// batchCall performs one request against a batch API and returns the body
+// of a successful response.
+func (t *Translator) batchCall(ctx context.Context, method, url, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if t.config.BatchAPI == BatchAPIAnthropic {
+		req.Header.Set("x-api-key", t.config.APIKey)
+		req.Header.Set("anthropic-version", anthropicVersion)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+t.config.APIKey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if auditErr := t.recordRequest(url, t.activeModel(), "", body, 0, nil, err); auditErr != nil {
+			return nil, auditErr
+		}
+		if ctx.Err() != nil {
+			return nil, canceled(ctx.Err())
+		}
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("batch request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if auditErr := t.recordRequest(url, t.activeModel(), "", body, resp.StatusCode, data, err); auditErr != nil {
+		return nil, auditErr
+	}
+	if err != nil {
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to read batch response: %w", err))
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, rateLimited(resp, fmt.Errorf("batch request failed with status %d: %s", resp.StatusCode, data))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("batch request failed with status %d: %s", resp.StatusCode, data))
+	}
+	return data, nil
+}
+
+// pollBatch calls check until it reports the batch as done, sleeping
+// Config.BatchPollInterval in between. When ctx ends first, cancel is called
+// so the provider stops working on the batch.
+func (t *Translator) pollBatch(ctx context.Context, check func() (bool, error), cancel func()) error {
+	interval := t.config.BatchPollInterval
+	if interval <= 0 {
+		interval = defaultBatchPollEvery
+	}
+
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			cancel()
+			return canceled(ctx.Err())
+		}
+	}
+}
+
+func (t *Translator) addUsage(prompt, completion int) {
+	t.mu.Lock()
+	t.result.PromptTokens += prompt
+	t.result.CompletionTokens += completion
+	t.mu.Unlock()
+}
+
+type openAIBatch struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	OutputFileID string `json:"output_file_id"`
+	ErrorFileID  string `json:"error_file_id"`
+}
+
+func (t *Translator) runOpenAIBatch(ctx context.Context, model string, prompts [][]Message) (map[int]batchResult, error) {
+	base := t.batchBaseURL(openAIBaseURL)
+	model = nativeModel(model, "openai")
+	profile := t.profileFor(model)
+
+	var input bytes.Buffer
+	for i, prompt := range prompts {
+		request := map[string]interface{}{
+			"model":    model,
+			"messages": prompt,
+		}
+		temp, seed := t.generation(profile, 0)
+		if temp != nil {
+			request["temperature"] = *temp
+		}
+		if seed != nil {
+			request["seed"] = *seed
+		}
+		if t.config.TopP != nil {
+			request["top_p"] = *t.config.TopP
+		}
+		if t.config.MaxTokens > 0 {
+			request["max_tokens"] = t.config.MaxTokens
+		}
+		line, err := json.Marshal(map[string]interface{}{
+			"custom_id": batchCustomID(i),
+			"method":    "POST",
+			"url":       "/v1/chat/completions",
+			"body":      request,
+		})
+		if err != nil {
+			return nil, err
+		}
+		input.Write(line)
+		input.WriteByte('\n')
+	}
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	mw.WriteField("purpose", "batch")
+	fw, err := mw.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	fw.Write(input.Bytes())
+	mw.Close()
+
+	data, err := t.batchCall(ctx, "POST", base+"/files", mw.FormDataContentType(), form.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil || file.ID == "" {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("unexpected file upload response: %s", data))
+	}
+
+	request := map[string]interface{}{
+		"input_file_id":     file.ID,
+		"endpoint":          "/v1/chat/completions",
+		"completion_window": "24h",
+	}
+	if !t.config.NoExternalMetadata {
+		request["metadata"] = map[string]string{"run_id": t.runID}
+	}
+	body, _ := json.Marshal(request)
+	if data, err = t.batchCall(ctx, "POST", base+"/batches", "application/json", body); err != nil {
+		return nil, err
+	}
+	var batch openAIBatch
+	if err := json.Unmarshal(data, &batch); err != nil || batch.ID == "" {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("unexpected batch response: %s", data))
+	}
+	t.noteBatch(batch.ID, batch.Status, len(prompts))
+
+	err = t.pollBatch(ctx, func() (bool, error) {
+		data, err := t.batchCall(ctx, "GET", base+"/batches/"+batch.ID, "", nil)
+		if err != nil {
+			return false, err
+		}
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return false, classify(ErrorClassAPI, fmt.Errorf("unexpected batch response: %s", data))
+		}
+		t.noteBatch(batch.ID, batch.Status, len(prompts))
+
+		switch batch.Status {
+		case "completed":
+			return true, nil
+		case "failed", "expired", "cancelled":
+			if batch.OutputFileID != "" {
+				return true, nil
+			}
+			return false, classify(ErrorClassAPI, fmt.Errorf("batch %s %s", batch.ID, batch.Status))
+		}
+		return false, nil
+	}, func() {
+		t.batchCall(context.Background(), "POST", base+"/batches/"+batch.ID+"/cancel", "", nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[int]batchResult{}
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		data, err := t.batchCall(ctx, "GET", base+"/files/"+fileID+"/content", "", nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := t.parseOpenAIResults(data, results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (t *Translator) parseOpenAIResults(data []byte, results map[int]batchResult) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line struct {
+			CustomID string `json:"custom_id"`
+			Response *struct {
+				StatusCode int                `json:"status_code"`
+				Body       OpenRouterResponse `json:"body"`
+			} `json:"response"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return classify(ErrorClassAPI, fmt.Errorf("unexpected batch result: %w", err))
+		}
+		i, ok := parseBatchCustomID(line.CustomID)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case line.Error != nil:
+			results[i] = batchResult{err: classify(ErrorClassAPI, fmt.Errorf("%s", line.Error.Message))}
+		case line.Response == nil || line.Response.StatusCode != http.StatusOK || len(line.Response.Body.Choices) == 0:
+			results[i] = batchResult{err: classify(ErrorClassAPI, fmt.Errorf("no translation in batch result"))}
+		default:
+			if u := line.Response.Body.Usage; u != nil {
+				t.addUsage(u.PromptTokens, u.CompletionTokens)
+			}
+			results[i] = batchResult{text: line.Response.Body.Choices[0].Message.Content}
+		}
+	}
+	return scanner.Err()
+}
+
+type anthropicBatch struct {
+	ID               string `json:"id"`
+	ProcessingStatus string `json:"processing_status"`
+	ResultsURL       string `json:"results_url"`
+}
+
+func (t *Translator) runAnthropicBatch(ctx context.Context, model string, prompts [][]Message) (map[int]batchResult, error) {
+	base := t.batchBaseURL(anthropicBaseURL)
+	model = nativeModel(model, "anthropic")
+	profile := t.profileFor(model)
+	// The Messages API has no seed; in deterministic mode temperature 0 is
+	// as close as it gets.
+	temp, _ := t.generation(profile, 0)
+	maxTokens := anthropicMaxTokens
+	if t.config.MaxTokens > 0 {
+		maxTokens = t.config.MaxTokens
+	}
+
+	type params struct {
+		Model       string    `json:"model"`
+		MaxTokens   int       `json:"max_tokens"`
+		System      string    `json:"system,omitempty"`
+		Messages    []Message `json:"messages"`
+		Temperature *float64  `json:"temperature,omitempty"`
+		TopP        *float64  `json:"top_p,omitempty"`
+	}
+	type request struct {
+		CustomID string `json:"custom_id"`
+		Params   params `json:"params"`
+	}
+	var requests []request
+	for i, prompt := range prompts {
+		requests = append(requests, request{
+			CustomID: batchCustomID(i),
+			Params: params{
+				Model:       model,
+				MaxTokens:   maxTokens,
+				System:      prompt[0].Content,
+				Messages:    prompt[1:],
+				Temperature: temp,
+				TopP:        t.config.TopP,
+			},
+		})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, err
+	}
+	data, err := t.batchCall(ctx, "POST", base+"/messages/batches", "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+	var batch anthropicBatch
+	if err := json.Unmarshal(data, &batch); err != nil || batch.ID == "" {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("unexpected batch response: %s", data))
+	}
+	t.noteBatch(batch.ID, batch.ProcessingStatus, len(prompts))
+
+	err = t.pollBatch(ctx, func() (bool, error) {
+		data, err := t.batchCall(ctx, "GET", base+"/messages/batches/"+batch.ID, "", nil)
+		if err != nil {
+			return false, err
+		}
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return false, classify(ErrorClassAPI, fmt.Errorf("unexpected batch response: %s", data))
+		}
+		t.noteBatch(batch.ID, batch.ProcessingStatus, len(prompts))
+		return batch.ProcessingStatus == "ended", nil
+	}, func() {
+		t.batchCall(context.Background(), "POST", base+"/messages/batches/"+batch.ID+"/cancel", "", nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if batch.ResultsURL == "" {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("batch %s ended without results", batch.ID))
+	}
+
+	if data, err = t.batchCall(ctx, "GET", batch.ResultsURL, "", nil); err != nil {
+		return nil, err
+	}
+
+	results := map[int]batchResult{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line struct {
+			CustomID string `json:"custom_id"`
+			Result   struct {
+				Type    string `json:"type"`
+				Message struct {
+					Content []struct {
+						Type string `json:"type"`
+						Text string `json:"text"`
+					} `json:"content"`
+					Usage struct {
+						InputTokens  int `json:"input_tokens"`
+						OutputTokens int `json:"output_tokens"`
+					} `json:"usage"`
+				} `json:"message"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, classify(ErrorClassAPI, fmt.Errorf("unexpected batch result: %w", err))
+		}
+		i, ok := parseBatchCustomID(line.CustomID)
+		if !ok {
+			continue
+		}
+		if line.Result.Type != "succeeded" {
+			results[i] = batchResult{err: classify(ErrorClassAPI, fmt.Errorf("batch result %s", line.Result.Type))}
+			continue
+		}
+
+		var text strings.Builder
+		for _, c := range line.Result.Message.Content {
+			if c.Type == "text" {
+				text.WriteString(c.Text)
+			}
+		}
+		t.addUsage(line.Result.Message.Usage.InputTokens, line.Result.Message.Usage.OutputTokens)
+		results[i] = batchResult{text: text.String()}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (t *Translator) noteBatch(id, status string, chunks int) {
+	if t.config.Verbose {
+		fmt.Printf("Batch %s: %s (%d chunks)\n", id, status, chunks)
+	}
+	t.emit(ProgressEvent{Event: "batch", Batch: id, Status: status, Chunks: chunks})
+}
@@ -0,0 +1,167 @@
+package translator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// promptText returns the chunk text of a translation prompt.
+func promptText(prompt string) string {
+	return prompt[strings.Index(prompt, ":\n\n")+3:]
+}
+
+func TestTranslateBatchOpenAI(t *testing.T) {
+	var input []byte
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/files" && r.Method == "POST":
+			f, _, err := r.FormFile("file")
+			if err != nil || r.FormValue("purpose") != "batch" {
+				t.Errorf("Invalid upload: %v", err)
+			}
+			input, _ = io.ReadAll(f)
+			fmt.Fprint(w, `{"id":"file-in"}`)
+		case r.URL.Path == "/batches" && r.Method == "POST":
+			fmt.Fprint(w, `{"id":"batch-1","status":"validating"}`)
+		case r.URL.Path == "/batches/batch-1":
+			polls++
+			if polls < 2 {
+				fmt.Fprint(w, `{"id":"batch-1","status":"in_progress"}`)
+				return
+			}
+			fmt.Fprint(w, `{"id":"batch-1","status":"completed","output_file_id":"file-out"}`)
+		case r.URL.Path == "/files/file-out/content":
+			scanner := bufio.NewScanner(strings.NewReader(string(input)))
+			n := 0
+			for scanner.Scan() {
+				var line struct {
+					CustomID string `json:"custom_id"`
+					Body     struct {
+						Model    string    `json:"model"`
+						Messages []Message `json:"messages"`
+					} `json:"body"`
+				}
+				json.Unmarshal(scanner.Bytes(), &line)
+				if line.Body.Model != "gpt-4o-mini" {
+					t.Errorf("Expected the native model name, got %q", line.Body.Model)
+				}
+				n++
+				if n == 2 {
+					// Leave one chunk out; it must be translated directly.
+					continue
+				}
+				content, _ := json.Marshal("<result>" + promptText(line.Body.Messages[len(line.Body.Messages)-1].Content) + "</result>")
+				fmt.Fprintf(w, `{"custom_id":%q,"response":{"status_code":200,"body":{"choices":[{"message":{"content":%s}}],"usage":{"prompt_tokens":10,"completion_tokens":5}}}}`+"\n", line.CustomID, content)
+			}
+		case r.URL.Path == "/chat/completions":
+			echoHandler(t)(w, r)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	input0 := "First paragraph.\n\nSecond paragraph.\n\nThird paragraph.\n"
+	in := filepath.Join(dir, "in.txt")
+	os.WriteFile(in, []byte(input0), 0644)
+
+	translator := NewTranslator(Config{
+		BaseURL:           server.URL,
+		Model:             "openai/gpt-4o-mini",
+		ChunkSize:         5,
+		NoDelay:           true,
+		BatchAPI:          BatchAPIOpenAI,
+		BatchPollInterval: time.Millisecond,
+	})
+	out := filepath.Join(dir, "out.txt")
+	if err := translator.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+
+	direct := filepath.Join(dir, "direct.txt")
+	if err := NewTranslator(Config{BaseURL: server.URL, ChunkSize: 5, NoDelay: true}).TranslateFile(in, direct); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(out)
+	want, _ := os.ReadFile(direct)
+	if string(got) != string(want) {
+		t.Errorf("Expected batch output to match direct output %q, got %q", want, got)
+	}
+	if r := translator.Result(); r.Chunks != 3 || r.ChunksTranslated != 3 || r.PromptTokens != 20 {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+}
+
+func TestTranslateBatchAnthropic(t *testing.T) {
+	var server *httptest.Server
+	var requests []struct {
+		CustomID string `json:"custom_id"`
+		Params   struct {
+			Model    string    `json:"model"`
+			Messages []Message `json:"messages"`
+		} `json:"params"`
+	}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "key" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("Missing Anthropic headers")
+		}
+		switch r.URL.Path {
+		case "/messages/batches":
+			var body struct {
+				Requests json.RawMessage `json:"requests"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			json.Unmarshal(body.Requests, &requests)
+			fmt.Fprint(w, `{"id":"msgbatch_1","processing_status":"in_progress"}`)
+		case "/messages/batches/msgbatch_1":
+			fmt.Fprintf(w, `{"id":"msgbatch_1","processing_status":"ended","results_url":%q}`, server.URL+"/results")
+		case "/results":
+			for _, req := range requests {
+				if req.Params.Model != "claude-3-5-haiku" {
+					t.Errorf("Expected the native model name, got %q", req.Params.Model)
+				}
+				text, _ := json.Marshal("<result>" + promptText(req.Params.Messages[0].Content) + "</result>")
+				fmt.Fprintf(w, `{"custom_id":%q,"result":{"type":"succeeded","message":{"content":[{"type":"text","text":%s}],"usage":{"input_tokens":7,"output_tokens":3}}}}`+"\n", req.CustomID, text)
+			}
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	content := "One.\n\nTwo.\n"
+	in := filepath.Join(dir, "in.txt")
+	os.WriteFile(in, []byte(content), 0644)
+
+	translator := NewTranslator(Config{
+		APIKey:            "key",
+		BaseURL:           server.URL,
+		Model:             "anthropic/claude-3-5-haiku",
+		ChunkSize:         2,
+		BatchAPI:          BatchAPIAnthropic,
+		BatchPollInterval: time.Millisecond,
+	})
+	out := filepath.Join(dir, "out.txt")
+	if err := translator.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != content {
+		t.Errorf("Expected %q, got %q", content, got)
+	}
+	if r := translator.Result(); r.CompletionTokens != 3*len(requests) {
+		t.Errorf("Unexpected usage: %+v", r)
+	}
+}
@@ -0,0 +1,30 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+)
+
+// checkBudget stops a job whose estimate exceeds Config.MaxChunks or
+// Config.MaxCost before anything is sent, unless Config.ConfirmBudget lets
+// it go ahead.
+func (t *Translator) checkBudget(ctx context.Context, est *Estimate, chunks int) error {
+	var problem error
+	switch {
+	case t.config.MaxChunks > 0 && chunks > t.config.MaxChunks:
+		problem = fmt.Errorf("the job has %d chunks, more than the maximum of %d", chunks, t.config.MaxChunks)
+	case t.config.MaxCost > 0:
+		if err := t.priceEstimate(ctx, est); err != nil {
+			problem = fmt.Errorf("the cost cannot be checked against the maximum of $%.4f: %v", t.config.MaxCost, err)
+		} else if est.Cost > t.config.MaxCost {
+			problem = fmt.Errorf("the job is estimated to cost $%.4f, more than the maximum of $%.4f", est.Cost, t.config.MaxCost)
+		}
+	}
+	if problem == nil {
+		return nil
+	}
+	if t.config.ConfirmBudget != nil && t.config.ConfirmBudget(est, problem) {
+		return nil
+	}
+	return classify(ErrorClassBudget, problem)
+}
@@ -0,0 +1,60 @@
+package translator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxChunks(t *testing.T) {
+	source := "First paragraph.\n\nSecond paragraph.\n\nThird paragraph."
+	provider := &promptRecorder{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 5, NoDelay: true, MaxChunks: 2})
+	_, err := tr.TranslateText(context.Background(), source)
+	if ErrorClass(err) != ErrorClassBudget || !strings.Contains(err.Error(), "3 chunks") {
+		t.Fatalf("Expected a budget error, got %v", err)
+	}
+	if len(provider.prompts) != 0 {
+		t.Errorf("Expected no requests, got %d", len(provider.prompts))
+	}
+
+	var asked error
+	tr = NewTranslator(Config{Provider: provider, ChunkSize: 5, NoDelay: true, NoSanityCheck: true, MaxChunks: 2,
+		ConfirmBudget: func(est *Estimate, problem error) bool {
+			asked = problem
+			return true
+		}})
+	if _, err := tr.TranslateText(context.Background(), source); err != nil || asked == nil {
+		t.Errorf("Expected the job to go ahead once confirmed, got %v %v", err, asked)
+	}
+}
+
+func TestMaxCost(t *testing.T) {
+	completions := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/models") {
+			w.Write([]byte(`{"data": [{"id": "m", "pricing": {"prompt": "0.001", "completion": "0.002"}}]}`))
+			return
+		}
+		completions++
+		w.Write([]byte(`{"choices": [{"message": {"content": "<result>Hallo.</result>"}}]}`))
+	}))
+	defer server.Close()
+
+	config := Config{BaseURL: server.URL, APIKey: "key", Model: "m", ToLang: "german", ChunkSize: 100, NoDelay: true, MaxCost: 0.01}
+	_, err := NewTranslator(config).TranslateText(context.Background(), "Hello.")
+	if ErrorClass(err) != ErrorClassBudget || completions != 0 {
+		t.Fatalf("Expected the job stopped before any request, got %v after %d requests", err, completions)
+	}
+
+	config.MaxCost = 10
+	tr := NewTranslator(config)
+	if _, err := tr.TranslateText(context.Background(), "Hello."); err != nil {
+		t.Fatal(err)
+	}
+	if est := tr.Result().Estimate; est == nil || est.Cost <= 0 {
+		t.Errorf("Expected the estimate priced, got %+v", est)
+	}
+}
@@ -0,0 +1,235 @@
+package translator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache keeps translated chunks across runs, so running again on a slightly
+// changed document only sends the chunks that changed. Keys are opaque
+// strings derived from the chunk, the model, the target language and the
+// instructions; Config.Cache selects the implementation.
+type Cache interface {
+	Get(key string) (translation string, ok bool)
+	Put(key, translation string) error
+}
+
+type cacheRecord struct {
+	Key         string    `json:"key"`
+	Translation string    `json:"translation"`
+	Created     time.Time `json:"created"`
+}
+
+// FileCache is the built-in Cache, a JSON lines file that is read once and
+// appended to. Records are sealed when it is opened with a storage key.
+// Every record is synced as it is written, and a record cut short by a
+// crash is dropped when the cache is opened again.
+type FileCache struct {
+	path    string
+	key     []byte
+	mu      sync.Mutex
+	entries map[string]string
+}
+
+// OpenFileCache loads the cache at path, which need not exist yet. key, when
+// set, seals new records and opens sealed ones, see StorageKey.
+func OpenFileCache(path string, key []byte) (*FileCache, error) {
+	return OpenFileCacheKeys(path, key, key)
+}
+
+// OpenFileCacheKeys is OpenFileCache with separate keys: readKey opens sealed
+// records and sealKey, when set, seals new ones.
+func OpenFileCacheKeys(path string, readKey, sealKey []byte) (*FileCache, error) {
+	c := &FileCache{path: path, key: sealKey, entries: map[string]string{}}
+	key := readKey
+
+	if err := dropTornTail(path); err != nil {
+		return nil, fmt.Errorf("failed to open cache: %w", err)
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		data, err := OpenRecord(key, scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("cache %s line %d: %w", path, line, err)
+		}
+		var r cacheRecord
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("cache %s line %d: %w", path, line, err)
+		}
+		c.entries[r.Key] = r.Translation
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+	return c, nil
+}
+
+func (c *FileCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	translation, ok := c.entries[key]
+	return translation, ok
+}
+
+func (c *FileCache) Put(key, translation string) error {
+	line, err := json.Marshal(cacheRecord{Key: key, Translation: translation, Created: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	if c.key != nil {
+		if line, err = SealRecord(c.key, line); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := appendRecords(c.path, 0600, line); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	c.entries[key] = translation
+	return nil
+}
+
+// ScrubCache rewrites the chunk cache at path without the records remove
+// selects, and returns how many it removed. remove gets the time a record
+// was written, which is zero for records cached before it was recorded.
+// Kept records are copied unchanged, sealed ones stay sealed.
+func ScrubCache(path string, key []byte, remove func(created time.Time) bool) (int, error) {
+	if err := dropTornTail(path); err != nil {
+		return 0, fmt.Errorf("failed to read cache: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	var kept strings.Builder
+	removed := 0
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		record, err := OpenRecord(key, []byte(line))
+		if err != nil {
+			return 0, fmt.Errorf("cache %s line %d: %w", path, i+1, err)
+		}
+		var r cacheRecord
+		if err := json.Unmarshal(record, &r); err != nil {
+			return 0, fmt.Errorf("cache %s line %d: %w", path, i+1, err)
+		}
+
+		if remove(r.Created) {
+			removed++
+			continue
+		}
+		kept.WriteString(line)
+		kept.WriteByte('\n')
+	}
+
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := WriteFileAtomic(path, []byte(kept.String()), 0600); err != nil {
+		return 0, fmt.Errorf("failed to write cache: %w", err)
+	}
+	return removed, nil
+}
+
+// cacheKey identifies the translation of chunk by the active model into the
+// target language under the current instructions, so a changed glossary or
+// system prompt does not return stale translations.
+func (t *Translator) cacheKey(chunk, hint string) string {
+	system, _ := t.buildPrompt(chunk, promptContext{hint: hint})
+	parts := []string{t.activeModel(), t.config.ToLang, system, chunk}
+	if pe := t.config.PostEdit; pe != nil {
+		parts = append(parts, "post-edit", pe.Model, pe.Instructions)
+	}
+	return sha256Hex([]byte(strings.Join(parts, "\x00")))
+}
+
+// cached returns the stored translation of chunk, if any. Runs that keep
+// document text off disk never use the cache.
+func (t *Translator) cached(chunk, hint string) (string, bool) {
+	if t.config.Cache == nil || t.config.NoPersist {
+		return "", false
+	}
+	return t.config.Cache.Get(t.cacheKey(chunk, hint))
+}
+
+// UncachedChunks splits inputPath as a translation would and returns the
+// 1-based numbers of the chunks whose translation is neither in Config.Cache
+// nor, exactly, in the translation memory at Config.TMPath, so translating
+// the file again would send them to the API. It makes no API calls itself
+// unless splitting the file does, as for scanned input.
+func (t *Translator) UncachedChunks(ctx context.Context, inputPath string) ([]int, error) {
+	prepared, err := t.prepareFile(ctx, inputPath)
+	if err != nil {
+		return nil, err
+	}
+	// The keys depend on the prompt, as in a translation of prepared.
+	if t.format, err = lookupFormat(prepared.Format, ""); err != nil {
+		return nil, classify(ErrorClassConfig, err)
+	}
+	t.selectModel(ctx)
+	t.sourceLang = t.sourceLanguage(&fileJob{chunks: prepared.Chunks})
+
+	var tm *translationMemory
+	if t.config.TMPath != "" {
+		if tm, err = openTranslationMemory(t.config.TMPath, t.readKey()); err != nil {
+			return nil, classify(ErrorClassConfig, err)
+		}
+	}
+
+	var missing []int
+	for i, chunk := range prepared.Chunks {
+		if onlyMarkers(chunk) {
+			continue
+		}
+		if _, ok := t.cached(chunk, t.chunkHint(i)); ok {
+			continue
+		}
+		if tm != nil && tm.covers(t.config.ToLang, unmaskSpans(chunk, prepared.SourceSpans)) {
+			continue
+		}
+		missing = append(missing, i+1)
+	}
+	return missing, nil
+}
+
+// cache stores the translation of chunk. Failing to store it does not fail
+// the run.
+func (t *Translator) cache(chunk, hint, translation string) {
+	if t.config.Cache == nil || t.config.NoPersist {
+		return
+	}
+	if err := t.config.Cache.Put(t.cacheKey(chunk, hint), translation); err != nil && t.config.Verbose {
+		fmt.Printf("Could not cache chunk: %v\n", err)
+	}
+}
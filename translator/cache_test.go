@@ -0,0 +1,183 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingProvider upper-cases chunks and records what it was sent.
+type countingProvider struct {
+	sent []string
+}
+
+func (p *countingProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	text := req.Prompt[strings.Index(req.Prompt, ":\n\n")+3:]
+	p.sent = append(p.sent, text)
+	return &Completion{Text: "<result>" + strings.ToUpper(text) + "</result>"}, nil
+}
+
+func TestFileCache(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	out := filepath.Join(dir, "out.txt")
+	cachePath := filepath.Join(dir, "cache", "chunks.jsonl")
+	key := StorageKey("secret")
+
+	run := func(text string, config Config) (*countingProvider, *Translator) {
+		t.Helper()
+		os.WriteFile(in, []byte(text), 0644)
+		cache, err := OpenFileCache(cachePath, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		provider := &countingProvider{}
+		config.Provider, config.Cache = provider, cache
+		config.ChunkSize, config.NoDelay, config.ToLang = 100, true, "german"
+		tr := NewTranslator(config)
+		if err := tr.TranslateFile(in, out); err != nil {
+			t.Fatal(err)
+		}
+		return provider, tr
+	}
+
+	same := strings.Repeat("The first paragraph stays the same. ", 8)
+	first := same + "\n\n" + strings.Repeat("The second paragraph is here. ", 8) + "\n"
+	if p, _ := run(first, Config{}); len(p.sent) != 2 {
+		t.Fatalf("Expected both chunks sent on the first run, got %q", p.sent)
+	}
+	if data, _ := os.ReadFile(cachePath); strings.Contains(string(data), "PARAGRAPH") {
+		t.Error("Expected cached translations to be sealed with the storage key")
+	}
+
+	p, tr := run(same+"\n\n"+strings.Repeat("The second paragraph was edited. ", 8)+"\n", Config{})
+	if len(p.sent) != 1 || !strings.Contains(p.sent[0], "edited") {
+		t.Errorf("Expected only the changed chunk sent, got %q", p.sent)
+	}
+	if tr.Result().ChunksCached != 1 {
+		t.Errorf("Expected one cached chunk, got %d", tr.Result().ChunksCached)
+	}
+	if got, _ := os.ReadFile(out); !strings.HasPrefix(string(got), strings.ToUpper(same)) || !strings.Contains(string(got), "WAS EDITED") {
+		t.Errorf("Unexpected output %q", got)
+	}
+
+	if p, _ := run(first, Config{Model: "openai/gpt-4o"}); len(p.sent) != 2 {
+		t.Errorf("Expected another model to miss the cache, got %q", p.sent)
+	}
+	if p, _ := run(first, Config{NoPersist: true}); len(p.sent) != 2 {
+		t.Errorf("Expected NoPersist to bypass the cache, got %q", p.sent)
+	}
+}
+
+func TestScrubCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chunks.jsonl")
+	key := StorageKey("secret")
+
+	// A record from before records were dated, and one from 40 days ago.
+	var lines []string
+	for _, r := range []string{
+		`{"key":"undated","translation":"UNDATED"}`,
+		`{"key":"old","translation":"OLD","created":"` + time.Now().AddDate(0, 0, -40).UTC().Format(time.RFC3339) + `"}`,
+	} {
+		sealed, err := SealRecord(key, []byte(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(sealed))
+	}
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+
+	cache, err := OpenFileCache(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Put("fresh", "FRESH"); err != nil {
+		t.Fatal(err)
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -30)
+	removed, err := ScrubCache(path, key, func(created time.Time) bool { return created.Before(cutoff) })
+	if err != nil || removed != 2 {
+		t.Fatalf("Expected 2 records removed, got %d, %v", removed, err)
+	}
+
+	reopened, err := OpenFileCache(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.Get("fresh"); !ok || len(reopened.entries) != 1 {
+		t.Errorf("Expected only the fresh record to remain, got %v", reopened.entries)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "FRESH") {
+		t.Error("Expected the kept record to stay sealed")
+	}
+}
+
+func TestFileCacheReadKeyOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chunks.jsonl")
+	key := StorageKey("secret")
+
+	sealed, err := OpenFileCache(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sealed.Put("old", "SEALED"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key opens what an encrypted run stored but seals nothing new.
+	cache, err := OpenFileCacheKeys(path, key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := cache.Get("old"); !ok || got != "SEALED" {
+		t.Errorf("Expected the sealed record to open, got %q", got)
+	}
+	if err := cache.Put("new", "PLAIN"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "PLAIN") || strings.Contains(string(data), "SEALED") {
+		t.Errorf("Expected only the new record in the clear, got %q", data)
+	}
+}
+
+func TestUncachedChunks(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	cache, err := OpenFileCache(filepath.Join(dir, "chunks.jsonl"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{Provider: &countingProvider{}, Cache: cache, ChunkSize: 100, NoDelay: true, ToLang: "german"}
+
+	same := strings.Repeat("The first paragraph stays the same. ", 8)
+	edited := strings.Repeat("The second paragraph was edited. ", 8)
+	os.WriteFile(in, []byte(same+"\n\n"+strings.Repeat("The second paragraph is here. ", 8)+"\n"), 0644)
+	if err := NewTranslator(config).TranslateFile(in, filepath.Join(dir, "out.txt")); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(in, []byte(same+"\n\n"+edited+"\n"), 0644)
+
+	missing, err := NewTranslator(config).UncachedChunks(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 1 || missing[0] != 2 {
+		t.Errorf("Expected the edited chunk to be missing, got %v", missing)
+	}
+
+	config.TMPath = filepath.Join(dir, "tm.jsonl")
+	entry := `{"lang":"german","source":"` + strings.TrimSpace(edited) + `","target":"GEÄNDERT","embedding":[1]}` + "\n"
+	os.WriteFile(config.TMPath, []byte(entry), 0644)
+	if missing, err := NewTranslator(config).UncachedChunks(context.Background(), in); err != nil || len(missing) != 0 {
+		t.Errorf("Expected the memory to cover the edited chunk, got %v: %v", missing, err)
+	}
+
+	config.ToLang = "french"
+	if missing, _ := NewTranslator(config).UncachedChunks(context.Background(), in); len(missing) != 2 {
+		t.Errorf("Expected another language to miss both chunks, got %v", missing)
+	}
+}
@@ -0,0 +1,35 @@
+package translator
+
+import (
+	"strings"
+)
+
+// lastSentences returns the last n sentences of text.
+func lastSentences(text string, n int, abbreviations map[string]bool) string {
+	sentences := splitSentences(strings.TrimSpace(text), abbreviations)
+	if len(sentences) > n {
+		sentences = sentences[len(sentences)-n:]
+	}
+	return strings.TrimSpace(strings.Join(sentences, ""))
+}
+
+// carryOver fills in the end of the chunk before chunk i and of its
+// translation, see Config.ContextSentences. The translation is only known
+// once that chunk is written, which with concurrent workers it may not be.
+func (t *Translator) carryOver(i int, pc *promptContext) {
+	n := t.config.ContextSentences
+	if n <= 0 || i == 0 || t.running == nil || i > len(t.running.chunks) {
+		return
+	}
+	if onlyMarkers(t.running.chunks[i-1]) {
+		return
+	}
+	pc.previousSource = lastSentences(t.running.source(i-1), n, t.abbreviations(t.sourceLang))
+
+	abbreviations := t.abbreviations(t.config.ToLang)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if target, ok := t.running.targets[i-1]; ok {
+		pc.previousTranslation = lastSentences(target, n, abbreviations)
+	}
+}
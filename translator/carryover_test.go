@@ -0,0 +1,67 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// promptRecorder upper-cases the text to translate and records the prompts.
+type promptRecorder struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *promptRecorder) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	p.mu.Lock()
+	p.prompts = append(p.prompts, cr.Prompt)
+	p.mu.Unlock()
+	text := cr.Prompt[strings.LastIndex(cr.Prompt, "Text to translate:\n\n")+20:]
+	return &Completion{Text: "<result>" + strings.ToUpper(text) + "</result>"}, nil
+}
+
+func TestLastSentences(t *testing.T) {
+	text := "One. Two! Three? Four."
+	if got := lastSentences(text, 2, nil); got != "Three? Four." {
+		t.Errorf("Expected the last two sentences, got %q", got)
+	}
+	if got := lastSentences(text, 9, nil); got != text {
+		t.Errorf("Expected the whole text, got %q", got)
+	}
+}
+
+func TestContextSentences(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	out := filepath.Join(dir, "out.txt")
+	first := strings.Repeat("The old cat slept on the warm mat by the kitchen door. ", 4) + "She was hungry."
+	second := strings.Repeat("Her owner came home late in the evening from work. ", 4) + "He fed her."
+	os.WriteFile(in, []byte(first+"\n\n"+second+"\n"), 0644)
+
+	provider := &promptRecorder{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 50, NoDelay: true, ToLang: "german", ContextSentences: 1})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(provider.prompts[0], "<context>") {
+		t.Errorf("Expected no context for the first chunk, got %q", provider.prompts[0])
+	}
+	var prompt string
+	for _, p := range provider.prompts {
+		if strings.Contains(p, "Text to translate:\n\nHer owner") {
+			prompt = p
+			break
+		}
+	}
+	want := "<context>\n<source>She was hungry.</source>\n<translation>SHE WAS HUNGRY.</translation>\n</context>"
+	if !strings.Contains(prompt, want) {
+		t.Errorf("Expected the end of the previous chunk and its translation, got %q", prompt)
+	}
+	got, _ := os.ReadFile(out)
+	if strings.Contains(string(got), "CONTEXT") || strings.Count(string(got), "SHE WAS HUNGRY.") != 1 {
+		t.Errorf("Expected the context left out of the output, got %q", got)
+	}
+}
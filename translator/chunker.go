@@ -0,0 +1,102 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Chunk is a piece of a document translated in one request, with the
+// Separator that follows it in the document, such as the blank line between
+// two paragraphs. Separators are written to the output as they are.
+type Chunk struct {
+	Text      string
+	Separator string
+}
+
+// Chunker splits the text of a document into chunks, see Config.Chunker.
+// The chunks and their separators must add up to text. Protected content
+// is replaced with markers such as ⟦0⟧ by then, which must not be split.
+type Chunker interface {
+	Split(text string) []Chunk
+}
+
+// ChunkerFunc adapts a function to the Chunker interface.
+type ChunkerFunc func(text string) []Chunk
+
+func (f ChunkerFunc) Split(text string) []Chunk {
+	return f(text)
+}
+
+// ParagraphChunker returns the Chunker of ChunkingParagraph with the chunk
+// size and tokenizers of config: chunks are filled with paragraphs, and
+// paragraphs longer than a chunk are split at lines, then sentences, then
+// the clauses proposed by Config.SplitModel if it is set.
+func ParagraphChunker(config Config) Chunker {
+	return builtinChunker{t: NewTranslator(config), chunking: ChunkingParagraph}
+}
+
+// MarkdownChunker returns the Chunker of ChunkingMarkdown with the chunk
+// size and tokenizers of config, for wrapping in a Chunker of your own.
+func MarkdownChunker(config Config) Chunker {
+	return builtinChunker{t: NewTranslator(config), chunking: ChunkingMarkdown}
+}
+
+type builtinChunker struct {
+	t        *Translator
+	chunking string
+}
+
+func (c builtinChunker) Split(text string) []Chunk {
+	return c.t.split(context.Background(), text, c.chunking)
+}
+
+// split cuts text into chunks in the chunking mode given; ctx is for the
+// requests of Config.SplitModel.
+func (t *Translator) split(ctx context.Context, text, chunking string) []Chunk {
+	var pieces []string
+	if chunking == ChunkingMarkdown {
+		pieces = t.splitMarkdown(ctx, text)
+	} else {
+		pieces = t.splitIntoChunks(ctx, text)
+	}
+
+	chunks := make([]Chunk, len(pieces))
+	separators, ok := chunkSeparators(text, pieces)
+	for i, piece := range pieces {
+		chunks[i].Text = piece
+		switch {
+		case ok:
+			chunks[i].Separator = separators[i]
+		case i < len(pieces)-1:
+			// Not expected from the splitters above; join the chunks
+			// with line breaks as earlier versions did.
+			breaks := 1
+			if chunking == ChunkingMarkdown {
+				breaks = 2
+			}
+			breaks -= len(piece) - len(strings.TrimRight(piece, "\n"))
+			if breaks > 0 {
+				chunks[i].Separator = strings.Repeat("\n", breaks)
+			}
+		}
+	}
+	return chunks
+}
+
+// chunk splits text with Config.Chunker, or the built-in splitter of the
+// chunking mode given, checking that a Config.Chunker loses no text.
+func (t *Translator) chunk(ctx context.Context, text, chunking string) ([]Chunk, error) {
+	if t.config.Chunker == nil {
+		return t.split(ctx, text, chunking), nil
+	}
+	chunks := t.config.Chunker.Split(text)
+	var joined strings.Builder
+	for _, c := range chunks {
+		joined.WriteString(c.Text + c.Separator)
+	}
+	if joined.String() != text {
+		return nil, fmt.Errorf("the chunks of Config.Chunker do not add up to the text they were split from")
+	}
+	return chunks, nil
+}
@@ -0,0 +1,58 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// cueChunker puts every subtitle cue, a block ending in a blank line, into a
+// chunk of its own.
+var cueChunker = ChunkerFunc(func(text string) []Chunk {
+	var chunks []Chunk
+	for _, cue := range strings.SplitAfter(text, "\n\n") {
+		if cue != "" {
+			body := strings.TrimRight(cue, "\n")
+			chunks = append(chunks, Chunk{Text: body, Separator: cue[len(body):]})
+		}
+	}
+	return chunks
+})
+
+func TestCustomChunker(t *testing.T) {
+	provider := &promptRecorder{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 500, NoDelay: true, Chunker: cueChunker})
+	source := "1\nHello there.\n\n2\nGeneral Kenobi.\n"
+	out, err := tr.TranslateText(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != strings.ToUpper(source) {
+		t.Errorf("Expected every cue translated in place, got %q", out)
+	}
+	if len(provider.prompts) != 2 || !strings.HasSuffix(provider.prompts[1], "2\nGeneral Kenobi.") {
+		t.Errorf("Expected a request per cue, got %q", provider.prompts)
+	}
+
+	// A chunker that loses text is rejected rather than dropping it from
+	// the output.
+	lossy := ChunkerFunc(func(text string) []Chunk { return []Chunk{{Text: strings.TrimSpace(text)}} })
+	tr = NewTranslator(Config{Provider: provider, ChunkSize: 500, NoDelay: true, Chunker: lossy})
+	if _, err := tr.TranslateText(context.Background(), source); ErrorClass(err) != ErrorClassConfig {
+		t.Errorf("Expected a config error, got %v", err)
+	}
+}
+
+func TestBuiltinChunkers(t *testing.T) {
+	text := "# Title\n\nFirst paragraph of the text.\n\n## Section\n\nSecond paragraph of the text.\n"
+	for _, chunker := range []Chunker{ParagraphChunker(Config{ChunkSize: 10}), MarkdownChunker(Config{ChunkSize: 10})} {
+		chunks := chunker.Split(text)
+		var joined strings.Builder
+		for _, c := range chunks {
+			joined.WriteString(c.Text + c.Separator)
+		}
+		if len(chunks) < 2 || joined.String() != text {
+			t.Errorf("Expected chunks adding up to the text, got %q", chunks)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package translator
+
+import "fmt"
+
+// DefaultChunkMargin is the share of ChunkSize held back when
+// Config.ChunkMargin is nil.
+const DefaultChunkMargin = 0.2
+
+// outputTokens is roughly how many tokens a translation into a language
+// takes per token of the same text in English. The ratio of two languages
+// is the expected growth of a chunk translated from one into the other.
+var outputTokens = map[string]float64{
+	"english": 1, "german": 1.3, "french": 1.3, "spanish": 1.25, "italian": 1.3, "portuguese": 1.25,
+	"dutch": 1.25, "polish": 1.6, "czech": 1.6, "turkish": 1.5, "finnish": 1.5, "russian": 2, "ukrainian": 2.1,
+	"bulgarian": 2, "greek": 2.4, "arabic": 1.8, "hebrew": 1.7, "chinese": 1.2, "japanese": 1.4, "korean": 1.6,
+}
+
+// ChunkSizing explains the size of the chunks a document is split into:
+// ChunkSize less the margin for token counts that come out low, and less
+// again if the translation of a chunk would not fit Config.MaxTokens or the
+// limits of the model, see ModelProfile.
+type ChunkSizing struct {
+	ChunkSize int     `json:"chunk_size"`
+	Margin    float64 `json:"margin"`
+	// Tokens is the size chunks are filled up to.
+	Tokens int `json:"tokens"`
+	// PromptOverhead is the number of tokens each request adds to its
+	// chunk for instructions.
+	PromptOverhead int `json:"prompt_overhead"`
+	// Expansion is the expected number of output tokens per chunk token
+	// for the language pair, see FromLang and ToLang.
+	Expansion float64 `json:"expansion"`
+	// MaxTokensBound is set when the longest answer allowed, by
+	// Config.MaxTokens or the model, lowered Tokens.
+	MaxTokensBound bool `json:"max_tokens_bound,omitempty"`
+	// ContextBound is set when the context length of the model lowered
+	// Tokens.
+	ContextBound bool `json:"context_bound,omitempty"`
+	// LimitModel is the model whose limit lowered Tokens.
+	LimitModel string `json:"limit_model,omitempty"`
+}
+
+// String explains the size, e.g. "400 tokens: chunk size 500 less a 20%
+// margin".
+func (s ChunkSizing) String() string {
+	if s.ContextBound {
+		return fmt.Sprintf("%d tokens: the chunk, the instructions and the translation, expected %.1f times as long, must fit the context of %s", s.Tokens, s.Expansion, s.LimitModel)
+	}
+	if s.MaxTokensBound && s.LimitModel != "" {
+		return fmt.Sprintf("%d tokens: the translation, expected %.1f times as long, must fit the longest answer of %s", s.Tokens, s.Expansion, s.LimitModel)
+	}
+	if s.MaxTokensBound {
+		return fmt.Sprintf("%d tokens: the translation, expected %.1f times as long, must fit the maximum tokens of an answer", s.Tokens, s.Expansion)
+	}
+	if s.Tokens == s.ChunkSize {
+		return fmt.Sprintf("%d tokens, the chunk size", s.Tokens)
+	}
+	return fmt.Sprintf("%d tokens: chunk size %d less a %.0f%% margin", s.Tokens, s.ChunkSize, s.Margin*100)
+}
+
+// ChunkSizing returns how the chunk size of the Translator's config comes
+// about.
+func (t *Translator) ChunkSizing() ChunkSizing {
+	s := t.chunkSizing()
+	s.PromptOverhead = t.promptOverhead()
+	return s
+}
+
+func (t *Translator) chunkSizing() ChunkSizing {
+	s := ChunkSizing{ChunkSize: t.config.ChunkSize, Margin: DefaultChunkMargin, Expansion: t.expansion()}
+	if t.config.ChunkMargin != nil {
+		s.Margin = *t.config.ChunkMargin
+	}
+	s.Tokens = int(float64(t.config.ChunkSize) * (1 - s.Margin))
+	if t.config.ChunkMargin == nil && s.Tokens < 100 {
+		// Small chunks make do without the default margin.
+		s.Tokens = t.config.ChunkSize
+	}
+
+	context, output := t.modelLimits()
+	answer, answerModel := t.config.MaxTokens, ""
+	if output.tokens > 0 && (answer <= 0 || output.tokens < answer) {
+		answer, answerModel = output.tokens, output.model
+	}
+	resultTag := t.countTokens("<result></result>")
+	if answer > 0 {
+		if fit := fitTokens(float64(answer-resultTag) / s.Expansion); fit < s.Tokens {
+			s.Tokens, s.MaxTokensBound, s.LimitModel = fit, true, answerModel
+		}
+	}
+	if context.tokens > 0 {
+		fit := fitTokens(float64(context.tokens-t.promptOverhead()-resultTag) / (1 + s.Expansion))
+		if fit < s.Tokens {
+			s.Tokens, s.MaxTokensBound, s.ContextBound, s.LimitModel = fit, false, true, context.model
+		}
+	}
+	return s
+}
+
+func fitTokens(fit float64) int {
+	if fit < 1 {
+		return 1
+	}
+	return int(fit)
+}
+
+// modelLimit is a limit of a model's profile.
+type modelLimit struct {
+	tokens int
+	model  string
+}
+
+// modelLimits returns the smallest context length and longest answer of the
+// models a chunk can be sent to, the active model and Config.FallbackModels.
+func (t *Translator) modelLimits() (context, output modelLimit) {
+	for _, model := range append([]string{t.activeModel()}, t.config.FallbackModels...) {
+		p := t.profileFor(model)
+		if p.ContextLength > 0 && (context.tokens == 0 || p.ContextLength < context.tokens) {
+			context = modelLimit{p.ContextLength, model}
+		}
+		if p.MaxOutputTokens > 0 && (output.tokens == 0 || p.MaxOutputTokens < output.tokens) {
+			output = modelLimit{p.MaxOutputTokens, model}
+		}
+	}
+	return context, output
+}
+
+// promptOverhead is the number of tokens the instructions add to a chunk.
+func (t *Translator) promptOverhead() int {
+	system, prompt := t.buildPrompt("", promptContext{})
+	return t.countTokens(system + prompt)
+}
+
+// expansion returns the expected growth of a chunk translated from
+// Config.FromLang into Config.ToLang; a language not in outputTokens, or
+// FromLang not set, counts as English.
+func (t *Translator) expansion() float64 {
+	ratio := func(lang string) float64 {
+		if r, ok := outputTokens[languageName(lang)]; ok {
+			return r
+		}
+		return 1
+	}
+	return ratio(t.config.ToLang) / ratio(t.config.FromLang)
+}
@@ -0,0 +1,85 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChunkSizing(t *testing.T) {
+	zero, half := 0.0, 0.5
+	testCases := []struct {
+		config Config
+		tokens int
+		bound  bool
+	}{
+		{Config{ChunkSize: 500}, 400, false},
+		{Config{ChunkSize: 500, ChunkMargin: &zero}, 500, false},
+		{Config{ChunkSize: 500, ChunkMargin: &half}, 250, false},
+		{Config{ChunkSize: 60}, 60, false},
+		{Config{ChunkSize: 60, ChunkMargin: &half}, 30, false},
+		// A German translation of 400 tokens of English would not fit in
+		// 300.
+		{Config{ChunkSize: 500, MaxTokens: 300, ToLang: "german"}, 227, true},
+		{Config{ChunkSize: 500, MaxTokens: 300, FromLang: "de", ToLang: "german"}, 296, true},
+	}
+	for _, tc := range testCases {
+		s := NewTranslator(tc.config).ChunkSizing()
+		if s.Tokens != tc.tokens || s.MaxTokensBound != tc.bound {
+			t.Errorf("%+v: expected %d tokens, got %+v", tc.config, tc.tokens, s)
+		}
+	}
+
+	s := NewTranslator(Config{ChunkSize: 500, ToLang: "german"}).ChunkSizing()
+	if s.String() != "400 tokens: chunk size 500 less a 20% margin" || s.PromptOverhead == 0 {
+		t.Errorf("Unexpected sizing %q %+v", s, s)
+	}
+
+	text := strings.Repeat("A sentence of a paragraph. ", 100)
+	full := NewTranslator(Config{ChunkSize: 200, ChunkMargin: &zero}).splitIntoChunks(context.Background(), text)
+	halved := NewTranslator(Config{ChunkSize: 200, ChunkMargin: &half}).splitIntoChunks(context.Background(), text)
+	if len(halved) < 2*len(full)-1 {
+		t.Errorf("Expected twice the chunks with half the size, got %d and %d", len(full), len(halved))
+	}
+	if err := (Config{ChunkSize: 500, ToLang: "german", ChunkMargin: &[]float64{1}[0]}).Validate(); ErrorClass(err) != ErrorClassConfig {
+		t.Errorf("Expected a margin of 1 rejected, got %v", err)
+	}
+}
+
+func TestChunkSizingModelLimits(t *testing.T) {
+	profiles := map[string]ModelProfile{
+		"small/context": {ContextLength: 4096},
+		"short/answers": {MaxOutputTokens: 1024},
+	}
+	s := NewTranslator(Config{ChunkSize: 4000, Model: "small/context", ModelProfiles: profiles, ToLang: "russian"}).ChunkSizing()
+	if !s.ContextBound || s.LimitModel != "small/context" || s.Tokens*3+s.PromptOverhead > 4096 {
+		t.Errorf("Expected the chunk and its translation to fit the context, got %+v", s)
+	}
+
+	// A fallback model with a shorter answer limit binds too.
+	s = NewTranslator(Config{ChunkSize: 4000, Model: "small/context", FallbackModels: []string{"short/answers"}, ModelProfiles: profiles}).ChunkSizing()
+	if !s.MaxTokensBound || s.ContextBound || s.LimitModel != "short/answers" || s.Tokens > 1024 {
+		t.Errorf("Expected the answer limit of the fallback model, got %+v", s)
+	}
+	if !strings.Contains(s.String(), "longest answer of short/answers") {
+		t.Errorf("Unexpected explanation %q", s)
+	}
+	s = NewTranslator(Config{ChunkSize: 4000, Model: "short/answers", MaxTokens: 500, ModelProfiles: profiles}).ChunkSizing()
+	if s.LimitModel != "" || !s.MaxTokensBound {
+		t.Errorf("Expected a lower --max-tokens to bind, got %+v", s)
+	}
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	os.WriteFile(in, []byte(strings.Repeat("A sentence of a paragraph. ", 1000)), 0644)
+	tr := NewTranslator(Config{Provider: &promptRecorder{}, ChunkSize: 10000, Model: "short/answers", ModelProfiles: profiles,
+		NoDelay: true, NoSanityCheck: true})
+	if err := tr.TranslateFile(in, filepath.Join(dir, "out.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if w := tr.Result().Warnings; len(w) != 1 || w[0].Kind != WarningChunkSize || tr.Result().ChunksTranslated < 6 {
+		t.Errorf("Expected smaller chunks and a warning, got %d chunks and %+v", tr.Result().ChunksTranslated, w)
+	}
+}
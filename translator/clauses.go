@@ -0,0 +1,108 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// splitLongParagraph cuts a paragraph without sentence or line breaks into
+// pieces of about size tokens: at the clause boundaries proposed by
+// Config.SplitModel if it is set and its answer fits the paragraph, by token
+// counts otherwise, see splitByTokens.
+func (t *Translator) splitLongParagraph(ctx context.Context, paragraph string, size int) []string {
+	if t.config.SplitModel == "" {
+		return t.splitByTokens(paragraph, size)
+	}
+	clauses, err := t.proposeClauses(ctx, paragraph, size)
+	if err != nil {
+		if t.config.Verbose {
+			fmt.Printf("Cutting a paragraph of ~%d tokens by length: %v\n", t.countTokens(paragraph), err)
+		}
+		return t.splitByTokens(paragraph, size)
+	}
+
+	// Clauses are packed into pieces up to size; a clause longer than that
+	// is still cut by length.
+	var pieces []string
+	current, currentTokens := "", 0
+	for _, clause := range clauses {
+		tokens := t.countTokens(clause)
+		if currentTokens > 0 && currentTokens+tokens > size {
+			pieces = append(pieces, current)
+			current, currentTokens = "", 0
+		}
+		if tokens > size {
+			pieces = append(pieces, t.splitByTokens(clause, size)...)
+			continue
+		}
+		current += clause
+		currentTokens += tokens
+	}
+	if current != "" {
+		pieces = append(pieces, current)
+	}
+	return pieces
+}
+
+// proposeClauses asks Config.SplitModel where paragraph can be cut between
+// clauses. The clauses add up to paragraph, each with the whitespace after
+// it.
+func (t *Translator) proposeClauses(ctx context.Context, paragraph string, size int) ([]string, error) {
+	model := t.config.SplitModel
+	completion, err := t.provider.Complete(ctx, CompletionRequest{
+		Model: model,
+		System: fmt.Sprintf("The text of the user message is too long to translate in one piece. Put a line break at the clause boundaries where it can be cut without losing meaning, "+
+			"such as after a semicolon or before a conjunction, so that no part is longer than about %d tokens. "+
+			"Do not change, add or remove anything else, and do not break markers like ⟦0⟧. Place the answer in the tag <result>", size),
+		Prompt: paragraph,
+		RunID:  t.runID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.addCompletionUsage(completion)
+	answer, err := t.extractTranslation(model, completion.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every line must be the next part of paragraph, with only whitespace
+	// between them.
+	var clauses []string
+	start, cursor, matched := 0, 0, 0
+	for _, line := range strings.Split(answer, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.Index(paragraph[cursor:], line)
+		if i < 0 || strings.TrimSpace(paragraph[cursor:cursor+i]) != "" {
+			return nil, fmt.Errorf("%s changed the text", model)
+		}
+		if matched++; matched > 1 {
+			if at := cursor + i; clauseBoundary(paragraph[:at]) {
+				clauses = append(clauses, paragraph[start:at])
+				start = at
+			}
+		}
+		cursor += i + len(line)
+	}
+	if strings.TrimSpace(paragraph[cursor:]) != "" {
+		return nil, fmt.Errorf("%s left out text", model)
+	}
+	return append(clauses, paragraph[start:]), nil
+}
+
+// clauseBoundary reports whether a paragraph can be cut after text: after
+// whitespace or punctuation, as after "，" in CJK text, and not inside a
+// word or a marker such as ⟦0⟧.
+func clauseBoundary(text string) bool {
+	if open := strings.LastIndex(text, "⟦"); open >= 0 && !strings.Contains(text[open:], "⟧") {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return unicode.IsSpace(r) || unicode.IsPunct(r)
+}
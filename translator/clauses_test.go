@@ -0,0 +1,71 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// clauseProvider proposes a cut after every comma, or rewrites the text if
+// garble is set, and upper-cases chunks to translate.
+type clauseProvider struct {
+	garble  bool
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *clauseProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	if strings.Contains(cr.System, "clause boundaries") {
+		if p.garble {
+			return &Completion{Text: "<result>Something else entirely.</result>"}, nil
+		}
+		return &Completion{Text: "<result>" + strings.ReplaceAll(cr.Prompt, ", ", ",\n") + "</result>"}, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	text := cr.Prompt[strings.LastIndex(cr.Prompt, "Text to translate:\n\n")+20:]
+	p.prompts = append(p.prompts, text)
+	return &Completion{Text: "<result>" + strings.ToUpper(text) + "</result>"}, nil
+}
+
+func TestSplitModel(t *testing.T) {
+	source := strings.Repeat("the river ran past the old mill and the fields beyond it, ", 6) + "and on to the sea"
+	for _, garble := range []bool{false, true} {
+		provider := &clauseProvider{garble: garble}
+		tr := NewTranslator(Config{Provider: provider, ChunkSize: 40, NoDelay: true, NoSanityCheck: true, SplitModel: "cheap"})
+		out, err := tr.TranslateText(context.Background(), source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != strings.ToUpper(source) {
+			t.Errorf("Expected the whole paragraph translated, got %q", out)
+		}
+		if len(provider.prompts) < 2 {
+			t.Fatalf("Expected the paragraph cut into several chunks, got %q", provider.prompts)
+		}
+		if garble {
+			// The answer is ignored and the paragraph cut by length.
+			continue
+		}
+		for _, chunk := range provider.prompts[:len(provider.prompts)-1] {
+			if !strings.HasSuffix(strings.TrimSpace(chunk), ",") {
+				t.Errorf("Expected a cut after a comma, got %q", chunk)
+			}
+		}
+	}
+}
+
+func TestClauseBoundary(t *testing.T) {
+	for text, want := range map[string]bool{
+		"one, ":     true,
+		"一，":        true,
+		"one":       false,
+		"keep ⟦1":   false,
+		"keep ⟦1⟧ ": true,
+	} {
+		if got := clauseBoundary(text); got != want {
+			t.Errorf("%q: expected %v, got %v", text, want, got)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// newHTTPClient builds the client shared by all requests of a Translator.
+// Connections are kept alive and reused across chunks, and HTTP/2 is
+// negotiated where the server offers it, so a run of many small chunks pays
+// for the TLS handshake once.
+func newHTTPClient(config Config) *http.Client {
+	idle := config.Concurrency * 2
+	if idle < 10 {
+		idle = 10
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		DisableKeepAlives:     false,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   idle,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return &http.Client{Transport: transport, Timeout: 5 * time.Minute}
+}
+
+// warmUp opens a connection to the API before the first chunk is sent.
+func (t *Translator) warmUp(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, "HEAD", t.baseURL()+"/models", nil)
+	if err != nil {
+		return
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if t.config.Verbose {
+			fmt.Printf("Warm-up request failed: %v\n", err)
+		}
+		return
+	}
+	resp.Body.Close()
+
+	if t.config.Verbose {
+		fmt.Printf("Warm-up connection established in %v (%s)\n", time.Since(start).Round(time.Millisecond), resp.Proto)
+	}
+}
+
+// CheckOnline reports whether the API can be reached at all. Any HTTP
+// response counts, only network failures are returned as errors.
+func (t *Translator) CheckOnline() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", t.baseURL()+"/models", nil)
+	if err != nil {
+		return classify(ErrorClassConfig, err)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return classify(ErrorClassNetwork, err)
+	}
+	resp.Body.Close()
+	return nil
+}
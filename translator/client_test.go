@@ -0,0 +1,44 @@
+package translator
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConnectionReuse(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(echoHandler(t))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "in.txt")
+	var paragraphs []string
+	for i := 0; i < 6; i++ {
+		paragraphs = append(paragraphs, strings.Repeat("Some words here. ", 12))
+	}
+	os.WriteFile(inputPath, []byte(strings.Join(paragraphs, "\n\n")), 0644)
+
+	translator := NewTranslator(Config{BaseURL: server.URL, ChunkSize: 60, WarmUp: true})
+	if err := translator.TranslateFile(inputPath, filepath.Join(dir, "out.txt")); err != nil {
+		t.Fatalf("TranslateFile failed: %v", err)
+	}
+
+	if translator.Result().Chunks < 3 {
+		t.Fatalf("Expected several chunks, got %d", translator.Result().Chunks)
+	}
+	if n := atomic.LoadInt32(&connections); n != 1 {
+		t.Errorf("Expected warm-up and all chunks to share one connection, got %d", n)
+	}
+}
@@ -0,0 +1,44 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTranslateFileContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(input, []byte("Hello world.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	translator := NewTranslator(Config{BaseURL: server.URL, ChunkSize: 100})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := translator.TranslateFileContext(ctx, input, filepath.Join(dir, "out.txt"))
+	if ErrorClass(err) != ErrorClassCanceled || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a canceled error wrapping the deadline, got %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("Cancellation took %v", time.Since(start))
+	}
+}
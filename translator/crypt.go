@@ -0,0 +1,187 @@
+package translator
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// StorageKeyEnv names the environment variable holding the passphrase for
+// encrypted local storage.
+const StorageKeyEnv = "GO_AI_TRANSLATE_STORAGE_KEY"
+
+// encryptedPrefix marks a record sealed with SealRecord: the base64 of a
+// salt, the nonce and the ciphertext.
+var encryptedPrefix = []byte("aes256gcm:")
+
+const (
+	saltSize = 16
+	// kdfIterations is the PBKDF2 work factor for each salt. Derived keys
+	// are kept for the life of the process, and a process seals all its
+	// records with one salt, so it is paid about once per run.
+	kdfIterations = 200000
+)
+
+// StorageKey turns a passphrase into the raw secret for encrypted local
+// storage. It is not a cipher key: the AES-256 keys are derived from it with
+// PBKDF2 and a random salt stored with every record, so equal passphrases
+// give different keys on different installs and guesses cost the full work
+// factor each.
+func StorageKey(passphrase string) []byte {
+	sum := sha256.Sum256([]byte(passphrase))
+	return sum[:]
+}
+
+// derivedKeys caches keys derived from a secret and salt, and the salt each
+// secret seals new records with.
+var derivedKeys = struct {
+	sync.Mutex
+	keys  map[string][]byte
+	salts map[string][]byte
+}{keys: map[string][]byte{}, salts: map[string][]byte{}}
+
+// deriveKey returns the AES-256 key of secret and salt.
+func deriveKey(secret, salt []byte) []byte {
+	id := string(secret) + "\x00" + string(salt)
+	derivedKeys.Lock()
+	key, ok := derivedKeys.keys[id]
+	derivedKeys.Unlock()
+	if ok {
+		return key
+	}
+
+	key = pbkdf2SHA256(secret, salt, kdfIterations, 32)
+	derivedKeys.Lock()
+	derivedKeys.keys[id] = key
+	if _, ok := derivedKeys.salts[string(secret)]; !ok {
+		// Records added to a store then share the salt of those read.
+		derivedKeys.salts[string(secret)] = salt
+	}
+	derivedKeys.Unlock()
+	return key
+}
+
+// sealSalt returns the salt new records are sealed with under secret.
+func sealSalt(secret []byte) ([]byte, error) {
+	derivedKeys.Lock()
+	salt, ok := derivedKeys.salts[string(secret)]
+	derivedKeys.Unlock()
+	if ok {
+		return salt, nil
+	}
+	salt = make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// pbkdf2SHA256 is PBKDF2 (RFC 8018) with HMAC-SHA256.
+func pbkdf2SHA256(password, salt []byte, iterations, size int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	u := make([]byte, 0, prf.Size())
+	for block := uint32(1); len(key) < size; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		key = prf.Sum(key)
+		t := key[len(key)-prf.Size():]
+		u = append(u[:0], t...)
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return key[:size]
+}
+
+// IsSealed reports whether record was written by SealRecord.
+func IsSealed(record []byte) bool {
+	return bytes.HasPrefix(record, encryptedPrefix)
+}
+
+// SealRecord encrypts record with AES-GCM under a key derived from the
+// secret key and a salt. The result is a single line of text, so sealed
+// records can be stored in JSON lines files.
+func SealRecord(key, record []byte) ([]byte, error) {
+	salt, err := sealSalt(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(deriveKey(key, salt))
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(append(append([]byte{}, salt...), nonce...), nonce, record, nil)
+
+	out := make([]byte, len(encryptedPrefix)+base64.StdEncoding.EncodedLen(len(sealed)))
+	copy(out, encryptedPrefix)
+	base64.StdEncoding.Encode(out[len(encryptedPrefix):], sealed)
+	return out, nil
+}
+
+// OpenRecord reverses SealRecord. Records that are not sealed are returned
+// unchanged, so storage written before encryption was enabled stays readable.
+func OpenRecord(key, record []byte) ([]byte, error) {
+	if !IsSealed(record) {
+		return record, nil
+	}
+	if key == nil {
+		return nil, classify(ErrorClassConfig, fmt.Errorf("record is encrypted, set %s", StorageKeyEnv))
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(record[len(encryptedPrefix):]))
+	if err != nil || len(sealed) < saltSize {
+		return nil, errors.New("malformed encrypted record")
+	}
+
+	gcm, err := newGCM(deriveKey(key, sealed[:saltSize]))
+	sealed = sealed[saltSize:]
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("malformed encrypted record")
+	}
+
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, classify(ErrorClassConfig, errors.New("cannot decrypt record, wrong storage key?"))
+	}
+	return plain, nil
+}
+
+// readKey is the key that opens records stored earlier: StorageKey, or
+// ReadKey when new records are not sealed.
+func (t *Translator) readKey() []byte {
+	if t.config.StorageKey != nil {
+		return t.config.StorageKey
+	}
+	return t.config.ReadKey
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, classify(ErrorClassConfig, fmt.Errorf("invalid storage key: %w", err))
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,105 @@
+package translator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSealRecord(t *testing.T) {
+	key := StorageKey("correct horse")
+	record := []byte(`{"source":"Hello","target":"Привет"}`)
+
+	sealed, err := SealRecord(key, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(sealed) || bytes.Contains(sealed, []byte("Hello")) || bytes.ContainsRune(sealed, '\n') {
+		t.Fatalf("Expected an opaque single-line record, got %q", sealed)
+	}
+
+	opened, err := OpenRecord(key, sealed)
+	if err != nil || !bytes.Equal(opened, record) {
+		t.Errorf("Expected %q, got %q, %v", record, opened, err)
+	}
+
+	if _, err := OpenRecord(StorageKey("wrong"), sealed); ErrorClass(err) != ErrorClassConfig {
+		t.Errorf("Expected a config error for a wrong key, got %v", err)
+	}
+	if _, err := OpenRecord(nil, sealed); err == nil {
+		t.Error("Expected an error without a key")
+	}
+	if plain, err := OpenRecord(nil, record); err != nil || !bytes.Equal(plain, record) {
+		t.Errorf("Expected plain records to pass through, got %q, %v", plain, err)
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	// RFC 7914, section 11.
+	got := hex.EncodeToString(pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64))
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestSealRecordSalt(t *testing.T) {
+	key := StorageKey("correct horse")
+	record := []byte("paragraph")
+
+	first, err := SealRecord(key, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another install seals with a salt of its own.
+	derivedKeys.Lock()
+	delete(derivedKeys.salts, string(key))
+	derivedKeys.Unlock()
+	second, err := SealRecord(key, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt := func(sealed []byte) []byte {
+		raw, _ := base64.StdEncoding.DecodeString(string(sealed[len(encryptedPrefix):]))
+		return raw[:saltSize]
+	}
+	if bytes.Equal(salt(first), salt(second)) {
+		t.Error("Expected a new random salt")
+	}
+	for _, sealed := range [][]byte{first, second} {
+		if opened, err := OpenRecord(key, sealed); err != nil || !bytes.Equal(opened, record) {
+			t.Errorf("Expected %q, got %q, %v", record, opened, err)
+		}
+	}
+
+}
+
+func TestEncryptedTranslationMemory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tm.jsonl")
+	key := StorageKey("secret")
+
+	tm, err := openTranslationMemory(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.add([]tmEntry{{Lang: "russian", Source: "Confidential paragraph", Target: "Секретный абзац", Embedding: []float64{1, 0}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "Confidential") {
+		t.Errorf("Expected the memory to be encrypted at rest, got %q", data)
+	}
+
+	reopened, err := openTranslationMemory(path, key)
+	if err != nil || reopened.size() != 1 || reopened.entries[0].Source != "Confidential paragraph" {
+		t.Fatalf("Expected to read the entry back, got %+v, %v", reopened, err)
+	}
+	if _, err := openTranslationMemory(path, nil); err == nil {
+		t.Error("Expected an error opening an encrypted memory without a key")
+	}
+}
@@ -0,0 +1,183 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	defaultDedupeThreshold = 0.97
+	// dedupeMinLength keeps headings and short lines out of deduplication,
+	// where near-duplicates rarely mean the same thing.
+	dedupeMinLength = 80
+)
+
+// Substitution records a paragraph whose translation was copied from a
+// near-duplicate paragraph translated earlier instead of being sent to the
+// model.
+type Substitution struct {
+	File            string  `json:"file"`
+	Paragraph       string  `json:"paragraph"`
+	CanonicalFile   string  `json:"canonical_file"`
+	CanonicalSource string  `json:"canonical_source"`
+	Similarity      float64 `json:"similarity"`
+}
+
+type dedupeParagraph struct {
+	file  string
+	text  string
+	score float64
+	// canonical is the paragraph this one duplicates, or itself.
+	canonical *dedupeParagraph
+}
+
+type dedupeIndex struct {
+	paragraphs   map[string]*dedupeParagraph
+	translations map[string]string
+	report       []Substitution
+}
+
+// PrepareDedupe embeds the paragraphs of all files and groups paragraphs that
+// are at least threshold similar. The first paragraph of each group, in the
+// order of paths, is translated normally; when later files contain one of its
+// duplicates, the translation is reused instead of requesting a new one.
+func (t *Translator) PrepareDedupe(paths []string, threshold float64) error {
+	if threshold <= 0 {
+		threshold = defaultDedupeThreshold
+	}
+	if err := t.openAudit(); err != nil {
+		return err
+	}
+
+	idx := &dedupeIndex{
+		paragraphs:   map[string]*dedupeParagraph{},
+		translations: map[string]string{},
+	}
+
+	var all []*dedupeParagraph
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, p := range splitSegments(string(content)) {
+			if len(p) < dedupeMinLength || idx.paragraphs[p] != nil {
+				continue
+			}
+			para := &dedupeParagraph{file: path, text: p, score: 1}
+			para.canonical = para
+			idx.paragraphs[p] = para
+			all = append(all, para)
+		}
+	}
+
+	const batch = 64
+	var vectors [][]float64
+	for i := 0; i < len(all); i += batch {
+		end := i + batch
+		if end > len(all) {
+			end = len(all)
+		}
+		inputs := make([]string, 0, end-i)
+		for _, p := range all[i:end] {
+			inputs = append(inputs, p.text)
+		}
+		v, err := t.embed(context.Background(), inputs)
+		if err != nil {
+			return fmt.Errorf("failed to embed paragraphs for deduplication: %w", err)
+		}
+		vectors = append(vectors, v...)
+	}
+
+	var canonicals []int
+	for i, p := range all {
+		bestScore, best := 0.0, -1
+		for _, c := range canonicals {
+			if all[c].file == p.file {
+				continue
+			}
+			if s := cosineSimilarity(vectors[i], vectors[c]); s >= threshold && s > bestScore {
+				bestScore, best = s, c
+			}
+		}
+		if best >= 0 {
+			p.canonical, p.score = all[best], bestScore
+		} else {
+			canonicals = append(canonicals, i)
+		}
+	}
+
+	if t.config.Verbose {
+		fmt.Printf("Deduplication: %d paragraphs, %d distinct\n", len(all), len(canonicals))
+	}
+
+	t.dedupe = idx
+	return nil
+}
+
+// DedupeReport lists the substitutions made since PrepareDedupe.
+func (t *Translator) DedupeReport() []Substitution {
+	if t.dedupe == nil {
+		return nil
+	}
+	return t.dedupe.report
+}
+
+// substituteDuplicates replaces paragraphs whose canonical paragraph has
+// already been translated by a marker that restores to that translation.
+// sourceSpans receives the original paragraph so the source side of the
+// chunk can still be reconstructed.
+func (t *Translator) substituteDuplicates(path, text string, spans, sourceSpans []string) (string, []string, []string) {
+	if t.dedupe == nil {
+		return text, spans, sourceSpans
+	}
+
+	parts := strings.Split(text, "\n\n")
+	for i, part := range parts {
+		key := strings.TrimSpace(unmaskSpans(part, sourceSpans))
+		p := t.dedupe.paragraphs[key]
+		if p == nil || p.canonical == p {
+			continue
+		}
+		translation, ok := t.dedupe.translations[p.canonical.text]
+		if !ok {
+			continue
+		}
+
+		core := strings.TrimSpace(part)
+		start := strings.Index(part, core)
+		spans = append(spans, translation)
+		sourceSpans = append(sourceSpans, core)
+		parts[i] = part[:start] + maskOpen + fmt.Sprint(len(spans)-1) + maskClose + part[start+len(core):]
+
+		t.dedupe.report = append(t.dedupe.report, Substitution{
+			File:            path,
+			Paragraph:       key,
+			CanonicalFile:   p.canonical.file,
+			CanonicalSource: p.canonical.text,
+			Similarity:      p.score,
+		})
+	}
+
+	return strings.Join(parts, "\n\n"), spans, sourceSpans
+}
+
+// rememberCanonical stores translations of canonical paragraphs so later
+// duplicates can reuse them.
+func (t *Translator) rememberCanonical(source, target string) {
+	if t.dedupe == nil {
+		return
+	}
+
+	sources, targets := splitSegments(source), splitSegments(target)
+	if len(sources) != len(targets) {
+		return
+	}
+	for i, s := range sources {
+		if p := t.dedupe.paragraphs[s]; p != nil && p.canonical == p {
+			t.dedupe.translations[s] = targets[i]
+		}
+	}
+}
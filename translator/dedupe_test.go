@@ -0,0 +1,53 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDedupe(t *testing.T) {
+	server := fakeEmbeddings(t)
+	defer server.Close()
+
+	dir := t.TempDir()
+	canonical := "The cat command prints files to standard output, it is part of the coreutils package."
+	duplicate := "The cat command prints files to the standard output and is part of the coreutils package."
+	first := filepath.Join(dir, "a.md")
+	second := filepath.Join(dir, "b.md")
+	os.WriteFile(first, []byte("# A\n\n"+canonical+"\n"), 0644)
+	secondText := "# B\n\n" + duplicate + "\n\nSomething entirely different happens in this paragraph of the document."
+	os.WriteFile(second, []byte(secondText), 0644)
+
+	translator := NewTranslator(Config{ToLang: "russian", EmbeddingsURL: server.URL})
+	if err := translator.PrepareDedupe([]string{first, second}, 0.95); err != nil {
+		t.Fatalf("PrepareDedupe failed: %v", err)
+	}
+
+	// Nothing is substituted before the canonical paragraph is translated.
+	if text, _, _ := translator.substituteDuplicates(second, secondText, nil, nil); text != secondText {
+		t.Errorf("Expected no substitution yet, got %q", text)
+	}
+
+	translator.rememberCanonical("# A\n\n"+canonical, "# А\n\nКоманда cat выводит файлы.")
+
+	text, spans, sourceSpans := translator.substituteDuplicates(second, secondText, nil, nil)
+	if strings.Contains(text, duplicate) {
+		t.Fatalf("Expected duplicate paragraph to be substituted, got %q", text)
+	}
+	if !strings.Contains(text, "Something entirely different") {
+		t.Errorf("Expected unrelated paragraph to stay, got %q", text)
+	}
+	if got := unmaskSpans(text, spans); !strings.Contains(got, "Команда cat выводит файлы.") {
+		t.Errorf("Expected canonical translation in output, got %q", got)
+	}
+	if got := unmaskSpans(text, sourceSpans); got != secondText {
+		t.Errorf("Expected source to be restorable, got %q", got)
+	}
+
+	report := translator.DedupeReport()
+	if len(report) != 1 || report[0].CanonicalFile != first || report[0].File != second {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
@@ -0,0 +1,162 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	deeplBaseURL     = "https://api.deepl.com"
+	deeplFreeBaseURL = "https://api-free.deepl.com"
+)
+
+// deeplLanguages maps language names accepted by --to to DeepL target
+// language codes. Codes such as "DE" or "pt-BR" are passed through.
+var deeplLanguages = map[string]string{
+	"arabic":     "AR",
+	"bulgarian":  "BG",
+	"chinese":    "ZH",
+	"czech":      "CS",
+	"danish":     "DA",
+	"dutch":      "NL",
+	"english":    "EN-US",
+	"estonian":   "ET",
+	"finnish":    "FI",
+	"french":     "FR",
+	"german":     "DE",
+	"greek":      "EL",
+	"hungarian":  "HU",
+	"indonesian": "ID",
+	"italian":    "IT",
+	"japanese":   "JA",
+	"korean":     "KO",
+	"latvian":    "LV",
+	"lithuanian": "LT",
+	"norwegian":  "NB",
+	"polish":     "PL",
+	"portuguese": "PT-PT",
+	"romanian":   "RO",
+	"russian":    "RU",
+	"slovak":     "SK",
+	"slovenian":  "SL",
+	"spanish":    "ES",
+	"swedish":    "SV",
+	"turkish":    "TR",
+	"ukrainian":  "UK",
+}
+
+func deeplTargetLang(lang string) (string, error) {
+	if code, ok := deeplLanguages[strings.ToLower(strings.TrimSpace(lang))]; ok {
+		return code, nil
+	}
+	if len(lang) == 2 || (len(lang) == 5 && lang[2] == '-') {
+		return strings.ToUpper(lang), nil
+	}
+	return "", fmt.Errorf("DeepL does not know the language %q, use a code such as DE", lang)
+}
+
+// deeplSourceLang is the DeepL source language code of lang, which has no
+// regional variant, or "" to let DeepL detect it.
+func deeplSourceLang(lang string) string {
+	code, err := deeplTargetLang(lang)
+	if err != nil {
+		return ""
+	}
+	return strings.SplitN(code, "-", 2)[0]
+}
+
+type deeplRequest struct {
+	Text       []string `json:"text"`
+	SourceLang string   `json:"source_lang,omitempty"`
+	TargetLang string   `json:"target_lang"`
+}
+
+type deeplResponse struct {
+	Translations []struct {
+		Text string `json:"text"`
+	} `json:"translations"`
+	Message string `json:"message"`
+}
+
+// deeplProvider translates chunks with the DeepL REST API. DeepL is a
+// machine translation service, not a language model: it receives the chunk
+// itself instead of the prompt and answers with the plain translation.
+type deeplProvider struct {
+	t *Translator
+}
+
+func (p deeplProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	t := p.t
+
+	req, requestBody, err := p.newRequest(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+	url := req.URL.String()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if auditErr := t.recordRequest(url, "deepl", cr.ChunkID, requestBody, 0, nil, err); auditErr != nil {
+			return nil, auditErr
+		}
+		if ctx.Err() != nil {
+			return nil, canceled(ctx.Err())
+		}
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if auditErr := t.recordRequest(url, "deepl", cr.ChunkID, requestBody, resp.StatusCode, body, err); auditErr != nil {
+		return nil, auditErr
+	}
+	if err != nil {
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to read response: %w", err))
+	}
+
+	var response deeplResponse
+	json.Unmarshal(body, &response)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, rateLimited(resp, fmt.Errorf("DeepL request failed with status %d: %s", resp.StatusCode, response.Message))
+	case resp.StatusCode == 456:
+		return nil, classify(ErrorClassAPI, fmt.Errorf("DeepL character quota exceeded"))
+	case resp.StatusCode != http.StatusOK:
+		return nil, classify(ErrorClassAPI, fmt.Errorf("DeepL request failed with status %d: %s", resp.StatusCode, string(body)))
+	case len(response.Translations) == 0:
+		return nil, classify(ErrorClassAPI, fmt.Errorf("no translation returned from DeepL"))
+	}
+
+	return &Completion{Text: response.Translations[0].Text, Plain: true}, nil
+}
+
+// newRequest builds the translation request of cr.
+func (p deeplProvider) newRequest(ctx context.Context, cr CompletionRequest) (*http.Request, []byte, error) {
+	t := p.t
+
+	targetLang, err := deeplTargetLang(cr.ToLang)
+	if err != nil {
+		return nil, nil, classify(ErrorClassConfig, err)
+	}
+	requestBody, err := json.Marshal(deeplRequest{Text: []string{cr.Text}, SourceLang: deeplSourceLang(t.sourceLang), TargetLang: targetLang})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := t.baseURL() + "/v2/translate"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+t.config.APIKey)
+	if cr.ChunkID != "" && !t.config.NoExternalMetadata {
+		req.Header.Set("X-Request-Id", cr.ChunkID)
+	}
+	return req, requestBody, nil
+}
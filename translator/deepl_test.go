@@ -0,0 +1,49 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeepLBackend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/translate" || r.Header.Get("Authorization") != "DeepL-Auth-Key secret:fx" {
+			t.Errorf("Unexpected request %s with authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req deeplRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TargetLang != "DE" || len(req.Text) != 1 {
+			t.Errorf("Unexpected DeepL request %+v: %v", req, err)
+		}
+		fmt.Fprintf(w, `{"translations":[{"detected_source_language":"EN","text":%q}]}`, strings.ToUpper(req.Text[0]))
+	}))
+	defer server.Close()
+
+	translator := NewTranslator(Config{Backend: BackendDeepL, APIKey: "secret:fx", BaseURL: server.URL, ToLang: "German", ChunkSize: 100, NoDelay: true})
+	var out bytes.Buffer
+	if err := translator.Translate(context.Background(), strings.NewReader("hello"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "HELLO" {
+		t.Errorf("Expected DeepL's translation as is, got %q", out.String())
+	}
+}
+
+func TestDeepLTargetLang(t *testing.T) {
+	testCases := map[string]string{"russian": "RU", "de": "DE", "pt-br": "PT-BR", "Klingon": ""}
+	for lang, expected := range testCases {
+		code, err := deeplTargetLang(lang)
+		if code != expected || (expected == "") != (err != nil) {
+			t.Errorf("%s: expected %q, got %q, %v", lang, expected, code, err)
+		}
+	}
+
+	if url := NewTranslator(Config{Backend: BackendDeepL, APIKey: "key:fx"}).baseURL(); url != deeplFreeBaseURL {
+		t.Errorf("Expected free plan keys to use %s, got %s", deeplFreeBaseURL, url)
+	}
+}
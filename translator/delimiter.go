@@ -0,0 +1,143 @@
+package translator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// delimiter is how a model is asked to mark the translation in its answer.
+// A model whose answers keep lacking the delimiter is asked for the next
+// one, see noteDelimiter.
+type delimiter int
+
+const (
+	// delimiterTag wraps the translation in <result></result>.
+	delimiterTag delimiter = iota
+	// delimiterFence puts it in a code block between ``` lines.
+	delimiterFence
+	// delimiterSentinel puts it between BEGIN TRANSLATION and END
+	// TRANSLATION lines.
+	delimiterSentinel
+	delimiterCount
+)
+
+// delimiterSwitchAfter is the number of answers in a row without the
+// delimiter after which a model is asked for the next one.
+const delimiterSwitchAfter = 2
+
+var (
+	fencedAnswerRe   = regexp.MustCompile("(?s)```[\\w-]*[ \t]*\r?\n(.*)\r?\n[ \t]*```")
+	sentinelAnswerRe = regexp.MustCompile(`(?s)BEGIN TRANSLATION[ \t]*\r?\n(.*)\r?\n[ \t]*END TRANSLATION`)
+	sentinelLineRe   = regexp.MustCompile(`(?m)^[ \t]*(?:BEGIN|END) TRANSLATION[ \t]*$`)
+)
+
+func (d delimiter) String() string {
+	switch d {
+	case delimiterFence:
+		return "``` code block"
+	case delimiterSentinel:
+		return "BEGIN TRANSLATION and END TRANSLATION lines"
+	}
+	return "<result> tag"
+}
+
+// instruction tells the model where to put the translation.
+func (d delimiter) instruction() string {
+	switch d {
+	case delimiterFence:
+		return "the answer place in a code block, between a line ``` before it and a line ``` after it"
+	case delimiterSentinel:
+		return "the answer place between a line BEGIN TRANSLATION before it and a line END TRANSLATION after it"
+	}
+	return "the answer place in the tag <result>"
+}
+
+// insist is the instruction of a retry after an answer without the
+// delimiter.
+func (d delimiter) insist() string {
+	switch d {
+	case delimiterFence:
+		return "Your previous answer had no ``` code block: answer with nothing but the translation, between a line ``` before it and a line ``` after it, without any comment"
+	case delimiterSentinel:
+		return "Your previous answer had no BEGIN TRANSLATION and END TRANSLATION lines: answer with nothing but the translation between them, without any comment"
+	}
+	return "Your previous answer had no <result> tag: answer with nothing but the translation, wrapped in <result></result>, without any comment"
+}
+
+// extract takes the translation out of an answer with the delimiter.
+func (d delimiter) extract(answer string) (string, bool) {
+	var m []string
+	switch d {
+	case delimiterFence:
+		m = fencedAnswerRe.FindStringSubmatch(answer)
+	case delimiterSentinel:
+		m = sentinelAnswerRe.FindStringSubmatch(answer)
+	default:
+		m = resultTagRe.FindStringSubmatch(answer)
+	}
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// extractDelimited takes the translation out of an answer asked for with
+// delimiter d. An answer that uses the <result> tag anyway is accepted.
+func (t *Translator) extractDelimited(model string, d delimiter, answer string) (string, error) {
+	if d == delimiterTag {
+		return t.extractTranslation(model, answer)
+	}
+	profile := t.profileFor(model)
+	if profile.Reasoning {
+		answer = thinkBlockRe.ReplaceAllString(answer, "")
+	}
+	if translation, ok := d.extract(answer); ok {
+		return translation, nil
+	}
+	if translation, ok := delimiterTag.extract(answer); ok {
+		return translation, nil
+	}
+	if profile.IgnoresResultTag && strings.TrimSpace(answer) != "" {
+		return strings.TrimSpace(answer), nil
+	}
+	return "", classify(ErrorClassExtraction, &missingTagError{answer: answer, delimiter: d})
+}
+
+// delimiterFor returns the delimiter model is asked for.
+func (t *Translator) delimiterFor(model string) delimiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.delimiters[model]
+}
+
+// noteDelimiter records whether an answer of model lacked delimiter d.
+// After delimiterSwitchAfter such answers in a row the model is asked for
+// the next delimiter, by the retries of the chunk and the chunks after it;
+// a delimiter that works is kept for the model for the Translator's
+// lifetime.
+func (t *Translator) noteDelimiter(model string, d delimiter, missing bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.delimiters[model] != d {
+		// A concurrent request has switched already.
+		return
+	}
+	if t.delimiterMisses == nil {
+		t.delimiterMisses = map[string]int{}
+		t.delimiters = map[string]delimiter{}
+	}
+	if !missing {
+		t.delimiterMisses[model] = 0
+		return
+	}
+	t.delimiterMisses[model]++
+	if t.delimiterMisses[model] < delimiterSwitchAfter {
+		return
+	}
+	next := (d + 1) % delimiterCount
+	t.delimiters[model], t.delimiterMisses[model] = next, 0
+	if t.config.Verbose {
+		fmt.Printf("%s keeps leaving out the %s, asking for the %s instead\n", model, d, next)
+	}
+}
@@ -0,0 +1,85 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDelimiterExtract(t *testing.T) {
+	testCases := []struct {
+		d              delimiter
+		answer, expect string
+	}{
+		{delimiterTag, "<result>Hallo.</result>", "Hallo."},
+		{delimiterFence, "Here you go:\n```text\nHallo.\nWelt.\n```\nEnjoy.", "Hallo.\nWelt."},
+		{delimiterSentinel, "BEGIN TRANSLATION\nHallo.\nEND TRANSLATION", "Hallo."},
+	}
+	for _, tc := range testCases {
+		if got, ok := tc.d.extract(tc.answer); !ok || got != tc.expect {
+			t.Errorf("%s: expected %q, got %q", tc.d, tc.expect, got)
+		}
+	}
+	if _, ok := delimiterSentinel.extract("BEGIN TRANSLATION\nHallo."); ok {
+		t.Error("Expected an unclosed sentinel to fail")
+	}
+
+	tr := NewTranslator(Config{})
+	if got, err := tr.extractDelimited("m", delimiterFence, "<result>Hallo.</result>"); err != nil || got != "Hallo." {
+		t.Errorf("Expected a tagged answer accepted, got %q %v", got, err)
+	}
+	_, err := tr.extractDelimited("m", delimiterSentinel, "Hallo.")
+	if !isMissingTag(err) || !strings.Contains(err.Error(), "BEGIN TRANSLATION") {
+		t.Errorf("Expected a missing delimiter error, got %v", err)
+	}
+}
+
+// fenceOnlyProvider ignores the <result> tag but follows instructions for a
+// code block.
+type fenceOnlyProvider struct {
+	mu      sync.Mutex
+	systems []string
+}
+
+func (p *fenceOnlyProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.systems = append(p.systems, cr.System)
+	text := strings.ToUpper(cr.Prompt[strings.LastIndex(cr.Prompt, "Text to translate:\n\n")+20:])
+	if strings.Contains(cr.System, "code block") {
+		return &Completion{Text: "```\n" + text + "\n```"}, nil
+	}
+	return &Completion{Text: "Translation: " + text}, nil
+}
+
+func TestDelimiterSwitch(t *testing.T) {
+	provider := &fenceOnlyProvider{}
+	tr := NewTranslator(Config{Provider: provider, Model: "m", ChunkSize: 5, NoDelay: true,
+		Retry: RetryPolicy{Extraction: 3, Backoff: time.Millisecond}})
+	out, err := tr.TranslateText(context.Background(), "First paragraph.\n\nSecond paragraph.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "FIRST PARAGRAPH.\n\nSECOND PARAGRAPH." {
+		t.Errorf("Unexpected translation %q", out)
+	}
+	// Two answers without the tag switch the model to code blocks, which
+	// the second chunk is asked for right away.
+	if len(provider.systems) != 4 {
+		t.Fatalf("Expected 4 requests, got %d: %q", len(provider.systems), provider.systems)
+	}
+	if !strings.Contains(provider.systems[1], "had no <result> tag") || !strings.Contains(provider.systems[2], "code block") {
+		t.Errorf("Expected the retry to switch to a code block, got %q", provider.systems[1:3])
+	}
+	if strings.Contains(provider.systems[3], "<result>") {
+		t.Errorf("Expected the next chunk asked for a code block, got %q", provider.systems[3])
+	}
+	if w := tr.Result().Warnings; len(w) != 0 {
+		t.Errorf("Expected no warnings, got %+v", w)
+	}
+	if tr.delimiterFor("m") != delimiterFence {
+		t.Errorf("Expected the code block remembered for the model, got %s", tr.delimiterFor("m"))
+	}
+}
@@ -0,0 +1,114 @@
+package translator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// scriptLanguages maps writing systems used by a single common language to
+// that language.
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Greek, "greek"},
+	{unicode.Hangul, "korean"},
+	{unicode.Hiragana, "japanese"},
+	{unicode.Katakana, "japanese"},
+	{unicode.Han, "chinese"},
+	{unicode.Arabic, "arabic"},
+	{unicode.Hebrew, "hebrew"},
+	{unicode.Devanagari, "hindi"},
+	{unicode.Thai, "thai"},
+	{unicode.Cyrillic, "russian"},
+}
+
+// stopwords are frequent short words that tell Latin script languages apart.
+var stopwords = map[string][]string{
+	"english":    {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "this", "are", "be", "on", "you"},
+	"german":     {"der", "die", "und", "das", "ist", "nicht", "mit", "ein", "eine", "zu", "den", "auf", "sie", "ich", "sich"},
+	"french":     {"le", "la", "les", "et", "est", "des", "une", "un", "du", "que", "pour", "dans", "pas", "sur", "avec"},
+	"spanish":    {"el", "la", "los", "las", "y", "es", "que", "de", "en", "un", "una", "por", "con", "para", "del"},
+	"italian":    {"il", "la", "di", "che", "e", "è", "un", "una", "per", "con", "non", "sono", "gli", "del", "della"},
+	"portuguese": {"o", "a", "os", "as", "e", "é", "de", "que", "um", "uma", "para", "com", "não", "do", "da"},
+	"dutch":      {"de", "het", "een", "en", "is", "van", "dat", "niet", "op", "te", "met", "zijn", "voor", "ik", "je"},
+	"polish":     {"i", "w", "nie", "się", "na", "jest", "to", "że", "do", "z", "jak", "ale", "co", "od", "dla"},
+}
+
+// DetectLanguage guesses the language of text from its script and, for Latin
+// script, from frequent words. It returns "" when the text is too short or
+// too mixed to tell.
+func DetectLanguage(text string) string {
+	letters := 0
+	scripts := map[string]int{}
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.table, r) {
+				scripts[s.language]++
+				break
+			}
+		}
+	}
+	if letters < 20 {
+		return ""
+	}
+
+	// Kana marks Japanese even though most of its characters are Han.
+	if scripts["japanese"] > 0 && scripts["japanese"]+scripts["chinese"] > letters/2 {
+		return "japanese"
+	}
+	for _, s := range scriptLanguages {
+		if scripts[s.language] > letters/2 {
+			if s.language == "russian" && strings.ContainsAny(text, "іїєґІЇЄҐ") {
+				return "ukrainian"
+			}
+			return s.language
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	scores := map[string]int{}
+	for _, w := range words {
+		for lang, list := range stopwords {
+			for _, s := range list {
+				if w == s {
+					scores[lang]++
+				}
+			}
+		}
+	}
+	best, second := "", 0
+	for lang, score := range scores {
+		if best == "" || score > scores[best] || score == scores[best] && lang < best {
+			if best != "" {
+				second = scores[best]
+			}
+			best = lang
+		} else if score > second {
+			second = score
+		}
+	}
+	// Too few stopwords, or a near tie, is likely code, names or a mix.
+	if best == "" || scores[best] < 3 || scores[best] < second*3/2 {
+		return ""
+	}
+	return best
+}
+
+// sourceLanguage returns Config.FromLang, or the language detected from the
+// first chunks of the document.
+func (t *Translator) sourceLanguage(job *fileJob) string {
+	if t.config.FromLang != "" {
+		return t.config.FromLang
+	}
+	var sample strings.Builder
+	for i := 0; i < len(job.chunks) && i < 3; i++ {
+		sample.WriteString(maskTokenRe.ReplaceAllString(job.chunks[i], " "))
+		sample.WriteString("\n")
+	}
+	return DetectLanguage(sample.String())
+}
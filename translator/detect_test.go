@@ -0,0 +1,59 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The quick brown fox jumps over the lazy dog, and this is the end of it.", "english"},
+		{"Der schnelle braune Fuchs springt über den faulen Hund, und das ist nicht alles.", "german"},
+		{"Le renard brun saute par-dessus le chien paresseux et il est dans la maison avec les enfants.", "french"},
+		{"Быстрая коричневая лиса перепрыгивает через ленивую собаку.", "russian"},
+		{"Швидка руда лисиця перестрибує через лінивого собаку і їсть.", "ukrainian"},
+		{"素早い茶色の狐がのろまな犬を飛び越えます。これはテストです。", "japanese"},
+		{"func main() { fmt.Println(x) }", ""},
+		{"Short", ""},
+	}
+	for _, tc := range tests {
+		if got := DetectLanguage(tc.text); got != tc.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+// systemRecorder answers every chunk with "ok" and keeps the last system
+// message.
+type systemRecorder struct {
+	system string
+}
+
+func (p *systemRecorder) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	p.system = cr.System
+	return &Completion{Text: "<result>ok</result>"}, nil
+}
+
+func TestSourceLanguageInPrompt(t *testing.T) {
+	provider := &systemRecorder{}
+
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "english", NoSanityCheck: true})
+	if _, err := tr.TranslateText(context.Background(), "Der schnelle braune Fuchs springt über den faulen Hund, und das ist nicht alles."); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(provider.system, "from german to english") || tr.Result().SourceLang != "german" {
+		t.Errorf("Expected the detected language in the prompt, got %q", provider.system)
+	}
+
+	tr = NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "english", FromLang: "dutch", NoSanityCheck: true})
+	if _, err := tr.TranslateText(context.Background(), "Der schnelle braune Fuchs springt über den faulen Hund."); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(provider.system, "from dutch to english") {
+		t.Errorf("Expected FromLang to override detection, got %q", provider.system)
+	}
+}
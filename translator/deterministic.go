@@ -0,0 +1,54 @@
+package translator
+
+// deterministicSeed is the seed of every request in deterministic mode.
+// Retries after unusable answers add their count to it, see sampling.
+const deterministicSeed = 1
+
+// RunInputs records what the output of a deterministic run depends on, so
+// that the same inputs reproduce it on a provider that honors seeds.
+type RunInputs struct {
+	InputSHA256 string   `json:"input_sha256,omitempty"`
+	Backend     string   `json:"backend,omitempty"`
+	Model       string   `json:"model"`
+	ToLang      string   `json:"to_lang"`
+	ChunkSize   int      `json:"chunk_size"`
+	Format      string   `json:"format"`
+	YAMLKeys    []string `json:"yaml_keys,omitempty"`
+	// FrontMatterKeys is Config.FrontMatterKeys.
+	FrontMatterKeys []string `json:"front_matter_keys,omitempty"`
+	// ProtectPatterns is Config.ProtectPatterns.
+	ProtectPatterns []string `json:"protect_patterns,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	Seed            int      `json:"seed"`
+	PromptSHA256    string   `json:"prompt_sha256"`
+}
+
+// deterministicSampling pins the temperature to zero, except for reasoning
+// models that take none, and uses a fixed seed.
+func deterministicSampling(profile ModelProfile, variation int) (*float64, *int) {
+	seed := deterministicSeed + variation
+	if profile.Reasoning && profile.Temperature == nil {
+		return nil, &seed
+	}
+	return temperature(0), &seed
+}
+
+func (t *Translator) runInputs(prepared *PreparedFile) *RunInputs {
+	model := t.activeModel()
+	temp, seed := deterministicSampling(t.profileFor(model), 0)
+	system, prompt := t.buildPrompt("", promptContext{})
+	return &RunInputs{
+		InputSHA256:     prepared.SourceSHA256,
+		Backend:         t.config.Backend,
+		Model:           model,
+		ToLang:          t.config.ToLang,
+		ChunkSize:       t.config.ChunkSize,
+		Format:          prepared.Format,
+		YAMLKeys:        t.config.YAMLKeys,
+		FrontMatterKeys: t.config.FrontMatterKeys,
+		ProtectPatterns: t.config.ProtectPatterns,
+		Temperature:     temp,
+		Seed:            *seed,
+		PromptSHA256:    sha256Hex([]byte(system + "\n\n" + prompt)),
+	}
+}
@@ -0,0 +1,52 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeterministicMode(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	os.WriteFile(in, []byte("First paragraph.\n\nSecond paragraph.\n\nThird paragraph.\n"), 0644)
+
+	var outputs []string
+	var inputs []*RunInputs
+	for run := 0; run < 2; run++ {
+		provider := &flakyProvider{answers: []string{"no tag", "<result>Translated</result>"}}
+		translator := NewTranslator(Config{
+			Provider:      provider,
+			Model:         "deepseek/deepseek-chat",
+			ChunkSize:     5,
+			Concurrency:   4,
+			HedgeDelay:    1,
+			NoDelay:       true,
+			Deterministic: true,
+			Retry:         RetryPolicy{Backoff: 1},
+		})
+		out := filepath.Join(dir, "out.txt")
+		if err := translator.TranslateFile(in, out); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(out)
+		outputs = append(outputs, string(data))
+		inputs = append(inputs, translator.Result().Inputs)
+
+		if got := strings.Join(provider.temperatures, " "); got != "0 0 0 0" {
+			t.Errorf("Expected temperature 0 on every request, got %s", got)
+		}
+		if provider.seeds[0] != deterministicSeed || provider.seeds[1] != deterministicSeed+1 || provider.seeds[2] != deterministicSeed {
+			t.Errorf("Expected the fixed seed, varied only on retries, got %v", provider.seeds)
+		}
+	}
+
+	if outputs[0] != outputs[1] {
+		t.Errorf("Expected identical outputs, got %q and %q", outputs[0], outputs[1])
+	}
+	in0, in1 := inputs[0], inputs[1]
+	if in0 == nil || in1 == nil || in0.InputSHA256 == "" || in0.PromptSHA256 != in1.PromptSHA256 || in0.Seed != deterministicSeed {
+		t.Errorf("Expected the run inputs to be recorded, got %+v and %+v", in0, in1)
+	}
+}
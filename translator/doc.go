@@ -0,0 +1,26 @@
+// Package translator translates documents with language models while keeping
+// their formatting: it protects code, markup and other spans that must not
+// change, splits the text into chunks, translates them through OpenRouter,
+// Ollama, DeepL or a custom Provider and reassembles the result.
+//
+//	t, err := translator.New(
+//		translator.WithAPIKey(os.Getenv("OPENROUTER_API_KEY")),
+//		translator.WithTargetLanguage("german"),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	german, err := t.TranslateText(ctx, "Hello, world!")
+//
+// Files are translated with TranslateFileContext, streams with Translate,
+// segments carrying caller metadata with TranslateSegments, and long jobs
+// that need pause and cancel controls with Start.
+//
+// # Compatibility
+//
+// From v1.0.0 the exported API of this package follows semantic versioning:
+// exported names, Config fields and the options keep their meaning within a
+// major version, and new features arrive as new options and fields. The
+// command line tool lives in cmd/go_ai_translate; its flags and output are
+// not part of this promise.
+package translator
@@ -0,0 +1,128 @@
+package translator
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the end-to-end tests")
+
+// stubOpenRouter emulates the chat completions endpoint. It "translates" by
+// upper-casing the chunk, and a chunk containing [429], [truncate] or
+// [malformed] gets a rate limit, an answer cut off before </result> or a
+// body that is not JSON on its first request.
+func stubOpenRouter(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	seen := map[string]int{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		var req OpenRouterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Invalid chat request: %v", err)
+		}
+		prompt := req.Messages[len(req.Messages)-1].Content
+		text := prompt[strings.Index(prompt, ":\n\n")+3:]
+
+		mu.Lock()
+		seen[text]++
+		first := seen[text] == 1
+		mu.Unlock()
+
+		answer := "<result>" + strings.ToUpper(text) + "</result>"
+		switch {
+		case first && strings.Contains(text, "[429]"):
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"Rate limit exceeded","code":"429"}}`)
+			return
+		case first && strings.Contains(text, "[truncate]"):
+			answer = answer[:len(answer)/2]
+		case first && strings.Contains(text, "[malformed]"):
+			fmt.Fprint(w, `<html>Bad Gateway</html>`)
+			return
+		}
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":%q}}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`, answer)
+	}))
+}
+
+// TestEndToEndGolden translates every file in testdata/e2e against the stub
+// server and compares the output byte for byte with its .golden file. Run
+// with -update to rewrite the golden files after an intended change.
+func TestEndToEndGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "e2e", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range inputs {
+		if strings.HasSuffix(input, ".golden") {
+			continue
+		}
+		golden := input + ".golden"
+
+		for _, concurrency := range []int{1, 3} {
+			name := fmt.Sprintf("%s/concurrency=%d", filepath.Base(input), concurrency)
+			t.Run(name, func(t *testing.T) {
+				server := stubOpenRouter(t)
+				defer server.Close()
+
+				out := filepath.Join(t.TempDir(), "out"+filepath.Ext(input))
+				var progress bytes.Buffer
+				translator := NewTranslator(Config{
+					BaseURL:        server.URL,
+					Model:          "test/model",
+					ToLang:         "english",
+					ChunkSize:      40,
+					Concurrency:    concurrency,
+					NoDelay:        true,
+					Retry:          RetryPolicy{Backoff: time.Millisecond},
+					ProgressOutput: &progress,
+				})
+				if err := translator.TranslateFile(input, out); err != nil {
+					t.Fatal(err)
+				}
+				got, err := os.ReadFile(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if *updateGolden && concurrency == 1 {
+					if err := os.WriteFile(golden, got, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("Missing golden file, run the test with -update: %v", err)
+				}
+				if string(got) != string(want) {
+					t.Errorf("Output differs from %s:\n--- got\n%s\n--- want\n%s", golden, got, want)
+				}
+				if r := translator.Result(); r.ChunksTranslated != r.Chunks || r.Chunks < 2 {
+					t.Errorf("Expected every one of several chunks to be translated, got %d of %d", r.ChunksTranslated, r.Chunks)
+				}
+
+				source, _ := os.ReadFile(input)
+				faults := 0
+				for _, marker := range []string{"[429]", "[truncate]", "[malformed]"} {
+					faults += strings.Count(string(source), marker)
+				}
+				if retries := strings.Count(progress.String(), `"event":"retry"`); retries != faults {
+					t.Errorf("Expected %d retries for the injected faults, got %d", faults, retries)
+				}
+			})
+		}
+	}
+}
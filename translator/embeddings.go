@@ -0,0 +1,100 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const defaultEmbeddingsModel = "openai/text-embedding-3-small"
+
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// embed returns one embedding vector per input using the OpenAI compatible
+// embeddings endpoint configured in Config.EmbeddingsURL.
+func (t *Translator) embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	model := t.config.EmbeddingsModel
+	if model == "" {
+		model = defaultEmbeddingsModel
+	}
+	url := t.config.EmbeddingsURL
+	if url == "" {
+		url = t.baseURL() + "/embeddings"
+	}
+
+	requestBody, err := json.Marshal(embeddingsRequest{Model: model, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embeddings request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.config.APIKey)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if auditErr := t.recordRequest(url, model, "", requestBody, 0, nil, err); auditErr != nil {
+			return nil, auditErr
+		}
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to send embeddings request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if auditErr := t.recordRequest(url, model, "", requestBody, resp.StatusCode, body, err); auditErr != nil {
+		return nil, auditErr
+	}
+	if err != nil {
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to read embeddings response: %w", err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("embeddings request failed with status %d: %s", resp.StatusCode, string(body)))
+	}
+
+	var response embeddingsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("failed to unmarshal embeddings response: %w", err))
+	}
+	if response.Error != nil {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("embeddings API error: %s", response.Error.Message))
+	}
+	if len(response.Data) != len(inputs) {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(response.Data)))
+	}
+
+	vectors := make([][]float64, len(inputs))
+	for _, d := range response.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, classify(ErrorClassAPI, fmt.Errorf("embedding index %d out of range", d.Index))
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,99 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ErrorClassInput      = "input"
+	ErrorClassOutput     = "output"
+	ErrorClassConfig     = "config"
+	ErrorClassNetwork    = "network"
+	ErrorClassAPI        = "api"
+	ErrorClassRateLimit  = "rate-limit"
+	ErrorClassExtraction = "extraction"
+	ErrorClassCanceled   = "canceled"
+	ErrorClassPaused     = "paused"
+	ErrorClassBudget     = "budget"
+	ErrorClassUnknown    = "unknown"
+)
+
+type classifiedError struct {
+	class string
+	err   error
+	// retryAfter is the pause a rate limited server asked for.
+	retryAfter time.Duration
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+// canceled wraps the error of a done context.
+func canceled(err error) error {
+	return classify(ErrorClassCanceled, fmt.Errorf("translation canceled: %w", err))
+}
+
+func classify(class string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+// ErrorClass reports the broad category of an error returned by the
+// translator, e.g. ErrorClassAPI or ErrorClassNetwork.
+func ErrorClass(err error) string {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return ce.class
+	}
+	return ErrorClassUnknown
+}
+
+// rateLimited classifies err, the answer to a request refused with status
+// 429, as ErrorClassRateLimit along with the pause its Retry-After header
+// asks for.
+func rateLimited(resp *http.Response, err error) error {
+	return &classifiedError{class: ErrorClassRateLimit, err: err,
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+}
+
+// parseRetryAfter reads a Retry-After header, a number of seconds or an HTTP
+// date, as a pause from now. It is zero when the header is missing or
+// invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// retryAfter is the pause the server asked for before err is retried, or
+// zero.
+func retryAfter(err error) time.Duration {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return ce.retryAfter
+	}
+	return 0
+}
@@ -0,0 +1,26 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestErrorClass(t *testing.T) {
+	apiErr := classify(ErrorClassAPI, errors.New("boom"))
+	wrapped := fmt.Errorf("chunk 3: %w", apiErr)
+
+	if got := ErrorClass(wrapped); got != ErrorClassAPI {
+		t.Errorf("Expected class %q, got %q", ErrorClassAPI, got)
+	}
+	if got := ErrorClass(errors.New("plain")); got != ErrorClassUnknown {
+		t.Errorf("Expected class %q, got %q", ErrorClassUnknown, got)
+	}
+
+	translator := NewTranslator(Config{ChunkSize: 50})
+	err := translator.TranslateFile(filepath.Join(t.TempDir(), "missing.txt"), "out.txt")
+	if got := ErrorClass(err); got != ErrorClassInput {
+		t.Errorf("Expected class %q for missing input, got %q", ErrorClassInput, got)
+	}
+}
@@ -0,0 +1,163 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// calibrationRuns caps how many earlier runs a correction factor averages
+// over, so it keeps following changes in models and prompts.
+const calibrationRuns = 10
+
+// Estimate is the expected usage of translating a prepared file. The token
+// counts are corrected with what earlier runs of the same model and target
+// language actually used, see Config.EstimatesPath. Cost is only known when
+// the model's pricing could be fetched.
+type Estimate struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost,omitempty"`
+	// Runs is the number of earlier runs behind the correction, zero for a
+	// plain characters-per-token guess.
+	Runs int `json:"runs"`
+	// Sizing is how the chunk size comes about.
+	Sizing ChunkSizing `json:"sizing"`
+
+	rawPrompt, rawCompletion int
+}
+
+// calibration is the correction for one model and target language: actual
+// tokens divided by the uncorrected estimate, averaged over recent runs.
+type calibration struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+	Runs       int     `json:"runs"`
+}
+
+func calibrationKey(model, toLang string) string {
+	return model + "|" + toLang
+}
+
+func readCalibrations(path string) (map[string]calibration, error) {
+	calibrations := map[string]calibration{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return calibrations, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &calibrations); err != nil {
+		return nil, fmt.Errorf("invalid estimates file %s: %w", path, err)
+	}
+	return calibrations, nil
+}
+
+// estimate guesses the usage of translating chunks from their size and
+// applies the stored correction for the active model.
+func (t *Translator) estimate(chunks []string) *Estimate {
+	est := &Estimate{}
+	for _, chunk := range chunks {
+		system, prompt := t.buildPrompt(chunk, promptContext{})
+		est.rawPrompt += t.countTokens(system + prompt)
+		est.rawCompletion += t.countTokens(chunk + "<result></result>")
+	}
+	est.PromptTokens, est.CompletionTokens = est.rawPrompt, est.rawCompletion
+
+	if t.config.EstimatesPath == "" {
+		return est
+	}
+	calibrations, err := readCalibrations(t.config.EstimatesPath)
+	if err != nil {
+		if t.config.Verbose {
+			fmt.Printf("Could not read estimates: %v\n", err)
+		}
+		return est
+	}
+	if c, ok := calibrations[calibrationKey(t.activeModel(), t.config.ToLang)]; ok && c.Runs > 0 {
+		est.PromptTokens = int(math.Round(float64(est.rawPrompt) * c.Prompt))
+		est.CompletionTokens = int(math.Round(float64(est.rawCompletion) * c.Completion))
+		est.Runs = c.Runs
+	}
+	return est
+}
+
+// EstimatePrepared estimates the usage of translating prepared without
+// calling the model. The model's pricing is fetched to estimate the cost.
+func (t *Translator) EstimatePrepared(ctx context.Context, prepared *PreparedFile) (*Estimate, error) {
+	format, err := lookupFormat(prepared.Format, "")
+	if err != nil {
+		return nil, classify(ErrorClassConfig, err)
+	}
+	t.format = format
+	t.selectModel(ctx)
+
+	est := t.estimate(prepared.Chunks[prepared.Done:])
+	est.Sizing = t.ChunkSizing()
+	if err := t.priceEstimate(ctx, est); err != nil && t.config.Verbose {
+		fmt.Printf("Could not fetch pricing: %v\n", err)
+	}
+	return est, nil
+}
+
+// priceEstimate sets the cost of est from the active model's pricing. Ollama
+// models cost nothing; an error means the cost is not known.
+func (t *Translator) priceEstimate(ctx context.Context, est *Estimate) error {
+	if t.config.Backend == BackendOllama {
+		return nil
+	}
+	models, err := t.fetchModels(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range models {
+		if m.ID != t.activeModel() {
+			continue
+		}
+		prompt, _ := strconv.ParseFloat(m.Pricing.Prompt, 64)
+		completion, _ := strconv.ParseFloat(m.Pricing.Completion, 64)
+		est.Cost = float64(est.PromptTokens)*prompt + float64(est.CompletionTokens)*completion
+		return nil
+	}
+	return fmt.Errorf("no pricing listed for %s", t.activeModel())
+}
+
+// reconcileEstimate compares the estimate of a finished run with the tokens
+// it actually used and updates the stored correction for the model and
+// target language. Runs without usage figures are skipped.
+func (t *Translator) reconcileEstimate() error {
+	est := t.result.Estimate
+	if t.config.EstimatesPath == "" || est == nil || est.rawPrompt == 0 || est.rawCompletion == 0 ||
+		t.result.PromptTokens == 0 || t.result.CompletionTokens == 0 {
+		return nil
+	}
+
+	calibrations, err := readCalibrations(t.config.EstimatesPath)
+	if err != nil {
+		return err
+	}
+
+	key := calibrationKey(t.result.Model, t.config.ToLang)
+	c := calibrations[key]
+	n := float64(c.Runs)
+	c.Prompt = (c.Prompt*n + float64(t.result.PromptTokens)/float64(est.rawPrompt)) / (n + 1)
+	c.Completion = (c.Completion*n + float64(t.result.CompletionTokens)/float64(est.rawCompletion)) / (n + 1)
+	if c.Runs < calibrationRuns {
+		c.Runs++
+	}
+	calibrations[key] = c
+
+	data, err := json.MarshalIndent(calibrations, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.config.EstimatesPath), 0755); err != nil {
+		return err
+	}
+	return WriteFileAtomic(t.config.EstimatesPath, data, 0644)
+}
@@ -0,0 +1,59 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixedUsageProvider echoes the chunk and reports the same usage for every
+// request.
+type fixedUsageProvider struct{}
+
+func (fixedUsageProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	text := req.Prompt[strings.Index(req.Prompt, ":\n\n")+3:]
+	return &Completion{Text: "<result>" + text + "</result>", PromptTokens: 1000, CompletionTokens: 100}, nil
+}
+
+func TestEstimateReconciliation(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	os.WriteFile(in, []byte("A short paragraph to translate.\n"), 0644)
+	config := Config{
+		Provider:      fixedUsageProvider{},
+		Model:         "test/model",
+		ToLang:        "german",
+		ChunkSize:     100,
+		NoDelay:       true,
+		EstimatesPath: filepath.Join(dir, "estimates.json"),
+		NoSanityCheck: true,
+	}
+
+	first := NewTranslator(config)
+	if err := first.TranslateFile(in, filepath.Join(dir, "out.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if est := first.Result().Estimate; est == nil || est.Runs != 0 || est.PromptTokens == 1000 {
+		t.Fatalf("Expected an uncorrected estimate for the first run, got %+v", est)
+	}
+
+	second := NewTranslator(config)
+	prepared, err := second.Prepare(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	est, err := second.EstimatePrepared(context.Background(), prepared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Runs != 1 || est.PromptTokens != 1000 || est.CompletionTokens != 100 {
+		t.Errorf("Expected the estimate to match the first run's usage, got %+v", est)
+	}
+
+	config.ToLang = "french"
+	if est, _ := NewTranslator(config).EstimatePrepared(context.Background(), prepared); est.Runs != 0 {
+		t.Errorf("Expected no correction for another language, got %+v", est)
+	}
+}
@@ -0,0 +1,121 @@
+package translator
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	SegmentUntranslated      = "untranslated"
+	SegmentMachineTranslated = "machine-translated"
+)
+
+// Segment is one translated unit of a document, as handed to external
+// translation management systems for post-editing.
+type Segment struct {
+	ID     int
+	Source string
+	Target string
+	State  string
+	// MaxLength, when set, is the most characters Target may have, e.g.
+	// from RESX comments or ARB metadata; KeyContext.MaxLength applies
+	// otherwise. Placeholders do not count.
+	MaxLength int
+	// Meta is caller data such as keys or file positions, carried through
+	// TranslateSegments unchanged.
+	Meta map[string]string
+}
+
+type xliffDocument struct {
+	XMLName xml.Name  `xml:"xliff"`
+	Version string    `xml:"version,attr"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	File    xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	Original string      `xml:"original,attr"`
+	Source   string      `xml:"source-language,attr"`
+	Target   string      `xml:"target-language,attr"`
+	DataType string      `xml:"datatype,attr"`
+	Units    []xliffUnit `xml:"body>trans-unit"`
+}
+
+type xliffUnit struct {
+	ID       string      `xml:"id,attr"`
+	MaxWidth int         `xml:"maxwidth,attr,omitempty"`
+	SizeUnit string      `xml:"size-unit,attr,omitempty"`
+	Source   string      `xml:"source"`
+	Target   xliffTarget `xml:"target"`
+}
+
+type xliffTarget struct {
+	State          string `xml:"state,attr"`
+	StateQualifier string `xml:"state-qualifier,attr,omitempty"`
+	Text           string `xml:",chardata"`
+}
+
+// WriteXLIFF writes segments as an XLIFF 1.2 document. Machine translated
+// segments are flagged for review so Crowdin and Weblate treat them as
+// suggestions rather than approved translations.
+func WriteXLIFF(w io.Writer, original, sourceLang, targetLang string, segments []Segment) error {
+	doc := xliffDocument{
+		Version: "1.2",
+		Xmlns:   "urn:oasis:names:tc:xliff:document:1.2",
+		File: xliffFile{
+			Original: original,
+			Source:   sourceLang,
+			Target:   targetLang,
+			DataType: "plaintext",
+		},
+	}
+
+	for _, s := range segments {
+		unit := xliffUnit{ID: strconv.Itoa(s.ID), Source: s.Source}
+		if s.MaxLength > 0 {
+			unit.MaxWidth, unit.SizeUnit = s.MaxLength, "char"
+		}
+		if s.State == SegmentMachineTranslated {
+			unit.Target = xliffTarget{State: "needs-review-translation", StateQualifier: "mt-suggestion", Text: s.Target}
+		} else {
+			unit.Target = xliffTarget{State: "new"}
+		}
+		doc.File.Units = append(doc.File.Units, unit)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode XLIFF: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteCSV writes segments as CSV with id, source, target, state and fuzzy
+// columns, the layout accepted by the CSV importers of Crowdin and Weblate.
+func WriteCSV(w io.Writer, segments []Segment) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "source", "target", "state", "fuzzy"}); err != nil {
+		return err
+	}
+
+	for _, s := range segments {
+		fuzzy := "false"
+		if s.State == SegmentMachineTranslated {
+			fuzzy = "true"
+		}
+		if err := cw.Write([]string{strconv.Itoa(s.ID), s.Source, s.Target, s.State, fuzzy}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
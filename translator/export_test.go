@@ -0,0 +1,55 @@
+package translator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+var exportSegments = []Segment{
+	{ID: 1, Source: "Hello <world>", Target: "Привет <мир>", State: SegmentMachineTranslated},
+	{ID: 2, Source: "Bye, \"friend\"", State: SegmentUntranslated},
+}
+
+func TestWriteXLIFF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXLIFF(&buf, "doc.md", "en", "ru", exportSegments); err != nil {
+		t.Fatalf("WriteXLIFF failed: %v", err)
+	}
+
+	var doc xliffDocument
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid XML: %v\n%s", err, buf.String())
+	}
+
+	if doc.File.Source != "en" || doc.File.Target != "ru" || len(doc.File.Units) != 2 {
+		t.Fatalf("Unexpected document: %+v", doc)
+	}
+	if u := doc.File.Units[0]; u.Source != "Hello <world>" || u.Target.Text != "Привет <мир>" || u.Target.State != "needs-review-translation" {
+		t.Errorf("Unexpected machine translated unit: %+v", u)
+	}
+	if u := doc.File.Units[1]; u.Target.State != "new" || u.Target.Text != "" {
+		t.Errorf("Unexpected untranslated unit: %+v", u)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, exportSegments); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("Output is not valid CSV: %v", err)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d", len(records))
+	}
+	if records[1][4] != "true" || records[2][1] != "Bye, \"friend\"" || records[2][3] != SegmentUntranslated {
+		t.Errorf("Unexpected rows: %q", records[1:])
+	}
+}
@@ -0,0 +1,223 @@
+package translator
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	maskOpen  = "⟦"
+	maskClose = "⟧"
+)
+
+var maskTokenRe = regexp.MustCompile(maskOpen + `(\d+)` + maskClose)
+
+type formatHandler struct {
+	name       string
+	extensions []string
+	// names matches file base names without extension, e.g. CHANGELOG.
+	names []string
+	// hint is appended to the translation instruction.
+	hint string
+	// protect lists the spans that are passed through untranslated, in the
+	// order they are masked. Earlier patterns win over later ones.
+	protect []*regexp.Regexp
+	// mask, when set, replaces pattern based protection for formats where
+	// only a few selected parts of the document are translatable.
+	mask func(text string, config Config) (string, []string)
+}
+
+var formatHandlers = []*formatHandler{
+	{
+		name:       "typst",
+		extensions: []string{".typ"},
+		protect: []*regexp.Regexp{
+			regexp.MustCompile("(?s)```.*?```"),
+			regexp.MustCompile("`[^`\n]+`"),
+			regexp.MustCompile(`(?s)/\*.*?\*/`),
+			// Comments start a line or follow whitespace, unlike the // of
+			// https://.
+			regexp.MustCompile(`(?m)(?:^|[ \t])//.*$`),
+			// An escaped \$ is a dollar sign, neither opening nor closing math.
+			regexp.MustCompile(`(?s)\\\$|\$(?:[^$\\]|\\.)+\$`),
+			regexp.MustCompile(`(?m)^[ \t]*#(set|show|import|include|let)\b.*$`),
+			regexp.MustCompile(`<[A-Za-z][\w:.-]*>`),
+			regexp.MustCompile(`@[A-Za-z][\w:.-]*[\w]`),
+		},
+	},
+	{
+		name:       "quarto",
+		extensions: []string{".qmd"},
+		protect: []*regexp.Regexp{
+			yamlFrontMatterRe,
+			tomlFrontMatterRe,
+			regexp.MustCompile("(?ms)^[ \t]*```.*?^[ \t]*```[ \t]*$"),
+			regexp.MustCompile(`(?ms)^[ \t]*~~~.*?^[ \t]*~~~[ \t]*$`),
+			regexp.MustCompile(`(?s)<!--.*?-->`),
+			regexp.MustCompile(`(?s)\$\$.*?\$\$`),
+			regexp.MustCompile(`\$[^$\n]+\$`),
+			regexp.MustCompile("`[^`\n]+`"),
+			regexp.MustCompile(`\{\{<.*?>\}\}`),
+			regexp.MustCompile(`(?m)^[ \t]*:::.*$`),
+			regexp.MustCompile(`\{#[^}\n]*\}`),
+			regexp.MustCompile(`@[A-Za-z][\w:.-]*[\w]`),
+		},
+	},
+	{
+		name:       "yaml",
+		extensions: []string{".yaml", ".yml"},
+		mask:       maskYAML,
+	},
+	{
+		name:  "changelog",
+		names: []string{"changelog", "changes", "history", "news"},
+		hint:  "This is a changelog or commit log: keep it terse, keep one entry per line and do not merge or split entries",
+		protect: []*regexp.Regexp{
+			regexp.MustCompile(`(?m)^(commit [0-9a-f]{7,40}.*|Merge: .*|Author: .*|Date: .*)$`),
+			regexp.MustCompile("(?s)```.*?```"),
+			regexp.MustCompile("`[^`\n]+`"),
+			regexp.MustCompile(`(?m)^\[[^\]]+\]: .*$`),
+			regexp.MustCompile(`(?m)^#+[ \t]+\[?v?\d+\.\d+[^\n]*$`),
+			regexp.MustCompile(`(?m)^([ \t]*(?:[-*][ \t]+)?(?:[0-9a-f]{7,40}[ \t]+)?)?(?:feat|fix|docs|style|refactor|perf|test|build|ci|chore|revert)(?:\([^)\n]*\))?!?:`),
+			regexp.MustCompile(`https?://[^\s)>\]]+`),
+			regexp.MustCompile(`\b[a-f]{0,6}[0-9][0-9a-f]{5,39}\b`),
+			regexp.MustCompile(`(?:[\w.-]+/[\w.-]+)?#\d+\b`),
+			regexp.MustCompile(`@[A-Za-z0-9][\w-]*`),
+		},
+	},
+	{
+		// After changelog, so CHANGELOG.md is a changelog.
+		name:       "markdown",
+		extensions: []string{".md", ".markdown"},
+		protect: []*regexp.Regexp{
+			yamlFrontMatterRe,
+			tomlFrontMatterRe,
+			backtickFenceRe,
+			tildeFenceRe,
+			regexp.MustCompile(`(?s)<!--.*?-->`),
+			inlineCodeRe,
+			regexp.MustCompile(`\]\([^)\s]+\)`),
+			regexp.MustCompile(`(?m)^[ \t]*\[[^\]\n]+\]:[ \t]+\S+.*$`),
+			regexp.MustCompile(`<a (?:id|name)="[^"\n]*"></a>`),
+		},
+	},
+}
+
+func lookupFormat(name, path string) (*formatHandler, error) {
+	if name == "" || name == "auto" {
+		ext := strings.ToLower(filepath.Ext(path))
+		base := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+		for _, h := range formatHandlers {
+			for _, e := range h.extensions {
+				if e == ext {
+					return h, nil
+				}
+			}
+			for _, n := range h.names {
+				if n == base {
+					return h, nil
+				}
+			}
+		}
+		return nil, nil
+	}
+
+	if name == "text" {
+		return nil, nil
+	}
+
+	for _, h := range formatHandlers {
+		if h.name == name {
+			return h, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown format %q", name)
+}
+
+func (h *formatHandler) maskText(text string, config Config) (string, []string) {
+	if h.mask != nil {
+		return h.mask(text, config)
+	}
+	return maskSpans(text, h.protect)
+}
+
+// maskBuilder assembles masked text piece by piece, merging adjacent
+// protected pieces into a single marker.
+type maskBuilder struct {
+	out     strings.Builder
+	pending strings.Builder
+	spans   []string
+}
+
+func (b *maskBuilder) protect(s string) {
+	b.pending.WriteString(s)
+}
+
+func (b *maskBuilder) keep(s string) {
+	if s == "" {
+		return
+	}
+	b.flush()
+	b.out.WriteString(s)
+}
+
+func (b *maskBuilder) flush() {
+	if b.pending.Len() == 0 {
+		return
+	}
+	b.spans = append(b.spans, b.pending.String())
+	b.out.WriteString(maskOpen + strconv.Itoa(len(b.spans)-1) + maskClose)
+	b.pending.Reset()
+}
+
+func (b *maskBuilder) result() (string, []string) {
+	b.flush()
+	return b.out.String(), b.spans
+}
+
+// maskSpans replaces every protected span with a numbered marker and returns
+// the masked text together with the original spans, indexed by marker number.
+func maskSpans(text string, patterns []*regexp.Regexp) (string, []string) {
+	var spans []string
+
+	for _, re := range patterns {
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			if maskTokenRe.MatchString(match) {
+				return match
+			}
+			spans = append(spans, match)
+			return maskOpen + strconv.Itoa(len(spans)-1) + maskClose
+		})
+	}
+
+	return text, spans
+}
+
+// maskMore is maskSpans for text that is already partly masked, appending
+// to its spans. Matches that take in a marker are left alone.
+func maskMore(text string, spans *[]string, patterns ...*regexp.Regexp) string {
+	for _, re := range patterns {
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			if maskTokenRe.MatchString(match) {
+				return match
+			}
+			*spans = append(*spans, match)
+			return maskOpen + strconv.Itoa(len(*spans)-1) + maskClose
+		})
+	}
+	return text
+}
+
+func unmaskSpans(text string, spans []string) string {
+	return maskTokenRe.ReplaceAllStringFunc(text, func(token string) string {
+		n, err := strconv.Atoi(token[len(maskOpen) : len(token)-len(maskClose)])
+		if err != nil || n >= len(spans) {
+			return token
+		}
+		return spans[n]
+	})
+}
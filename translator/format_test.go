@@ -0,0 +1,108 @@
+package translator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookupFormat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   string
+		path     string
+		expected string
+	}{
+		{"Typst by extension", "", "paper.typ", "typst"},
+		{"Quarto by extension", "auto", "report.QMD", "quarto"},
+		{"Plain text", "auto", "notes.txt", ""},
+		{"Explicit text", "text", "paper.typ", ""},
+		{"Explicit quarto", "quarto", "notes.txt", "quarto"},
+		{"Changelog by name", "auto", "docs/CHANGELOG.md", "changelog"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := lookupFormat(tc.format, tc.path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			name := ""
+			if h != nil {
+				name = h.name
+			}
+			if name != tc.expected {
+				t.Errorf("Expected format %q, got %q", tc.expected, name)
+			}
+		})
+	}
+
+	if _, err := lookupFormat("docx", "a.docx"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestMaskSpans(t *testing.T) {
+	testCases := []struct {
+		name      string
+		format    string
+		input     string
+		protected []string
+		prose     []string
+	}{
+		{
+			name:      "Typst",
+			format:    "typst",
+			input:     "#set page(width: 10cm)\n= Introduction <intro>\nThe area is $pi r^2$, see @intro.\n```rust\nfn main() {}\n```\n",
+			protected: []string{"#set page(width: 10cm)", "<intro>", "$pi r^2$", "@intro", "fn main() {}"},
+			prose:     []string{"Introduction", "The area is", "see"},
+		},
+		{
+			name:      "Typst URLs and escaped dollars",
+			format:    "typst",
+			input:     "See https://example.com for details. // a note\nIt costs \\$5, not \\$10, in $x$ steps.\n",
+			protected: []string{"// a note", "$x$"},
+			prose:     []string{"https://example.com for details.", "It costs", "5, not", "10, in", "steps."},
+		},
+		{
+			name:      "Quarto",
+			format:    "quarto",
+			input:     "---\ntitle: Report\n---\n\n## Results {#sec-results}\n\n::: {.callout-note}\nSee @fig-plot and $x^2$.\n:::\n\n```{python}\nprint(1)\n```\n",
+			protected: []string{"title: Report", "{#sec-results}", "::: {.callout-note}", "@fig-plot", "$x^2$", "print(1)"},
+			prose:     []string{"Results", "See", "and"},
+		},
+		{
+			name:      "Changelog",
+			format:    "changelog",
+			input:     "## [1.2.0] - 2024-05-01\n\n- feat(api): add retries for failed requests (#42)\n- fix: handle empty input, see a1b2c3d\n\ncommit 9f8e7d6c5b4a\nAuthor: Jane <jane@example.com>\nDate:   2024-05-01\n\n    docs: describe the defaced option\n",
+			protected: []string{"[1.2.0]", "feat(api):", "#42", "fix:", "a1b2c3d", "9f8e7d6c5b4a", "Author: Jane", "docs:"},
+			prose:     []string{"add retries for failed requests", "handle empty input", "describe the defaced option"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := lookupFormat(tc.format, "")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			masked, spans := maskSpans(tc.input, h.protect)
+
+			for _, p := range tc.protected {
+				if strings.Contains(masked, p) {
+					t.Errorf("Expected %q to be masked, got %q", p, masked)
+				}
+			}
+			for _, p := range tc.prose {
+				if !strings.Contains(masked, p) {
+					t.Errorf("Expected prose %q to stay visible, got %q", p, masked)
+				}
+			}
+
+			if restored := unmaskSpans(masked, spans); restored != tc.input {
+				t.Errorf("Unmasking did not restore the input\nExpected: %q\nGot: %q", tc.input, restored)
+			}
+		})
+	}
+}
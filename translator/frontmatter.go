@@ -0,0 +1,84 @@
+package translator
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	yamlFrontMatterRe = regexp.MustCompile(`(?s)\A---\n.*?\n---\n`)
+	tomlFrontMatterRe = regexp.MustCompile(`(?s)\A\+\+\+\n.*?\n\+\+\+\n`)
+	tomlStringRe      = regexp.MustCompile(`^(\s*("[^"]*"|[A-Za-z0-9_.-]+)\s*=\s*")((?:[^"\\]|\\.)*)("\s*(?:#.*)?)$`)
+)
+
+// maskFrontMatter opens up the values of Config.FrontMatterKeys in the
+// front matter of a Markdown or Quarto document, which its format protects
+// as a whole and so leaves to the text's first marker. The rest of the
+// front matter stays protected.
+func (t *Translator) maskFrontMatter(text string, spans *[]string) string {
+	keys := t.config.FrontMatterKeys
+	loc := maskTokenRe.FindStringSubmatchIndex(text)
+	if len(keys) == 0 || loc == nil || loc[0] != 0 {
+		return text
+	}
+	n, _ := strconv.Atoi(text[loc[2]:loc[3]])
+	block := (*spans)[n]
+
+	var masked string
+	var local []string
+	switch {
+	case yamlFrontMatterRe.FindString(block) == block:
+		body := block[len("---\n") : len(block)-len("---\n")]
+		masked, local = maskYAML(body, Config{YAMLKeys: keys})
+		masked, local = wrapMasked("---\n", masked, "---\n", local)
+	case tomlFrontMatterRe.FindString(block) == block:
+		body := block[len("+++\n") : len(block)-len("+++\n")]
+		masked, local = maskTOML(body, keys)
+		masked, local = wrapMasked("+++\n", masked, "+++\n", local)
+	default:
+		return text
+	}
+	return adoptSpans(masked, local, spans) + text[loc[1]:]
+}
+
+// maskTOML leaves only the basic string values of the selected keys of a
+// TOML document visible to the model.
+func maskTOML(text string, keys []string) (string, []string) {
+	var b maskBuilder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		body := strings.TrimSuffix(line, "\n")
+		if m := tomlStringRe.FindStringSubmatch(body); m != nil && m[3] != "" && yamlKeySelected(m[2], keys) {
+			b.protect(m[1])
+			b.keep(m[3])
+			b.protect(m[4] + line[len(body):])
+			continue
+		}
+		b.protect(line)
+	}
+	return b.result()
+}
+
+// wrapMasked protects open and close around masked text whose markers
+// number into spans.
+func wrapMasked(open, masked, close string, spans []string) (string, []string) {
+	all := []string{open}
+	masked = adoptSpans(masked, spans, &all)
+	all = append(all, close)
+	return marker(0) + masked + marker(len(all)-1), all
+}
+
+// adoptSpans renumbers the markers of masked, which index local, to follow
+// on from spans, and appends local to spans.
+func adoptSpans(masked string, local []string, spans *[]string) string {
+	offset := len(*spans)
+	*spans = append(*spans, local...)
+	return maskTokenRe.ReplaceAllStringFunc(masked, func(token string) string {
+		n, _ := strconv.Atoi(token[len(maskOpen) : len(token)-len(maskClose)])
+		return marker(n + offset)
+	})
+}
+
+func marker(n int) string {
+	return maskOpen + strconv.Itoa(n) + maskClose
+}
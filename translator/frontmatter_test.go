@@ -0,0 +1,58 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFrontMatter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		keys     []string
+		expected string
+	}{
+		{
+			"YAML untouched",
+			"---\ntitle: Hello\nlayout: post\n---\n\nSome text.\n",
+			nil,
+			"---\ntitle: Hello\nlayout: post\n---\n\nSOME TEXT.\n",
+		},
+		{
+			"TOML untouched",
+			"+++\ntitle = \"Hello\"\ndraft = false\n+++\n\nSome text.\n",
+			nil,
+			"+++\ntitle = \"Hello\"\ndraft = false\n+++\n\nSOME TEXT.\n",
+		},
+		{
+			"YAML keys",
+			"---\ntitle: \"Hello\"\ndescription: A short post\nlayout: post\ntags: [news]\n---\n\nSome text.\n",
+			[]string{"title", "description"},
+			"---\ntitle: \"HELLO\"\ndescription: A SHORT POST\nlayout: post\ntags: [news]\n---\n\nSOME TEXT.\n",
+		},
+		{
+			"TOML keys",
+			"+++\ntitle = \"Hello\" # shown in lists\nslug = \"hello\"\n+++\n\nSome text.\n",
+			[]string{"title"},
+			"+++\ntitle = \"HELLO\" # shown in lists\nslug = \"hello\"\n+++\n\nSOME TEXT.\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			in := filepath.Join(dir, "post.md")
+			out := filepath.Join(dir, "post.de.md")
+			os.WriteFile(in, []byte(tc.input), 0644)
+
+			tr := NewTranslator(Config{Provider: &promptRecorder{}, ChunkSize: 100, NoDelay: true, ToLang: "german", FrontMatterKeys: tc.keys})
+			if err := tr.TranslateFile(in, out); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(out); string(got) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package translator
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LoadGlossary reads a CSV file of source terms and the translations the
+// model must use for them, one pair per row. A first row of "source,target"
+// is skipped as a header.
+func LoadGlossary(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read glossary: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse glossary %s: %w", path, err)
+	}
+
+	glossary := map[string]string{}
+	for i, row := range rows {
+		if i == 0 && len(row) == 2 && strings.EqualFold(row[0], "source") && strings.EqualFold(row[1], "target") {
+			continue
+		}
+		if len(row) != 2 || strings.TrimSpace(row[0]) == "" {
+			return nil, fmt.Errorf("glossary %s, row %d: expected a source term and its translation", path, i+1)
+		}
+		glossary[strings.TrimSpace(row[0])] = strings.TrimSpace(row[1])
+	}
+	return glossary, nil
+}
+
+// glossaryMatches lists the glossary terms that occur in text, sorted.
+func (t *Translator) glossaryMatches(text string) []string {
+	lower := strings.ToLower(text)
+	var terms []string
+	for term := range t.config.Glossary {
+		if term != "" && strings.Contains(lower, strings.ToLower(term)) {
+			terms = append(terms, term)
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// glossaryTerms lists the glossary entries whose term occurs in text, as
+// "term" = "translation" pairs for the prompt.
+func (t *Translator) glossaryTerms(text string) []string {
+	var pairs []string
+	for _, term := range t.glossaryMatches(text) {
+		pairs = append(pairs, fmt.Sprintf("%q = %q", term, t.config.Glossary[term]))
+	}
+	return pairs
+}
+
+// missingTerms lists the glossary terms of source whose required
+// translation does not appear in translation.
+func (t *Translator) missingTerms(source, translation string) []string {
+	lower := strings.ToLower(translation)
+	var missing []string
+	for _, term := range t.glossaryMatches(source) {
+		if !strings.Contains(lower, strings.ToLower(t.config.Glossary[term])) {
+			missing = append(missing, term)
+		}
+	}
+	return missing
+}
+
+// Derive returns a Translator with config for files that need other
+// settings than the rest of a job, e.g. another glossary. It keeps the
+// deduplication index prepared by PrepareDedupe on t as long as the target
+// language is the same.
+func (t *Translator) Derive(config Config) *Translator {
+	d := NewTranslator(config)
+	if config.ToLang == t.config.ToLang {
+		d.dedupe = t.dedupe
+	}
+	return d
+}
@@ -0,0 +1,68 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlossaryPrompt(t *testing.T) {
+	tr := NewTranslator(Config{ToLang: "german", Glossary: map[string]string{
+		"pull request": "Pull-Request",
+		"Commit":       "Commit",
+		"merge queue":  "Merge-Warteschlange",
+	}})
+
+	prompt, _ := tr.buildPrompt("Open a Pull Request and commit.", promptContext{})
+	if !strings.Contains(prompt, `these terms exactly as given: "Commit" = "Commit"; "pull request" = "Pull-Request"`) {
+		t.Errorf("Expected the matching terms in the prompt, got %q", prompt)
+	}
+	if strings.Contains(prompt, "merge queue") {
+		t.Errorf("Expected terms missing from the chunk to be left out, got %q", prompt)
+	}
+	if prompt, _ := tr.buildPrompt("Nothing here.", promptContext{}); strings.Contains(prompt, "terms") {
+		t.Errorf("Expected no glossary without matching terms, got %q", prompt)
+	}
+}
+
+func TestDeriveSharesDedupe(t *testing.T) {
+	tr := NewTranslator(Config{ToLang: "german"})
+	tr.dedupe = &dedupeIndex{}
+
+	if d := tr.Derive(Config{ToLang: "german", Model: "openai/gpt-4o"}); d.dedupe != tr.dedupe {
+		t.Error("Expected a derived translator to share the deduplication index")
+	}
+	if d := tr.Derive(Config{ToLang: "french"}); d.dedupe != nil {
+		t.Error("Expected no shared index for another target language")
+	}
+}
+
+func TestLoadGlossary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "glossary.csv")
+	os.WriteFile(path, []byte("source,target\npull request, Pull-Request\n\"commit, squashed\",Squash-Commit\n"), 0644)
+
+	glossary, err := LoadGlossary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(glossary) != 2 || glossary["pull request"] != "Pull-Request" || glossary["commit, squashed"] != "Squash-Commit" {
+		t.Errorf("Unexpected glossary %v", glossary)
+	}
+
+	os.WriteFile(path, []byte("only one column\n"), 0644)
+	if _, err := LoadGlossary(path); err == nil || !strings.Contains(err.Error(), "row 1") {
+		t.Errorf("Expected an error naming the bad row, got %v", err)
+	}
+}
+
+func TestGlossaryCheck(t *testing.T) {
+	tr := NewTranslator(Config{Glossary: map[string]string{"pull request": "Pull-Request", "branch": "Branch"}})
+
+	tr.validateChunk(2, 7, "Open a pull request from your branch.", "Öffne einen Pull-Request von deinem Zweig.")
+	warnings := tr.Result().Warnings
+	if len(warnings) != 1 || warnings[0].Kind != WarningGlossaryTerm || warnings[0].Chunk != 2 || warnings[0].Line != 7 ||
+		!strings.Contains(warnings[0].Message, `"branch"`) {
+		t.Errorf("Expected one glossary warning for branch, got %+v", warnings)
+	}
+}
@@ -0,0 +1,65 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// hedgedRequest sends text to model and, if no response bytes have arrived
+// after Config.HedgeDelay, sends the same request once more. The first valid
+// answer wins and the other request is cancelled.
+func (t *Translator) hedgedRequest(ctx context.Context, model, text string, pc promptContext) (string, error) {
+	if t.config.HedgeDelay <= 0 {
+		return t.requestTranslation(ctx, model, text, pc)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	firstByte := make(chan struct{})
+	var once sync.Once
+	traced := httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { once.Do(func() { close(firstByte) }) },
+	})
+
+	outcomes := make(chan raceOutcome, 2)
+	send := func(ctx context.Context) {
+		go func() {
+			translated, err := t.requestTranslation(ctx, model, text, pc)
+			outcomes <- raceOutcome{model: model, text: translated, err: err}
+		}()
+	}
+	send(traced)
+
+	timer := time.NewTimer(t.config.HedgeDelay)
+	defer timer.Stop()
+
+	select {
+	case o := <-outcomes:
+		return o.text, o.err
+	case <-firstByte:
+		o := <-outcomes
+		return o.text, o.err
+	case <-timer.C:
+	}
+
+	if t.config.Verbose {
+		fmt.Printf("No response from %s after %v, sending a hedged request\n", model, t.config.HedgeDelay)
+	}
+	send(ctx)
+
+	var firstErr error
+	for pending := 2; pending > 0; pending-- {
+		o := <-outcomes
+		if o.err == nil {
+			return o.text, nil
+		}
+		if firstErr == nil {
+			firstErr = o.err
+		}
+	}
+	return "", firstErr
+}
@@ -0,0 +1,49 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgedRequest(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"<result>slow</result>"}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"<result>hedged</result>"}}]}`)
+	}))
+	defer server.Close()
+
+	translator := NewTranslator(Config{BaseURL: server.URL, HedgeDelay: 50 * time.Millisecond})
+
+	start := time.Now()
+	got, err := translator.translateChunk(context.Background(), "text", promptContext{})
+	if err != nil || got != "hedged" {
+		t.Fatalf("Expected the hedged request to win, got %q, %v", got, err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("Hedging waited for the slow request")
+	}
+
+	atomic.StoreInt32(&calls, 1)
+	translator = NewTranslator(Config{BaseURL: server.URL, HedgeDelay: time.Second})
+	if got, err := translator.translateChunk(context.Background(), "text", promptContext{}); err != nil || got != "hedged" {
+		t.Fatalf("Expected a direct answer, got %q, %v", got, err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected no hedged request for a fast response, got %d requests", calls-1)
+	}
+}
@@ -0,0 +1,236 @@
+package translator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// icuArgument is a {…} argument of an ICU MessageFormat message, such as
+// {name}, {n, number} or {count, plural, one {…} other {…}}.
+type icuArgument struct {
+	// start and end are byte offsets into the message, braces included.
+	start, end int
+	name       string
+	// kind is the argument type, e.g. "plural" or "select", or "" for a
+	// plain {name}.
+	kind    string
+	offset  string
+	options []icuOption
+}
+
+// icuOption is one selector of a plural or select argument, e.g. one {…}.
+type icuOption struct {
+	selector string
+	message  string
+}
+
+// parseICU checks the syntax of an ICU MessageFormat message and returns its
+// top-level arguments. Nested messages of plural and select options are
+// checked too.
+func parseICU(msg string) ([]icuArgument, error) {
+	end, args, err := scanICUMessage(msg, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	if end != len(msg) {
+		return nil, fmt.Errorf("unbalanced } at offset %d", end)
+	}
+	return args, nil
+}
+
+// scanICUMessage reads message text from i up to the } closing a nested
+// message or to the end of msg, and returns where it stopped.
+func scanICUMessage(msg string, i int, nested bool) (int, []icuArgument, error) {
+	var args []icuArgument
+	for i < len(msg) {
+		switch msg[i] {
+		case '\'':
+			i = skipICUQuote(msg, i)
+		case '{':
+			arg, err := scanICUArgument(msg, i)
+			if err != nil {
+				return 0, nil, err
+			}
+			args = append(args, arg)
+			i = arg.end
+		case '}':
+			// Closes a nested message; at the top level parseICU reports it.
+			return i, args, nil
+		default:
+			i++
+		}
+	}
+	if nested {
+		return 0, nil, fmt.Errorf("unclosed { in option")
+	}
+	return i, args, nil
+}
+
+// skipICUQuote skips the apostrophe at i. Two apostrophes stand for one, and
+// one before { or } quotes text up to the next apostrophe.
+func skipICUQuote(msg string, i int) int {
+	if i+1 < len(msg) && msg[i+1] == '\'' {
+		return i + 2
+	}
+	if i+1 >= len(msg) || (msg[i+1] != '{' && msg[i+1] != '}') {
+		return i + 1
+	}
+	for j := i + 1; j < len(msg); j++ {
+		if msg[j] == '\'' {
+			if j+1 < len(msg) && msg[j+1] == '\'' {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(msg)
+}
+
+func scanICUArgument(msg string, start int) (icuArgument, error) {
+	arg := icuArgument{start: start}
+	i := start + 1
+
+	field := func() string {
+		j := i
+		for j < len(msg) && msg[j] != ',' && msg[j] != '}' && msg[j] != '{' {
+			j++
+		}
+		s := strings.TrimSpace(msg[i:j])
+		i = j
+		return s
+	}
+
+	arg.name = field()
+	if arg.name == "" || strings.IndexFunc(arg.name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) >= 0 {
+		return arg, fmt.Errorf("invalid argument name %q at offset %d", arg.name, start)
+	}
+	if i >= len(msg) || msg[i] == '{' {
+		return arg, fmt.Errorf("unclosed argument {%s at offset %d", arg.name, start)
+	}
+	if msg[i] == '}' {
+		arg.end = i + 1
+		return arg, nil
+	}
+
+	i++
+	arg.kind = field()
+	if i >= len(msg) || msg[i] == '{' {
+		return arg, fmt.Errorf("unclosed argument {%s at offset %d", arg.name, start)
+	}
+	if msg[i] == '}' {
+		arg.end = i + 1
+		return arg, nil
+	}
+	i++
+
+	switch arg.kind {
+	case "plural", "selectordinal", "select":
+	default:
+		// A style such as "integer" or "::currency/EUR".
+		for ; i < len(msg) && msg[i] != '}'; i++ {
+			if msg[i] == '{' {
+				return arg, fmt.Errorf("unexpected { in the style of {%s, %s} at offset %d", arg.name, arg.kind, i)
+			}
+		}
+		if i >= len(msg) {
+			return arg, fmt.Errorf("unclosed argument {%s at offset %d", arg.name, start)
+		}
+		arg.end = i + 1
+		return arg, nil
+	}
+
+	for {
+		for i < len(msg) && unicode.IsSpace(rune(msg[i])) {
+			i++
+		}
+		if i >= len(msg) {
+			return arg, fmt.Errorf("unclosed argument {%s at offset %d", arg.name, start)
+		}
+		if msg[i] == '}' {
+			arg.end = i + 1
+			break
+		}
+
+		j := i
+		for j < len(msg) && msg[j] != '{' && msg[j] != '}' && !unicode.IsSpace(rune(msg[j])) {
+			j++
+		}
+		selector := msg[i:j]
+		i = j
+		if arg.kind != "select" && strings.HasPrefix(selector, "offset:") {
+			arg.offset = strings.TrimPrefix(selector, "offset:")
+			continue
+		}
+		for i < len(msg) && unicode.IsSpace(rune(msg[i])) {
+			i++
+		}
+		if selector == "" || i >= len(msg) || msg[i] != '{' {
+			return arg, fmt.Errorf("expected a selector followed by {message} in {%s, %s} at offset %d", arg.name, arg.kind, i)
+		}
+
+		end, _, err := scanICUMessage(msg, i+1, true)
+		if err != nil {
+			return arg, fmt.Errorf("option %s of {%s}: %w", selector, arg.name, err)
+		}
+		arg.options = append(arg.options, icuOption{selector: selector, message: msg[i+1 : end]})
+		i = end + 1
+	}
+
+	if arg.option("other") == nil {
+		return arg, fmt.Errorf("{%s, %s} has no other option", arg.name, arg.kind)
+	}
+	return arg, nil
+}
+
+func (a icuArgument) option(selector string) *icuOption {
+	for i := range a.options {
+		if a.options[i].selector == selector {
+			return &a.options[i]
+		}
+	}
+	return nil
+}
+
+// icuArgNames returns the names of all arguments of msg, nested ones
+// included.
+func icuArgNames(args []icuArgument) []string {
+	var names []string
+	for _, a := range args {
+		names = append(names, a.name)
+		for _, o := range a.options {
+			if nested, err := parseICU(o.message); err == nil {
+				names = append(names, icuArgNames(nested)...)
+			}
+		}
+	}
+	return names
+}
+
+// icuProblem reports why translation is not a usable ICU MessageFormat
+// rendering of source: broken syntax, or arguments that were dropped or
+// renamed. It only checks runs over localization strings, see Config.ICU.
+func (t *Translator) icuProblem(source, translation string) error {
+	if !t.config.ICU && !t.icu {
+		return nil
+	}
+	sourceArgs, err := parseICU(source)
+	if err != nil {
+		// Not an ICU message to begin with.
+		return nil
+	}
+	args, err := parseICU(translation)
+	if err != nil {
+		return fmt.Errorf("invalid ICU MessageFormat: %w", err)
+	}
+	if lost := missingItems(icuArgNames(sourceArgs), icuArgNames(args)); len(lost) > 0 {
+		return fmt.Errorf("ICU arguments dropped or renamed: {%s}", strings.Join(lost, "}, {"))
+	}
+	if extra := missingItems(icuArgNames(args), icuArgNames(sourceArgs)); len(extra) > 0 {
+		return fmt.Errorf("unknown ICU arguments: {%s}", strings.Join(extra, "}, {"))
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package translator
+
+import (
+	"context"
+	"sync"
+)
+
+// Job is a translation running in the background, started with Start.
+type Job struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+
+	mu sync.Mutex
+	// resumed is open while the job is paused and closed by Resume.
+	resumed  chan struct{}
+	progress Progress
+}
+
+// Progress is a snapshot of a job's state.
+type Progress struct {
+	Chunks           int  `json:"chunks"`
+	ChunksTranslated int  `json:"chunks_translated"`
+	Retries          int  `json:"retries"`
+	Bytes            int  `json:"bytes"`
+	Paused           bool `json:"paused"`
+	Done             bool `json:"done"`
+}
+
+// Start translates inputPath to outputPath in the background. The returned
+// Job pauses, resumes or cancels the run and reports its progress. A
+// Translator runs one job at a time; Result is complete once Wait returns.
+func (t *Translator) Start(ctx context.Context, inputPath, outputPath string) *Job {
+	ctx, cancel := context.WithCancel(ctx)
+	j := &Job{cancel: cancel, done: make(chan struct{})}
+	t.job = j
+
+	go func() {
+		defer close(j.done)
+		defer cancel()
+		err := t.TranslateFileContext(ctx, inputPath, outputPath)
+		t.job = nil
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.err = err
+		j.progress.Done = true
+	}()
+	return j
+}
+
+// Pause stops the job from starting new chunks. Chunks already sent to the
+// model are finished and written.
+func (j *Job) Pause() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.resumed == nil && !j.progress.Done {
+		j.resumed = make(chan struct{})
+		j.progress.Paused = true
+	}
+}
+
+// Resume continues a paused job.
+func (j *Job) Resume() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.resumed != nil {
+		close(j.resumed)
+		j.resumed = nil
+		j.progress.Paused = false
+	}
+}
+
+// Cancel stops the job, paused or not. Wait then returns an error of class
+// ErrorClassCanceled; chunks finished so far stay written.
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// Done is closed when the job has finished.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait blocks until the job has finished and returns its error.
+func (j *Job) Wait() error {
+	<-j.done
+	return j.err
+}
+
+// Progress returns a snapshot of the job's progress.
+func (j *Job) Progress() Progress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress
+}
+
+// wait blocks while the job is paused. It is safe to call on a nil Job.
+func (j *Job) wait(ctx context.Context) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	resumed := j.resumed
+	j.mu.Unlock()
+	if resumed == nil {
+		return nil
+	}
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// observe updates the progress snapshot from a progress event.
+func (j *Job) observe(ev ProgressEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch ev.Event {
+	case "split":
+		j.progress.Chunks = ev.Chunks
+	case "retry":
+		j.progress.Retries++
+	case "chunk_done":
+		j.progress.ChunksTranslated++
+		j.progress.Bytes += ev.Bytes
+	}
+}
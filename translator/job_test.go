@@ -0,0 +1,58 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJobPauseResume(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	out := filepath.Join(dir, "out.txt")
+	os.WriteFile(in, []byte("First paragraph.\n\nSecond paragraph.\n\nThird paragraph.\n"), 0644)
+
+	tr := NewTranslator(Config{Provider: slowUpperProvider{delay: 50 * time.Millisecond}, ChunkSize: 5, NoDelay: true})
+	job := tr.Start(context.Background(), in, out)
+	job.Pause()
+
+	// The first chunk may already have started; nothing starts after it.
+	time.Sleep(200 * time.Millisecond)
+	p := job.Progress()
+	if !p.Paused || p.ChunksTranslated > 1 || p.Chunks != 3 {
+		t.Fatalf("Expected a paused job with at most one chunk done, got %+v", p)
+	}
+
+	job.Resume()
+	if err := job.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if p := job.Progress(); p.Paused || !p.Done || p.ChunksTranslated != 3 || p.Bytes == 0 {
+		t.Errorf("Expected a finished job, got %+v", p)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "FIRST PARAGRAPH.\n\nSECOND PARAGRAPH.\n\nTHIRD PARAGRAPH.\n" {
+		t.Errorf("Unexpected output %q", data)
+	}
+}
+
+func TestJobCancelWhilePaused(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	os.WriteFile(in, []byte("First paragraph.\n\nSecond paragraph.\n"), 0644)
+
+	tr := NewTranslator(Config{Provider: slowUpperProvider{delay: 50 * time.Millisecond}, ChunkSize: 5, NoDelay: true})
+	job := tr.Start(context.Background(), in, filepath.Join(dir, "out.txt"))
+	job.Pause()
+	job.Cancel()
+
+	select {
+	case <-job.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the canceled job to finish")
+	}
+	if err := job.Wait(); ErrorClass(err) != ErrorClassCanceled {
+		t.Errorf("Expected a canceled error, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	os.WriteFile(path, []byte("old"), 0644)
+
+	if err := WriteFileAtomic(path, []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "new" {
+		t.Errorf("Expected the file replaced, got %q", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files left, got %v", entries)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestFileCacheTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	c, err := OpenFileCache(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("a", "Hallo")
+
+	// A crash in the middle of appending the next record.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write([]byte(`{"key":"b","transl`))
+	f.Close()
+
+	c, err = OpenFileCache(path, nil)
+	if err != nil {
+		t.Fatalf("Expected the torn record dropped, got %v", err)
+	}
+	if got, ok := c.Get("a"); !ok || got != "Hallo" {
+		t.Errorf("Expected the complete record kept, got %q", got)
+	}
+	if err := c.Put("b", "Welt"); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = OpenFileCache(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.Get("b"); got != "Welt" {
+		t.Errorf("Expected records appended after the repair to load, got %q", got)
+	}
+}
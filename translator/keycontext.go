@@ -0,0 +1,73 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyContext tells the model what a UI string is for, so short ambiguous
+// strings such as "Open" or "Save" come out right.
+type KeyContext struct {
+	Description string `json:"description"`
+	// MaxLength is the number of characters the translation must fit in,
+	// e.g. the width of a button; zero means no limit.
+	MaxLength int `json:"max_length"`
+	// Screenshot is the URL of an image showing where the string appears.
+	// It is attached to requests for multimodal models.
+	Screenshot string `json:"screenshot"`
+}
+
+// LoadKeyContext reads a JSON file mapping string keys to their KeyContext:
+//
+//	{"toolbar.open": {"description": "Button that opens a file", "max_length": 10}}
+func LoadKeyContext(path string) (map[string]KeyContext, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key context: %w", err)
+	}
+	var contexts map[string]KeyContext
+	if err := json.Unmarshal(data, &contexts); err != nil {
+		return nil, fmt.Errorf("failed to parse key context %s: %w", path, err)
+	}
+	for key, c := range contexts {
+		if c.MaxLength < 0 {
+			return nil, fmt.Errorf("key context %s, key %q: max_length must not be negative", path, key)
+		}
+	}
+	return contexts, nil
+}
+
+// keyContext returns the context of a segment, looked up by its "key" Meta.
+func (t *Translator) keyContext(s Segment) (KeyContext, bool) {
+	key := s.Meta["key"]
+	if key == "" {
+		return KeyContext{}, false
+	}
+	c, ok := t.config.KeyContext[key]
+	return c, ok
+}
+
+// hint phrases the context as instructions for the prompt.
+func (c KeyContext) hint(key string) string {
+	var parts []string
+	if d := strings.TrimSpace(c.Description); d != "" {
+		parts = append(parts, fmt.Sprintf("The text is the UI string %q: %s", key, strings.TrimRight(d, ". ")))
+	}
+	if c.Screenshot != "" {
+		parts = append(parts, fmt.Sprintf("The attached screenshot (%s) shows where the string appears", c.Screenshot))
+	}
+	return strings.Join(parts, ". ")
+}
+
+// joinHints combines the instructions for one chunk.
+func joinHints(hints ...string) string {
+	var parts []string
+	for _, h := range hints {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, ". ")
+}
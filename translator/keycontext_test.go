@@ -0,0 +1,85 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadKeyContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "context.json")
+	content := `{"toolbar.open": {"description": "Button that opens a file.", "max_length": 8, "screenshot": "https://example.com/toolbar.png"}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	contexts, err := LoadKeyContext(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := contexts["toolbar.open"]
+	if c.Description != "Button that opens a file." || c.MaxLength != 8 || c.Screenshot != "https://example.com/toolbar.png" {
+		t.Errorf("Unexpected context %+v", c)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"a": {"max_length": -1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeyContext(path); err == nil {
+		t.Error("Expected a negative max_length to be rejected")
+	}
+}
+
+func TestKeyContextInPrompt(t *testing.T) {
+	var requests []OpenRouterRequest
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw json.RawMessage
+		json.NewDecoder(r.Body).Decode(&raw)
+		bodies = append(bodies, string(raw))
+		var req OpenRouterRequest
+		json.Unmarshal(raw, &req)
+		requests = append(requests, req)
+		w.Write([]byte(`{"choices": [{"message": {"content": "<result>Öffnen</result>"}}]}`))
+	}))
+	defer server.Close()
+
+	tr := NewTranslator(Config{BaseURL: server.URL, APIKey: "key", Model: "m", ToLang: "german", ChunkSize: 100, NoDelay: true,
+		KeyContext: map[string]KeyContext{
+			"toolbar.open": {Description: "Button that opens a file", MaxLength: 8, Screenshot: "https://example.com/toolbar.png"},
+		}})
+	_, err := tr.TranslateSegments(context.Background(), []Segment{
+		{ID: 1, Source: "Open", Meta: map[string]string{"key": "toolbar.open"}},
+		{ID: 2, Source: "Open", Meta: map[string]string{"key": "menu.open"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+
+	var withContext, plain int
+	for i, body := range bodies {
+		if strings.Contains(body, "toolbar.png") {
+			withContext = i
+		} else {
+			plain = i
+		}
+	}
+	system := requests[withContext].Messages[0].Content
+	if !strings.Contains(system, `"toolbar.open": Button that opens a file`) || !strings.Contains(system, "longer than 8 characters") {
+		t.Errorf("Expected the key context in the prompt, got %q", system)
+	}
+	if !strings.Contains(bodies[withContext], `{"type":"image_url","image_url":{"url":"https://example.com/toolbar.png"}}`) {
+		t.Errorf("Expected the screenshot as an image part, got %s", bodies[withContext])
+	}
+	if strings.Contains(bodies[plain], "image_url") || strings.Contains(requests[plain].Messages[0].Content, "UI string") {
+		t.Errorf("Expected no context for a key without one, got %s", bodies[plain])
+	}
+}
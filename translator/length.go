@@ -0,0 +1,38 @@
+package translator
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// textLength counts the characters of text as a user sees them. Protection
+// markers stand for placeholders filled in at run time and do not count.
+func textLength(text string) int {
+	return utf8.RuneCountInString(strings.TrimSpace(maskTokenRe.ReplaceAllString(text, "")))
+}
+
+// chunkMaxLength returns the length limit of chunk i in characters, or 0
+// when it has none, see Segment.MaxLength.
+func (t *Translator) chunkMaxLength(i int) int {
+	if i >= 0 && i < len(t.maxLengths) {
+		return t.maxLengths[i]
+	}
+	return 0
+}
+
+// lengthProblem reports a translation of chunk i longer than its limit.
+func (t *Translator) lengthProblem(i int, translation string) error {
+	limit := t.chunkMaxLength(i)
+	if n := textLength(translation); limit > 0 && n > limit {
+		return fmt.Errorf("translation has %d characters, more than the limit of %d", n, limit)
+	}
+	return nil
+}
+
+func lengthHint(limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("The translation must not be longer than %d characters", limit)
+}
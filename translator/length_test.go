@@ -0,0 +1,82 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verboseProvider answers with a long rendering unless asked for a shorter
+// one, and keeps the system messages it got.
+type verboseProvider struct {
+	systems []string
+}
+
+func (p *verboseProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	p.systems = append(p.systems, cr.System)
+	if strings.Contains(cr.System, "shorter rendering") {
+		return &Completion{Text: "<result>Einst.</result>"}, nil
+	}
+	return &Completion{Text: "<result>Einstellungen</result>"}, nil
+}
+
+func TestMaxLengthRetry(t *testing.T) {
+	provider := &verboseProvider{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "german",
+		Retry:      RetryPolicy{Backoff: time.Millisecond},
+		KeyContext: map[string]KeyContext{"menu.settings": {MaxLength: 6}}})
+
+	results, err := tr.TranslateSegments(context.Background(), []Segment{
+		{ID: 1, Source: "Settings", Meta: map[string]string{"key": "menu.settings"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Target != "Einst." || len(provider.systems) != 2 {
+		t.Fatalf("Expected a retry with a shorter answer, got %q after %d requests", results[0].Target, len(provider.systems))
+	}
+	if !strings.Contains(provider.systems[0], "not be longer than 6 characters") ||
+		!strings.Contains(provider.systems[1], "previous translation was 13 characters long, over the limit of 6") {
+		t.Errorf("Expected the limit and the retry request in the prompts, got %q", provider.systems)
+	}
+	if len(tr.Result().Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %+v", tr.Result().Warnings)
+	}
+
+	// Segment.MaxLength takes precedence; when no answer fits, the last one
+	// is kept with a warning.
+	provider.systems = nil
+	results, err = tr.TranslateSegments(context.Background(), []Segment{
+		{ID: 1, Source: "Settings", MaxLength: 3, Meta: map[string]string{"key": "menu.settings"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].State != SegmentMachineTranslated || results[0].Target != "Einst." {
+		t.Errorf("Expected the last answer kept, got %+v", results[0])
+	}
+	if w := tr.Result().Warnings; len(w) != 1 || w[0].Kind != WarningTooLong {
+		t.Errorf("Expected a too-long warning, got %+v", w)
+	}
+}
+
+func TestTextLength(t *testing.T) {
+	if n := textLength(" Hallo " + maskOpen + "0" + maskClose + "! "); n != 7 {
+		t.Errorf("Expected markers and surrounding space not to count, got %d", n)
+	}
+	if n := textLength("Привет"); n != 6 {
+		t.Errorf("Expected characters rather than bytes, got %d", n)
+	}
+}
+
+func TestWriteXLIFFMaxWidth(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXLIFF(&buf, "strings.arb", "en", "de", []Segment{{ID: 1, Source: "Open", MaxLength: 8}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `maxwidth="8" size-unit="char"`) {
+		t.Errorf("Expected the length limit in the unit, got %s", buf.String())
+	}
+}
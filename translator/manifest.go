@@ -0,0 +1,195 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ManifestSuffix is appended to the output path to name the chunk manifest,
+// see Config.Manifest.
+const ManifestSuffix = ".chunks.json"
+
+const (
+	// ChunkTranslated is a chunk written with its translation.
+	ChunkTranslated = "translated"
+	// ChunkSourceKept is a chunk written with its source text, because the
+	// translation would have broken it, see WarningICUSyntax.
+	ChunkSourceKept = "source-kept"
+	// ChunkPending is a chunk not written yet, as the run failed or paused
+	// before it.
+	ChunkPending = "pending"
+)
+
+// ChunkManifest lists the chunks of a translated file, see Config.Manifest.
+type ChunkManifest struct {
+	RunID  string `json:"run_id"`
+	Input  string `json:"input"`
+	Output string `json:"output"`
+	ToLang string `json:"to_lang,omitempty"`
+	// SourceSHA256 is the hash of the input file.
+	SourceSHA256 string          `json:"source_sha256,omitempty"`
+	Chunks       []ManifestChunk `json:"chunks"`
+}
+
+// ManifestChunk is a chunk of a ChunkManifest. Source offsets are byte
+// offsets into the source as put back together from its chunks, which is the
+// input file unless options such as Config.Rewrap or Config.Select changed
+// it; output offsets are byte offsets into the output file as written,
+// before Config.PostCommands. The separator follows the chunk in both, so
+// the output can be reassembled from the chunk translations in index order.
+type ManifestChunk struct {
+	Index        int    `json:"index"`
+	SourceStart  int    `json:"source_start"`
+	SourceEnd    int    `json:"source_end"`
+	SourceSHA256 string `json:"source_sha256"`
+	OutputStart  int    `json:"output_start,omitempty"`
+	OutputEnd    int    `json:"output_end,omitempty"`
+	OutputSHA256 string `json:"output_sha256,omitempty"`
+	Separator    string `json:"separator,omitempty"`
+	Status       string `json:"status"`
+}
+
+// ReadChunkManifest reads a manifest written by a run with Config.Manifest.
+func ReadChunkManifest(path string) (*ChunkManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk manifest: %w", err)
+	}
+	var manifest ChunkManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse chunk manifest %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// startManifest lists the chunks of job as pending. A resumed job keeps the
+// entries of the chunks written before from the manifest of the earlier run,
+// where their source is unchanged.
+func (t *Translator) startManifest(job *fileJob, prepared *PreparedFile) {
+	if !t.config.Manifest || t.result.Output == "" || t.config.Via != "" {
+		return
+	}
+	job.manifest = &ChunkManifest{
+		RunID:        t.runID,
+		Input:        prepared.Input,
+		Output:       t.result.Output,
+		ToLang:       t.config.ToLang,
+		SourceSHA256: prepared.SourceSHA256,
+		Chunks:       make([]ManifestChunk, len(job.chunks)),
+	}
+	offset := 0
+	for i := range job.chunks {
+		source := job.source(i)
+		c := &job.manifest.Chunks[i]
+		c.Index = i + 1
+		c.SourceStart, c.SourceEnd = offset, offset+len(source)
+		c.SourceSHA256 = sha256Hex([]byte(source))
+		c.Status = ChunkPending
+		offset = c.SourceEnd
+		if job.separators != nil {
+			c.Separator = unmaskSpans(job.separators[i], job.sourceSpans)
+			offset += len(c.Separator)
+		}
+	}
+
+	if job.first == 0 {
+		return
+	}
+	if info, err := os.Stat(t.result.Output); err == nil {
+		job.outputBytes = int(info.Size())
+	}
+	previous, err := ReadChunkManifest(t.result.Output + ManifestSuffix)
+	for i := 0; i < job.first; i++ {
+		c := &job.manifest.Chunks[i]
+		if err == nil && i < len(previous.Chunks) && previous.Chunks[i].SourceSHA256 == c.SourceSHA256 {
+			*c = previous.Chunks[i]
+		} else {
+			// Without it the chunk is only known to be written.
+			c.Status = ChunkTranslated
+		}
+	}
+}
+
+// skipDone continues the translation of prepared into outputPath where an
+// earlier run with Config.Manifest left off, when the manifest and the output
+// file are there: the leading chunks that were translated, whose source,
+// separator and target language are unchanged and whose output is still in
+// the file are kept, and the output after them is cut off. A chunk whose
+// source was kept in place of a failed translation is translated again. It returns prepared as is when there
+// is nothing to keep, or a copy with Done set.
+func (t *Translator) skipDone(prepared *PreparedFile, outputPath string) (*PreparedFile, error) {
+	if !t.config.Manifest || prepared.Done > 0 || t.config.Via != "" || len(prepared.Separators) != len(prepared.Chunks) {
+		return prepared, nil
+	}
+	previous, err := ReadChunkManifest(outputPath + ManifestSuffix)
+	if err != nil || previous.ToLang != t.config.ToLang {
+		return prepared, nil
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		return prepared, nil
+	}
+	output := string(data)
+
+	done, offset := 0, 0
+	for i, c := range previous.Chunks {
+		if i >= len(prepared.Chunks) || c.Index != i+1 || c.Status != ChunkTranslated || c.OutputStart != offset ||
+			c.OutputEnd > len(output) || c.OutputEnd < c.OutputStart {
+			break
+		}
+		source := unmaskSpans(prepared.Chunks[i], prepared.SourceSpans)
+		separator := prepared.Separators[i]
+		if c.SourceSHA256 != sha256Hex([]byte(source)) || c.Separator != unmaskSpans(separator, prepared.SourceSpans) ||
+			c.OutputSHA256 != sha256Hex([]byte(output[c.OutputStart:c.OutputEnd])) ||
+			!strings.HasPrefix(output[c.OutputEnd:], separator) {
+			break
+		}
+		done, offset = i+1, c.OutputEnd+len(separator)
+	}
+	if done == 0 {
+		return prepared, nil
+	}
+
+	if err := os.Truncate(outputPath, int64(offset)); err != nil {
+		return nil, classify(ErrorClassOutput, fmt.Errorf("failed to cut off the output after the chunks done: %w", err))
+	}
+	if t.config.Verbose {
+		fmt.Printf("Keeping %d of %d chunks translated by an earlier run\n", done, len(prepared.Chunks))
+	}
+	resumed := *prepared
+	resumed.Done = done
+	resumed.NextLine = 1 + strings.Count(output[:offset], "\n")
+	return &resumed, nil
+}
+
+// noteManifest records chunk i, written as output at the output offset of
+// job.
+func (job *fileJob) noteManifest(i int, output, state string) {
+	if job.manifest == nil {
+		return
+	}
+	c := &job.manifest.Chunks[i]
+	c.OutputStart, c.OutputEnd = job.outputBytes, job.outputBytes+len(output)
+	c.OutputSHA256 = sha256Hex([]byte(output))
+	c.Status = ChunkTranslated
+	if state == SegmentUntranslated {
+		c.Status = ChunkSourceKept
+	}
+}
+
+// writeManifest writes the manifest of job next to the output file.
+func (t *Translator) writeManifest(job *fileJob) error {
+	if job.manifest == nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(job.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(job.manifest.Output+ManifestSuffix, append(data, '\n'), 0644); err != nil {
+		return classify(ErrorClassOutput, fmt.Errorf("failed to write chunk manifest: %w", err))
+	}
+	return nil
+}
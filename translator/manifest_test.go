@@ -0,0 +1,204 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChunkManifest(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	out := filepath.Join(dir, "out.txt")
+	source := "First paragraph.\n\n\nSecond paragraph.\n\nThird paragraph.\n"
+	os.WriteFile(in, []byte(source), 0644)
+
+	tr := NewTranslator(Config{Provider: &promptRecorder{}, ChunkSize: 5, NoDelay: true, NoSanityCheck: true, Manifest: true})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := ReadChunkManifest(out + ManifestSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := readFile(t, out)
+	if len(manifest.Chunks) != 3 || manifest.SourceSHA256 != sha256Hex([]byte(source)) || manifest.RunID != tr.Result().RunID {
+		t.Fatalf("Unexpected manifest %+v", manifest)
+	}
+
+	var reassembled strings.Builder
+	for i, c := range manifest.Chunks {
+		if c.Index != i+1 || c.Status != ChunkTranslated {
+			t.Errorf("Unexpected entry %+v", c)
+		}
+		chunkSource := source[c.SourceStart:c.SourceEnd]
+		chunkOutput := output[c.OutputStart:c.OutputEnd]
+		if c.SourceSHA256 != sha256Hex([]byte(chunkSource)) || c.OutputSHA256 != sha256Hex([]byte(chunkOutput)) {
+			t.Errorf("Expected the hashes of the chunk at its offsets, got %+v", c)
+		}
+		if chunkOutput != strings.ToUpper(chunkSource) {
+			t.Errorf("Expected the offsets of chunk %d to match, got %q and %q", c.Index, chunkSource, chunkOutput)
+		}
+		reassembled.WriteString(chunkOutput + c.Separator)
+	}
+	if reassembled.String() != output {
+		t.Errorf("Expected the chunks and separators to add up to the output, got %q", reassembled.String())
+	}
+
+	paused := NewTranslator(Config{Provider: &promptRecorder{}, ChunkSize: 5, NoDelay: true, Manifest: true,
+		Until: time.Now().Add(-time.Minute)})
+	out = filepath.Join(dir, "paused.txt")
+	if err := paused.TranslateFile(in, out); ErrorClass(err) != ErrorClassPaused {
+		t.Fatalf("Expected the run to pause, got %v", err)
+	}
+	if manifest, err = ReadChunkManifest(out + ManifestSuffix); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range manifest.Chunks {
+		if c.Status != ChunkPending || c.OutputSHA256 != "" {
+			t.Errorf("Expected every chunk pending, got %+v", c)
+		}
+	}
+}
+
+func TestRerunSkipsDoneChunks(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	out := filepath.Join(dir, "out.txt")
+	source := "First paragraph.\n\nSecond paragraph.\n\nThird paragraph.\n"
+	os.WriteFile(in, []byte(source), 0644)
+
+	config := Config{Provider: &promptRecorder{}, ChunkSize: 5, NoDelay: true, NoSanityCheck: true, Manifest: true}
+	if err := NewTranslator(config).TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	want := readFile(t, out)
+
+	// A crash after the first chunk leaves part of the second one behind.
+	manifest, _ := ReadChunkManifest(out + ManifestSuffix)
+	os.WriteFile(out, []byte(want[:manifest.Chunks[1].OutputStart+5]), 0644)
+	manifest.Chunks[1].Status, manifest.Chunks[2].Status = ChunkPending, ChunkPending
+	data, _ := json.Marshal(manifest)
+	os.WriteFile(out+ManifestSuffix, data, 0644)
+
+	provider := &promptRecorder{}
+	config.Provider = provider
+	tr := NewTranslator(config)
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, out); got != want {
+		t.Errorf("Expected the rerun to complete the output, got %q", got)
+	}
+	if len(provider.prompts) != 2 || tr.Result().ChunksTranslated != 2 {
+		t.Errorf("Expected only the missing chunks translated, got %q", provider.prompts)
+	}
+
+	// Nothing is left to do the next time, unless the source changes.
+	provider.prompts = nil
+	if err := NewTranslator(config).TranslateFile(in, out); err != nil || len(provider.prompts) != 0 {
+		t.Errorf("Expected no requests, got %q %v", provider.prompts, err)
+	}
+	os.WriteFile(in, []byte(strings.Replace(source, "Third", "Last", 1)), 0644)
+	if err := NewTranslator(config).TranslateFile(in, out); err != nil || len(provider.prompts) != 1 {
+		t.Errorf("Expected the changed chunk translated, got %q %v", provider.prompts, err)
+	}
+	if got := readFile(t, out); got != strings.ToUpper(strings.Replace(source, "Third", "Last", 1)) {
+		t.Errorf("Unexpected output %q", got)
+	}
+}
+
+func TestRerunRetriesSourceKeptChunks(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	out := filepath.Join(dir, "out.txt")
+	source := "First paragraph.\n\nSecond paragraph.\n\nThird paragraph.\n"
+	os.WriteFile(in, []byte(source), 0644)
+
+	config := Config{Provider: &promptRecorder{}, ChunkSize: 5, NoDelay: true, NoSanityCheck: true, Manifest: true}
+	if err := NewTranslator(config).TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second chunk failed its checks and its source was written instead.
+	manifest, _ := ReadChunkManifest(out + ManifestSuffix)
+	manifest.Chunks[1].Status = ChunkSourceKept
+	data, _ := json.Marshal(manifest)
+	os.WriteFile(out+ManifestSuffix, data, 0644)
+
+	provider := &promptRecorder{}
+	config.Provider = provider
+	if err := NewTranslator(config).TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.prompts) != 2 || !strings.Contains(provider.prompts[0], "Second paragraph.") {
+		t.Errorf("Expected the source-kept chunk and the rest translated again, got %q", provider.prompts)
+	}
+	if got := readFile(t, out); got != strings.ToUpper(source) {
+		t.Errorf("Unexpected output %q", got)
+	}
+}
+
+// stallingProvider upper-cases chunks until its stall-th request, which
+// waits for the run to be canceled.
+type stallingProvider struct {
+	stall   int
+	calls   int
+	stalled chan struct{}
+}
+
+func (p *stallingProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	p.calls++
+	if p.calls == p.stall {
+		close(p.stalled)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	text := req.Prompt[strings.Index(req.Prompt, ":\n\n")+3:]
+	return &Completion{Text: "<result>" + strings.ToUpper(text) + "</result>"}, nil
+}
+
+func TestManifestWrittenAfterEveryChunk(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	out := filepath.Join(dir, "out.txt")
+	source := "First paragraph.\n\nSecond paragraph.\n\nThird paragraph.\n"
+	os.WriteFile(in, []byte(source), 0644)
+
+	provider := &stallingProvider{stall: 3, stalled: make(chan struct{})}
+	config := Config{Provider: provider, ChunkSize: 5, NoDelay: true, NoSanityCheck: true, Manifest: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- NewTranslator(config).TranslateFileContext(ctx, in, out) }()
+
+	// While the third chunk is under way, the manifest on disk already
+	// lists the first two, as after a kill.
+	<-provider.stalled
+	manifest, err := ReadChunkManifest(out + ManifestSuffix)
+	if err != nil {
+		t.Fatalf("Expected a manifest during the run, got %v", err)
+	}
+	if manifest.Chunks[0].Status != ChunkTranslated || manifest.Chunks[1].Status != ChunkTranslated || manifest.Chunks[2].Status != ChunkPending {
+		t.Errorf("Expected two chunks done, got %+v", manifest.Chunks)
+	}
+	cancel()
+	if err := <-done; err == nil {
+		t.Fatal("Expected the canceled run to fail")
+	}
+
+	recorder := &promptRecorder{}
+	config.Provider = recorder
+	if err := NewTranslator(config).TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.prompts) != 1 {
+		t.Errorf("Expected only the third chunk translated again, got %q", recorder.prompts)
+	}
+	if got := readFile(t, out); got != strings.ToUpper(source) {
+		t.Errorf("Unexpected output %q", got)
+	}
+}
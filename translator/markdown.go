@@ -0,0 +1,196 @@
+package translator
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ChunkingParagraph fills chunks with paragraphs, falling back to lines
+	// and sentences for long ones.
+	ChunkingParagraph = "paragraph"
+	// ChunkingMarkdown follows the Markdown structure: code blocks are
+	// passed through untranslated, tables are never split and headings stay
+	// in the chunk of their section.
+	ChunkingMarkdown = "markdown"
+)
+
+var (
+	backtickFenceRe = regexp.MustCompile("(?ms)^[ \t]*```.*?^[ \t]*```[ \t]*$")
+	tildeFenceRe    = regexp.MustCompile(`(?ms)^[ \t]*~~~.*?^[ \t]*~~~[ \t]*$`)
+	// inlineCodeRe matches code spans, including ``a `quoted` backtick``.
+	inlineCodeRe   = regexp.MustCompile("``[^\n]+?``|`[^`\n]+`")
+	tableDividerRe = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+)
+
+// chunking returns the chunking mode of a run: Config.Chunking, or
+// ChunkingMarkdown for the markdown format and ChunkingParagraph otherwise.
+func (t *Translator) chunking(format *formatHandler) string {
+	if t.config.Chunking != "" {
+		return t.config.Chunking
+	}
+	if format != nil && format.name == "markdown" {
+		return ChunkingMarkdown
+	}
+	return ChunkingParagraph
+}
+
+// maskCodeFences protects the fenced code blocks of text not yet protected
+// by its format, appending them to spans.
+func maskCodeFences(text string, spans *[]string) string {
+	return maskMore(text, spans, backtickFenceRe, tildeFenceRe)
+}
+
+// maskCode protects fenced code blocks and inline code spans, see
+// Config.MaskCode.
+func maskCode(text string, spans *[]string) string {
+	return maskMore(text, spans, backtickFenceRe, tildeFenceRe, inlineCodeRe)
+}
+
+// markdownBlock is a heading or a run of non-blank lines, such as a
+// paragraph, a list or a table, with the line breaks that follow it.
+type markdownBlock struct {
+	text    string
+	gap     string
+	heading bool
+	table   bool
+}
+
+func markdownBlocks(text string) []markdownBlock {
+	var blocks []markdownBlock
+	var current []string
+	// Line breaks are counted into the gap of the block before them; those
+	// at the start of the document go before the first block.
+	lead := ""
+	newline := func() {
+		if len(blocks) > 0 {
+			blocks[len(blocks)-1].gap += "\n"
+		} else {
+			lead += "\n"
+		}
+	}
+	add := func(b markdownBlock) {
+		if len(blocks) == 0 {
+			b.text = lead + b.text
+		}
+		blocks = append(blocks, b)
+	}
+	flush := func() {
+		if len(current) > 0 {
+			add(markdownBlock{
+				text:  strings.Join(current, "\n"),
+				table: len(current) > 1 && strings.Contains(current[0], "|") && tableDividerRe.MatchString(current[1]),
+			})
+			current = nil
+		}
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+			if i > 0 {
+				newline()
+			}
+		case headingRe.MatchString(line):
+			flush()
+			if i > 0 {
+				newline()
+			}
+			add(markdownBlock{text: line, heading: true})
+		default:
+			if len(current) == 0 && i > 0 {
+				newline()
+			}
+			current = append(current, line)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// splitMarkdown splits text into chunks at Markdown block boundaries. A
+// heading is kept with the block after it, and a section that fits into the
+// rest of the current chunk is not split. Paragraphs longer than a chunk go
+// through splitIntoChunks; tables are kept whole even then.
+func (t *Translator) splitMarkdown(ctx context.Context, text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	if t.fitsOneChunk(t.countTokens(text)) {
+		return []string{text}
+	}
+	size := t.effectiveChunkSize()
+
+	// Units are blocks with the headings before them attached; sections
+	// start at a unit with a heading.
+	var sections [][]markdownBlock
+	var pending []markdownBlock
+	for _, b := range markdownBlocks(text) {
+		pending = append(pending, b)
+		if b.heading {
+			continue
+		}
+		unit := pending[0]
+		for _, p := range pending[1:] {
+			unit.text += unit.gap + p.text
+			unit.gap, unit.table = p.gap, p.table
+		}
+		if pending[0].heading || len(sections) == 0 {
+			sections = append(sections, nil)
+		}
+		sections[len(sections)-1] = append(sections[len(sections)-1], unit)
+		pending = nil
+	}
+	if len(pending) > 0 {
+		sections = append(sections, pending)
+	}
+
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+	gap := ""
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentTokens = 0
+		}
+	}
+
+	for _, section := range sections {
+		sectionTokens := 0
+		for _, u := range section {
+			sectionTokens += t.countTokens(u.text)
+		}
+		if currentTokens > 0 && currentTokens+sectionTokens > size {
+			flush()
+		}
+
+		for _, u := range section {
+			tokens := t.countTokens(u.text)
+			if currentTokens > 0 && currentTokens+tokens > size {
+				flush()
+			}
+			if tokens > size && !u.table {
+				chunks = append(chunks, t.splitIntoChunks(ctx, u.text)...)
+				gap = u.gap
+				continue
+			}
+			if current.Len() > 0 {
+				current.WriteString(gap)
+			}
+			current.WriteString(u.text)
+			currentTokens += tokens
+			gap = u.gap
+		}
+	}
+	flush()
+
+	// The line breaks at the end of the document belong to the last chunk.
+	if len(chunks) > 0 {
+		chunks[len(chunks)-1] += gap
+	}
+	return chunks
+}
@@ -0,0 +1,91 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const markdownDoc = `# Guide
+
+This guide explains how the tool works and what it expects from the input files.
+
+## Install
+
+Download the release archive for your platform and unpack it anywhere on the path.
+
+` + "```sh" + `
+tar xzf tool.tar.gz
+
+sudo mv tool /usr/local/bin
+` + "```" + `
+
+## Options
+
+| Flag | Meaning |
+|------|---------|
+| --to | target language of the translation |
+| --from | source language of the input file |
+| --model | model that translates the chunks |
+
+## Usage
+
+Run the tool with an input file and a target language, and read the output file.
+`
+
+func TestSplitMarkdown(t *testing.T) {
+	tr := NewTranslator(Config{ChunkSize: 50, Chunking: ChunkingMarkdown})
+	prepared, err := tr.prepare(context.Background(), "doc.txt", []byte(markdownDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := prepared.Chunks
+	if len(chunks) < 3 {
+		t.Fatalf("Expected the document split into sections, got %q", chunks)
+	}
+
+	for i, chunk := range chunks {
+		lines := strings.Split(strings.TrimRight(chunk, "\n"), "\n")
+		if headingRe.MatchString(lines[len(lines)-1]) {
+			t.Errorf("Chunk %d ends with a heading cut off from its section: %q", i+1, chunk)
+		}
+		if strings.Contains(chunk, "|------|") && !strings.Contains(chunk, "--model") {
+			t.Errorf("Chunk %d splits the table: %q", i+1, chunk)
+		}
+		if strings.Contains(chunk, "tar xzf") {
+			t.Errorf("Chunk %d exposes code to the model: %q", i+1, chunk)
+		}
+	}
+
+	joined := unmaskSpans(strings.Join(chunks, "\n\n"), prepared.Spans)
+	if joined != markdownDoc {
+		t.Errorf("Expected chunks to add up to the document, got %q", joined)
+	}
+}
+
+func TestTranslateMarkdownFile(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "guide.md")
+	out := filepath.Join(dir, "guide.de.md")
+	os.WriteFile(in, []byte(markdownDoc), 0644)
+
+	provider := &countingProvider{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 50, NoDelay: true})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(out)
+
+	code := markdownDoc[strings.Index(markdownDoc, "```sh") : strings.LastIndex(markdownDoc, "```")+3]
+	if !strings.Contains(string(got), code) {
+		t.Errorf("Expected the code block passed through untranslated, got %q", got)
+	}
+	if want := strings.Replace(strings.ToUpper(markdownDoc), strings.ToUpper(code), code, 1); string(got) != want {
+		t.Errorf("Expected the structure of the document kept, got %q", got)
+	}
+	if tr.Result().Format != "markdown" || len(provider.sent) < 3 {
+		t.Errorf("Expected the markdown format and several chunks, got %q and %d requests", tr.Result().Format, len(provider.sent))
+	}
+}
@@ -0,0 +1,30 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaskCode(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "notes.txt")
+	out := filepath.Join(dir, "notes.de.txt")
+	os.WriteFile(in, []byte("Call `getUser()` or ``echo `id` `` first.\n\n```go\n// fetch the user\nuser := getUser()\n```\n\nThen log in.\n"), 0644)
+
+	provider := &promptRecorder{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "german", MaskCode: true})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	for _, prompt := range provider.prompts {
+		if strings.Contains(prompt, "getUser") || strings.Contains(prompt, "echo") {
+			t.Errorf("Expected code kept from the model, got %q", prompt)
+		}
+	}
+	want := "CALL `getUser()` OR ``echo `id` `` FIRST.\n\n```go\n// fetch the user\nuser := getUser()\n```\n\nTHEN LOG IN.\n"
+	if got, _ := os.ReadFile(out); string(got) != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,212 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MergedFile is a file given to TranslateMerged and how its translation
+// went.
+type MergedFile struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+	// Batch is the number of the merged batch the file went out in, 0 when
+	// it was translated on its own.
+	Batch  int `json:"batch,omitempty"`
+	Chunks int `json:"chunks,omitempty"`
+	// Warnings of a batch are given for each of its files.
+	Warnings []Warning `json:"warnings,omitempty"`
+	Err      error     `json:"-"`
+}
+
+// mergeSeparatorRe matches the lines TranslateMerged puts between files. They
+// are protected like Config.ProtectPatterns, so the model sees a marker.
+var mergeSeparatorRe = regexp.MustCompile(`⟪go_ai_translate file \d+⟫`)
+
+func mergeSeparator(i int) string {
+	return fmt.Sprintf("⟪go_ai_translate file %d⟫", i)
+}
+
+// TranslateMerged translates files, pairs of an input and an output path,
+// with as few requests as it takes: plain text and Markdown files that fit a
+// chunk are joined into batches of up to a chunk, with separator lines
+// between them, and the translation of a batch is split back into their
+// outputs. Other files, and those starting with front matter, are
+// translated on their own. A batch whose separators do not all come back is
+// translated file by file instead.
+//
+// Failed files carry their error in Err; the first one is also returned.
+// The output directories must exist.
+func (t *Translator) TranslateMerged(ctx context.Context, files []MergedFile) ([]MergedFile, error) {
+	results := append([]MergedFile(nil), files...)
+	contents := make([]string, len(files))
+	var alone []int
+	groups := map[string][]int{}
+	var groupOrder []string
+	for i, f := range files {
+		data, err := os.ReadFile(f.Input)
+		if err != nil {
+			results[i].Err = classify(ErrorClassInput, fmt.Errorf("failed to read input file: %w", err))
+			continue
+		}
+		contents[i] = string(data)
+		format, err := lookupFormat(t.config.Format, f.Input)
+		if err != nil || (format != nil && format.name != "markdown") || scannedInput(f.Input) ||
+			strings.HasPrefix(contents[i], "---") || !t.fitsOneChunk(t.countTokens(contents[i])) {
+			alone = append(alone, i)
+			continue
+		}
+		key := ""
+		if format != nil {
+			key = format.name
+		}
+		if groups[key] == nil {
+			groupOrder = append(groupOrder, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	// Batches are filled up to a chunk, counting the separators.
+	var batches [][]int
+	size := t.effectiveChunkSize()
+	for _, key := range groupOrder {
+		var batch []int
+		tokens := 0
+		for _, i := range groups[key] {
+			if strings.TrimSpace(contents[i]) == "" {
+				// Nothing to translate.
+				results[i].Err = WriteFileAtomic(files[i].Output, []byte(contents[i]), 0644)
+				continue
+			}
+			n := t.countTokens(contents[i]) + t.countTokens(mergeSeparator(len(batch)))
+			if len(batch) > 0 && tokens+n > size {
+				batches = append(batches, batch)
+				batch, tokens = nil, 0
+			}
+			batch = append(batch, i)
+			tokens += n
+		}
+		if len(batch) > 0 {
+			batches = append(batches, batch)
+		}
+	}
+
+	number := 0
+	for _, batch := range batches {
+		if len(batch) == 1 {
+			alone = append(alone, batch[0])
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, classify(ErrorClassCanceled, err)
+		}
+		number++
+		if !t.translateMergedBatch(ctx, number, batch, contents, results) {
+			alone = append(alone, batch...)
+		}
+	}
+
+	for _, i := range alone {
+		if err := ctx.Err(); err != nil {
+			return results, classify(ErrorClassCanceled, err)
+		}
+		d := t.Derive(t.config)
+		err := d.TranslateFileContext(ctx, files[i].Input, files[i].Output)
+		results[i].Batch = 0
+		results[i].Chunks = d.Result().Chunks
+		results[i].Warnings = d.Result().Warnings
+		results[i].Err = err
+	}
+
+	for _, r := range results {
+		if r.Err != nil {
+			return results, r.Err
+		}
+	}
+	return results, nil
+}
+
+// translateMergedBatch translates the files of batch in one document and writes
+// their outputs, or sets their Err when the translation fails. It reports
+// false, writing nothing, when the translation cannot be split back into the
+// files.
+func (t *Translator) translateMergedBatch(ctx context.Context, number int, batch []int, contents []string, results []MergedFile) bool {
+	var merged strings.Builder
+	for n, i := range batch {
+		if n > 0 {
+			merged.WriteString("\n\n" + mergeSeparator(n) + "\n\n")
+		}
+		merged.WriteString(strings.TrimSpace(contents[i]))
+	}
+
+	config := t.config
+	config.ProtectPatterns = append(append([]string(nil), config.ProtectPatterns...), mergeSeparatorRe.String())
+	d := t.Derive(config)
+	if t.config.Verbose {
+		fmt.Printf("Translating %d files in batch %d\n", len(batch), number)
+	}
+
+	var out strings.Builder
+	d.begin(ctx, "", "")
+	prepared, err := d.prepare(ctx, results[batch[0]].Input, []byte(merged.String()))
+	if err == nil {
+		err = d.translatePrepared(ctx, prepared, func() (io.WriteCloser, error) {
+			return nopWriteCloser{&out}, nil
+		})
+	}
+	err = d.finish("", err)
+
+	if err != nil {
+		for _, i := range batch {
+			results[i].Batch, results[i].Err = number, err
+		}
+		return true
+	}
+	translations := splitMerged(out.String(), len(batch))
+	if translations == nil {
+		if t.config.Verbose {
+			fmt.Printf("Translating the files of batch %d one by one, as the separators between them were lost\n", number)
+		}
+		return false
+	}
+
+	for n, i := range batch {
+		content := contents[i]
+		lead := content[:len(content)-len(strings.TrimLeftFunc(content, unicode.IsSpace))]
+		trail := content[len(strings.TrimRightFunc(content, unicode.IsSpace)):]
+		r := &results[i]
+		r.Batch, r.Chunks = number, len(prepared.Chunks)
+		if err := WriteFileAtomic(r.Output, []byte(lead+translations[n]+trail), 0644); err != nil {
+			r.Err = classify(ErrorClassOutput, fmt.Errorf("failed to write output file: %w", err))
+			continue
+		}
+		r.Err = d.polish(ctx, r.Output)
+	}
+	for _, i := range batch {
+		results[i].Warnings = d.Result().Warnings
+	}
+	return true
+}
+
+// splitMerged splits the translation of a batch of files at its separators,
+// or returns nil when they are not all there in order.
+func splitMerged(text string, files int) []string {
+	var parts []string
+	for n := 1; n < files; n++ {
+		i := strings.Index(text, mergeSeparator(n))
+		if i < 0 {
+			return nil
+		}
+		parts = append(parts, strings.TrimSpace(text[:i]))
+		text = text[i+len(mergeSeparator(n)):]
+	}
+	if mergeSeparatorRe.MatchString(text) {
+		return nil
+	}
+	return append(parts, strings.TrimSpace(text))
+}
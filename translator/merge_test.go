@@ -0,0 +1,87 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// paragraphReverser answers like promptRecorder, with the paragraphs in
+// reverse order.
+type paragraphReverser struct {
+	promptRecorder
+}
+
+func (p *paragraphReverser) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	c, err := p.promptRecorder.Complete(ctx, cr)
+	if err == nil {
+		text := strings.TrimSuffix(strings.TrimPrefix(c.Text, "<result>"), "</result>")
+		paragraphs := strings.Split(text, "\n\n")
+		for i, j := 0, len(paragraphs)-1; i < j; i, j = i+1, j-1 {
+			paragraphs[i], paragraphs[j] = paragraphs[j], paragraphs[i]
+		}
+		c.Text = "<result>" + strings.Join(paragraphs, "\n\n") + "</result>"
+	}
+	return c, err
+}
+
+func TestTranslateMerged(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"a.md":     "# One\n\nFirst snippet.\n",
+		"b.md":     "\nSecond snippet, with `code`.\n\n",
+		"c.md":     "Third snippet.",
+		"d.txt":    "A text file.\n",
+		"e.txt":    "Another text file.\n",
+		"f.md":     "---\ntitle: Front matter\n---\nBody.\n",
+		"long.txt": strings.Repeat("A paragraph of a long file.\n\n", 40),
+		"empty.md": "\n",
+	}
+	var files []MergedFile
+	for _, name := range []string{"a.md", "b.md", "c.md", "d.txt", "e.txt", "f.md", "long.txt", "empty.md"} {
+		os.WriteFile(filepath.Join(dir, name), []byte(sources[name]), 0644)
+		files = append(files, MergedFile{Input: filepath.Join(dir, name), Output: filepath.Join(dir, name+".out")})
+	}
+
+	provider := &promptRecorder{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, NoSanityCheck: true})
+	results, err := tr.TranslateMerged(context.Background(), files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		name := filepath.Base(r.Input)
+		want := strings.ToUpper(sources[name])
+		switch name {
+		case "b.md":
+			want = "\nSECOND SNIPPET, WITH `code`.\n\n"
+		case "f.md":
+			want = sources[name][:strings.Index(sources[name], "Body")] + "BODY.\n"
+		}
+		if got := readFile(t, r.Output); got != want {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+		if merged := i < 5; merged != (r.Batch > 0) {
+			t.Errorf("%s: unexpected batch %d", name, r.Batch)
+		}
+	}
+	// One request per batch of Markdown and text files, the rest on their own.
+	if n := len(provider.prompts); n != 2+1+results[6].Chunks || results[6].Chunks < 2 {
+		t.Errorf("Expected merged requests, got %d for %d chunks of the long file", n, results[6].Chunks)
+	}
+
+	// Without the separators in order the files go out one by one.
+	reverser := &paragraphReverser{}
+	tr = NewTranslator(Config{Provider: reverser, ChunkSize: 100, NoDelay: true, NoSanityCheck: true})
+	if results, err = tr.TranslateMerged(context.Background(), files[:3]); err != nil {
+		t.Fatal(err)
+	}
+	if len(reverser.prompts) != 4 || results[0].Batch != 0 {
+		t.Errorf("Expected a batch and three single requests, got %d", len(reverser.prompts))
+	}
+	if got := readFile(t, results[2].Output); got != "THIRD SNIPPET." {
+		t.Errorf("Unexpected output %q", got)
+	}
+}
@@ -0,0 +1,199 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const openRouterBaseURL = "https://openrouter.ai/api/v1"
+
+// freeModelRateLimits is the number of consecutive rate limited chunks after
+// which a run started on a free model switches to the configured model.
+const freeModelRateLimits = 2
+
+// multilingualFamilies lists model families known to translate well, in
+// order of preference. The models API does not describe language support,
+// so this is the best signal available.
+var multilingualFamilies = []string{"deepseek/", "google/", "qwen/", "meta-llama/", "mistralai/"}
+
+type ModelInfo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	ContextLength int    `json:"context_length"`
+	Pricing       struct {
+		Prompt     string `json:"prompt"`
+		Completion string `json:"completion"`
+	} `json:"pricing"`
+	Architecture struct {
+		InputModalities  []string `json:"input_modalities"`
+		OutputModalities []string `json:"output_modalities"`
+	} `json:"architecture"`
+	TopProvider struct {
+		MaxCompletionTokens int `json:"max_completion_tokens"`
+	} `json:"top_provider"`
+}
+
+func (m ModelInfo) free() bool {
+	if strings.HasSuffix(m.ID, ":free") {
+		return true
+	}
+	prompt, err1 := strconv.ParseFloat(m.Pricing.Prompt, 64)
+	completion, err2 := strconv.ParseFloat(m.Pricing.Completion, 64)
+	return err1 == nil && err2 == nil && prompt == 0 && completion == 0
+}
+
+func (m ModelInfo) textOnly() bool {
+	hasText := func(modalities []string) bool {
+		if len(modalities) == 0 {
+			return true
+		}
+		for _, mod := range modalities {
+			if mod == "text" {
+				return true
+			}
+		}
+		return false
+	}
+	return hasText(m.Architecture.InputModalities) && hasText(m.Architecture.OutputModalities)
+}
+
+func (t *Translator) fetchModels(ctx context.Context) ([]ModelInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", t.baseURL()+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	if t.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.config.APIKey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to fetch models: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to read models: %w", err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("models request failed with status %d: %s", resp.StatusCode, string(body)))
+	}
+
+	var list struct {
+		Data []ModelInfo `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("failed to unmarshal models: %w", err))
+	}
+	return list.Data, nil
+}
+
+// pickFreeModel chooses a free text model whose context fits a chunk of
+// chunkTokens together with the prompt and the translation. Models from
+// multilingualFamilies are preferred, then larger contexts.
+func pickFreeModel(models []ModelInfo, chunkTokens int) (string, bool) {
+	needed := chunkTokens*3 + 200
+
+	var candidates []ModelInfo
+	for _, m := range models {
+		if m.free() && m.textOnly() && m.ContextLength >= needed {
+			candidates = append(candidates, m)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	rank := func(id string) int {
+		for i, f := range multilingualFamilies {
+			if strings.HasPrefix(id, f) {
+				return i
+			}
+		}
+		return len(multilingualFamilies)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		ri, rj := rank(candidates[i].ID), rank(candidates[j].ID)
+		if ri != rj {
+			return ri < rj
+		}
+		if candidates[i].ContextLength != candidates[j].ContextLength {
+			return candidates[i].ContextLength > candidates[j].ContextLength
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+
+	return candidates[0].ID, true
+}
+
+// selectModel decides which model a run starts with.
+func (t *Translator) selectModel(ctx context.Context) {
+	t.model = t.config.Model
+	t.rateLimited = 0
+
+	if !t.config.PreferFree {
+		return
+	}
+
+	models, err := t.fetchModels(ctx)
+	if err != nil {
+		if t.config.Verbose {
+			fmt.Printf("Could not list models, using %s: %v\n", t.config.Model, err)
+		}
+		return
+	}
+
+	if id, ok := pickFreeModel(models, t.config.ChunkSize); ok {
+		t.model = id
+		if t.config.Verbose {
+			fmt.Printf("Using free model %s (fallback: %s)\n", id, t.config.Model)
+		}
+	} else if t.config.Verbose {
+		fmt.Printf("No suitable free model found, using %s\n", t.config.Model)
+	}
+}
+
+// noteChunkResult tracks rate limits on a free model and falls back to the
+// configured model once they keep happening.
+func (t *Translator) noteChunkResult(err error) {
+	t.mu.Lock()
+
+	if t.model == "" || t.model == t.config.Model {
+		t.mu.Unlock()
+		return
+	}
+
+	if err == nil || ErrorClass(err) != ErrorClassRateLimit {
+		t.rateLimited = 0
+		t.mu.Unlock()
+		return
+	}
+
+	t.rateLimited++
+	if t.rateLimited < freeModelRateLimits {
+		t.mu.Unlock()
+		return
+	}
+
+	from := t.model
+	t.model = t.config.Model
+	t.rateLimited = 0
+	t.mu.Unlock()
+
+	if t.config.Verbose {
+		fmt.Printf("Model %s keeps being rate limited, falling back to %s\n", from, t.config.Model)
+	}
+	t.warn(Warning{Kind: WarningModelFallback, Message: fmt.Sprintf("switched from %s to %s after repeated rate limits", from, t.config.Model)})
+}
@@ -0,0 +1,59 @@
+package translator
+
+import (
+	"errors"
+	"testing"
+)
+
+func testModel(id string, context int, prompt, completion string) ModelInfo {
+	m := ModelInfo{ID: id, ContextLength: context}
+	m.Pricing.Prompt = prompt
+	m.Pricing.Completion = completion
+	return m
+}
+
+func TestPickFreeModel(t *testing.T) {
+	models := []ModelInfo{
+		testModel("openai/gpt-4o", 128000, "0.0000025", "0.00001"),
+		testModel("some/tiny:free", 2048, "0", "0"),
+		testModel("other/large:free", 131072, "0", "0"),
+		testModel("qwen/qwen-2.5:free", 32768, "0", "0"),
+		testModel("deepseek/deepseek-chat:free", 16000, "0", "0"),
+	}
+
+	id, ok := pickFreeModel(models, 500)
+	if !ok || id != "deepseek/deepseek-chat:free" {
+		t.Errorf("Expected deepseek free model, got %q", id)
+	}
+
+	id, ok = pickFreeModel(models, 10000)
+	if !ok || id != "qwen/qwen-2.5:free" {
+		t.Errorf("Expected qwen free model for large chunks, got %q", id)
+	}
+
+	if _, ok := pickFreeModel(models[:1], 500); ok {
+		t.Error("Expected no free model among paid ones")
+	}
+}
+
+func TestFreeModelFallback(t *testing.T) {
+	translator := NewTranslator(Config{Model: "paid/model"})
+	translator.model = "free/model:free"
+
+	rateLimit := classify(ErrorClassRateLimit, errors.New("429"))
+
+	translator.noteChunkResult(rateLimit)
+	translator.noteChunkResult(nil)
+	translator.noteChunkResult(rateLimit)
+	if translator.activeModel() != "free/model:free" {
+		t.Fatalf("Expected to stay on free model after non-consecutive rate limits")
+	}
+
+	translator.noteChunkResult(rateLimit)
+	if translator.activeModel() != "paid/model" {
+		t.Errorf("Expected fallback to paid model, got %q", translator.activeModel())
+	}
+	if len(translator.Result().Warnings) != 1 || translator.Result().Warnings[0].Kind != WarningModelFallback {
+		t.Errorf("Expected a model fallback warning, got %+v", translator.Result().Warnings)
+	}
+}
@@ -0,0 +1,150 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// OCRTesseract reads scanned pages with the tesseract command.
+	OCRTesseract = "tesseract"
+	// OCRVision has VisionModel, by default the active model, transcribe
+	// scanned pages.
+	OCRVision = "vision"
+)
+
+var scannedExtensions = []string{".pdf", ".png", ".jpg", ".jpeg", ".tif", ".tiff", ".webp", ".bmp", ".gif"}
+
+// scannedInput reports whether path is a PDF or an image, which is read
+// with OCR before translation.
+func scannedInput(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range scannedExtensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// ocr reads the text of a scanned PDF or image as Markdown, each page after
+// an anchor <a id="page-N"></a> so the translation can link to pages.
+func (t *Translator) ocr(ctx context.Context, path string) ([]byte, error) {
+	switch t.config.OCR {
+	case OCRTesseract, OCRVision:
+	case "":
+		return nil, classify(ErrorClassInput, fmt.Errorf("%s is a PDF or image, set OCR to %s or %s to read it", path, OCRTesseract, OCRVision))
+	default:
+		return nil, configError("unknown OCR engine %q, use %s or %s", t.config.OCR, OCRTesseract, OCRVision)
+	}
+
+	pages := []string{path}
+	if strings.EqualFold(filepath.Ext(path), ".pdf") {
+		dir, err := os.MkdirTemp("", "go_ai_translate-ocr-")
+		if err != nil {
+			return nil, classify(ErrorClassInput, fmt.Errorf("failed to create a directory for page images: %w", err))
+		}
+		defer os.RemoveAll(dir)
+		if pages, err = pdfPages(ctx, path, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	var b strings.Builder
+	for i, page := range pages {
+		if t.config.Verbose {
+			fmt.Printf("Reading page %d of %d with %s\n", i+1, len(pages), t.config.OCR)
+		}
+		var text string
+		var err error
+		if t.config.OCR == OCRTesseract {
+			text, err = tesseractPage(ctx, page, t.config.OCRLanguages)
+		} else {
+			text, err = t.visionPage(ctx, page)
+		}
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "<a id=\"page-%d\"></a>\n\n%s\n\n", i+1, strings.TrimSpace(text))
+	}
+	return []byte(strings.TrimSuffix(b.String(), "\n")), nil
+}
+
+// pdfPages renders the pages of a PDF into dir with pdftoppm from poppler
+// and returns the images in page order.
+func pdfPages(ctx context.Context, path, dir string) ([]string, error) {
+	if err := runTool(ctx, "pdftoppm", "-r", "300", "-png", path, filepath.Join(dir, "page")); err != nil {
+		return nil, err
+	}
+	pages, err := filepath.Glob(filepath.Join(dir, "page*.png"))
+	if err != nil || len(pages) == 0 {
+		return nil, classify(ErrorClassInput, fmt.Errorf("pdftoppm found no pages in %s", path))
+	}
+	// pdftoppm pads page numbers to the same width, so names sort by page.
+	sort.Strings(pages)
+	return pages, nil
+}
+
+func tesseractPage(ctx context.Context, page, languages string) (string, error) {
+	args := []string{page, "stdout"}
+	if languages != "" {
+		args = append(args, "-l", languages)
+	}
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, "tesseract", args...)
+	cmd.Stdout = &out
+	if err := runCommand(ctx, cmd); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func (t *Translator) visionPage(ctx context.Context, page string) (string, error) {
+	url, err := imageURL(page, "")
+	if err != nil {
+		return "", classify(ErrorClassInput, fmt.Errorf("failed to read page image: %w", err))
+	}
+	model := t.config.VisionModel
+	if model == "" {
+		model = t.activeModel()
+	}
+	completion, err := t.provider.Complete(ctx, CompletionRequest{
+		Model:  model,
+		System: "Transcribe the scanned page of the user message as Markdown, keeping headings, lists and tables, without translating it. Leave out running headers, footers and page numbers. Place the answer in the tag <result>",
+		Prompt: "Page: " + filepath.Base(page),
+		Images: []string{url},
+		RunID:  t.runID,
+	})
+	if err != nil {
+		return "", err
+	}
+	t.addCompletionUsage(completion)
+	return t.extractTranslation(model, completion.Text)
+}
+
+func runTool(ctx context.Context, name string, args ...string) error {
+	return runCommand(ctx, exec.CommandContext(ctx, name, args...))
+}
+
+// runCommand runs an external tool, keeping its error output for the error.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return canceled(ctx.Err())
+	}
+	if execErr, ok := err.(*exec.Error); ok {
+		return configError("%s is needed but cannot be run: %v", filepath.Base(cmd.Path), execErr.Err)
+	}
+	if err != nil {
+		return classify(ErrorClassInput, fmt.Errorf("%s failed: %v: %s", filepath.Base(cmd.Path), err, strings.TrimSpace(stderr.String())))
+	}
+	return nil
+}
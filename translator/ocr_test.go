@@ -0,0 +1,85 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// scanProvider transcribes every page image as "# Kapitel" and a line of
+// text, and upper-cases text to translate.
+type scanProvider struct{}
+
+func (scanProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	if len(cr.Images) > 0 {
+		return &Completion{Text: "<result># Kapitel\n\nEs war einmal.</result>"}, nil
+	}
+	text := cr.Prompt[strings.Index(cr.Prompt, ":\n\n")+3:]
+	return &Completion{Text: "<result>" + strings.ToUpper(text) + "</result>"}, nil
+}
+
+func TestOCRVision(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "scan.png")
+	out := filepath.Join(dir, "scan.md")
+	os.WriteFile(in, []byte("\x89PNG"), 0644)
+
+	tr := NewTranslator(Config{Provider: scanProvider{}, ChunkSize: 100, NoDelay: true, OCR: OCRVision})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(out)
+	if want := "<a id=\"page-1\"></a>\n\n# KAPITEL\n\nES WAR EINMAL.\n"; string(got) != want {
+		t.Errorf("Expected the transcribed page translated with its anchor, got %q", got)
+	}
+	if tr.Result().Format != "markdown" {
+		t.Errorf("Expected OCR output to be translated as Markdown, got %q", tr.Result().Format)
+	}
+
+	tr = NewTranslator(Config{Provider: scanProvider{}, ChunkSize: 100, NoDelay: true})
+	if err := tr.TranslateFile(in, out); ErrorClass(err) != ErrorClassInput || !strings.Contains(err.Error(), "OCR") {
+		t.Errorf("Expected an input error asking for OCR, got %v", err)
+	}
+}
+
+func TestOCRTesseract(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as stand-ins for pdftoppm and tesseract")
+	}
+	bin := t.TempDir()
+	// pdftoppm -r 300 -png <pdf> <prefix> writes one image per page.
+	os.WriteFile(filepath.Join(bin, "pdftoppm"), []byte("#!/bin/sh\nfor n in 1 2; do echo page > \"$5-$n.png\"; done\n"), 0755)
+	// tesseract <image> stdout -l <languages> prints the page text.
+	os.WriteFile(filepath.Join(bin, "tesseract"), []byte("#!/bin/sh\necho \"Text of $(basename $1) in $4\"\n"), 0755)
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "book.pdf")
+	os.WriteFile(in, []byte("%PDF-1.4"), 0644)
+
+	tr := NewTranslator(Config{Provider: scanProvider{}, ChunkSize: 100, NoDelay: true, OCR: OCRTesseract, OCRLanguages: "deu"})
+	prepared, err := tr.Prepare(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := unmaskSpans(strings.Join(prepared.Chunks, "\n\n"), prepared.Spans)
+	want := "<a id=\"page-1\"></a>\n\nText of page-1.png in deu\n\n<a id=\"page-2\"></a>\n\nText of page-2.png in deu\n"
+	if text != want {
+		t.Errorf("Expected both pages with anchors, got %q", text)
+	}
+	for _, chunk := range prepared.Chunks {
+		if strings.Contains(chunk, "<a id") {
+			t.Errorf("Expected page anchors to be protected, got %q", chunk)
+		}
+	}
+
+	os.Setenv("PATH", t.TempDir())
+	if _, err := tr.Prepare(in); ErrorClass(err) != ErrorClassConfig || !strings.Contains(err.Error(), "pdftoppm") {
+		t.Errorf("Expected a config error naming the missing tool, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Backends accepted by Config.Backend.
+const (
+	BackendOpenRouter = "openrouter"
+	BackendOllama     = "ollama"
+	BackendDeepL      = "deepl"
+)
+
+const ollamaBaseURL = "http://localhost:11434"
+
+type ollamaRequest struct {
+	Model    string                 `json:"model"`
+	Messages []Message              `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Think    *bool                  `json:"think,omitempty"`
+	Format   string                 `json:"format,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message         Message `json:"message"`
+	PromptEvalCount int     `json:"prompt_eval_count"`
+	EvalCount       int     `json:"eval_count"`
+	Error           string  `json:"error"`
+}
+
+// ollamaProvider talks to a local Ollama server's /api/chat endpoint. It
+// needs no API key, so documents never leave the machine.
+type ollamaProvider struct {
+	t *Translator
+}
+
+func (p ollamaProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	t := p.t
+
+	req, requestBody, err := p.newRequest(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+	url := req.URL.String()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if auditErr := t.recordRequest(url, cr.Model, cr.ChunkID, requestBody, 0, nil, err); auditErr != nil {
+			return nil, auditErr
+		}
+		if ctx.Err() != nil {
+			return nil, canceled(ctx.Err())
+		}
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to reach Ollama at %s: %w", t.baseURL(), err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if auditErr := t.recordRequest(url, cr.Model, cr.ChunkID, requestBody, resp.StatusCode, body, err); auditErr != nil {
+		return nil, auditErr
+	}
+	if err != nil {
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to read response: %w", err))
+	}
+
+	var response ollamaResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("Ollama request failed with status %d: %s", resp.StatusCode, string(body)))
+	}
+	if resp.StatusCode != http.StatusOK || response.Error != "" {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("Ollama request failed with status %d: %s", resp.StatusCode, response.Error))
+	}
+
+	return &Completion{
+		Text:             response.Message.Content,
+		PromptTokens:     response.PromptEvalCount,
+		CompletionTokens: response.EvalCount,
+	}, nil
+}
+
+// newRequest builds the chat request of cr.
+func (p ollamaProvider) newRequest(ctx context.Context, cr CompletionRequest) (*http.Request, []byte, error) {
+	t := p.t
+
+	request := ollamaRequest{
+		Model:    cr.Model,
+		Messages: chatMessages(cr),
+	}
+	if cr.Temperature != nil || cr.Seed != nil || cr.TopP != nil || cr.MaxTokens > 0 {
+		request.Options = map[string]interface{}{}
+	}
+	if cr.Temperature != nil {
+		request.Options["temperature"] = *cr.Temperature
+	}
+	if cr.Seed != nil {
+		request.Options["seed"] = *cr.Seed
+	}
+	if cr.TopP != nil {
+		request.Options["top_p"] = *cr.TopP
+	}
+	if cr.MaxTokens > 0 {
+		request.Options["num_predict"] = cr.MaxTokens
+	}
+	if cr.JSON {
+		request.Format = "json"
+	}
+	if cr.ExcludeReasoning {
+		think := false
+		request.Think = &think
+	}
+
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := t.baseURL() + "/api/chat"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cr.ChunkID != "" && !t.config.NoExternalMetadata {
+		req.Header.Set("X-Request-Id", cr.ChunkID)
+		req.Header.Set("X-Run-Id", cr.RunID)
+	}
+	return req, requestBody, nil
+}
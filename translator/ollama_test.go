@@ -0,0 +1,58 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOllamaBackend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" || r.Header.Get("Authorization") != "" {
+			t.Errorf("Unexpected request %s with authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Stream || req.Model != "llama3.1" {
+			t.Errorf("Unexpected Ollama request %+v: %v", req, err)
+		}
+		prompt := req.Messages[len(req.Messages)-1].Content
+		if req.Messages[0].Role != "system" {
+			t.Errorf("Expected the instructions in a system message, got %+v", req.Messages)
+		}
+		text := prompt[strings.Index(prompt, ":\n\n")+3:]
+		fmt.Fprintf(w, `{"message":{"role":"assistant","content":%q},"done":true,"prompt_eval_count":12,"eval_count":4}`,
+			"<result>"+strings.ToUpper(text)+"</result>")
+	}))
+	defer server.Close()
+
+	translator := NewTranslator(Config{Backend: BackendOllama, BaseURL: server.URL, Model: "llama3.1", ChunkSize: 100, NoDelay: true})
+	var out bytes.Buffer
+	if err := translator.Translate(context.Background(), strings.NewReader("hello"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "HELLO" {
+		t.Errorf("Expected the Ollama answer, got %q", out.String())
+	}
+	if r := translator.Result(); r.PromptTokens != 12 || r.CompletionTokens != 4 {
+		t.Errorf("Expected usage from Ollama, got %+v", r)
+	}
+}
+
+func TestOllamaModelNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"missing\" not found, try pulling it first"}`)
+	}))
+	defer server.Close()
+
+	translator := NewTranslator(Config{Backend: BackendOllama, BaseURL: server.URL, Model: "missing"})
+	_, err := translator.provider.Complete(context.Background(), CompletionRequest{Model: "missing", Prompt: "hi"})
+	if ErrorClass(err) != ErrorClassAPI || !strings.Contains(err.Error(), "try pulling it first") {
+		t.Errorf("Expected an API error naming the problem, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package translator
+
+import (
+	"io"
+	"time"
+)
+
+// Defaults applied by New, the same as the command line tool's.
+const (
+	DefaultModel     = "deepseek/deepseek-chat"
+	DefaultToLang    = "russian"
+	DefaultChunkSize = 500
+)
+
+// Option configures a Translator created with New.
+type Option func(*Config)
+
+// New returns a Translator configured by opts. Unlike NewTranslator with a
+// bare Config, it starts from working defaults: DefaultModel, DefaultToLang,
+// DefaultChunkSize and automatic format detection, and it rejects invalid
+// settings up front, see Config.Validate.
+func New(opts ...Option) (*Translator, error) {
+	config := Config{
+		Model:      DefaultModel,
+		ToLang:     DefaultToLang,
+		ChunkSize:  DefaultChunkSize,
+		MaxRetries: defaultMaxRetries,
+		Format:     "auto",
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return NewTranslator(config), nil
+}
+
+// WithConfig replaces the whole configuration, for settings without an
+// option of their own. Options after it still apply.
+func WithConfig(c Config) Option {
+	return func(config *Config) { *config = c }
+}
+
+// WithAPIKey sets the API key of the backend.
+func WithAPIKey(key string) Option {
+	return func(c *Config) { c.APIKey = key }
+}
+
+// WithTargetLanguage sets the language to translate to, e.g. "german".
+func WithTargetLanguage(lang string) Option {
+	return func(c *Config) { c.ToLang = lang }
+}
+
+// WithModel sets the model and the fallback models tried in order when it
+// keeps failing on a chunk.
+func WithModel(model string, fallbacks ...string) Option {
+	return func(c *Config) {
+		c.Model = model
+		c.FallbackModels = fallbacks
+	}
+}
+
+// WithSystemPrompt opens the system message of every request, see
+// Config.SystemPrompt.
+func WithSystemPrompt(prompt string) Option {
+	return func(c *Config) { c.SystemPrompt = prompt }
+}
+
+// WithChunkSize sets the chunk size in tokens.
+func WithChunkSize(tokens int) Option {
+	return func(c *Config) { c.ChunkSize = tokens }
+}
+
+// WithChunker replaces the built-in splitter, see Config.Chunker.
+func WithChunker(c Chunker) Option {
+	return func(config *Config) { config.Chunker = c }
+}
+
+// WithFormat sets the document format, e.g. "markdown" or "auto".
+func WithFormat(name string) Option {
+	return func(c *Config) { c.Format = name }
+}
+
+// WithBackend selects a built-in backend, BackendOpenRouter, BackendOllama
+// or BackendDeepL. An empty baseURL uses the backend's default.
+func WithBackend(backend, baseURL string) Option {
+	return func(c *Config) {
+		c.Backend = backend
+		c.BaseURL = baseURL
+	}
+}
+
+// WithProvider sends chunks to p instead of a built-in backend.
+func WithProvider(p Provider) Option {
+	return func(c *Config) { c.Provider = p }
+}
+
+// WithConcurrency translates up to n chunks at the same time.
+func WithConcurrency(n int) Option {
+	return func(c *Config) { c.Concurrency = n }
+}
+
+// WithSampling sets the temperature and top_p of every request; nil keeps
+// the model profile's or the provider's default.
+func WithSampling(temperature, topP *float64) Option {
+	return func(c *Config) {
+		c.Temperature = temperature
+		c.TopP = topP
+	}
+}
+
+// WithMaxTokens limits the length of each answer.
+func WithMaxTokens(n int) Option {
+	return func(c *Config) { c.MaxTokens = n }
+}
+
+// WithSeed sets the sampling seed of every request.
+func WithSeed(seed int) Option {
+	return func(c *Config) { c.Seed = &seed }
+}
+
+// WithRetry sets the retry budget of a chunk.
+func WithRetry(p RetryPolicy) Option {
+	return func(c *Config) { c.Retry = p }
+}
+
+// WithDelay sets the pause between chunk requests; zero sends them without
+// any pause.
+func WithDelay(d time.Duration) Option {
+	return func(c *Config) {
+		c.Delay = d
+		c.NoDelay = d == 0
+	}
+}
+
+// WithProgress calls fn with every progress event.
+func WithProgress(fn func(ev ProgressEvent)) Option {
+	return func(c *Config) { c.Progress = fn }
+}
+
+// WithProgressOutput writes progress events to w as JSON lines.
+func WithProgressOutput(w io.Writer) Option {
+	return func(c *Config) { c.ProgressOutput = w }
+}
+
+// WithTranslationMemory reuses earlier translations stored at path.
+func WithTranslationMemory(path string) Option {
+	return func(c *Config) { c.TMPath = path }
+}
+
+// WithCache reuses chunks translated by earlier runs, see Config.Cache.
+func WithCache(c Cache) Option {
+	return func(config *Config) { config.Cache = c }
+}
+
+// WithTokenizer counts tokens with tok for models whose ID starts with
+// modelPrefix, see Config.Tokenizers. An empty prefix covers every model.
+func WithTokenizer(modelPrefix string, tok Tokenizer) Option {
+	return func(c *Config) {
+		tokenizers := map[string]Tokenizer{}
+		for prefix, t := range c.Tokenizers {
+			tokenizers[prefix] = t
+		}
+		tokenizers[modelPrefix] = tok
+		c.Tokenizers = tokenizers
+	}
+}
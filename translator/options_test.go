@@ -0,0 +1,69 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	tr, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.config.Model != DefaultModel || tr.config.ChunkSize != DefaultChunkSize || tr.config.ToLang != DefaultToLang {
+		t.Errorf("Expected defaults, got %+v", tr.config)
+	}
+
+	var events int
+	tr, err = New(
+		WithTargetLanguage("german"),
+		WithModel("a/a", "b/b", "c/c"),
+		WithChunkSize(50),
+		WithProvider(slowUpperProvider{}),
+		WithDelay(0),
+		WithProgress(func(ProgressEvent) { events++ }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.config.ToLang != "german" || tr.config.Model != "a/a" || len(tr.config.FallbackModels) != 2 || !tr.config.NoDelay {
+		t.Errorf("Expected options to apply, got %+v", tr.config)
+	}
+
+	out, err := tr.TranslateText(context.Background(), "Hello")
+	if err != nil || out != "HELLO" || events == 0 {
+		t.Errorf("Expected a translation with progress events, got %q after %d events: %v", out, events, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	temp := 3.0
+	tests := []struct {
+		name    string
+		opts    []Option
+		message string
+	}{
+		{"small chunks", []Option{WithChunkSize(10)}, "at least 50 tokens"},
+		{"no language", []Option{WithTargetLanguage(" ")}, "no target language"},
+		{"unknown model", []Option{WithModel("gpt4")}, "vendor/model"},
+		{"unknown fallback", []Option{WithModel(DefaultModel, "gpt4")}, "unknown model \"gpt4\""},
+		{"unknown backend", []Option{WithBackend("bing", "")}, "unknown backend"},
+		{"unknown format", []Option{WithFormat("docx")}, "unknown format"},
+		{"deepl language", []Option{WithBackend(BackendDeepL, ""), WithTargetLanguage("klingon")}, "DeepL does not know"},
+		{"temperature", []Option{WithSampling(&temp, nil)}, "out of range"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tr, err := New(tc.opts...)
+			if tr != nil || ErrorClass(err) != ErrorClassConfig || !strings.Contains(err.Error(), tc.message) {
+				t.Errorf("Expected a config error mentioning %q, got %v", tc.message, err)
+			}
+		})
+	}
+
+	if _, err := New(WithBackend(BackendOllama, ""), WithModel("llama3.1")); err != nil {
+		t.Errorf("Expected Ollama model names without a vendor to pass, got %v", err)
+	}
+}
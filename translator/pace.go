@@ -0,0 +1,117 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// pacer spaces out chunk requests. Every finished chunk pushes back the
+// earliest start of the next one, whichever worker picks it up. Each wait
+// claims the next start for its caller and moves it on by the last delay, so
+// workers waiting together start one delay apart rather than all at once.
+//
+// It also holds the rate limit of Config.RateLimit, a token bucket of one
+// request that refills at the limit: limitNext is when the next request
+// may be sent, claimed by every request whichever worker sends it.
+type pacer struct {
+	mu        sync.Mutex
+	next      time.Time
+	gap       time.Duration
+	limitNext time.Time
+}
+
+func (p *pacer) wait(ctx context.Context, verbose bool) error {
+	p.mu.Lock()
+	now := time.Now()
+	start := p.next
+	if start.Before(now) {
+		start = now
+	}
+	p.next = start.Add(p.gap)
+	p.mu.Unlock()
+
+	d := start.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	if verbose {
+		fmt.Printf("Waiting %v before next chunk...\n", d.Round(time.Millisecond))
+	}
+
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limit waits until the rate limit of perMinute requests a minute allows
+// another request, and claims it.
+func (p *pacer) limit(ctx context.Context, perMinute float64, verbose bool) error {
+	if perMinute <= 0 {
+		return nil
+	}
+	p.mu.Lock()
+	now := time.Now()
+	start := p.limitNext
+	if start.Before(now) {
+		start = now
+	}
+	p.limitNext = start.Add(time.Duration(float64(time.Minute) / perMinute))
+	p.mu.Unlock()
+
+	d := start.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	if verbose {
+		fmt.Printf("Waiting %v for the rate limit...\n", d.Round(time.Millisecond))
+	}
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *pacer) after(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gap = d
+	if next := time.Now().Add(d); next.After(p.next) {
+		p.next = next
+	}
+}
+
+// chunkDelay is the politeness delay after chunk. Without an explicit Delay
+// it grows with the chunk size, up to 1.5s on top of the base 10ms, unless
+// Config.RateLimit paces the requests instead.
+func (t *Translator) chunkDelay(chunk string) time.Duration {
+	if t.config.NoDelay {
+		return 0
+	}
+	if t.config.Delay > 0 {
+		return t.config.Delay
+	}
+	if t.config.RateLimit > 0 {
+		return 0
+	}
+
+	delay := 10 * time.Millisecond
+	if len(chunk) > 1000 {
+		additionalDelay := time.Duration(len(chunk)/1000) * 300 * time.Millisecond
+		if additionalDelay > 1500*time.Millisecond {
+			additionalDelay = 1500 * time.Millisecond
+		}
+		delay += additionalDelay
+	}
+	return delay
+}
@@ -0,0 +1,109 @@
+package translator
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChunkDelay(t *testing.T) {
+	small := "short chunk"
+	large := strings.Repeat("a", 3500)
+	huge := strings.Repeat("a", 20000)
+
+	testCases := []struct {
+		name     string
+		config   Config
+		chunk    string
+		expected time.Duration
+	}{
+		{"Automatic small", Config{}, small, 10 * time.Millisecond},
+		{"Automatic large", Config{}, large, 910 * time.Millisecond},
+		{"Automatic capped", Config{}, huge, 1510 * time.Millisecond},
+		{"Fixed", Config{Delay: 250 * time.Millisecond}, huge, 250 * time.Millisecond},
+		{"Disabled", Config{NoDelay: true, Delay: time.Second}, large, 0},
+		{"Rate limited", Config{RateLimit: 60}, large, 0},
+		{"Rate limited and fixed", Config{RateLimit: 60, Delay: 250 * time.Millisecond}, large, 250 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := NewTranslator(tc.config).chunkDelay(tc.chunk); got != tc.expected {
+				t.Errorf("Expected delay %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestPacer(t *testing.T) {
+	var p pacer
+
+	start := time.Now()
+	p.wait(context.Background(), false)
+	if time.Since(start) > 5*time.Millisecond {
+		t.Error("Expected the first wait to return immediately")
+	}
+
+	p.after(30 * time.Millisecond)
+	p.after(time.Millisecond)
+	p.wait(context.Background(), false)
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected to wait at least 30ms, waited %v", elapsed)
+	}
+}
+
+func TestPacerSpacesConcurrentWaits(t *testing.T) {
+	var p pacer
+	p.after(20 * time.Millisecond)
+
+	start := time.Now()
+	var mu sync.Mutex
+	var waited []time.Duration
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.wait(context.Background(), false)
+			mu.Lock()
+			waited = append(waited, time.Since(start))
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(waited, func(i, j int) bool { return waited[i] < waited[j] })
+	for i, d := range waited {
+		if want := time.Duration(i+1) * 20 * time.Millisecond; d < want-2*time.Millisecond {
+			t.Errorf("Expected worker %d to start after %v, started after %v", i+1, want, d)
+		}
+	}
+}
+
+func TestPacerRateLimit(t *testing.T) {
+	var p pacer
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.limit(context.Background(), 3000, false)
+		}()
+	}
+	wg.Wait()
+	// 3000 a minute is one every 20ms, the first at once.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("Expected 5 requests to take at least 80ms, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.limitNext = time.Now().Add(time.Hour)
+	if err := p.limit(ctx, 1, false); err == nil {
+		t.Error("Expected a canceled wait to fail")
+	}
+}
@@ -0,0 +1,62 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowUpperProvider upper-cases the chunk after a delay.
+type slowUpperProvider struct {
+	delay time.Duration
+}
+
+func (p slowUpperProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	time.Sleep(p.delay)
+	text := req.Prompt[strings.Index(req.Prompt, ":\n\n")+3:]
+	return &Completion{Text: "<result>" + strings.ToUpper(text) + "</result>"}, nil
+}
+
+func TestPauseAndResume(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	source := "First paragraph.\n\nSecond paragraph.\n\nThird paragraph.\n"
+	os.WriteFile(in, []byte(source), 0644)
+
+	full := filepath.Join(dir, "full.txt")
+	if err := NewTranslator(Config{Provider: slowUpperProvider{}, ChunkSize: 5, NoDelay: true}).TranslateFile(in, full); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "out.txt")
+	paused := NewTranslator(Config{
+		Provider:  slowUpperProvider{delay: 300 * time.Millisecond},
+		ChunkSize: 5,
+		NoDelay:   true,
+		Until:     time.Now().Add(150 * time.Millisecond),
+	})
+	err := paused.TranslateFile(in, out)
+	if ErrorClass(err) != ErrorClassPaused {
+		t.Fatalf("Expected the run to pause, got %v", err)
+	}
+	checkpoint := paused.Result().Checkpoint
+	if checkpoint == nil || checkpoint.Done != 1 || len(checkpoint.Chunks) != 3 {
+		t.Fatalf("Expected a checkpoint after the first chunk, got %+v", checkpoint)
+	}
+
+	resumed := NewTranslator(Config{Provider: slowUpperProvider{}, ChunkSize: 5, NoDelay: true})
+	if err := resumed.TranslatePrepared(checkpoint, out); err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Result().ChunksTranslated != 2 {
+		t.Errorf("Expected only the remaining chunks to be translated, got %d", resumed.Result().ChunksTranslated)
+	}
+
+	want, _ := os.ReadFile(full)
+	if got, _ := os.ReadFile(out); string(got) != string(want) {
+		t.Errorf("Expected the resumed output to match an uninterrupted run:\n%q\n%q", got, want)
+	}
+}
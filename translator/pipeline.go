@@ -0,0 +1,275 @@
+package translator
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Pipeline stages, in the order they run.
+const (
+	StageMask       = "mask"
+	StageDraft      = "mt-draft"
+	StagePostEdit   = "llm-postedit"
+	StageValidate   = "validate"
+	StageTypography = "typography"
+)
+
+var stageOrder = []string{StageMask, StageDraft, StagePostEdit, StageValidate, StageTypography}
+
+// stageOptions are the options each stage takes.
+var stageOptions = map[string][]string{
+	StageMask:       {"code", "protect", "front_matter_keys"},
+	StageDraft:      {"backend", "model", "api_key_env", "base_url"},
+	StagePostEdit:   {"backend", "model", "api_key_env", "base_url", "instructions"},
+	StageValidate:   {"retries"},
+	StageTypography: {"file"},
+}
+
+// listOptions take a list of values rather than one.
+var listOptions = map[string]bool{"protect": true, "front_matter_keys": true}
+
+// Pipeline is a translation workflow declared in a YAML file, see
+// LoadPipeline:
+//
+//	stages:
+//	  - mask:
+//	      code: true
+//	      protect:
+//	        - 'JIRA-[0-9]+'
+//	  - mt-draft:
+//	      backend: deepl
+//	      api_key_env: DEEPL_AUTH_KEY
+//	  - llm-postedit:
+//	      model: anthropic/claude-3.5-sonnet
+//	  - validate:
+//	      retries: 2
+//	  - typography
+//
+// Stages come in the order of the constants above, each at most once, and
+// mt-draft is required. Configure turns a pipeline into the Config the
+// translator runs.
+type Pipeline struct {
+	Stages []Stage
+}
+
+// Stage is one step of a Pipeline with its options.
+type Stage struct {
+	Name    string
+	Options map[string]string
+	Lists   map[string][]string
+}
+
+// LoadPipeline reads a pipeline from a YAML file.
+func LoadPipeline(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline: %w", err)
+	}
+	p, err := ParsePipeline(data)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParsePipeline reads the small YAML subset of a pipeline: a stages list
+// whose items are stage names, with the stage's options nested below.
+func ParsePipeline(data []byte) (*Pipeline, error) {
+	p := &Pipeline{}
+	var stage *Stage
+	stageIndent, list := -1, ""
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := yamlIndent(line)
+
+		switch {
+		case indent == 0:
+			if trimmed != "stages:" {
+				return nil, fmt.Errorf("line %d: expected \"stages:\"", n+1)
+			}
+			if p.Stages != nil {
+				return nil, fmt.Errorf("line %d: stages is given twice", n+1)
+			}
+			p.Stages = []Stage{}
+
+		case strings.HasPrefix(trimmed, "- ") && (stageIndent < 0 || indent == stageIndent):
+			if p.Stages == nil {
+				return nil, fmt.Errorf("line %d: put the stages under \"stages:\"", n+1)
+			}
+			name := strings.TrimSuffix(strings.TrimSpace(trimmed[2:]), ":")
+			if _, ok := stageOptions[name]; !ok {
+				return nil, fmt.Errorf("line %d: unknown stage %q, use %s", n+1, name, strings.Join(stageOrder, ", "))
+			}
+			p.Stages = append(p.Stages, Stage{Name: name, Options: map[string]string{}, Lists: map[string][]string{}})
+			stage, stageIndent, list = &p.Stages[len(p.Stages)-1], indent, ""
+
+		case stage == nil || indent <= stageIndent:
+			return nil, fmt.Errorf("line %d: expected a \"- stage\" item", n+1)
+
+		case strings.HasPrefix(trimmed, "- "):
+			if list == "" {
+				return nil, fmt.Errorf("line %d: list item without a list option", n+1)
+			}
+			stage.Lists[list] = append(stage.Lists[list], unquoteScalar(strings.TrimSpace(trimmed[2:])))
+
+		default:
+			i := strings.Index(trimmed, ":")
+			if i < 0 {
+				return nil, fmt.Errorf("line %d: expected \"option: value\"", n+1)
+			}
+			key, value := trimmed[:i], unquoteScalar(strings.TrimSpace(trimmed[i+1:]))
+			if !stageTakes(stage.Name, key) {
+				return nil, fmt.Errorf("line %d: unknown option %q of stage %s, use %s",
+					n+1, key, stage.Name, strings.Join(stageOptions[stage.Name], ", "))
+			}
+			list = ""
+			switch {
+			case listOptions[key] && value != "":
+				return nil, fmt.Errorf("line %d: put the entries of %s on the following lines", n+1, key)
+			case listOptions[key]:
+				list = key
+			default:
+				stage.Options[key] = value
+			}
+		}
+	}
+
+	if len(p.Stages) == 0 {
+		return nil, fmt.Errorf("no stages")
+	}
+	return p, nil
+}
+
+func stageTakes(stage, option string) bool {
+	for _, o := range stageOptions[stage] {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func unquoteScalar(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// Configure applies the stages of p to config: mask sets MaskCode,
+// ProtectPatterns and FrontMatterKeys, mt-draft the Backend and Model that
+// write the first translation, llm-postedit PostEdit, validate the retries
+// of answers that fail the checks and typography Typography. Stage options
+// override the settings of config, the rest of which it keeps. An api_key_env
+// option names the environment variable holding the stage's API key;
+// without it the stage uses config.APIKey.
+func (p *Pipeline) Configure(config Config) (Config, error) {
+	if err := p.checkOrder(); err != nil {
+		return config, err
+	}
+	apiKey := config.APIKey
+	for _, s := range p.Stages {
+		o := s.Options
+		switch s.Name {
+		case StageMask:
+			if v, ok := o["code"]; ok {
+				code, err := strconv.ParseBool(v)
+				if err != nil {
+					return config, configError("mask: code %q is not true or false", v)
+				}
+				config.MaskCode = code
+			}
+			config.ProtectPatterns = append(config.ProtectPatterns, s.Lists["protect"]...)
+			config.FrontMatterKeys = append(config.FrontMatterKeys, s.Lists["front_matter_keys"]...)
+
+		case StageDraft:
+			if o["backend"] != "" {
+				config.Backend = o["backend"]
+			}
+			if o["model"] != "" {
+				config.Model = o["model"]
+			}
+			if o["base_url"] != "" {
+				config.BaseURL = o["base_url"]
+			}
+			key, err := stageAPIKey(s, apiKey)
+			if err != nil {
+				return config, err
+			}
+			config.APIKey = key
+
+		case StagePostEdit:
+			if o["model"] == "" {
+				return config, configError("%s needs a model", StagePostEdit)
+			}
+			key, err := stageAPIKey(s, apiKey)
+			if err != nil {
+				return config, err
+			}
+			config.PostEdit = &PostEdit{Backend: o["backend"], Model: o["model"], APIKey: key,
+				BaseURL: o["base_url"], Instructions: o["instructions"]}
+
+		case StageValidate:
+			if v, ok := o["retries"]; ok {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 {
+					return config, configError("validate: retries %q is not a number of 1 or more", v)
+				}
+				config.Retry.Extraction = n
+			}
+			if config.PostEdit != nil {
+				config.PostEdit.Validate = true
+			}
+
+		case StageTypography:
+			config.Typography = true
+			if o["file"] != "" {
+				overrides, err := LoadTypography(o["file"])
+				if err != nil {
+					return config, classify(ErrorClassConfig, err)
+				}
+				config.TypographyOverrides = overrides
+			}
+		}
+	}
+	return config, nil
+}
+
+// checkOrder makes sure the stages come in the order they run, which the
+// file should show, each at most once, and that a draft is written.
+func (p *Pipeline) checkOrder() error {
+	last, draft := -1, false
+	for _, s := range p.Stages {
+		pos := 0
+		for stageOrder[pos] != s.Name {
+			pos++
+		}
+		if pos <= last {
+			return configError("stage %s cannot come after %s, use the order %s",
+				s.Name, stageOrder[last], strings.Join(stageOrder, ", "))
+		}
+		last, draft = pos, draft || s.Name == StageDraft
+	}
+	if !draft {
+		return configError("the pipeline has no %s stage to translate with", StageDraft)
+	}
+	return nil
+}
+
+func stageAPIKey(s Stage, fallback string) (string, error) {
+	name := s.Options["api_key_env"]
+	if name == "" {
+		return fallback, nil
+	}
+	key := os.Getenv(name)
+	if key == "" {
+		return "", configError("%s: the environment variable %s holding the API key is not set", s.Name, name)
+	}
+	return key, nil
+}
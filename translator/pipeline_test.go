@@ -0,0 +1,169 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// postEditor answers with the draft of the prompt in title case, after
+// dropping the protected spans for the first broken answers.
+type postEditor struct {
+	mu      sync.Mutex
+	broken  int
+	prompts []CompletionRequest
+}
+
+var draftRe = regexp.MustCompile(`(?s)<draft>(.*)</draft>`)
+
+func (p *postEditor) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = append(p.prompts, cr)
+	draft := draftRe.FindStringSubmatch(cr.Prompt)[1]
+	if p.broken > 0 {
+		p.broken--
+		draft = maskTokenRe.ReplaceAllString(draft, "")
+	}
+	return &Completion{Text: "<result>" + strings.Title(strings.ToLower(draft)) + "</result>", Cost: 0.5}, nil
+}
+
+func TestParsePipeline(t *testing.T) {
+	data := `# draft with DeepL, polish with a large model
+stages:
+  - mask:
+      code: true
+      protect:
+        - 'JIRA-[0-9]+'
+        - "https?://\S+"
+  - mt-draft:
+      backend: deepl
+  - llm-postedit:
+      model: anthropic/claude-3.5-sonnet
+      instructions: Use a friendly tone.
+  - validate:
+      retries: 2
+  - typography
+`
+	p, err := ParsePipeline([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range p.Stages {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, " "); got != "mask mt-draft llm-postedit validate typography" {
+		t.Errorf("Expected all stages, got %s", got)
+	}
+
+	config, err := p.Configure(Config{APIKey: "key", Model: DefaultModel, ToLang: "german", ChunkSize: DefaultChunkSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.MaskCode || strings.Join(config.ProtectPatterns, " ") != `JIRA-[0-9]+ https?://\S+` {
+		t.Errorf("Expected the mask options, got %v %q", config.MaskCode, config.ProtectPatterns)
+	}
+	if config.Backend != BackendDeepL || config.Model != DefaultModel || config.APIKey != "key" {
+		t.Errorf("Expected a DeepL draft, got %s %s", config.Backend, config.Model)
+	}
+	pe := config.PostEdit
+	if pe == nil || pe.Model != "anthropic/claude-3.5-sonnet" || pe.Instructions != "Use a friendly tone." || !pe.Validate || pe.APIKey != "key" {
+		t.Errorf("Expected the post-edit stage, got %+v", pe)
+	}
+	if config.Retry.Extraction != 2 || !config.Typography {
+		t.Errorf("Expected 2 retries and typography, got %d %v", config.Retry.Extraction, config.Typography)
+	}
+	if err := config.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	testCases := []struct{ name, data string }{
+		{"unknown stage", "stages:\n  - translate\n"},
+		{"unknown option", "stages:\n  - mt-draft:\n      temperature: 1\n"},
+		{"list inline", "stages:\n  - mask:\n      protect: x\n  - mt-draft\n"},
+		{"item without list", "stages:\n  - mt-draft:\n      - x\n"},
+		{"no stages key", "steps:\n  - mt-draft\n"},
+		{"empty", "stages:\n"},
+	}
+	for _, tc := range testCases {
+		if _, err := ParsePipeline([]byte(tc.data)); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+
+	configCases := []struct{ name, data string }{
+		{"no draft", "stages:\n  - mask\n  - typography\n"},
+		{"order", "stages:\n  - mt-draft\n  - typography\n  - validate\n"},
+		{"twice", "stages:\n  - mt-draft\n  - mt-draft\n"},
+		{"post-edit model", "stages:\n  - mt-draft\n  - llm-postedit\n"},
+		{"retries", "stages:\n  - mt-draft\n  - validate:\n      retries: none\n"},
+		{"missing key", "stages:\n  - mt-draft:\n      api_key_env: GO_AI_TRANSLATE_TEST_UNSET\n"},
+	}
+	for _, tc := range configCases {
+		p, err := ParsePipeline([]byte(tc.data))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if _, err := p.Configure(Config{}); ErrorClass(err) != ErrorClassConfig {
+			t.Errorf("%s: expected a config error, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestPostEdit(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "notes.txt")
+	out := filepath.Join(dir, "notes.de.txt")
+	os.WriteFile(in, []byte("see `make test` first.\n"), 0644)
+
+	editor := &postEditor{broken: 1}
+	config := Config{Provider: &promptRecorder{}, ChunkSize: 100, NoDelay: true, ToLang: "german",
+		MaskCode: true, Retry: RetryPolicy{Backoff: time.Millisecond},
+		PostEdit: &PostEdit{Model: "editor", Provider: editor, Validate: true}}
+	tr := NewTranslator(config)
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	// The first revision drops the code span and is asked for again.
+	if got, want := readFile(t, out), "See `make test` First.\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if len(editor.prompts) != 2 || !strings.Contains(editor.prompts[0].Prompt, "<source>see ⟦0⟧ first.") {
+		t.Errorf("Expected two post-edit requests with the source, got %+v", editor.prompts)
+	}
+	if tr.Result().Cost != 1 {
+		t.Errorf("Expected the post-edit cost counted, got %v", tr.Result().Cost)
+	}
+
+	// A revision that stays broken keeps the draft.
+	editor = &postEditor{broken: 10}
+	config.PostEdit = &PostEdit{Model: "editor", Provider: editor, Validate: true}
+	config.Retry.Extraction = 1
+	tr = NewTranslator(config)
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, out), "SEE `make test` FIRST.\n"; got != want {
+		t.Errorf("Expected the draft %q, got %q", want, got)
+	}
+	if w := tr.Result().Warnings; len(w) != 1 || w[0].Kind != WarningPostEdit {
+		t.Errorf("Expected a post-edit warning, got %+v", w)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
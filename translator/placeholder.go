@@ -0,0 +1,60 @@
+package translator
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholderRe matches the placeholders of templated strings: Go and
+// Handlebars templates ({{ .Name }}), shell and JavaScript variables
+// (${NAME}, $NAME), Ruby (%{name}), Python (%(name)s), printf (%s, %1$d,
+// %.2f, %@) and positional and named arguments ({0}, {name}, {0:N2}).
+var placeholderRe = regexp.MustCompile(`\{\{[^{}\n]*\}\}` +
+	`|\$\{[^{}\n]+\}` +
+	`|\$[A-Z_][A-Z0-9_]*\b` +
+	`|%\{\w+\}` +
+	`|%\(\w+\)[-+#0]*\d*(?:\.\d+)?[sdifrxXeEgGc]` +
+	`|%(?:\d+\$)?[-+#0]*(?:\d+|\*)?(?:\.\d+)?(?:hh|h|ll|l|L|q|j|z|t)?[diouxXeEfFgGaAcsp@]` +
+	`|\{\w+(?::[^{}\n]*)?\}`)
+
+// placeholders returns the placeholders of text in order of appearance.
+func placeholders(text string) []string {
+	return placeholderRe.FindAllString(text, -1)
+}
+
+// missingPlaceholders returns the placeholders of source that translation
+// lacks, counting repeated ones, in sorted order. The order of placeholders
+// may change with the word order of the target language.
+func missingPlaceholders(source, translation string) []string {
+	found := map[string]int{}
+	for _, p := range placeholders(translation) {
+		found[p]++
+	}
+	var missing []string
+	for _, p := range placeholders(source) {
+		if found[p] > 0 {
+			found[p]--
+		} else {
+			missing = append(missing, p)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// placeholderHint asks the model to keep the placeholders of text.
+func placeholderHint(text string) string {
+	var distinct []string
+	seen := map[string]bool{}
+	for _, p := range placeholders(text) {
+		if !seen[p] {
+			seen[p] = true
+			distinct = append(distinct, p)
+		}
+	}
+	if len(distinct) == 0 {
+		return ""
+	}
+	return "Keep these placeholders exactly as they are, only moving them where the grammar needs: " + strings.Join(distinct, " ")
+}
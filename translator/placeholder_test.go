@@ -0,0 +1,70 @@
+package translator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlaceholders(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"Deleted %d of %s files at 50% off", []string{"%d", "%s"}},
+		{"%1$s sent %2$.2f, %@ and %lld", []string{"%1$s", "%2$.2f", "%@", "%lld"}},
+		{"Hello {0}, you have {count} messages, {0:N2} total", []string{"{0}", "{count}", "{0:N2}"}},
+		{"Hi {{ .User.Name }} and {{name}}", []string{"{{ .User.Name }}", "{{name}}"}},
+		{"Path ${HOME}/bin or $PATH, not $5", []string{"${HOME}", "$PATH"}},
+		{"Ruby %{name}, Python %(count)d", []string{"%{name}", "%(count)d"}},
+		{"No placeholders here.", nil},
+	}
+	for _, tc := range testCases {
+		if got := placeholders(tc.input); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%q: expected %q, got %q", tc.input, tc.expected, got)
+		}
+	}
+
+	if got := missingPlaceholders("%s of %s in {0}", "{0}: %s"); !reflect.DeepEqual(got, []string{"%s"}) {
+		t.Errorf("Expected the second %%s missing, got %q", got)
+	}
+	if got := missingPlaceholders("{{ .Count }} files", "{{.Count}} Dateien"); !reflect.DeepEqual(got, []string{"{{ .Count }}"}) {
+		t.Errorf("Expected an altered placeholder reported, got %q", got)
+	}
+}
+
+// mangleOnceProvider translates placeholders to "{Name}" on its first
+// answer and keeps them afterwards.
+type mangleOnceProvider struct {
+	calls  int
+	system string
+}
+
+func (p *mangleOnceProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	p.calls++
+	p.system = cr.System
+	if p.calls == 1 {
+		return &Completion{Text: "<result>Hallo {Name}!</result>"}, nil
+	}
+	return &Completion{Text: "<result>Hallo {name}!</result>"}, nil
+}
+
+func TestPlaceholderRetry(t *testing.T) {
+	provider := &mangleOnceProvider{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "german", Retry: RetryPolicy{Backoff: time.Millisecond}})
+	got, err := tr.TranslateText(context.Background(), "Hello {name}!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Hallo {name}!" || provider.calls != 2 {
+		t.Errorf("Expected the mangled placeholder retried, got %q after %d calls", got, provider.calls)
+	}
+	if !strings.Contains(provider.system, "placeholders exactly as they are") || !strings.Contains(provider.system, "{name}") {
+		t.Errorf("Expected the prompt to name the placeholders, got %q", provider.system)
+	}
+	if len(tr.Result().Warnings) != 0 {
+		t.Errorf("Expected no warnings after the retry, got %v", tr.Result().Warnings)
+	}
+}
@@ -0,0 +1,210 @@
+package translator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pluralCategory is a CLDR cardinal plural category of a language together
+// with counts that fall into it, which tell the model which form to write.
+type pluralCategory struct {
+	name     string
+	examples string
+}
+
+var (
+	pluralOneOther     = []pluralCategory{{"one", "1"}, {"other", "0, 2, 5, 100"}}
+	pluralOneManyOther = []pluralCategory{{"one", "1"}, {"many", "1000000"}, {"other", "0, 2, 5, 100"}}
+	pluralEastSlavic   = []pluralCategory{{"one", "1, 21, 31"}, {"few", "2-4, 22-24"}, {"many", "0, 5-20, 25-30"}, {"other", "1.5"}}
+	pluralOther        = []pluralCategory{{"other", "0, 1, 2, 5"}}
+)
+
+// pluralRules lists the cardinal plural categories of the languages --to
+// accepts by name, after CLDR.
+var pluralRules = map[string][]pluralCategory{
+	"bulgarian":  pluralOneOther,
+	"danish":     pluralOneOther,
+	"dutch":      pluralOneOther,
+	"english":    pluralOneOther,
+	"estonian":   pluralOneOther,
+	"finnish":    pluralOneOther,
+	"german":     pluralOneOther,
+	"greek":      pluralOneOther,
+	"hungarian":  pluralOneOther,
+	"norwegian":  pluralOneOther,
+	"swedish":    pluralOneOther,
+	"turkish":    pluralOneOther,
+	"italian":    pluralOneManyOther,
+	"spanish":    pluralOneManyOther,
+	"french":     {{"one", "0, 1"}, {"many", "1000000"}, {"other", "2, 5, 100"}},
+	"portuguese": {{"one", "0, 1"}, {"many", "1000000"}, {"other", "2, 5, 100"}},
+	"russian":    pluralEastSlavic,
+	"ukrainian":  pluralEastSlavic,
+	"belarusian": pluralEastSlavic,
+	"polish":     {{"one", "1"}, {"few", "2-4, 22-24"}, {"many", "0, 5-21, 25-31"}, {"other", "1.5"}},
+	"czech":      {{"one", "1"}, {"few", "2-4"}, {"many", "1.5"}, {"other", "0, 5-100"}},
+	"slovak":     {{"one", "1"}, {"few", "2-4"}, {"many", "1.5"}, {"other", "0, 5-100"}},
+	"lithuanian": {{"one", "1, 21, 31"}, {"few", "2-9, 22-29"}, {"many", "0.1"}, {"other", "0, 10-20, 30"}},
+	"latvian":    {{"zero", "0, 10-20, 30"}, {"one", "1, 21, 31"}, {"other", "2-9, 22-29"}},
+	"romanian":   {{"one", "1"}, {"few", "0, 2-19, 101-119"}, {"other", "20-100"}},
+	"slovenian":  {{"one", "1, 101"}, {"two", "2, 102"}, {"few", "3, 4, 103"}, {"other", "0, 5-100"}},
+	"arabic":     {{"zero", "0"}, {"one", "1"}, {"two", "2"}, {"few", "3-10, 103-110"}, {"many", "11-99"}, {"other", "100-102"}},
+	"hebrew":     {{"one", "1"}, {"two", "2"}, {"other", "0, 3-100"}},
+	"hindi":      {{"one", "0, 1"}, {"other", "2-100"}},
+	"chinese":    pluralOther,
+	"indonesian": pluralOther,
+	"japanese":   pluralOther,
+	"korean":     pluralOther,
+	"thai":       pluralOther,
+	"vietnamese": pluralOther,
+}
+
+// pluralCategories returns the plural categories of lang, a language name
+// or code such as "ru" or "pt-BR", or nil when it is unknown.
+func pluralCategories(lang string) []pluralCategory {
+	return pluralRules[languageName(lang)]
+}
+
+// languageName returns the lower-case English name of lang, a language name
+// or code such as "ru" or "pt-BR", or lang lower-cased when it is unknown.
+func languageName(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if _, ok := deeplLanguages[lang]; ok {
+		return lang
+	}
+	code := strings.SplitN(lang, "-", 2)[0]
+	for name, c := range deeplLanguages {
+		if strings.ToLower(strings.SplitN(c, "-", 2)[0]) == code {
+			return name
+		}
+	}
+	switch code {
+	case "he", "iw":
+		return "hebrew"
+	case "hi":
+		return "hindi"
+	case "be":
+		return "belarusian"
+	case "th":
+		return "thai"
+	case "vi":
+		return "vietnamese"
+	}
+	return lang
+}
+
+// pluralMessage is a UI string holding one plural argument, expanded into
+// one variant per plural form of the target language.
+type pluralMessage struct {
+	arg      icuArgument
+	variants []pluralVariant
+}
+
+type pluralVariant struct {
+	selector string
+	// text is the full source sentence of the form, hint tells the model
+	// which form it is.
+	text        string
+	hint        string
+	translation string
+}
+
+// expandPlural returns the plural variants of msg, or nil when msg is not a
+// valid ICU message with exactly one top-level plural argument. Text around
+// the argument is moved into every variant, so each is a whole sentence.
+func (t *Translator) expandPlural(msg string) *pluralMessage {
+	args, err := parseICU(msg)
+	if err != nil {
+		return nil
+	}
+	var p *pluralMessage
+	for _, a := range args {
+		if a.kind == "plural" || a.kind == "selectordinal" {
+			if p != nil {
+				return nil
+			}
+			p = &pluralMessage{arg: a}
+		}
+	}
+	if p == nil {
+		return nil
+	}
+
+	prefix, suffix := msg[:p.arg.start], msg[p.arg.end:]
+	add := func(selector, source, hint string) {
+		p.variants = append(p.variants, pluralVariant{selector: selector, text: prefix + source + suffix, hint: hint})
+	}
+
+	for _, o := range p.arg.options {
+		if strings.HasPrefix(o.selector, "=") {
+			add(o.selector, o.message, fmt.Sprintf("This is the form of a UI string used when the count is exactly %s; # stands for the count", o.selector[1:]))
+		}
+	}
+
+	categories := pluralCategories(t.config.ToLang)
+	if p.arg.kind == "selectordinal" || categories == nil {
+		// Without the target's rules, each source form is translated as is.
+		for _, o := range p.arg.options {
+			if !strings.HasPrefix(o.selector, "=") {
+				add(o.selector, o.message, fmt.Sprintf("This is the %q %s form of a UI string; # stands for the count", o.selector, p.arg.kind))
+			}
+		}
+		return p
+	}
+	for _, c := range categories {
+		source := p.arg.option(c.name)
+		if source == nil {
+			source = p.arg.option("other")
+		}
+		add(c.name, source.message, fmt.Sprintf("This is the %q plural form of a UI string, used in %s for counts such as %s. Write it in that form; # stands for the count",
+			c.name, t.config.ToLang, c.examples))
+	}
+	return p
+}
+
+// assemble builds the translated ICU message from the translated variants.
+func (p *pluralMessage) assemble() string {
+	var b strings.Builder
+	b.WriteString("{" + p.arg.name + ", " + p.arg.kind + ",")
+	if p.arg.offset != "" {
+		b.WriteString(" offset:" + p.arg.offset)
+	}
+	for _, v := range p.variants {
+		b.WriteString(" " + v.selector + " {" + v.translation + "}")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// maskICU protects the arguments of an ICU message and the # count
+// placeholders, appending them to spans.
+func maskICU(text string, spans *[]string) string {
+	args, err := parseICU(text)
+	if err != nil {
+		return text
+	}
+
+	var b strings.Builder
+	protect := func(s string) {
+		*spans = append(*spans, s)
+		b.WriteString(maskOpen + strconv.Itoa(len(*spans)-1) + maskClose)
+	}
+	literal := func(s string) {
+		for i, part := range strings.Split(s, "#") {
+			if i > 0 {
+				protect("#")
+			}
+			b.WriteString(part)
+		}
+	}
+
+	last := 0
+	for _, a := range args {
+		literal(text[last:a.start])
+		protect(text[a.start:a.end])
+		last = a.end
+	}
+	literal(text[last:])
+	return b.String()
+}
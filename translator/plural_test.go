@@ -0,0 +1,141 @@
+package translator
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pluralProvider answers with the plural form named in the instructions
+// followed by the upper-cased text, and breaks the ICU syntax of text
+// containing "broken".
+type pluralProvider struct{}
+
+var pluralFormRe = regexp.MustCompile(`"(\w+)" plural form|exactly (\d+)`)
+
+func (pluralProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	text := cr.Prompt[strings.Index(cr.Prompt, ":\n\n")+3:]
+	answer := strings.ToUpper(text)
+	if m := pluralFormRe.FindStringSubmatch(cr.System); m != nil {
+		answer = m[1] + m[2] + ":" + answer
+	}
+	if strings.Contains(text, "broken") {
+		answer += " {"
+	}
+	return &Completion{Text: "<result>" + answer + "</result>"}, nil
+}
+
+func TestTranslatePlurals(t *testing.T) {
+	tr := NewTranslator(Config{Provider: pluralProvider{}, ChunkSize: 100, NoDelay: true, ToLang: "russian", FromLang: "english",
+		Retry: RetryPolicy{Backoff: time.Millisecond}})
+	results, err := tr.TranslateSegments(context.Background(), []Segment{
+		{ID: 1, Source: "{count, plural, =0 {No files} one {# file} other {# files}}"},
+		{ID: 2, Source: "{name} has {count, plural, offset:1 one {# message} other {# messages}}"},
+		{ID: 3, Source: "Plain text"},
+		{ID: 4, Source: "{count, plural, one {# broken} other {# items}}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"{count, plural, =0 {0:NO FILES} one {one:# FILE} few {few:# FILES} many {many:# FILES} other {other:# FILES}}",
+		"{count, plural, offset:1 one {one:{name} HAS # MESSAGE} few {few:{name} HAS # MESSAGES} many {many:{name} HAS # MESSAGES} other {other:{name} HAS # MESSAGES}}",
+		"PLAIN TEXT",
+		"",
+	}
+	for i, w := range want {
+		if results[i].Target != w {
+			t.Errorf("Segment %d: expected %q, got %q", i+1, w, results[i].Target)
+		}
+	}
+	if results[3].State != SegmentUntranslated {
+		t.Errorf("Expected the segment with broken ICU syntax untranslated, got %q", results[3].State)
+	}
+	warnings := tr.Result().Warnings
+	if len(warnings) != 1 || warnings[0].Kind != WarningICUSyntax {
+		t.Errorf("Expected one ICU syntax warning, got %+v", warnings)
+	}
+
+	tr = NewTranslator(Config{Provider: pluralProvider{}, ChunkSize: 100, NoDelay: true, ToLang: "japanese"})
+	results, _ = tr.TranslateSegments(context.Background(), []Segment{{ID: 1, Source: "{count, plural, one {# file} other {# files}}"}})
+	if want := "{count, plural, other {other:# FILES}}"; results[0].Target != want {
+		t.Errorf("Expected only the other form for Japanese, got %q", results[0].Target)
+	}
+}
+
+func TestParseICU(t *testing.T) {
+	valid := []string{
+		"Hello {name}",
+		"It''s '{literal}' {n, number, integer}",
+		"{g, select, male {He} female {She} other {They}} has {n, plural, =0 {none} one {# {item}} other {# items}}",
+	}
+	for _, msg := range valid {
+		if _, err := parseICU(msg); err != nil {
+			t.Errorf("parseICU(%q): %v", msg, err)
+		}
+	}
+
+	invalid := []string{
+		"{count, plural, one {# file}}",
+		"Hello {name",
+		"Hello } there",
+		"{count, plural, one {# file} other {# files}",
+		"{first name}",
+	}
+	for _, msg := range invalid {
+		if _, err := parseICU(msg); err == nil {
+			t.Errorf("Expected parseICU(%q) to fail", msg)
+		}
+	}
+}
+
+func TestPluralCategories(t *testing.T) {
+	for lang, want := range map[string]int{"russian": 4, "ru": 4, "pt-BR": 3, "arabic": 6, "ja": 1, "klingon": 0} {
+		if got := len(pluralCategories(lang)); got != want {
+			t.Errorf("pluralCategories(%q) has %d categories, want %d", lang, got, want)
+		}
+	}
+}
+
+// renamingProvider renames the {name} argument on its first answer.
+type renamingProvider struct {
+	calls int
+}
+
+func (p *renamingProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	p.calls++
+	text := cr.Prompt[strings.Index(cr.Prompt, ":\n\n")+3:]
+	if p.calls == 1 {
+		text = strings.Replace(text, "{name}", "{nom}", 1)
+	}
+	return &Completion{Text: "<result>" + text + "</result>"}, nil
+}
+
+func TestICUValidation(t *testing.T) {
+	provider := &renamingProvider{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, Retry: RetryPolicy{Backoff: time.Millisecond}})
+	results, err := tr.TranslateSegments(context.Background(), []Segment{{ID: 1, Source: "Hello {name}"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 2 || results[0].Target != "Hello {name}" {
+		t.Errorf("Expected the renamed argument retried, got %d calls and %q", provider.calls, results[0].Target)
+	}
+
+	tr = NewTranslator(Config{Provider: pluralProvider{}, ChunkSize: 100, NoDelay: true, ICU: true, Retry: RetryPolicy{Backoff: time.Millisecond}})
+	got, err := tr.TranslateText(context.Background(), "{n} broken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "{n} broken" || len(tr.Result().Warnings) != 1 || tr.Result().Warnings[0].Kind != WarningICUSyntax {
+		t.Errorf("Expected the source kept with a warning, got %q and %+v", got, tr.Result().Warnings)
+	}
+
+	tr = NewTranslator(Config{Provider: pluralProvider{}, ChunkSize: 100, NoDelay: true, Retry: RetryPolicy{Backoff: time.Millisecond}})
+	if got, _ := tr.TranslateText(context.Background(), "{n} broken"); got != "{N} BROKEN {" {
+		t.Errorf("Expected no ICU check without Config.ICU, got %q", got)
+	}
+}
@@ -0,0 +1,56 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// polish finishes the output file for the target repository's formatting
+// checks, see Config.FinalNewline and Config.PostCommands. A command that
+// fails is reported as a warning; the translation is written either way.
+func (t *Translator) polish(ctx context.Context, outputPath string) error {
+	if t.config.FinalNewline {
+		data, err := os.ReadFile(outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to read output file: %w", err)
+		}
+		if fixed := finalNewline(string(data)); fixed != string(data) {
+			if err := WriteFileAtomic(outputPath, []byte(fixed), 0644); err != nil {
+				return fmt.Errorf("failed to write output file: %w", err)
+			}
+		}
+	}
+
+	for _, command := range t.config.PostCommands {
+		if strings.TrimSpace(command) == "" {
+			continue
+		}
+		if !strings.Contains(command, "{output}") {
+			command += " {output}"
+		}
+		err := runCommandLine(ctx, command, "", outputPath)
+		if ErrorClass(err) == ErrorClassCanceled {
+			return err
+		}
+		if err != nil {
+			t.warn(Warning{Kind: WarningPostCommand, Message: fmt.Sprintf("%s: %v", strings.Fields(command)[0], err)})
+		}
+	}
+	return nil
+}
+
+// finalNewline ends text with exactly one line break, in the style of its
+// other line breaks, dropping trailing blank lines.
+func finalNewline(text string) string {
+	newline := "\n"
+	if strings.Contains(text, "\r\n") {
+		newline = "\r\n"
+	}
+	trimmed := strings.TrimRight(text, "\r\n")
+	if trimmed == "" {
+		return ""
+	}
+	return trimmed + newline
+}
@@ -0,0 +1,54 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFinalNewline(t *testing.T) {
+	testCases := []struct{ input, expected string }{
+		{"text", "text\n"},
+		{"text\n", "text\n"},
+		{"text\n\n\n", "text\n"},
+		{"a\r\nb", "a\r\nb\r\n"},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		if got := finalNewline(tc.input); got != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestPostCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as stand-ins for formatters")
+	}
+	bin := t.TempDir()
+	// fmt-stub <file> appends a line to the file; fail-stub always fails.
+	os.WriteFile(filepath.Join(bin, "fmt-stub"), []byte("#!/bin/sh\necho formatted >> \"$1\"\n"), 0755)
+	os.WriteFile(filepath.Join(bin, "fail-stub"), []byte("#!/bin/sh\necho 'MD041 first line' >&2\nexit 1\n"), 0755)
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	out := filepath.Join(dir, "out.txt")
+	os.WriteFile(in, []byte("Hello.\n\n\n"), 0644)
+
+	tr := NewTranslator(Config{Provider: &promptRecorder{}, ChunkSize: 100, NoDelay: true, ToLang: "german",
+		FinalNewline: true, PostCommands: []string{"fail-stub --fix", "fmt-stub {output}"}})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "HELLO.\nformatted\n" {
+		t.Errorf("Expected a single final newline and the formatter's change, got %q", got)
+	}
+	warnings := tr.Result().Warnings
+	if len(warnings) != 1 || warnings[0].Kind != WarningPostCommand {
+		t.Errorf("Expected a warning for the failing command, got %v", warnings)
+	}
+}
@@ -0,0 +1,114 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PostEdit has a language model revise the draft translation of every
+// chunk against its source, e.g. a DeepL draft post-edited by a large
+// model, see Config.PostEdit.
+type PostEdit struct {
+	// Backend is BackendOpenRouter, the default, or BackendOllama; APIKey
+	// and BaseURL are the backend's.
+	Backend string
+	Model   string
+	APIKey  string
+	BaseURL string
+	// Instructions are added to the post-editor's instructions, e.g. the
+	// tone of the document.
+	Instructions string
+	// Validate checks revised chunks like translations: a revision that
+	// breaks protected spans, placeholders, ICU syntax or the length limit,
+	// or fails the sanity check, is asked for again while the retries for
+	// unusable answers last, then the draft is kept with a WarningPostEdit.
+	Validate bool
+	// Provider replaces the built-in backend.
+	Provider Provider `json:"-"`
+}
+
+// newPostEditor returns the translator whose provider revises drafts.
+func newPostEditor(config Config) *Translator {
+	pe := config.PostEdit
+	return NewTranslator(Config{
+		Backend:       pe.Backend,
+		Model:         pe.Model,
+		APIKey:        pe.APIKey,
+		BaseURL:       pe.BaseURL,
+		Provider:      pe.Provider,
+		ToLang:        config.ToLang,
+		ModelProfiles: config.ModelProfiles,
+	})
+}
+
+// postEdit returns the revision of draft, the translation of chunk i. A
+// failed revision keeps the draft and gives a WarningPostEdit.
+func (t *Translator) postEdit(ctx context.Context, i int, chunk, draft string) string {
+	pe := t.config.PostEdit
+	system := fmt.Sprintf("You post-edit machine translations into %s language. "+
+		"Compare the draft translation of the user message with its source and correct mistranslations, omissions, grammar and awkward phrasing; keep what is right as it is. "+
+		"Keep the formatting, and every marker like %s0%s and placeholder exactly as in the source. "+
+		"Place the edited translation in the tag <result>", t.config.ToLang, maskOpen, maskClose)
+	if terms := t.glossaryTerms(chunk); len(terms) > 0 {
+		system += ". Translate these terms exactly as given: " + strings.Join(terms, "; ")
+	}
+	if instructions := strings.TrimSpace(pe.Instructions); instructions != "" {
+		system += ". " + instructions
+	}
+	prompt := fmt.Sprintf("<source>%s</source>\n<draft>%s</draft>", chunk, draft)
+
+	attempts := 1
+	if pe.Validate {
+		attempts += t.retryPolicy().Extraction
+	}
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		var completion *Completion
+		completion, err = t.postEditor.provider.Complete(ctx, CompletionRequest{
+			Model:   pe.Model,
+			System:  system,
+			Prompt:  prompt,
+			ToLang:  t.config.ToLang,
+			RunID:   t.runID,
+			ChunkID: t.chunkID(i),
+		})
+		if ctx.Err() != nil {
+			break
+		}
+		var edited string
+		if err == nil {
+			t.addCompletionUsage(completion)
+			edited, err = t.postEditor.extractTranslation(pe.Model, completion.Text)
+		}
+		if err == nil && pe.Validate {
+			err = t.answerProblem(i, chunk, edited)
+		}
+		if err == nil {
+			return edited
+		}
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	t.warn(Warning{Kind: WarningPostEdit, Chunk: i + 1, Message: fmt.Sprintf("chunk %d keeps the draft translation: %v", i+1, err)})
+	return draft
+}
+
+// answerProblem reports what makes translation unusable as the translation
+// of chunk i, the checks retryChunk retries answers for.
+func (t *Translator) answerProblem(i int, chunk, translation string) error {
+	if missing := missingMarkers(chunk, translation); missing > 0 {
+		return fmt.Errorf("the model dropped or altered %d protected spans", missing)
+	}
+	if broken := missingPlaceholders(chunk, translation); len(broken) > 0 {
+		return fmt.Errorf("the model dropped or altered the placeholders %s", strings.Join(broken, " "))
+	}
+	if err := t.icuProblem(chunk, translation); err != nil {
+		return err
+	}
+	if err := t.lengthProblem(i, translation); err != nil {
+		return err
+	}
+	return t.sanityProblem(chunk, translation)
+}
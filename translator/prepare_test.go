@@ -0,0 +1,65 @@
+package translator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranslatePrepared(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "paper.typ")
+	content := "= Introduction\n\nThe area is $pi r^2$.\n\nSecond paragraph.\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{ChunkSize: 500, MaxRetries: 1, Format: "auto"}
+	prepared, err := NewTranslator(config).Prepare(input)
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if prepared.Format != "typst" || len(prepared.Spans) == 0 {
+		t.Fatalf("Expected masked typst chunks, got %+v", prepared)
+	}
+
+	data, err := json.Marshal(prepared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var queued PreparedFile
+	if err := json.Unmarshal(data, &queued); err != nil {
+		t.Fatal(err)
+	}
+
+	config.APIKey = "test"
+	config.BaseURL = server.URL
+	tr := NewTranslator(config)
+	if err := tr.CheckOnline(); err != nil {
+		t.Fatalf("Expected API to be reachable: %v", err)
+	}
+
+	output := filepath.Join(dir, "out.typ")
+	if err := tr.TranslatePrepared(&queued, output); err != nil {
+		t.Fatalf("TranslatePrepared failed: %v", err)
+	}
+
+	got, _ := os.ReadFile(output)
+	if string(got) != content {
+		t.Errorf("Expected echoed output %q, got %q", content, got)
+	}
+	if r := tr.Result(); r.Format != "typst" || r.ChunksTranslated != r.Chunks {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+}
+
+func TestCheckOnlineUnreachable(t *testing.T) {
+	tr := NewTranslator(Config{BaseURL: "http://127.0.0.1:1"})
+	if err := tr.CheckOnline(); ErrorClass(err) != ErrorClassNetwork {
+		t.Errorf("Expected network error, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// redacted replaces the API key in previewed requests.
+const redacted = "REDACTED"
+
+// RequestPreview is an API request as it would be sent, see
+// PreviewRequests.
+type RequestPreview struct {
+	Chunk  int             `json:"chunk"`
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Header http.Header     `json:"header"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// requestBuilder is implemented by the built-in providers, whose requests
+// PreviewRequests shows.
+type requestBuilder interface {
+	newRequest(ctx context.Context, cr CompletionRequest) (*http.Request, []byte, error)
+}
+
+// PreviewRequests returns the requests translating the remaining chunks of
+// prepared would send, with the API key redacted, without sending them.
+// They are the first attempts, without the context, summary and translation
+// memory references that depend on earlier answers. A Config.Provider has
+// no requests to show.
+func (t *Translator) PreviewRequests(ctx context.Context, prepared *PreparedFile) ([]RequestPreview, error) {
+	builder, ok := t.provider.(requestBuilder)
+	if !ok {
+		return nil, configError("the configured provider does not show its requests")
+	}
+	format, err := lookupFormat(prepared.Format, "")
+	if err != nil {
+		return nil, classify(ErrorClassConfig, err)
+	}
+	t.format = format
+	t.runID = newRunID()
+	t.selectModel(ctx)
+	t.sourceLang = t.sourceLanguage(&fileJob{chunks: prepared.Chunks})
+
+	var previews []RequestPreview
+	for i := prepared.Done; i < len(prepared.Chunks); i++ {
+		model := t.activeModel()
+		pc := promptContext{chunkID: t.chunkID(i), chunk: i + 1, hint: t.chunkHint(i), screenshot: t.chunkScreenshot(i), delimiter: t.delimiterFor(model)}
+		cr := t.completionRequest(model, prepared.Chunks[i], pc)
+		if t.config.Stream {
+			cr.Delta = func(string) {}
+		}
+		req, body, err := builder.newRequest(ctx, cr)
+		if err != nil {
+			return nil, err
+		}
+		previews = append(previews, RequestPreview{
+			Chunk:  i + 1,
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: t.redactHeader(req.Header),
+			Body:   t.redact(body),
+		})
+	}
+	return previews, nil
+}
+
+func (t *Translator) redactHeader(header http.Header) http.Header {
+	if t.config.APIKey == "" {
+		return header
+	}
+	for _, values := range header {
+		for i, v := range values {
+			values[i] = strings.ReplaceAll(v, t.config.APIKey, redacted)
+		}
+	}
+	return header
+}
+
+func (t *Translator) redact(body []byte) []byte {
+	if t.config.APIKey == "" {
+		return body
+	}
+	return bytes.ReplaceAll(body, []byte(t.config.APIKey), []byte(redacted))
+}
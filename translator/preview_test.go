@@ -0,0 +1,71 @@
+package translator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPreviewRequests(t *testing.T) {
+	prepared := &PreparedFile{Chunks: []string{"Hello sk-secret.", "Second chunk."}, Done: 1}
+	tr := NewTranslator(Config{APIKey: "sk-secret", Model: "m", ToLang: "german", ChunkSize: 100})
+	previews, err := tr.PreviewRequests(context.Background(), prepared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previews) != 1 || previews[0].Chunk != 2 || previews[0].Method != "POST" || !strings.HasSuffix(previews[0].URL, "/chat/completions") {
+		t.Fatalf("Expected the request of the remaining chunk, got %+v", previews)
+	}
+	p := previews[0]
+	if p.Header.Get("Authorization") != "Bearer REDACTED" || p.Header.Get("X-Title") == "" || p.Header.Get("X-Request-Id") == "" {
+		t.Errorf("Expected the headers with the key redacted, got %v", p.Header)
+	}
+	if !strings.Contains(string(p.Body), `"model":"m"`) || !strings.Contains(string(p.Body), "Second chunk.") {
+		t.Errorf("Expected the request body, got %s", p.Body)
+	}
+
+	prepared.Done = 0
+	tr = NewTranslator(Config{APIKey: "sk-secret", Model: "m", ToLang: "german", ChunkSize: 100, NoExternalMetadata: true})
+	previews, err = tr.PreviewRequests(context.Background(), prepared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(previews[0].Body), "sk-secret") {
+		t.Errorf("Expected the key redacted in the body, got %s", previews[0].Body)
+	}
+	for _, name := range []string{"HTTP-Referer", "X-Title", "X-Request-Id", "X-Run-Id"} {
+		if v := previews[0].Header.Get(name); v != "" {
+			t.Errorf("Expected no %s header, got %q", name, v)
+		}
+	}
+
+	tr = NewTranslator(Config{Provider: &promptRecorder{}, ToLang: "german", ChunkSize: 100})
+	if _, err := tr.PreviewRequests(context.Background(), prepared); ErrorClass(err) != ErrorClassConfig {
+		t.Errorf("Expected a config error for a custom provider, got %v", err)
+	}
+}
+
+func TestNoExternalMetadata(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.Write([]byte(`{"choices": [{"message": {"content": "<result>Hallo.</result>"}}]}`))
+	}))
+	defer server.Close()
+
+	tr := NewTranslator(Config{BaseURL: server.URL, APIKey: "key", Model: "m", ToLang: "german", ChunkSize: 100,
+		NoDelay: true, NoExternalMetadata: true})
+	if _, err := tr.TranslateText(context.Background(), "Hello."); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("Authorization") != "Bearer key" {
+		t.Errorf("Expected the API key sent, got %v", header)
+	}
+	for _, name := range []string{"HTTP-Referer", "X-Title", "X-Request-Id", "X-Run-Id"} {
+		if v := header.Get(name); v != "" {
+			t.Errorf("Expected no %s header, got %q", name, v)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package translator
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority orders chunk requests of translators sharing a PriorityGate.
+type Priority int
+
+const (
+	PriorityBackground Priority = iota
+	PriorityInteractive
+)
+
+// PriorityGate limits how many chunk requests run at once across the
+// translators that share it. A chunk holds a slot only while it is being
+// translated, so a waiting interactive chunk takes the next free slot ahead
+// of any waiting background chunk: long jobs yield at chunk boundaries.
+type PriorityGate struct {
+	mu      sync.Mutex
+	free    int
+	waiting [PriorityInteractive + 1][]chan struct{}
+}
+
+// NewPriorityGate returns a gate admitting slots chunk requests at a time.
+func NewPriorityGate(slots int) *PriorityGate {
+	if slots < 1 {
+		slots = 1
+	}
+	return &PriorityGate{free: slots}
+}
+
+func (g *PriorityGate) acquire(ctx context.Context, p Priority) error {
+	if p < PriorityBackground || p > PriorityInteractive {
+		p = PriorityBackground
+	}
+
+	g.mu.Lock()
+	if g.free > 0 && !g.waitingAbove(p-1) {
+		g.free--
+		g.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	g.waiting[p] = append(g.waiting[p], ready)
+	g.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		for i, ch := range g.waiting[p] {
+			if ch == ready {
+				g.waiting[p] = append(g.waiting[p][:i], g.waiting[p][i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// The slot was handed over while ctx was canceled; pass it on.
+		g.releaseLocked()
+		return ctx.Err()
+	}
+}
+
+// waitingAbove reports whether any chunk with a priority higher than p is
+// waiting.
+func (g *PriorityGate) waitingAbove(p Priority) bool {
+	for q := PriorityInteractive; q > p; q-- {
+		if len(g.waiting[q]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *PriorityGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.releaseLocked()
+}
+
+func (g *PriorityGate) releaseLocked() {
+	for p := PriorityInteractive; p >= PriorityBackground; p-- {
+		if len(g.waiting[p]) > 0 {
+			ready := g.waiting[p][0]
+			g.waiting[p] = g.waiting[p][1:]
+			close(ready)
+			return
+		}
+	}
+	g.free++
+}
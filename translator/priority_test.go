@@ -0,0 +1,67 @@
+package translator
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPriorityGateInteractiveFirst(t *testing.T) {
+	gate := NewPriorityGate(1)
+	ctx := context.Background()
+	if err := gate.acquire(ctx, PriorityBackground); err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan Priority, 2)
+	wait := func(p Priority) {
+		if err := gate.acquire(ctx, p); err != nil {
+			t.Error(err)
+			return
+		}
+		order <- p
+		gate.release()
+	}
+
+	go wait(PriorityBackground)
+	waitForWaiters(t, gate, PriorityBackground, 1)
+	go wait(PriorityInteractive)
+	waitForWaiters(t, gate, PriorityInteractive, 1)
+
+	gate.release()
+	if first, second := <-order, <-order; first != PriorityInteractive || second != PriorityBackground {
+		t.Errorf("expected interactive then background, got %v then %v", first, second)
+	}
+}
+
+func TestPriorityGateCancel(t *testing.T) {
+	gate := NewPriorityGate(1)
+	if err := gate.acquire(context.Background(), PriorityInteractive); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := gate.acquire(ctx, PriorityBackground); err == nil {
+		t.Fatal("expected the wait to be canceled")
+	}
+
+	gate.release()
+	if err := gate.acquire(context.Background(), PriorityBackground); err != nil {
+		t.Fatalf("slot was not returned: %v", err)
+	}
+}
+
+func waitForWaiters(t *testing.T, gate *PriorityGate, p Priority, n int) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		gate.mu.Lock()
+		waiting := len(gate.waiting[p])
+		gate.mu.Unlock()
+		if waiting >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("no %v chunk waiting", p)
+}
@@ -0,0 +1,88 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ModelProfile shapes requests for a family of models. Profiles are matched
+// by the longest model ID prefix.
+type ModelProfile struct {
+	// Temperature is sent with every request when set. Models that reject
+	// the parameter, such as OpenAI o-series, leave it unset.
+	Temperature *float64 `json:"temperature,omitempty"`
+	// IgnoresResultTag marks models that often answer without the <result>
+	// tag; their whole answer is used when the tag is missing.
+	IgnoresResultTag bool `json:"ignores_result_tag,omitempty"`
+	// JSONMode marks models that support response_format json_object.
+	JSONMode bool `json:"json_mode,omitempty"`
+	// Reasoning marks models that think out loud; their reasoning is
+	// excluded from the answer and any <think> block is stripped.
+	Reasoning bool `json:"reasoning,omitempty"`
+	// ContextLength is the number of tokens of prompt and answer together
+	// the model takes, and MaxOutputTokens the length of its longest
+	// answer; chunks are made small enough for both. 0 means unknown.
+	ContextLength   int `json:"context_length,omitempty"`
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+}
+
+func temperature(v float64) *float64 {
+	return &v
+}
+
+var builtinProfiles = map[string]ModelProfile{
+	"deepseek/deepseek-chat": {Temperature: temperature(1.3), ContextLength: 64000, MaxOutputTokens: 8192},
+	"deepseek/deepseek-r1":   {Temperature: temperature(0.6), Reasoning: true, ContextLength: 64000},
+	"openai/gpt-4o":          {Temperature: temperature(0.3), JSONMode: true, ContextLength: 128000, MaxOutputTokens: 16384},
+	"openai/gpt-4.1":         {Temperature: temperature(0.3), JSONMode: true, ContextLength: 1047576, MaxOutputTokens: 32768},
+	"openai/gpt-3.5-turbo":   {Temperature: temperature(0.3), JSONMode: true, ContextLength: 16385, MaxOutputTokens: 4096},
+	"openai/o1":              {Reasoning: true},
+	"openai/o3":              {Reasoning: true},
+	"openai/o4":              {Reasoning: true},
+	"anthropic/claude":       {Temperature: temperature(0.3), ContextLength: 200000, MaxOutputTokens: 8192},
+	"google/gemini":          {Temperature: temperature(0.3), JSONMode: true, ContextLength: 1000000, MaxOutputTokens: 8192},
+	"qwen/qwq":               {Temperature: temperature(0.6), Reasoning: true},
+	"qwen/qwen":              {Temperature: temperature(0.7), ContextLength: 32768, MaxOutputTokens: 8192},
+	"meta-llama/llama":       {Temperature: temperature(0.3), IgnoresResultTag: true},
+	"mistralai/":             {Temperature: temperature(0.3), IgnoresResultTag: true},
+	"google/gemma":           {Temperature: temperature(0.3), IgnoresResultTag: true},
+	"moonshotai/kimi":        {Temperature: temperature(0.6)},
+	"x-ai/grok":              {Temperature: temperature(0.3)},
+}
+
+var thinkBlockRe = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// LoadModelProfiles reads profile overrides from a JSON object keyed by model
+// ID prefix.
+func LoadModelProfiles(path string) (map[string]ModelProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model profiles: %w", err)
+	}
+
+	var profiles map[string]ModelProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse model profiles %s: %w", path, err)
+	}
+	return profiles, nil
+}
+
+// profileFor returns the profile for model, preferring user overrides from
+// Config.ModelProfiles over built-in profiles at equal prefix length.
+func (t *Translator) profileFor(model string) ModelProfile {
+	var best ModelProfile
+	bestLen := -1
+
+	for _, set := range []map[string]ModelProfile{builtinProfiles, t.config.ModelProfiles} {
+		for prefix, p := range set {
+			if strings.HasPrefix(model, prefix) && len(prefix) >= bestLen {
+				best, bestLen = p, len(prefix)
+			}
+		}
+	}
+
+	return best
+}
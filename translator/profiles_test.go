@@ -0,0 +1,50 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileFor(t *testing.T) {
+	translator := NewTranslator(Config{
+		ModelProfiles: map[string]ModelProfile{
+			"openai/gpt-4o":    {Temperature: temperature(0.1)},
+			"custom/local-llm": {IgnoresResultTag: true},
+		},
+	})
+
+	if p := translator.profileFor("deepseek/deepseek-r1:free"); !p.Reasoning || p.Temperature == nil || *p.Temperature != 0.6 {
+		t.Errorf("Unexpected profile for deepseek-r1: %+v", p)
+	}
+	if p := translator.profileFor("openai/gpt-4o-mini"); p.Temperature == nil || *p.Temperature != 0.1 || p.JSONMode {
+		t.Errorf("Expected user override for gpt-4o, got %+v", p)
+	}
+	if p := translator.profileFor("openai/o3-mini"); p.Temperature != nil {
+		t.Errorf("Expected no temperature for o3, got %v", *p.Temperature)
+	}
+	if p := translator.profileFor("custom/local-llm-7b"); !p.IgnoresResultTag {
+		t.Errorf("Expected custom profile, got %+v", p)
+	}
+	if p := translator.profileFor("unknown/model"); p != (ModelProfile{}) {
+		t.Errorf("Expected empty profile for unknown model, got %+v", p)
+	}
+}
+
+func TestLoadModelProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	data := `{"deepseek/": {"temperature": 0.2, "ignores_result_tag": true}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := LoadModelProfiles(path)
+	if err != nil {
+		t.Fatalf("LoadModelProfiles failed: %v", err)
+	}
+
+	p := profiles["deepseek/"]
+	if p.Temperature == nil || *p.Temperature != 0.2 || !p.IgnoresResultTag {
+		t.Errorf("Unexpected profile: %+v", p)
+	}
+}
@@ -0,0 +1,50 @@
+package translator
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProgressEvent reports a step of a run: "start", "split", "chunk_start",
+// "chunk_cached", "chunk_delta", "chunk_done", "retry", "warning", "batch", "speech", "error" or "done".
+type ProgressEvent struct {
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	Run     string    `json:"run,omitempty"`
+	Input   string    `json:"input,omitempty"`
+	Output  string    `json:"output,omitempty"`
+	Chunk   int       `json:"chunk,omitempty"`
+	Chunks  int       `json:"chunks,omitempty"`
+	Attempt int       `json:"attempt,omitempty"`
+	Bytes   int       `json:"bytes,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Batch   string    `json:"batch,omitempty"`
+	Status  string    `json:"status,omitempty"`
+	Text    string    `json:"text,omitempty"`
+}
+
+// emit passes ev to Config.Progress and writes it as a single JSON line to
+// Config.ProgressOutput. Progress reporting is best effort and never fails
+// the translation.
+func (t *Translator) emit(ev ProgressEvent) {
+	if t.job != nil {
+		t.job.observe(ev)
+	}
+	if t.config.ProgressOutput == nil && t.config.Progress == nil {
+		return
+	}
+
+	ev.Time = time.Now().UTC()
+	ev.Run = t.runID
+
+	t.progressMu.Lock()
+	defer t.progressMu.Unlock()
+	if t.config.Progress != nil {
+		t.config.Progress(ev)
+	}
+	if t.config.ProgressOutput != nil {
+		if line, err := json.Marshal(ev); err == nil {
+			t.config.ProgressOutput.Write(append(line, '\n'))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProgressEvents(t *testing.T) {
+	var buf bytes.Buffer
+	translator := NewTranslator(Config{ChunkSize: 50, ProgressOutput: &buf})
+
+	dir := t.TempDir()
+	err := translator.TranslateFile(filepath.Join(dir, "missing.txt"), filepath.Join(dir, "out.txt"))
+	if err == nil {
+		t.Fatal("Expected error for missing input file")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 progress events, got %d: %q", len(lines), buf.String())
+	}
+
+	var events []ProgressEvent
+	for _, line := range lines {
+		var ev ProgressEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("Invalid JSON event %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+
+	if events[0].Event != "start" || events[1].Event != "error" {
+		t.Errorf("Expected start and error events, got %q and %q", events[0].Event, events[1].Event)
+	}
+	if events[1].Error == "" {
+		t.Error("Expected error event to carry the error message")
+	}
+}
+
+func TestProgressCallback(t *testing.T) {
+	var events []string
+	bytesWritten := 0
+	translator := NewTranslator(Config{
+		Provider:  slowUpperProvider{},
+		ChunkSize: 5,
+		NoDelay:   true,
+		Progress: func(ev ProgressEvent) {
+			events = append(events, ev.Event)
+			if ev.Event == "chunk_done" {
+				bytesWritten += ev.Bytes
+			}
+			if ev.Run == "" {
+				t.Errorf("Expected event %q to carry the run ID", ev.Event)
+			}
+		},
+	})
+
+	out, err := translator.TranslateText(context.Background(), "First paragraph.\n\nSecond paragraph.\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "start split chunk_start chunk_done chunk_start chunk_done done"
+	if got := strings.Join(events, " "); got != expected {
+		t.Errorf("Expected events %q, got %q", expected, got)
+	}
+	if bytesWritten != len(out) {
+		t.Errorf("Expected chunk_done events to add up to %d bytes, got %d", len(out), bytesWritten)
+	}
+}
+
+func TestConfigWithProgressRoundTrip(t *testing.T) {
+	// Queued jobs store their Config as JSON and read it back.
+	config := Config{ToLang: "german", Progress: func(ev ProgressEvent) {}}
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("Expected the config to marshal, got %v", err)
+	}
+	var decoded Config
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected the config to unmarshal, got %v", err)
+	}
+	if decoded.ToLang != "german" || decoded.Progress != nil {
+		t.Errorf("Expected the config back without its callback, got %+v", decoded)
+	}
+}
@@ -0,0 +1,46 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type echoProvider struct{}
+
+func (echoProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	text := req.Prompt[strings.Index(req.Prompt, ":\n\n")+3:]
+	return &Completion{Text: "<result>" + text + "</result>"}, nil
+}
+
+func TestRunPromptTests(t *testing.T) {
+	config := Config{Provider: echoProvider{}, ChunkSize: 500, NoDelay: true, NoSanityCheck: true}
+	for _, r := range RunPromptTests(context.Background(), config, PromptTests) {
+		if !r.Passed() {
+			t.Errorf("Expected %s to pass with an echoing model, got %v %v", r.Name, r.Err, r.Problems)
+		}
+	}
+
+	results := RunPromptTests(context.Background(), Config{Provider: slowUpperProvider{}, ChunkSize: 500, NoDelay: true, NoSanityCheck: true, Retry: RetryPolicy{Backoff: time.Millisecond}}, PromptTests)
+	failed := map[string]bool{}
+	for _, r := range results {
+		failed[r.Name] = !r.Passed()
+	}
+	for _, name := range []string{"code-block", "placeholders", "list-and-links"} {
+		if !failed[name] {
+			t.Errorf("Expected %s to fail when the model upper-cases everything", name)
+		}
+	}
+}
+
+func TestPromptTestProblems(t *testing.T) {
+	source := "| a | b |\n|---|---|\n| `x` | y |\n"
+	if p := promptTestProblems(source, "| а | б |\n|---|---|\n| `x` | у |\n"); len(p) != 0 {
+		t.Errorf("Expected no problems, got %v", p)
+	}
+	p := promptTestProblems(source, "<result>| а | б |\n|---|---|\n| `y` | у | в |\n")
+	if len(p) != 3 {
+		t.Errorf("Expected a leaked tag, a changed code span and a changed table, got %v", p)
+	}
+}
@@ -0,0 +1,24 @@
+package translator
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// protectPatterns compiles Config.ProtectPatterns. A pattern that matches
+// the empty string would protect nothing but litter the text with markers,
+// so it is refused.
+func protectPatterns(exprs []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protect regex: %w", err)
+		}
+		if re.MatchString("") {
+			return nil, fmt.Errorf("protect regex %q matches empty text", expr)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
@@ -0,0 +1,46 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProtectPatterns(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "notes.txt")
+	out := filepath.Join(dir, "notes.de.txt")
+	os.WriteFile(in, []byte("See JIRA-1234 at https://example.com/docs and open /etc/hosts.\n\nAcme Cloud is fast.\n"), 0644)
+
+	provider := &promptRecorder{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "german",
+		ProtectPatterns: []string{`https?://\S+[^\s.]`, `\b[A-Z]+-\d+\b`, `/etc/\w+`, `Acme Cloud`}})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	for _, prompt := range provider.prompts {
+		for _, kept := range []string{"JIRA-1234", "example.com", "/etc/hosts", "Acme"} {
+			if strings.Contains(prompt, kept) {
+				t.Errorf("Expected %s kept from the model, got %q", kept, prompt)
+			}
+		}
+	}
+	want := "SEE JIRA-1234 AT https://example.com/docs AND OPEN /etc/hosts.\n\nAcme Cloud IS FAST.\n"
+	if got, _ := os.ReadFile(out); string(got) != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestProtectPatternsValidation(t *testing.T) {
+	config := Config{ChunkSize: MinChunkSize, ToLang: "de", Backend: BackendOllama, Model: "llama3.1", ProtectPatterns: []string{`\bACME\b`}}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, expr := range []string{`(unclosed`, `x*`} {
+		config.ProtectPatterns = []string{expr}
+		if err := config.Validate(); ErrorClass(err) != ErrorClassConfig {
+			t.Errorf("%s: expected a config error, got %v", expr, err)
+		}
+	}
+}
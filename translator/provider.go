@@ -0,0 +1,230 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Provider sends a prompt to a language model and returns its answer. The
+// translator builds the prompt, retries, validates and extracts the
+// translation; a Provider only talks to the backend. Errors should be wrapped
+// with ClassifyError so the retry policy can tell network failures, API
+// errors and rate limits apart.
+type Provider interface {
+	Complete(ctx context.Context, req CompletionRequest) (*Completion, error)
+}
+
+// CompletionRequest is a single-turn prompt for Provider.Complete.
+type CompletionRequest struct {
+	Model string
+	// System holds the instructions and Prompt the user message with the
+	// chunk. Backends without system messages should send both joined by a
+	// blank line.
+	System string
+	Prompt string
+	// Text is the chunk the prompt asks to translate into ToLang, for
+	// machine translation backends that take no prompt.
+	Text        string
+	ToLang      string
+	Temperature *float64
+	// Seed is set on retries after unusable answers, so the model samples
+	// differently.
+	Seed *int
+	// TopP and MaxTokens are sent when set, see Config.
+	TopP      *float64
+	MaxTokens int
+	// ExcludeReasoning asks reasoning models to leave their reasoning out
+	// of the answer, where the backend supports it.
+	ExcludeReasoning bool
+	// JSON asks for the answer as a JSON object, where the backend
+	// supports it; the prompt says which.
+	JSON bool
+	// RunID and ChunkID identify the request; providers pass them on as
+	// headers or metadata where the backend supports it, so a failed
+	// request can be found in the provider's logs.
+	RunID   string
+	ChunkID string
+	// Delta, when set, asks for a streamed answer and receives its text
+	// piece by piece as it arrives. Providers that cannot stream ignore it.
+	Delta func(text string)
+	// Images are URLs of images that belong with the prompt, such as a
+	// screenshot of a UI string; backends without image input ignore them.
+	Images []string
+}
+
+// Completion is a model answer with the usage the backend reported, if any.
+type Completion struct {
+	Text string
+	// Plain marks an answer that is the translation itself rather than a
+	// model answer with a <result> tag, as machine translation backends
+	// return it.
+	Plain            bool
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+}
+
+// chatMessages is the conversation of a chat completions request.
+func chatMessages(cr CompletionRequest) []Message {
+	messages := []Message{{Role: "user", Content: cr.Prompt}}
+	if cr.System != "" {
+		messages = append([]Message{{Role: "system", Content: cr.System}}, messages...)
+	}
+	return messages
+}
+
+// ClassifyError tags err with one of the ErrorClass constants, for use by
+// Provider implementations.
+func ClassifyError(class string, err error) error {
+	return classify(class, err)
+}
+
+// openRouterProvider is the default Provider, talking to the OpenRouter
+// compatible chat completions endpoint at Config.BaseURL.
+type openRouterProvider struct {
+	t *Translator
+}
+
+func (p openRouterProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	t := p.t
+	model := cr.Model
+
+	req, requestBody, err := p.newRequest(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+	url := req.URL.String()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if auditErr := t.recordRequest(url, model, cr.ChunkID, requestBody, 0, nil, err); auditErr != nil {
+			return nil, auditErr
+		}
+		if ctx.Err() != nil {
+			return nil, canceled(ctx.Err())
+		}
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if cr.Delta != nil && resp.StatusCode == http.StatusOK {
+		completion, raw, err := readStream(resp.Body, cr.Delta)
+		if auditErr := t.recordRequest(url, model, cr.ChunkID, requestBody, resp.StatusCode, raw, err); auditErr != nil {
+			return nil, auditErr
+		}
+		if err != nil && ctx.Err() != nil {
+			return nil, canceled(ctx.Err())
+		}
+		return completion, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if auditErr := t.recordRequest(url, model, cr.ChunkID, requestBody, resp.StatusCode, body, err); auditErr != nil {
+		return nil, auditErr
+	}
+	if err != nil {
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to read response: %w", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errorMsg := fmt.Sprintf("API request failed with status %d: %s", resp.StatusCode, string(body))
+
+		var errorResponse struct {
+			Error struct {
+				Message string `json:"message"`
+				Type    string `json:"type"`
+				Code    string `json:"code"`
+			} `json:"error"`
+		}
+
+		if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse.Error.Message != "" {
+			errorMsg = fmt.Sprintf("API request failed: %s (Type: %s, Code: %s)",
+				errorResponse.Error.Message,
+				errorResponse.Error.Type,
+				errorResponse.Error.Code)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, rateLimited(resp, fmt.Errorf("%s", errorMsg))
+		}
+		return nil, classify(ErrorClassAPI, fmt.Errorf("%s", errorMsg))
+	}
+
+	var response OpenRouterResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("failed to unmarshal response: %w", err))
+	}
+
+	if response.Error != nil {
+		errorMsg := fmt.Sprintf("API error: %s", response.Error.Message)
+
+		if t.config.Verbose {
+			fmt.Printf("API error details: %s\n", errorMsg)
+			fmt.Printf("Request body: %s\n", string(requestBody))
+		}
+
+		return nil, classify(ErrorClassAPI, fmt.Errorf("%s", errorMsg))
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("no translation returned from API"))
+	}
+
+	completion := &Completion{Text: response.Choices[0].Message.Content}
+	if response.Usage != nil {
+		completion.PromptTokens = response.Usage.PromptTokens
+		completion.CompletionTokens = response.Usage.CompletionTokens
+		completion.Cost = response.Usage.Cost
+	}
+	return completion, nil
+}
+
+// newRequest builds the chat completions request of cr.
+func (p openRouterProvider) newRequest(ctx context.Context, cr CompletionRequest) (*http.Request, []byte, error) {
+	t := p.t
+	messages := chatMessages(cr)
+	messages[len(messages)-1].Images = cr.Images
+	request := OpenRouterRequest{
+		Model:       cr.Model,
+		Messages:    messages,
+		Temperature: cr.Temperature,
+		Seed:        cr.Seed,
+		TopP:        cr.TopP,
+		MaxTokens:   cr.MaxTokens,
+		Usage:       &UsageRequest{Include: true},
+		Stream:      cr.Delta != nil,
+	}
+
+	if cr.JSON {
+		request.ResponseFormat = &ResponseFormat{Type: "json_object"}
+	}
+	if cr.ExcludeReasoning {
+		request.Reasoning = &ReasoningRequest{Exclude: true}
+	}
+
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.baseURL()+"/chat/completions", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.config.APIKey)
+	if !t.config.NoExternalMetadata {
+		req.Header.Set("HTTP-Referer", "https://github.com/hightemp/go_ai_translate")
+		req.Header.Set("X-Title", "Go AI Translate")
+		if cr.ChunkID != "" {
+			req.Header.Set("X-Request-Id", cr.ChunkID)
+			req.Header.Set("X-Run-Id", cr.RunID)
+		}
+	}
+	return req, requestBody, nil
+}
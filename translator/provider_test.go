@@ -0,0 +1,83 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// upperProvider "translates" by upper-casing the chunk, failing the first
+// call with a retryable error.
+type upperProvider struct {
+	calls int
+}
+
+func (p *upperProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	p.calls++
+	if p.calls == 1 {
+		return nil, ClassifyError(ErrorClassNetwork, errors.New("connection reset"))
+	}
+	text := req.Prompt[strings.Index(req.Prompt, ":\n\n")+3:]
+	return &Completion{Text: "<result>" + strings.ToUpper(text) + "</result>", PromptTokens: 3}, nil
+}
+
+func TestCustomProvider(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	os.WriteFile(in, []byte("hello world\n"), 0644)
+
+	provider := &upperProvider{}
+	translator := NewTranslator(Config{
+		Provider:   provider,
+		ChunkSize:  100,
+		NoDelay:    true,
+		Retry:      RetryPolicy{Backoff: 1},
+		MaxRetries: 2,
+	})
+	out := filepath.Join(dir, "out.txt")
+	if err := translator.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := os.ReadFile(out); string(got) != "HELLO WORLD\n" {
+		t.Errorf("Expected the provider's answer, got %q", got)
+	}
+	if provider.calls != 2 || translator.Result().PromptTokens != 3 {
+		t.Errorf("Expected one retry and usage from the provider, got %d calls, %+v", provider.calls, translator.Result())
+	}
+}
+
+func TestSystemMessage(t *testing.T) {
+	var messages []Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		messages = req.Messages
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"<result>Hallo</result>"}}]}`)
+	}))
+	defer server.Close()
+
+	tr := NewTranslator(Config{BaseURL: server.URL, ToLang: "german", ChunkSize: 100, NoDelay: true,
+		SystemPrompt: "You translate a children's book into {to}. Keep the tone playful."})
+	if _, err := tr.TranslateText(context.Background(), "Hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 2 || messages[0].Role != "system" || messages[1].Role != "user" {
+		t.Fatalf("Expected a system and a user message, got %+v", messages)
+	}
+	if !strings.HasPrefix(messages[0].Content, "You translate a children's book into german. Keep the tone playful.\n\n") ||
+		!strings.Contains(messages[0].Content, "<result>") {
+		t.Errorf("Expected the custom prompt ahead of the instructions, got %q", messages[0].Content)
+	}
+	if messages[1].Content != "Text to translate:\n\nHello" {
+		t.Errorf("Expected the chunk alone in the user message, got %q", messages[1].Content)
+	}
+}
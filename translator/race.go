@@ -0,0 +1,44 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+)
+
+type raceOutcome struct {
+	model string
+	text  string
+	err   error
+}
+
+// raceChunk sends text to the active model and to Config.RaceModel at the
+// same time and returns the first valid translation, cancelling the slower
+// request. It fails only when both requests fail.
+func (t *Translator) raceChunk(ctx context.Context, text string, pc promptContext) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	models := []string{t.modelFor(pc), t.config.RaceModel}
+	outcomes := make(chan raceOutcome, len(models))
+	for _, model := range models {
+		go func(model string) {
+			translated, err := t.hedgedRequest(ctx, model, text, pc)
+			outcomes <- raceOutcome{model: model, text: translated, err: err}
+		}(model)
+	}
+
+	var errs []error
+	for range models {
+		o := <-outcomes
+		if o.err == nil {
+			if t.config.Verbose {
+				fmt.Printf("Model %s answered first\n", o.model)
+			}
+			return o.text, nil
+		}
+		errs = append(errs, o.err)
+	}
+
+	// The class of the first failure drives retry and fallback decisions.
+	return "", classify(ErrorClass(errs[0]), fmt.Errorf("all raced models failed: %v; %v", errs[0], errs[1]))
+}
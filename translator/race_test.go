@@ -0,0 +1,57 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRaceChunk(t *testing.T) {
+	cancelled := make(chan struct{}, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch req.Model {
+		case "slow/model":
+			select {
+			case <-r.Context().Done():
+				cancelled <- struct{}{}
+			case <-time.After(5 * time.Second):
+			}
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"<result>slow</result>"}}]}`)
+		case "broken/model":
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"no tag"}}]}`)
+		default:
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"<result>fast</result>"}}]}`)
+		}
+	}))
+	defer server.Close()
+
+	translator := NewTranslator(Config{BaseURL: server.URL, Model: "slow/model", RaceModel: "fast/model"})
+
+	start := time.Now()
+	got, err := translator.translateChunk(context.Background(), "text", promptContext{})
+	if err != nil || got != "fast" {
+		t.Fatalf("Expected fast model to win, got %q, %v", got, err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("Race waited for the slow model")
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the slow request to be cancelled")
+	}
+
+	translator = NewTranslator(Config{BaseURL: server.URL, Model: "broken/model", RaceModel: "fast/model"})
+	if got, err := translator.translateChunk(context.Background(), "text", promptContext{}); err != nil || got != "fast" {
+		t.Errorf("Expected the valid answer to win over an invalid one, got %q, %v", got, err)
+	}
+}
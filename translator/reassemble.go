@@ -0,0 +1,41 @@
+package translator
+
+import "strings"
+
+// chunkSeparators returns the text the splitter left out after each chunk
+// of text: the line breaks between paragraphs or lines at a chunk boundary,
+// and whatever follows the last chunk, so that every chunk followed by its
+// separator adds up to text byte for byte. Whitespace before the first chunk
+// is moved into it. It returns false when the chunks are not the pieces of
+// text in order.
+func chunkSeparators(text string, chunks []string) ([]string, bool) {
+	separators := make([]string, len(chunks))
+	pos := 0
+	for i, chunk := range chunks {
+		start := strings.Index(text[pos:], chunk)
+		if start < 0 || strings.TrimSpace(text[pos:pos+start]) != "" {
+			return nil, false
+		}
+		if i == 0 {
+			chunks[0] = text[:start] + chunk
+		} else {
+			separators[i-1] = text[pos : pos+start]
+		}
+		pos += start + len(chunk)
+	}
+	if len(chunks) > 0 {
+		separators[len(chunks)-1] = text[pos:]
+	}
+	return separators, strings.TrimSpace(text[pos:]) == ""
+}
+
+// keepEdges gives translation the leading and trailing whitespace of the
+// chunk it translates, which models tend to drop or add to.
+func keepEdges(chunk, translation string) string {
+	core := strings.TrimSpace(chunk)
+	if core == "" {
+		return translation
+	}
+	start := strings.Index(chunk, core)
+	return chunk[:start] + strings.TrimSpace(translation) + chunk[start+len(core):]
+}
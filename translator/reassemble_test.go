@@ -0,0 +1,72 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestChunkSeparators(t *testing.T) {
+	texts := []string{
+		"\n\nLeading blank lines. Then a second sentence that is long enough.\n\nAnd a paragraph.\n",
+		"One paragraph here.\n\n\n\nFour line breaks before this one.\nA second line.\nA third line.\n\n\n",
+		"Sentence one is here. Sentence two is here. Sentence three is here. Sentence four.",
+		"# Title\n\nIntro text of the section.\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n## Next\n\nMore text here.\n\n\n",
+	}
+	for _, chunking := range []string{ChunkingParagraph, ChunkingMarkdown} {
+		tr := NewTranslator(Config{ChunkSize: 8, Chunking: chunking})
+		for _, text := range texts {
+			chunks := tr.splitIntoChunks(context.Background(), text)
+			if chunking == ChunkingMarkdown {
+				chunks = tr.splitMarkdown(context.Background(), text)
+			}
+			separators, ok := chunkSeparators(text, chunks)
+			if !ok || len(chunks) < 2 {
+				t.Fatalf("%s %q: expected separators of several chunks, got %q", chunking, text, chunks)
+			}
+			var joined strings.Builder
+			for i := range chunks {
+				joined.WriteString(chunks[i] + separators[i])
+			}
+			if joined.String() != text {
+				t.Errorf("%s: expected %q back, got %q", chunking, text, joined.String())
+			}
+		}
+	}
+
+	if _, ok := chunkSeparators("a b c", []string{"a", "c"}); ok {
+		t.Error("Expected chunks with text left out between them to be rejected")
+	}
+}
+
+func TestKeepEdges(t *testing.T) {
+	testCases := []struct{ chunk, translation, expected string }{
+		{"Hello.\n", "Hallo.", "Hallo.\n"},
+		{"Hello.", "Hallo.\n\n", "Hallo."},
+		{"\n  Hello.\n\n", " Hallo.\n", "\n  Hallo.\n\n"},
+		{"\n", "", ""},
+	}
+	for _, tc := range testCases {
+		if got := keepEdges(tc.chunk, tc.translation); got != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.chunk, tc.expected, got)
+		}
+	}
+}
+
+func TestLosslessReassembly(t *testing.T) {
+	// promptRecorder upper-cases, so upper-case text comes back unchanged.
+	source := "\n\nFIRST PARAGRAPH. IT HAS TWO SENTENCES.\n\n\nSECOND ONE,\nOVER TWO LINES. \n\nLAST"
+	for _, chunking := range []string{ChunkingParagraph, ChunkingMarkdown} {
+		tr := NewTranslator(Config{Provider: &promptRecorder{}, ChunkSize: 8, NoDelay: true, Chunking: chunking, NoSanityCheck: true})
+		out, err := tr.TranslateText(context.Background(), source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != source {
+			t.Errorf("%s: expected the source back byte for byte, got %q", chunking, out)
+		}
+		if tr.Result().Chunks < 3 {
+			t.Errorf("%s: expected several chunks, got %d", chunking, tr.Result().Chunks)
+		}
+	}
+}
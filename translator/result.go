@@ -0,0 +1,135 @@
+package translator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Result summarizes the last TranslateFile run.
+type Result struct {
+	RunID            string    `json:"run_id"`
+	Input            string    `json:"input"`
+	Output           string    `json:"output"`
+	Format           string    `json:"format"`
+	Model            string    `json:"model"`
+	Chunks           int       `json:"chunks"`
+	ChunksTranslated int       `json:"chunks_translated"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Cost             float64   `json:"cost"`
+	Estimate         *Estimate `json:"estimate,omitempty"`
+	Warnings         []Warning `json:"warnings,omitempty"`
+	Segments         []Segment `json:"-"`
+	// SourceLang is Config.FromLang or the detected source language.
+	SourceLang string `json:"source_lang,omitempty"`
+	// ChunksCached counts the chunks taken from Config.Cache.
+	ChunksCached int `json:"chunks_cached,omitempty"`
+	// Inputs is set in deterministic mode, see Config.Deterministic.
+	Inputs *RunInputs `json:"inputs,omitempty"`
+	// Audio lists the MP3 files written, see Config.SpeechModel.
+	Audio []string `json:"audio,omitempty"`
+	// Checkpoint resumes a run that stopped with ErrorClassPaused.
+	Checkpoint *PreparedFile `json:"-"`
+}
+
+const (
+	WarningBrokenPlaceholders = "broken-placeholders"
+	WarningDroppedParagraphs  = "dropped-paragraphs"
+	WarningModelFallback      = "model-fallback"
+	WarningGlossaryTerm       = "glossary-term"
+	WarningICUSyntax          = "icu-syntax"
+	WarningTooLong            = "too-long"
+	WarningImageText          = "image-text"
+	WarningSummary            = "summary"
+	WarningPostCommand        = "post-command"
+	WarningPostEdit           = "post-edit"
+	WarningSuspectOutput      = "suspect-output"
+	WarningResultTag          = "result-tag"
+	WarningChunkSize          = "chunk-size"
+)
+
+// Warning is a validation problem found in a translated chunk. Line is the
+// 1-based line of the output file where the chunk starts.
+type Warning struct {
+	Kind    string `json:"kind"`
+	Chunk   int    `json:"chunk"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (t *Translator) Result() Result {
+	return t.result
+}
+
+func (t *Translator) warn(w Warning) {
+	t.mu.Lock()
+	t.result.Warnings = append(t.result.Warnings, w)
+	t.mu.Unlock()
+
+	if t.config.Verbose {
+		fmt.Printf("Warning: chunk %d: %s\n", w.Chunk, w.Message)
+	}
+	t.emit(ProgressEvent{Event: "warning", Chunk: w.Chunk, Error: w.Message})
+}
+
+// validateChunk compares a translated chunk with its source and records a
+// warning for every structural problem found.
+func (t *Translator) validateChunk(chunk, line int, source, translation string) {
+	if missing := missingMarkers(source, translation); missing > 0 {
+		t.warn(Warning{
+			Kind:    WarningBrokenPlaceholders,
+			Chunk:   chunk,
+			Line:    line,
+			Message: fmt.Sprintf("%d protected spans were dropped or altered by the model", missing),
+		})
+	}
+
+	if broken := missingPlaceholders(source, translation); len(broken) > 0 {
+		t.warn(Warning{
+			Kind:    WarningBrokenPlaceholders,
+			Chunk:   chunk,
+			Line:    line,
+			Message: fmt.Sprintf("placeholders %s were dropped or altered by the model", strings.Join(broken, " ")),
+		})
+	}
+
+	if want, got := countParagraphs(source), countParagraphs(translation); got < want {
+		t.warn(Warning{
+			Kind:    WarningDroppedParagraphs,
+			Chunk:   chunk,
+			Line:    line,
+			Message: fmt.Sprintf("translation has %d paragraphs, source has %d", got, want),
+		})
+	}
+
+	for _, term := range t.missingTerms(source, translation) {
+		t.warn(Warning{
+			Kind:    WarningGlossaryTerm,
+			Chunk:   chunk,
+			Line:    line,
+			Message: fmt.Sprintf("%q is not translated as %q", term, t.config.Glossary[term]),
+		})
+	}
+}
+
+// missingMarkers counts protection markers of source that are absent from
+// translation.
+func missingMarkers(source, translation string) int {
+	missing := 0
+	for _, token := range maskTokenRe.FindAllString(source, -1) {
+		if !strings.Contains(translation, token) {
+			missing++
+		}
+	}
+	return missing
+}
+
+func countParagraphs(text string) int {
+	count := 0
+	for _, p := range strings.Split(text, "\n\n") {
+		if strings.TrimSpace(p) != "" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,47 @@
+package translator
+
+import "testing"
+
+func TestValidateChunk(t *testing.T) {
+	testCases := []struct {
+		name        string
+		source      string
+		translation string
+		kinds       []string
+	}{
+		{
+			name:        "Valid",
+			source:      "First ⟦0⟧.\n\nSecond.",
+			translation: "Первый ⟦0⟧.\n\nВторой.",
+		},
+		{
+			name:        "Dropped marker",
+			source:      "Run ⟦0⟧ and ⟦1⟧.",
+			translation: "Запустите ⟦0⟧.",
+			kinds:       []string{WarningBrokenPlaceholders},
+		},
+		{
+			name:        "Merged paragraphs",
+			source:      "First.\n\nSecond.\n\nThird.",
+			translation: "Первый. Второй.\n\nТретий.",
+			kinds:       []string{WarningDroppedParagraphs},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			translator := NewTranslator(Config{})
+			translator.validateChunk(1, 10, tc.source, tc.translation)
+
+			warnings := translator.Result().Warnings
+			if len(warnings) != len(tc.kinds) {
+				t.Fatalf("Expected %d warnings, got %+v", len(tc.kinds), warnings)
+			}
+			for i, w := range warnings {
+				if w.Kind != tc.kinds[i] || w.Chunk != 1 || w.Line != 10 {
+					t.Errorf("Unexpected warning %+v", w)
+				}
+			}
+		})
+	}
+}
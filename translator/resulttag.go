@@ -0,0 +1,75 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// missingTagError is the error of an answer without the <result> tag. It
+// keeps the answer, which often is the translation with some commentary,
+// for salvageAnswer.
+type missingTagError struct {
+	answer string
+	// structured marks an answer that should have been a JSON object, see
+	// extractStructured; it is not salvaged.
+	structured bool
+	// delimiter is the one the answer was asked for with.
+	delimiter delimiter
+}
+
+func (e *missingTagError) Error() string {
+	switch {
+	case e.structured:
+		return "no JSON object with a translation found"
+	case e.delimiter != delimiterTag:
+		return fmt.Sprintf("no %s found", e.delimiter)
+	}
+	return "tag <result> not found"
+}
+
+// Commentary models put around a translation they forgot to tag: a short
+// first paragraph such as "Here is the translation:" and a last one such as
+// "Note: ...".
+var (
+	leadingCommentaryRe  = regexp.MustCompile(`(?i)^(?:(?:(?:sure|certainly|of course|okay)\b|here(?:'s| is| are)\b)[^\n]*\n|[^\n]{0,80}(?:translation|translated|перевод|übersetzung|traduction|traducción)[^\n]*:\s*(?:\n|$))`)
+	trailingCommentaryRe = regexp.MustCompile(`(?is)\n\s*\(?(?:note|notes|translator's note|please note)\b[^\n]*\)?\s*$`)
+	wrappingFenceRe      = regexp.MustCompile("(?s)^```[\\w-]*\\n(.*)\\n```$")
+)
+
+// salvageAnswer recovers the translation from an answer that lacks the
+// <result> tag: the text after an unclosed <result> or before a lone
+// </result>, or else the answer without the commentary around it. It
+// returns false when nothing is left.
+func salvageAnswer(answer string) (string, bool) {
+	answer = thinkBlockRe.ReplaceAllString(answer, "")
+	answer = sentinelLineRe.ReplaceAllString(answer, "")
+	if i := strings.Index(answer, "<result>"); i >= 0 {
+		answer = answer[i+len("<result>"):]
+	} else if i := strings.Index(answer, "</result>"); i >= 0 {
+		answer = answer[:i]
+	} else {
+		answer = strings.TrimSpace(answer)
+		answer = leadingCommentaryRe.ReplaceAllString(answer, "")
+		answer = trailingCommentaryRe.ReplaceAllString(answer, "")
+		answer = wrappingFenceRe.ReplaceAllString(strings.TrimSpace(answer), "$1")
+	}
+	answer = strings.TrimSpace(answer)
+	return answer, answer != ""
+}
+
+func isMissingTag(err error) bool {
+	var tagErr *missingTagError
+	return errors.As(err, &tagErr)
+}
+
+// salvage returns the recovered translation of the answer err was given
+// for, when err is a missingTagError of the <result> tag.
+func salvage(err error) (string, bool) {
+	var tagErr *missingTagError
+	if !errors.As(err, &tagErr) || tagErr.structured {
+		return "", false
+	}
+	return salvageAnswer(tagErr.answer)
+}
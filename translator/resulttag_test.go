@@ -0,0 +1,77 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSalvageAnswer(t *testing.T) {
+	testCases := []struct{ answer, expected string }{
+		{"Here is the translation:\n\nHallo Welt.", "Hallo Welt."},
+		{"Sure! The German version follows.\nHallo Welt.\n\nNote: \"Welt\" can also mean \"earth\".", "Hallo Welt."},
+		{"Перевод на русский:\nПривет, мир.", "Привет, мир."},
+		{"<result>Hallo Welt.", "Hallo Welt."},
+		{"Hallo Welt.</result>", "Hallo Welt."},
+		{"```\nHallo Welt.\n```", "Hallo Welt."},
+		{"<think>Easy.</think>Hallo Welt.", "Hallo Welt."},
+		{"Zutaten:\nMehl", "Zutaten:\nMehl"},
+	}
+	for _, tc := range testCases {
+		if got, ok := salvageAnswer(tc.answer); !ok || got != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.answer, tc.expected, got)
+		}
+	}
+	if _, ok := salvageAnswer("Here is the translation:\n"); ok {
+		t.Error("Expected nothing to salvage from commentary alone")
+	}
+}
+
+// untaggedProvider answers without the <result> tag until the instructions
+// insist on it, or always when stubborn.
+type untaggedProvider struct {
+	mu       sync.Mutex
+	stubborn bool
+	systems  []string
+}
+
+func (p *untaggedProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.systems = append(p.systems, cr.System)
+	text := strings.ToUpper(cr.Prompt[strings.LastIndex(cr.Prompt, "Text to translate:\n\n")+20:])
+	if !p.stubborn && strings.Contains(cr.System, "had no <result> tag") {
+		return &Completion{Text: "<result>" + text + "</result>"}, nil
+	}
+	return &Completion{Text: "Here is the translation:\n\n" + text}, nil
+}
+
+func TestMissingResultTag(t *testing.T) {
+	provider := &untaggedProvider{}
+	config := Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "german",
+		Retry: RetryPolicy{Backoff: time.Millisecond}}
+	tr := NewTranslator(config)
+	out, err := tr.TranslateText(context.Background(), "Hello world.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "HELLO WORLD." || len(provider.systems) != 2 || len(tr.Result().Warnings) != 0 {
+		t.Errorf("Expected the stricter retry to succeed, got %q after %d requests, %+v", out, len(provider.systems), tr.Result().Warnings)
+	}
+
+	provider = &untaggedProvider{stubborn: true}
+	config.Provider = provider
+	tr = NewTranslator(config)
+	out, err = tr.TranslateText(context.Background(), "Hello world.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "HELLO WORLD." {
+		t.Errorf("Expected the salvaged answer, got %q", out)
+	}
+	if w := tr.Result().Warnings; len(w) != 1 || w[0].Kind != WarningResultTag {
+		t.Errorf("Expected a result-tag warning, got %+v", w)
+	}
+}
@@ -0,0 +1,140 @@
+package translator
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const defaultMaxRetries = 3
+
+// RetryPolicy is the retry budget of a single chunk. Each kind of failure has
+// its own number of retries, so a flaky connection does not use up the
+// retries meant for a model that forgets the <result> tag. Zero falls back
+// to Config.MaxRetries, a negative value disables retries of that kind.
+type RetryPolicy struct {
+	// Network covers failures to connect or to read the response.
+	Network int
+	// HTTP covers non-200 responses, rate limits and API errors.
+	HTTP int
+	// Extraction covers answers without a usable <result> tag.
+	Extraction int
+	// Backoff is the pause before the first retry; it doubles after each
+	// retry. Zero means 2s.
+	Backoff time.Duration
+	// MaxBackoff caps the doubled pause. Zero means 1m.
+	MaxBackoff time.Duration
+}
+
+func (t *Translator) retryPolicy() RetryPolicy {
+	p := t.config.Retry
+	fallback := t.config.MaxRetries
+	if fallback <= 0 {
+		fallback = defaultMaxRetries
+	}
+
+	for _, n := range []*int{&p.Network, &p.HTTP, &p.Extraction} {
+		if *n == 0 {
+			*n = fallback
+		}
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = 2 * time.Second
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = time.Minute
+	}
+	if p.MaxBackoff < p.Backoff {
+		p.MaxBackoff = p.Backoff
+	}
+	return p
+}
+
+// pause is the wait before retry number n, counted from 1, after err: the
+// Retry-After of a rate limit if the server sent one, otherwise Backoff
+// doubled n-1 times up to MaxBackoff, of which a random half is taken
+// off so that the workers of a run do not retry in lockstep.
+func (p RetryPolicy) pause(n int, err error) time.Duration {
+	if wait := retryAfter(err); wait > 0 {
+		return wait
+	}
+	backoff := p.Backoff
+	for i := 1; i < n && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// retryBudget tracks the retries a chunk has left.
+type retryBudget struct {
+	policy     RetryPolicy
+	network    int
+	http       int
+	extraction int
+}
+
+// allow reports whether err may be retried and, if so, uses up one retry of
+// its kind. Input, output and config errors are never retried.
+func (b *retryBudget) allow(err error) bool {
+	var used *int
+	var limit int
+	switch ErrorClass(err) {
+	case ErrorClassNetwork:
+		used, limit = &b.network, b.policy.Network
+	case ErrorClassAPI, ErrorClassRateLimit:
+		used, limit = &b.http, b.policy.HTTP
+	case ErrorClassExtraction:
+		used, limit = &b.extraction, b.policy.Extraction
+	default:
+		return false
+	}
+
+	if *used >= limit {
+		return false
+	}
+	*used++
+	return true
+}
+
+// sampling varies the request of a chunk whose earlier answers could not be
+// used: an identical request tends to fail identically, so every retry
+// halves the temperature and sends another seed. Models without a
+// temperature in their profile only get the seed.
+func sampling(temp *float64, variation int) (*float64, *int) {
+	if variation == 0 {
+		return temp, nil
+	}
+
+	seed := variation
+	if temp == nil {
+		return nil, &seed
+	}
+	lowered := *temp * math.Pow(0.5, float64(variation))
+	return &lowered, &seed
+}
+
+// generation picks the temperature and seed of a request: Config overrides
+// the model profile, retries vary both, see sampling, and deterministic mode
+// pins them.
+func (t *Translator) generation(profile ModelProfile, variation int) (*float64, *int) {
+	if t.config.Deterministic {
+		return deterministicSampling(profile, variation)
+	}
+
+	temp := profile.Temperature
+	if t.config.Temperature != nil {
+		temp = t.config.Temperature
+	}
+	temp, seed := sampling(temp, variation)
+	if base := t.config.Seed; base != nil {
+		s := *base
+		if seed != nil {
+			s += *seed
+		}
+		seed = &s
+	}
+	return temp, seed
+}
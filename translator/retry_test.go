@@ -0,0 +1,247 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudget(t *testing.T) {
+	tr := NewTranslator(Config{MaxRetries: 2, Retry: RetryPolicy{Extraction: -1, Network: 1}})
+	budget := retryBudget{policy: tr.retryPolicy()}
+
+	network := classify(ErrorClassNetwork, fmt.Errorf("connection reset"))
+	api := classify(ErrorClassRateLimit, fmt.Errorf("too many requests"))
+	extraction := classify(ErrorClassExtraction, fmt.Errorf("tag <result> not found"))
+
+	steps := []struct {
+		err      error
+		expected bool
+	}{
+		{network, true},
+		{network, false},
+		{api, true},
+		{api, true},
+		{api, false},
+		{extraction, false},
+		{classify(ErrorClassConfig, fmt.Errorf("bad")), false},
+	}
+	for i, s := range steps {
+		if got := budget.allow(s.err); got != s.expected {
+			t.Errorf("Step %d (%s): expected %v, got %v", i, ErrorClass(s.err), s.expected, got)
+		}
+	}
+}
+
+func TestRetriesDoNotMultiply(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	tr := NewTranslator(Config{
+		BaseURL:    server.URL,
+		MaxRetries: 3,
+		Retry:      RetryPolicy{Backoff: time.Millisecond},
+	})
+
+	_, err := tr.translateChunkWithRetries(context.Background(), 0, 1, "Hello", "Hello")
+	if ErrorClass(err) != ErrorClassAPI {
+		t.Fatalf("Expected API error, got %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("Expected 1 attempt and 3 retries, got %d requests", calls)
+	}
+}
+
+// flakyProvider answers without a usable translation until its answers run
+// out and records how each request was sampled.
+type flakyProvider struct {
+	answers      []string
+	temperatures []string
+	seeds        []int
+}
+
+func (p *flakyProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	temp, seed := "none", 0
+	if req.Temperature != nil {
+		temp = strconv.FormatFloat(*req.Temperature, 'f', -1, 64)
+	}
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	p.temperatures = append(p.temperatures, temp)
+	p.seeds = append(p.seeds, seed)
+
+	answer := p.answers[0]
+	if len(p.answers) > 1 {
+		p.answers = p.answers[1:]
+	}
+	return &Completion{Text: answer}, nil
+}
+
+func TestRetriesVarySampling(t *testing.T) {
+	provider := &flakyProvider{answers: []string{"no tag", "still no tag", "<result>Hallo</result>"}}
+	tr := NewTranslator(Config{Provider: provider, Model: "openai/gpt-4o", NoDelay: true, Retry: RetryPolicy{Backoff: time.Millisecond}})
+
+	if _, err := tr.translateChunkWithRetries(context.Background(), 0, 1, "Hello", "Hello"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(provider.temperatures, " "); got != "0.3 0.15 0.075" {
+		t.Errorf("Expected the temperature to drop on every retry, got %s", got)
+	}
+	if provider.seeds[0] != 0 || provider.seeds[1] == provider.seeds[2] {
+		t.Errorf("Expected no seed first and a new seed on every retry, got %v", provider.seeds)
+	}
+}
+
+func TestBrokenSpansAreRetried(t *testing.T) {
+	marker := maskOpen + "0" + maskClose
+	provider := &flakyProvider{answers: []string{"<result>Hallo</result>", "<result>Hallo " + marker + "</result>"}}
+	tr := NewTranslator(Config{Provider: provider, NoDelay: true, Retry: RetryPolicy{Backoff: time.Millisecond}})
+
+	translated, err := tr.translateChunkWithRetries(context.Background(), 0, 1, "Hello "+marker, "Hello")
+	if err != nil || translated != "Hallo "+marker || len(provider.seeds) != 2 {
+		t.Errorf("Expected a retry restoring the span, got %q after %d requests: %v", translated, len(provider.seeds), err)
+	}
+
+	provider = &flakyProvider{answers: []string{"<result>Hallo</result>"}}
+	tr = NewTranslator(Config{Provider: provider, NoDelay: true, Retry: RetryPolicy{Extraction: 1, Backoff: time.Millisecond}})
+	if translated, err := tr.translateChunkWithRetries(context.Background(), 0, 1, "Hello "+marker, "Hello"); err != nil || translated != "Hallo" {
+		t.Errorf("Expected the last answer to be kept once retries run out, got %q: %v", translated, err)
+	}
+}
+
+// outageProvider fails every request to its broken models.
+type outageProvider struct {
+	broken map[string]bool
+	models []string
+}
+
+func (p *outageProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	p.models = append(p.models, req.Model)
+	if p.broken[req.Model] {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("provider returned error"))
+	}
+	return &Completion{Text: "<result>Hallo</result>"}, nil
+}
+
+func TestFallbackModels(t *testing.T) {
+	provider := &outageProvider{broken: map[string]bool{"a": true, "b": true}}
+	tr := NewTranslator(Config{
+		Provider:       provider,
+		Model:          "a",
+		FallbackModels: []string{"b", "c"},
+		MaxRetries:     1,
+		NoDelay:        true,
+		Retry:          RetryPolicy{Backoff: time.Millisecond},
+	})
+
+	translated, err := tr.translateChunkWithRetries(context.Background(), 0, 1, "Hello", "Hello")
+	if err != nil || translated != "Hallo" {
+		t.Fatalf("Expected the last fallback model to translate, got %q: %v", translated, err)
+	}
+	if got := strings.Join(provider.models, " "); got != "a a b b c" {
+		t.Errorf("Expected every model to use up its retries in order, got %s", got)
+	}
+	warnings := tr.Result().Warnings
+	if len(warnings) != 2 || warnings[0].Kind != WarningModelFallback || warnings[0].Chunk != 1 {
+		t.Errorf("Expected two model fallback warnings, got %+v", warnings)
+	}
+
+	provider = &outageProvider{broken: map[string]bool{"a": true, "b": true}}
+	tr = NewTranslator(Config{Provider: provider, Model: "a", FallbackModels: []string{"b"}, MaxRetries: 1, NoDelay: true,
+		Retry: RetryPolicy{Backoff: time.Millisecond}})
+	if _, err := tr.translateChunkWithRetries(context.Background(), 0, 1, "Hello", "Hello"); ErrorClass(err) != ErrorClassAPI {
+		t.Errorf("Expected the last model's error once every model failed, got %v", err)
+	}
+}
+
+func TestGenerationParameters(t *testing.T) {
+	var requests []OpenRouterRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		if len(requests) == 1 {
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"no tag"}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"<result>Hallo</result>"}}]}`)
+	}))
+	defer server.Close()
+
+	temp, topP, seed := 0.8, 0.9, 42
+	tr := NewTranslator(Config{
+		BaseURL:     server.URL,
+		Model:       "openai/gpt-4o",
+		NoDelay:     true,
+		Retry:       RetryPolicy{Backoff: time.Millisecond},
+		Temperature: &temp,
+		TopP:        &topP,
+		MaxTokens:   2000,
+		Seed:        &seed,
+	})
+	if _, err := tr.translateChunkWithRetries(context.Background(), 0, 1, "Hello", "Hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	first, retry := requests[0], requests[1]
+	if *first.Temperature != 0.8 || *first.TopP != 0.9 || first.MaxTokens != 2000 || *first.Seed != 42 {
+		t.Errorf("Expected the configured parameters instead of the profile's, got %+v", first)
+	}
+	if *retry.Temperature != 0.4 || *retry.Seed != 43 {
+		t.Errorf("Expected the retry to lower the temperature and move the seed, got %v and %v", *retry.Temperature, *retry.Seed)
+	}
+}
+
+func TestRetryPause(t *testing.T) {
+	policy := NewTranslator(Config{Retry: RetryPolicy{Backoff: time.Second, MaxBackoff: 4 * time.Second}}).retryPolicy()
+	apiErr := classify(ErrorClassAPI, fmt.Errorf("bad gateway"))
+	for n, backoff := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second, 4 * time.Second} {
+		for i := 0; i < 20; i++ {
+			if pause := policy.pause(n+1, apiErr); pause < backoff/2 || pause > backoff {
+				t.Fatalf("Expected retry %d to wait between %s and %s, got %s", n+1, backoff/2, backoff, pause)
+			}
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	tr := NewTranslator(Config{BaseURL: server.URL})
+	_, err := tr.provider.Complete(context.Background(), tr.completionRequest("test/model", "Hello", promptContext{}))
+	if ErrorClass(err) != ErrorClassRateLimit {
+		t.Fatalf("Expected a rate limit, got %v", err)
+	}
+	if pause := policy.pause(1, err); pause != 7*time.Second {
+		t.Errorf("Expected the Retry-After of the server, got %s", pause)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"":                              0,
+		"30":                            30 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Wed, 01 May 2024 12:01:30 GMT": 90 * time.Second,
+		"Wed, 01 May 2024 11:00:00 GMT": 0,
+	} {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("Expected %q to mean %s, got %s", value, want, got)
+		}
+	}
+}
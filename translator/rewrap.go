@@ -0,0 +1,63 @@
+package translator
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// rewrap joins hard-wrapped lines, as in Project Gutenberg books wrapped at
+// 72 columns, into one line per paragraph. A line is joined with the next
+// when the next line's first word would not have fitted after it within the
+// wrap width, so short lines such as verse, headings and the ends of
+// paragraphs stay as they are. Indented lines and list items are never
+// joined onto the line before.
+func rewrap(text string) string {
+	lines := strings.Split(text, "\n")
+	width := wrapWidth(lines)
+	if width == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	for i, line := range lines {
+		switch {
+		case i == len(lines)-1:
+			b.WriteString(line)
+		case joinsNext(line, lines[i+1], width):
+			b.WriteString(strings.TrimRight(line, " \t\r") + " ")
+		default:
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+func joinsNext(line, next string, width int) bool {
+	line = strings.TrimRight(line, " \t\r")
+	if strings.TrimSpace(line) == "" || strings.TrimSpace(next) == "" {
+		return false
+	}
+	if next[0] == ' ' || next[0] == '\t' || listItemRe.MatchString(next) || headingRe.MatchString(next) {
+		return false
+	}
+	word := strings.Fields(next)[0]
+	return utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width
+}
+
+// wrapWidth guesses the column text was wrapped at: the length most lines
+// stay within, ignoring the longest few, which are often URLs or tables. It
+// returns 0 when there are too few lines to tell.
+func wrapWidth(lines []string) int {
+	var lengths []int
+	for _, line := range lines {
+		if n := utf8.RuneCountInString(strings.TrimRight(line, " \t\r")); n > 0 {
+			lengths = append(lengths, n)
+		}
+	}
+	if len(lengths) < 3 {
+		return 0
+	}
+	sort.Ints(lengths)
+	return lengths[len(lengths)*95/100]
+}
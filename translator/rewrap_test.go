@@ -0,0 +1,49 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const gutenbergText = `It is a truth universally acknowledged, that a single man in possession
+of a good fortune, must be in want of a wife.
+
+However little known the feelings or views of such a man may be on his
+first entering a neighbourhood, this truth is so well fixed in the minds
+of the surrounding families, that he is considered the rightful property
+of some one or other of their daughters.
+
+  The rain it raineth
+  every day.
+Short line.
+Another short one.
+`
+
+func TestRewrap(t *testing.T) {
+	want := "It is a truth universally acknowledged, that a single man in possession of a good fortune, must be in want of a wife.\n\n" +
+		"However little known the feelings or views of such a man may be on his first entering a neighbourhood, this truth is so well fixed in the minds of the surrounding families, that he is considered the rightful property of some one or other of their daughters.\n\n" +
+		"  The rain it raineth\n  every day.\nShort line.\nAnother short one.\n"
+	if got := rewrap(gutenbergText); got != want {
+		t.Errorf("Unexpected rewrapped text:\n%q\nwant:\n%q", got, want)
+	}
+
+	if got := rewrap("one\ntwo"); got != "one\ntwo" {
+		t.Errorf("Expected too short a text to stay as is, got %q", got)
+	}
+	crlf := strings.Replace(gutenbergText, "\n", "\r\n", -1)
+	if got := rewrap(crlf); !strings.Contains(got, "possession of a good") {
+		t.Errorf("Expected CRLF lines to be joined, got %q", got)
+	}
+}
+
+func TestRewrapBeforeChunking(t *testing.T) {
+	provider := &countingProvider{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 200, NoDelay: true, Rewrap: true})
+	if _, err := tr.TranslateText(context.Background(), gutenbergText); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.sent) != 1 || !strings.Contains(provider.sent[0], "in possession of a good fortune") {
+		t.Errorf("Expected the rewrapped text to be sent, got %q", provider.sent)
+	}
+}
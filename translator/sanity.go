@@ -0,0 +1,79 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Bounds of the length of a translation relative to its source, in
+// characters. Languages differ a lot: Chinese takes about a third of the
+// characters of English, German a third more.
+const (
+	minLengthRatio = 0.2
+	maxLengthRatio = 4.0
+	// sanityMinLength is the source length below which length ratios say
+	// nothing, as in "OK" and "In Ordnung".
+	sanityMinLength = 40
+	// leakMinWords is the length of a source sentence that is not a name
+	// or a term when the translation repeats it.
+	leakMinWords = 5
+)
+
+// sanityProblem reports a translation that cannot be right: empty, far
+// shorter or longer than its source, or repeating sentences of the source
+// untranslated. See Config.NoSanityCheck.
+func (t *Translator) sanityProblem(source, translation string) error {
+	if t.config.NoSanityCheck {
+		return nil
+	}
+	source = maskTokenRe.ReplaceAllString(source, " ")
+	translation = maskTokenRe.ReplaceAllString(translation, " ")
+	if strings.IndexFunc(source, unicode.IsLetter) < 0 {
+		return nil
+	}
+
+	n, sourceLen := textLength(translation), textLength(source)
+	if n == 0 {
+		return errors.New("the translation is empty")
+	}
+	if sourceLen >= sanityMinLength {
+		switch ratio := float64(n) / float64(sourceLen); {
+		case ratio < minLengthRatio:
+			return fmt.Errorf("the translation has %d characters for %d in the source, too few to be complete", n, sourceLen)
+		case ratio > maxLengthRatio:
+			return fmt.Errorf("the translation has %d characters for %d in the source, more than a translation", n, sourceLen)
+		}
+	}
+
+	if t.sourceLang != "" && languageName(t.sourceLang) == languageName(t.config.ToLang) {
+		return nil
+	}
+	leaked, sentences := 0, 0
+	for _, sentence := range splitSentences(source, t.abbreviations(t.sourceLang)) {
+		sentence = strings.TrimSpace(sentence)
+		if countWords(sentence) < leakMinWords {
+			continue
+		}
+		sentences++
+		if strings.Contains(translation, sentence) {
+			leaked++
+		}
+	}
+	if leaked > 0 {
+		return fmt.Errorf("%d of %d sentences of the source are left untranslated", leaked, sentences)
+	}
+	return nil
+}
+
+// countWords counts the words of text, leaving out numbers and punctuation.
+func countWords(text string) int {
+	n := 0
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, unicode.IsLetter) >= 0 {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,77 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSanityProblem(t *testing.T) {
+	source := "The quick brown fox jumps over the lazy dog. Then it runs away."
+	testCases := []struct {
+		translation string
+		problem     string
+	}{
+		{"Der schnelle braune Fuchs springt über den faulen Hund. Dann läuft er weg.", ""},
+		{"  ", "empty"},
+		{"Fuchs.", "too few"},
+		{strings.Repeat("Der schnelle braune Fuchs springt. ", 10), "more than a translation"},
+		{"The quick brown fox jumps over the lazy dog. Dann läuft er weg.", "1 of 1 sentences"},
+	}
+	tr := NewTranslator(Config{ToLang: "german"})
+	for _, tc := range testCases {
+		err := tr.sanityProblem(source, tc.translation)
+		if tc.problem == "" && err != nil || tc.problem != "" && (err == nil || !strings.Contains(err.Error(), tc.problem)) {
+			t.Errorf("%q: expected %q, got %v", tc.translation, tc.problem, err)
+		}
+	}
+
+	// Short strings, markers and text without letters say little.
+	for _, pair := range [][2]string{{"OK", "In Ordnung, das machen wir so"}, {"⟦0⟧ ⟦1⟧", ""}, {"42", "42"}, {"Save", "Save"}} {
+		if err := tr.sanityProblem(pair[0], pair[1]); err != nil {
+			t.Errorf("%q: expected no problem, got %v", pair, err)
+		}
+	}
+	if err := NewTranslator(Config{ToLang: "german", NoSanityCheck: true}).sanityProblem(source, ""); err != nil {
+		t.Errorf("Expected no check, got %v", err)
+	}
+}
+
+// lazyProvider copies the text untranslated until it is told what went
+// wrong.
+type lazyProvider struct {
+	mu      sync.Mutex
+	systems []string
+}
+
+func (p *lazyProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	p.mu.Lock()
+	p.systems = append(p.systems, cr.System)
+	p.mu.Unlock()
+	text := cr.Prompt[strings.LastIndex(cr.Prompt, "Text to translate:\n\n")+20:]
+	if strings.Contains(cr.System, "previous answer was unusable") {
+		text = strings.ToUpper(text)
+	}
+	return &Completion{Text: "<result>" + text + "</result>"}, nil
+}
+
+func TestSanityRetry(t *testing.T) {
+	provider := &lazyProvider{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "german",
+		Retry: RetryPolicy{Backoff: time.Millisecond}})
+	out, err := tr.TranslateText(context.Background(), "The quick brown fox jumps over the lazy dog.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG." {
+		t.Errorf("Expected the corrected answer, got %q", out)
+	}
+	if len(provider.systems) != 2 || !strings.Contains(provider.systems[1], "1 of 1 sentences of the source are left untranslated") {
+		t.Errorf("Expected a corrective retry, got %q", provider.systems)
+	}
+	if w := tr.Result().Warnings; len(w) != 0 {
+		t.Errorf("Expected no warnings, got %+v", w)
+	}
+}
@@ -0,0 +1,121 @@
+package translator
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	ScheduleFIFO         = "fifo"
+	ScheduleLargestFirst = "largest-first"
+)
+
+// scheduleChunks returns chunk indexes in the order they are handed to
+// workers. Starting with the largest chunks keeps a single huge chunk from
+// dominating the tail of a concurrent run.
+func scheduleChunks(chunks []string, schedule string) ([]int, error) {
+	order := make([]int, len(chunks))
+	for i := range order {
+		order[i] = i
+	}
+
+	switch schedule {
+	case "", ScheduleFIFO:
+	case ScheduleLargestFirst:
+		sort.SliceStable(order, func(a, b int) bool {
+			return len(chunks[order[a]]) > len(chunks[order[b]])
+		})
+	default:
+		return nil, fmt.Errorf("unknown schedule %q, use %s or %s", schedule, ScheduleFIFO, ScheduleLargestFirst)
+	}
+
+	return order, nil
+}
+
+type chunkOutcome struct {
+	index int
+	text  string
+	err   error
+}
+
+// translateConcurrently translates the chunks of job with Config.Concurrency
+// workers and writes them in document order as soon as every earlier chunk
+// is done. The first failure stops the hand-out of new chunks.
+func (t *Translator) translateConcurrently(job *fileJob) error {
+	order, err := scheduleChunks(job.chunks, t.config.Schedule)
+	if err != nil {
+		return classify(ErrorClassConfig, err)
+	}
+	pending := order[:0]
+	for _, i := range order {
+		if i >= job.first {
+			pending = append(pending, i)
+		}
+	}
+	order = pending
+
+	jobs := make(chan int)
+	outcomes := make(chan chunkOutcome)
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for w := 0; w < t.config.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				text, err := t.translateChunkWithRetries(job.ctx, i, len(job.chunks), job.chunks[i], job.source(i))
+				outcomes <- chunkOutcome{index: i, text: text, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, i := range order {
+			select {
+			case jobs <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	done := make([]*chunkOutcome, len(job.chunks))
+	next := job.first
+	var firstErr error
+
+	for outcome := range outcomes {
+		outcome := outcome
+		if firstErr != nil {
+			continue
+		}
+		if outcome.err != nil {
+			firstErr = outcome.err
+			close(stop)
+			continue
+		}
+
+		done[outcome.index] = &outcome
+		for next < len(done) && done[next] != nil {
+			if err := t.writeChunk(job, next, done[next].text); err != nil {
+				firstErr = err
+				close(stop)
+				break
+			}
+			next++
+		}
+	}
+
+	if firstErr != nil {
+		t.markUntranslated(job, next)
+		return firstErr
+	}
+	return nil
+}
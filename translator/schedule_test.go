@@ -0,0 +1,98 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoServer answers every chat completion with the text to translate, so
+// the output of a run must equal its input.
+func echoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(echoHandler(t))
+}
+
+func echoHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+
+		var req OpenRouterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Invalid chat request: %v", err)
+		}
+		prompt := req.Messages[len(req.Messages)-1].Content
+		text := prompt[strings.Index(prompt, ":\n\n")+3:]
+
+		time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":%q}}]}`, "<result>"+text+"</result>")
+	}
+}
+
+func TestScheduleChunks(t *testing.T) {
+	chunks := []string{"aa", "a", "aaaa", "aaa"}
+
+	order, err := scheduleChunks(chunks, ScheduleLargestFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(order) != "[2 3 0 1]" {
+		t.Errorf("Unexpected largest-first order %v", order)
+	}
+
+	order, _ = scheduleChunks(chunks, ScheduleFIFO)
+	if fmt.Sprint(order) != "[0 1 2 3]" {
+		t.Errorf("Unexpected fifo order %v", order)
+	}
+
+	if _, err := scheduleChunks(chunks, "random"); err == nil {
+		t.Error("Expected error for unknown schedule")
+	}
+}
+
+func TestTranslateConcurrently(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+
+	var paragraphs []string
+	for i := 0; i < 40; i++ {
+		paragraphs = append(paragraphs, fmt.Sprintf("Paragraph %d %s", i, strings.Repeat("word ", 10+i%5*20)))
+	}
+	input := strings.Join(paragraphs, "\n\n")
+
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "in.txt")
+	outputPath := filepath.Join(dir, "out.txt")
+	os.WriteFile(inputPath, []byte(input), 0644)
+
+	for _, schedule := range []string{ScheduleFIFO, ScheduleLargestFirst} {
+		translator := NewTranslator(Config{
+			BaseURL:     server.URL,
+			ChunkSize:   200,
+			Concurrency: 4,
+			Schedule:    schedule,
+			// The echo server returns the text untranslated.
+			NoSanityCheck: true,
+		})
+
+		if err := translator.TranslateFile(inputPath, outputPath); err != nil {
+			t.Fatalf("%s: TranslateFile failed: %v", schedule, err)
+		}
+
+		output, _ := os.ReadFile(outputPath)
+		if normalizeText(string(output)) != normalizeText(input) {
+			t.Errorf("%s: output does not match input", schedule)
+		}
+		if r := translator.Result(); r.Chunks < 2 || r.ChunksTranslated != r.Chunks {
+			t.Errorf("%s: expected all of several chunks to be translated, got %+v", schedule, r)
+		}
+	}
+}
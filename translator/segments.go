@@ -0,0 +1,128 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TranslateSegments translates each segment's Source on its own and returns
+// the segments with Target and State filled in. ID and Meta are passed
+// through, so callers can map results back to keys, file positions or TMS
+// entries. Segments longer than ChunkSize are split and reassembled. When
+// the run fails, segments that were not fully translated are returned as
+// SegmentUntranslated along with the error.
+//
+// A segment holding an ICU MessageFormat plural, such as
+// "{count, plural, one {# file} other {# files}}", is expanded into the
+// plural categories of the target language, e.g. one, few, many and other
+// for Russian. Each form is translated as a whole sentence of its own and
+// the result is checked to be valid ICU syntax.
+//
+// Segments whose "key" Meta is in Config.KeyContext are translated with
+// that description, length limit and screenshot in their prompt. An answer
+// over the segment's MaxLength is retried with a request for a shorter
+// rendering, and kept with a too-long warning when retries do not help.
+func (t *Translator) TranslateSegments(ctx context.Context, segments []Segment) ([]Segment, error) {
+	t.begin(ctx, "", "")
+	t.icu = true
+
+	prepared := &PreparedFile{}
+	var owners, variants []int
+	plurals := map[int]*pluralMessage{}
+	firstChunk := map[int]int{}
+	for i, s := range segments {
+		var keyHint, screenshot string
+		limit := s.MaxLength
+		if c, ok := t.keyContext(s); ok {
+			keyHint, screenshot = c.hint(s.Meta["key"]), c.Screenshot
+			if limit == 0 {
+				limit = c.MaxLength
+			}
+		}
+		if p := t.expandPlural(s.Source); p != nil {
+			plurals[i], firstChunk[i] = p, len(prepared.Chunks)+1
+			for v, variant := range p.variants {
+				prepared.Chunks = append(prepared.Chunks, maskICU(variant.text, &prepared.Spans))
+				owners, variants = append(owners, i), append(variants, v)
+				t.hints = append(t.hints, joinHints(variant.hint, keyHint, lengthHint(limit)))
+				t.screenshots = append(t.screenshots, screenshot)
+				t.maxLengths = append(t.maxLengths, limit)
+			}
+			continue
+		}
+		chunks := t.splitIntoChunks(ctx, s.Source)
+		if len(chunks) > 1 {
+			// The limit is for short UI strings, not text long enough to split.
+			limit = 0
+		}
+		for _, chunk := range chunks {
+			prepared.Chunks = append(prepared.Chunks, chunk)
+			owners, variants = append(owners, i), append(variants, -1)
+			t.hints = append(t.hints, joinHints(keyHint, lengthHint(limit)))
+			t.screenshots = append(t.screenshots, screenshot)
+			t.maxLengths = append(t.maxLengths, limit)
+		}
+	}
+	prepared.SourceSpans = prepared.Spans
+
+	err := t.translatePrepared(ctx, prepared, func() (io.WriteCloser, error) {
+		return nopWriteCloser{io.Discard}, nil
+	})
+
+	results := make([]Segment, len(segments))
+	for i, s := range segments {
+		results[i] = s
+		results[i].Target, results[i].State = "", SegmentMachineTranslated
+	}
+	for _, chunk := range t.result.Segments {
+		r := &results[owners[chunk.ID-1]]
+		if chunk.State != SegmentMachineTranslated {
+			r.State = SegmentUntranslated
+			continue
+		}
+		if p := plurals[owners[chunk.ID-1]]; p != nil {
+			p.variants[variants[chunk.ID-1]].translation = strings.TrimSpace(chunk.Target)
+			continue
+		}
+		if r.Target != "" && !strings.HasSuffix(r.Target, "\n") {
+			r.Target += "\n"
+		}
+		r.Target += chunk.Target
+	}
+	for i, p := range plurals {
+		r := &results[i]
+		if r.State == SegmentUntranslated {
+			continue
+		}
+		for _, v := range p.variants {
+			if v.translation == "" {
+				r.State = SegmentUntranslated
+			}
+		}
+		if r.State == SegmentUntranslated {
+			continue
+		}
+		r.Target = p.assemble()
+		if _, icuErr := parseICU(r.Target); icuErr != nil {
+			t.warn(Warning{Kind: WarningICUSyntax, Chunk: firstChunk[i], Message: fmt.Sprintf("segment %d is not valid ICU MessageFormat: %v", r.ID, icuErr)})
+			r.State = SegmentUntranslated
+		}
+	}
+	for i := range results {
+		if results[i].State == SegmentUntranslated {
+			results[i].Target = ""
+		}
+	}
+	if err != nil {
+		for i := range results {
+			if results[i].Target == "" && results[i].Source != "" {
+				results[i].State = SegmentUntranslated
+			}
+		}
+	}
+
+	t.result.Segments = results
+	return results, t.finish("", err)
+}
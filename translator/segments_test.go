@@ -0,0 +1,47 @@
+package translator
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTranslateSegments(t *testing.T) {
+	segments := []Segment{
+		{ID: 7, Source: "Hello", Meta: map[string]string{"key": "greeting"}},
+		{ID: 9, Source: "First paragraph.\n\nSecond paragraph.", Meta: map[string]string{"file": "doc.md", "line": "12"}},
+		{ID: 11, Source: "Bye"},
+	}
+
+	tr := NewTranslator(Config{Provider: slowUpperProvider{}, ChunkSize: 5, NoDelay: true, Concurrency: 2})
+	translated, err := tr.TranslateSegments(context.Background(), segments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"HELLO", "FIRST PARAGRAPH.\nSECOND PARAGRAPH.", "BYE"}
+	for i, s := range translated {
+		if s.ID != segments[i].ID || s.Source != segments[i].Source || s.Target != expected[i] || s.State != SegmentMachineTranslated {
+			t.Errorf("Segment %d: unexpected %+v", i, s)
+		}
+	}
+	if translated[1].Meta["line"] != "12" || translated[0].Meta["key"] != "greeting" {
+		t.Errorf("Expected metadata to be passed through, got %+v", translated)
+	}
+	if r := tr.Result(); r.Chunks != 4 || len(r.Segments) != 3 {
+		t.Errorf("Expected 4 chunks reported as 3 segments, got %d chunks and %d segments", r.Chunks, len(r.Segments))
+	}
+}
+
+func TestTranslateSegmentsFailure(t *testing.T) {
+	provider := &outageProvider{broken: map[string]bool{"a": true}}
+	tr := NewTranslator(Config{Provider: provider, Model: "a", ChunkSize: 100, NoDelay: true,
+		Retry: RetryPolicy{Network: -1, HTTP: -1, Extraction: -1}})
+
+	translated, err := tr.TranslateSegments(context.Background(), []Segment{{ID: 1, Source: "Hello", Meta: map[string]string{"key": "a"}}})
+	if ErrorClass(err) != ErrorClassAPI {
+		t.Fatalf("Expected an API error, got %v", err)
+	}
+	if translated[0].State != SegmentUntranslated || translated[0].Meta["key"] != "a" {
+		t.Errorf("Expected an untranslated segment with its metadata, got %+v", translated[0])
+	}
+}
@@ -0,0 +1,115 @@
+package translator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// headingRe matches Markdown and Quarto (#) and Typst (=) headings.
+var headingRe = regexp.MustCompile(`^(#{1,6}|={1,6})[ \t]+(.*?)[ \t]*$`)
+
+// sectionSelector picks the sections of a document whose heading matches,
+// each running until the next heading of the same or a higher level.
+type sectionSelector struct {
+	title string
+	re    *regexp.Regexp
+}
+
+// parseSelector reads Config.Select, "heading:<title>", and
+// Config.SelectRegex, a regular expression matched against heading titles.
+// It returns nil when neither is set.
+func parseSelector(sel, expr string) (*sectionSelector, error) {
+	if sel == "" && expr == "" {
+		return nil, nil
+	}
+	s := &sectionSelector{}
+	if sel != "" {
+		if !strings.HasPrefix(sel, "heading:") || strings.TrimSpace(sel[len("heading:"):]) == "" {
+			return nil, fmt.Errorf("unknown selector %q, use heading:<title>", sel)
+		}
+		s.title = strings.TrimSpace(sel[len("heading:"):])
+	}
+	if expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector regex: %w", err)
+		}
+		s.re = re
+	}
+	return s, nil
+}
+
+func (s *sectionSelector) matches(title string) bool {
+	title = strings.TrimRight(title, " \t#=")
+	if s.title != "" && strings.EqualFold(strings.TrimSpace(title), s.title) {
+		return true
+	}
+	return s.re != nil && s.re.MatchString(title)
+}
+
+func (s *sectionSelector) String() string {
+	if s.re == nil {
+		return "heading:" + s.title
+	}
+	if s.title == "" {
+		return s.re.String()
+	}
+	return "heading:" + s.title + " or " + s.re.String()
+}
+
+// selectSections protects every part of masked text outside the selected
+// sections, so only those are translated and the rest is written through
+// unchanged. Headings are looked for in the unmasked lines, so protected
+// headings count and headings inside code blocks do not. It returns the
+// new masked text and spans.
+func selectSections(text string, spans []string, s *sectionSelector) (string, []string, error) {
+	var b maskBuilder
+	selectedLevel := 0
+	inFence := false
+	found := false
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		source := unmaskSpans(line, spans)
+		if !inFence {
+			if m := headingRe.FindStringSubmatch(strings.SplitN(source, "\n", 2)[0]); m != nil {
+				level := len(m[1])
+				if selectedLevel > 0 && level <= selectedLevel {
+					selectedLevel = 0
+				}
+				if selectedLevel == 0 && s.matches(m[2]) {
+					selectedLevel, found = level, true
+				}
+			}
+		}
+		for _, l := range strings.Split(source, "\n") {
+			if l = strings.TrimSpace(l); strings.HasPrefix(l, "```") || strings.HasPrefix(l, "~~~") {
+				inFence = !inFence
+			}
+		}
+
+		if selectedLevel == 0 {
+			b.protect(source)
+			continue
+		}
+		last := 0
+		for _, loc := range maskTokenRe.FindAllStringSubmatchIndex(line, -1) {
+			b.keep(line[last:loc[0]])
+			b.protect(unmaskSpans(line[loc[0]:loc[1]], spans))
+			last = loc[1]
+		}
+		b.keep(line[last:])
+	}
+
+	if !found {
+		return "", nil, fmt.Errorf("no section matches %s", s)
+	}
+	masked, selected := b.result()
+	return masked, selected, nil
+}
+
+// onlyMarkers reports whether chunk holds nothing to translate, as is the
+// case for the parts of a document outside the selected sections.
+func onlyMarkers(chunk string) bool {
+	return strings.TrimSpace(maskTokenRe.ReplaceAllString(chunk, "")) == ""
+}
@@ -0,0 +1,48 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelectSections(t *testing.T) {
+	doc := "# Intro\n\nintro text\n\n## Installation\n\ninstall text\n\n```\n# not a heading\n```\n\n### From source\n\nsource text\n\n## Usage\n\nusage text\n"
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.md")
+	out := filepath.Join(dir, "out.md")
+	os.WriteFile(in, []byte(doc), 0644)
+
+	provider := &countingProvider{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, Select: "heading:installation"})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	want := "# Intro\n\nintro text\n\n## INSTALLATION\n\nINSTALL TEXT\n\n```\n# not a heading\n```\n\n### FROM SOURCE\n\nSOURCE TEXT\n\n## Usage\n\nusage text\n"
+	if got, _ := os.ReadFile(out); string(got) != want {
+		t.Errorf("Expected only the selected section translated, got %q", got)
+	}
+	for _, sent := range provider.sent {
+		if strings.Contains(sent, "intro") || strings.Contains(sent, "usage") {
+			t.Errorf("Expected unselected text to stay out of requests, got %q", sent)
+		}
+	}
+
+	provider.sent = nil
+	tr = NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, SelectRegex: "^(From source|Usage)$"})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); !strings.Contains(string(got), "SOURCE TEXT") || !strings.Contains(string(got), "USAGE TEXT") || !strings.Contains(string(got), "install text") {
+		t.Errorf("Expected the sections matching the regex translated, got %q", got)
+	}
+
+	tr = NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, Select: "heading:Missing"})
+	if err := tr.TranslateFile(in, out); ErrorClass(err) != ErrorClassInput {
+		t.Errorf("Expected an input error when nothing matches, got %v", err)
+	}
+	if err := (Config{ChunkSize: 100, ToLang: "german", Model: DefaultModel, Select: "title:Intro"}).Validate(); ErrorClass(err) != ErrorClassConfig {
+		t.Errorf("Expected a config error for an unknown selector, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// builtinAbbreviations are the words, per language, that end in a full
+// stop without ending the sentence, lower-cased and without the final stop.
+// Config.Abbreviations adds to them.
+var builtinAbbreviations = map[string][]string{
+	"english": {"mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "vs", "etc", "e.g", "i.e", "cf", "no", "fig",
+		"vol", "approx", "inc", "ltd", "co", "jan", "feb", "aug", "sept", "oct", "nov", "dec"},
+	"german": {"z.b", "bzw", "usw", "ca", "evtl", "ggf", "vgl", "nr", "d.h", "u.a", "hr", "fr", "dr", "prof",
+		"abs", "bd"},
+	"french":  {"mme", "mlle", "p.ex", "env", "cf", "etc", "dr", "st"},
+	"spanish": {"sr", "sra", "sres", "srta", "dr", "dra", "pág", "etc", "p.ej"},
+	"italian": {"sig", "sig.ra", "dott", "ecc", "pag", "ing", "avv"},
+	"russian": {"т.д", "т.п", "т.е", "т.к", "т.н", "др", "пр", "им", "стр", "см", "ул", "г", "гг", "в", "вв", "тыс",
+		"млн", "млрд", "руб", "проф", "акад"},
+	"ukrainian": {"т.д", "т.п", "т.ч", "ім", "вул", "див", "р", "рр", "ст", "тис", "млн", "млрд", "грн", "проф"},
+}
+
+// sentenceClosers are quotes and brackets that belong to the sentence
+// before them when they follow its final punctuation, German “ and ‘
+// among them.
+const sentenceClosers = "\"'”’“‘»)]」』）"
+
+// splitSentences cuts text after sentence-ending punctuation, together with
+// the closing quotes and brackets right after it and the whitespace that
+// follows, so the sentences add up to text. A full stop does not end a
+// sentence after an abbreviation such as "Mr." or "т.д.", after a single
+// letter, which is likely an initial, before a lower-case word, or inside
+// quotes or brackets, as in "He said: 'Stop. Now.' and left." The CJK full
+// stops 。！？ end a sentence without a following space. abbreviations are
+// those of the text's language, see Translator.abbreviations.
+func splitSentences(text string, abbreviations map[string]bool) []string {
+	var sentences []string
+	quoted := quotedPositions(text)
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !strings.ContainsRune(".!?…。！？", r) {
+			i += size
+			continue
+		}
+
+		end := i + size
+		cjk := strings.ContainsRune("。！？", r)
+		for end < len(text) {
+			next, n := utf8.DecodeRuneInString(text[end:])
+			if !strings.ContainsRune(".!?…。！？"+sentenceClosers, next) {
+				break
+			}
+			end += n
+		}
+
+		after := end
+		for after < len(text) {
+			next, n := utf8.DecodeRuneInString(text[after:])
+			if !unicode.IsSpace(next) {
+				break
+			}
+			after += n
+		}
+
+		boundary := (cjk || after > end || after == len(text)) && (quoted == nil || !quoted[end])
+		if boundary && !cjk && after < len(text) {
+			next, _ := utf8.DecodeRuneInString(text[after:])
+			if unicode.IsLower(next) {
+				boundary = false
+			} else if r == '.' && end == i+size && abbreviation(text[start:i], abbreviations) {
+				boundary = false
+			}
+		}
+
+		if boundary {
+			sentences = append(sentences, text[start:after])
+			start = after
+		}
+		i = after
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
+
+// abbreviation reports whether the last word of text, which stands before
+// a full stop, is one of abbreviations or an initial.
+func abbreviation(text string, abbreviations map[string]bool) bool {
+	word := text[strings.LastIndexFunc(text, unicode.IsSpace)+1:]
+	word = strings.TrimLeft(word, "\"'“‘«([")
+	if utf8.RuneCountInString(word) == 1 {
+		r, _ := utf8.DecodeRuneInString(word)
+		return unicode.IsLetter(r)
+	}
+	return abbreviations[strings.ToLower(word)]
+}
+
+// quotePairs maps opening quotes and brackets to their closing counterpart.
+// German „ and ‚ close with the quotes English text opens with.
+var quotePairs = map[rune]rune{
+	'(': ')', '[': ']', '«': '»', '“': '”', '‘': '’', '„': '“', '‚': '‘',
+	'「': '」', '『': '』', '（': '）',
+}
+
+// quotedPositions reports for every rune position of text whether it is
+// inside quotes or brackets. It returns nil when they do not balance, as
+// when a quote runs on into the next paragraph, so that a stray quote does
+// not keep the rest of the text from being split. Straight quotes open after
+// a space or an opening bracket, and a ' between letters is an apostrophe.
+func quotedPositions(text string) []bool {
+	quoted := make([]bool, len(text)+1)
+	var open []rune
+	prev := ' '
+	for i, r := range text {
+		quoted[i] = len(open) > 0
+		next, _ := utf8.DecodeRuneInString(text[i+utf8.RuneLen(r):])
+		switch {
+		case r == '\'' && unicode.IsLetter(prev) && unicode.IsLetter(next):
+		case len(open) > 0 && r == open[len(open)-1]:
+			open = open[:len(open)-1]
+		case r == '"' || r == '\'':
+			if (unicode.IsSpace(prev) || unicode.In(prev, unicode.Ps, unicode.Pi)) && next != utf8.RuneError && !unicode.IsSpace(next) {
+				open = append(open, r)
+			}
+		case quotePairs[r] != 0:
+			open = append(open, quotePairs[r])
+		}
+		prev = r
+	}
+	if len(open) > 0 {
+		return nil
+	}
+	return quoted
+}
+
+// abbreviations returns the abbreviations of lang, a language name or code:
+// the built-in ones and those of Config.Abbreviations. When lang is unknown
+// or empty, as before the source language is detected, those of every
+// language are used.
+func (t *Translator) abbreviations(lang string) map[string]bool {
+	name := languageName(lang)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if set, ok := t.abbreviationSets[name]; ok {
+		return set
+	}
+
+	set := map[string]bool{}
+	add := func(words []string) {
+		for _, word := range words {
+			set[strings.TrimSuffix(strings.ToLower(strings.TrimSpace(word)), ".")] = true
+		}
+	}
+	_, known := builtinAbbreviations[name]
+	for key := range t.config.Abbreviations {
+		known = known || name != "" && languageName(key) == name
+	}
+	for key, words := range builtinAbbreviations {
+		if !known || key == name {
+			add(words)
+		}
+	}
+	for key, words := range t.config.Abbreviations {
+		if !known || languageName(key) == name {
+			add(words)
+		}
+	}
+
+	if t.abbreviationSets == nil {
+		t.abbreviationSets = map[string]map[string]bool{}
+	}
+	t.abbreviationSets[name] = set
+	return set
+}
+
+// LoadAbbreviations reads abbreviations for Config.Abbreviations from a JSON
+// file keyed by language name or code, e.g. {"de": ["Abs.", "Bd."]}.
+func LoadAbbreviations(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read abbreviations: %w", err)
+	}
+	var abbreviations map[string][]string
+	if err := json.Unmarshal(data, &abbreviations); err != nil {
+		return nil, fmt.Errorf("abbreviations %s: %w", path, err)
+	}
+	return abbreviations, nil
+}
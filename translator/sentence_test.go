@@ -0,0 +1,78 @@
+package translator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSentences(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"Plain", "One. Two! Three? Four", []string{"One. ", "Two! ", "Three? ", "Four"}},
+		{"Abbreviations", "Mr. Smith met Dr. Jones, e.g. at noon. Then he left.", []string{"Mr. Smith met Dr. Jones, e.g. at noon. ", "Then he left."}},
+		{"Russian abbreviations", "Яблоки, груши и т.д. Всё это в 2024 г. было дёшево. Конец.", []string{"Яблоки, груши и т.д. Всё это в 2024 г. было дёшево. ", "Конец."}},
+		{"German", "Er kam z.B. am Montag. Dann ging er.", []string{"Er kam z.B. am Montag. ", "Dann ging er."}},
+		{"Initials and numbers", "J. R. R. Tolkien wrote 3.5 books. Really.", []string{"J. R. R. Tolkien wrote 3.5 books. ", "Really."}},
+		{"Lower case continues", "It costs approx. five euros... or less. Fine.", []string{"It costs approx. five euros... or less. ", "Fine."}},
+		{"Closing quotes", "He said \"Stop.\" Then silence. «Да!» Она ушла.", []string{"He said \"Stop.\" ", "Then silence. ", "«Да!» ", "Она ушла."}},
+		{"Quoted dialogue", "He said: 'Stop. Now.' and left. She didn't (not again. Never.) follow.", []string{"He said: 'Stop. Now.' and left. ", "She didn't (not again. Never.) follow."}},
+		{"German quotes", "Sie rief: „Halt. Jetzt.“ Dann ging sie.", []string{"Sie rief: „Halt. Jetzt.“ ", "Dann ging sie."}},
+		{"Unbalanced quote", "He said: \"Stop. Wait. Now.", []string{"He said: \"Stop. ", "Wait. ", "Now."}},
+		{"CJK", "今日は晴れです。明日は雨！本当？はい", []string{"今日は晴れです。", "明日は雨！", "本当？", "はい"}},
+		{"Ellipsis", "Wait… What happened?\nNothing.", []string{"Wait… ", "What happened?\n", "Nothing."}},
+	}
+
+	abbreviations := NewTranslator(Config{}).abbreviations("")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := splitSentences(tc.input, abbreviations)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+			if strings.Join(got, "") != tc.input {
+				t.Errorf("Expected the sentences to add up to the input, got %q", got)
+			}
+		})
+	}
+}
+
+func TestSplitIntoChunksKeepsSentences(t *testing.T) {
+	tr := NewTranslator(Config{ChunkSize: 50})
+	paragraph := strings.Repeat("Mr. Smith visited the museum in the city, as he does every year. ", 6)
+	chunks := tr.splitIntoChunks(context.Background(), strings.TrimSpace(paragraph))
+	if len(chunks) < 2 {
+		t.Fatalf("Expected the paragraph to be split, got %q", chunks)
+	}
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk, "Mr. Smith") {
+			t.Errorf("Expected chunks to start at a sentence, got %q", chunk)
+		}
+	}
+	if strings.Join(chunks, "") != strings.TrimSpace(paragraph) {
+		t.Errorf("Expected chunks to add up to the paragraph, got %q", chunks)
+	}
+}
+
+func TestAbbreviations(t *testing.T) {
+	tr := NewTranslator(Config{Abbreviations: map[string][]string{"de": {"Abs.", "Bd."}, "pl": {"np"}}})
+	text := "Siehe Bd. Zwei und Abs. Drei. Fertig."
+	want := []string{"Siehe Bd. Zwei und Abs. Drei. ", "Fertig."}
+	if got := splitSentences(text, tr.abbreviations("german")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the added abbreviations kept, got %q", got)
+	}
+	// The abbreviations of one language do not hold for another.
+	if got := splitSentences("Siehe Bd. Zwei.", tr.abbreviations("en")); len(got) != 2 {
+		t.Errorf("Expected a German abbreviation to end an English sentence, got %q", got)
+	}
+	if set := tr.abbreviations("polish"); !set["np"] || set["mr"] {
+		t.Errorf("Expected only the Polish abbreviations, got %v", set)
+	}
+	if set := tr.abbreviations(""); !set["np"] || !set["т.д"] || !set["abs"] {
+		t.Errorf("Expected the abbreviations of every language, got %v", set)
+	}
+}
@@ -0,0 +1,301 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultSpeechURL   = "https://api.openai.com/v1/audio/speech"
+	defaultSpeechVoice = "alloy"
+	// speechMaxChars keeps each request under the 4096 character input
+	// limit of the OpenAI speech endpoint.
+	speechMaxChars = 4000
+)
+
+var (
+	// chapterLineRe matches chapter titles of plain text books, such as
+	// "Chapter 3" or "CHAPTER IV. The Return".
+	chapterLineRe = regexp.MustCompile(`(?i)^[ \t]*(?:chapter|part|book|kapitel|teil|chapitre|partie|capítulo|capitolo|hoofdstuk|rozdział|глава|часть|розділ)[ \t]+(?:\d+|[ivxlcdm]+)\b.{0,60}$`)
+
+	speechLinkRe   = regexp.MustCompile(`!?\[([^\]\n]*)\]\([^)\n]*\)`)
+	speechTagRe    = regexp.MustCompile(`<[^>\n]+>`)
+	speechMarkRe   = regexp.MustCompile("[*_`~]+")
+	speechListRe   = regexp.MustCompile(`(?m)^[ \t]*(?:[-*+]|\d+[.)])[ \t]+`)
+	speechQuoteRe  = regexp.MustCompile(`(?m)^[ \t]*>[ \t]?`)
+	speechHeadRe   = regexp.MustCompile(`(?m)^#{1,6}[ \t]+`)
+	speechRuleRe   = regexp.MustCompile(`(?m)^[ \t]*(?:[-*_][ \t]*){3,}$`)
+	speechBlanksRe = regexp.MustCompile(`\n{3,}`)
+)
+
+type speechRequest struct {
+	Model          string `json:"model"`
+	Input          string `json:"input"`
+	Voice          string `json:"voice"`
+	ResponseFormat string `json:"response_format"`
+}
+
+// speak reads the translation in textPath aloud, see Config.SpeechModel,
+// writing an MP3 per chapter next to outputPath.
+func (t *Translator) speak(ctx context.Context, textPath, outputPath string) error {
+	if t.config.SpeechModel == "" {
+		return nil
+	}
+	content, err := os.ReadFile(textPath)
+	if err != nil {
+		return classify(ErrorClassInput, fmt.Errorf("failed to read %s for speech: %w", textPath, err))
+	}
+
+	var chapters []string
+	switch t.result.Format {
+	case "markdown":
+		chapters = markdownChapters(string(content))
+		for i := range chapters {
+			chapters[i] = speakableMarkdown(chapters[i])
+		}
+	case "text":
+		chapters = textChapters(string(content))
+	default:
+		return configError("speech output needs a text or Markdown document, not %s", t.result.Format)
+	}
+
+	for n, chapter := range chapters {
+		path := chapterAudioPath(outputPath, n+1, len(chapters))
+		var audio bytes.Buffer
+		for _, piece := range speechPieces(chapter, speechMaxChars, t.abbreviations(t.config.ToLang)) {
+			data, err := t.synthesize(ctx, piece)
+			if err != nil {
+				return err
+			}
+			// MP3 streams are sequences of frames and can be joined.
+			audio.Write(data)
+		}
+		if err := os.WriteFile(path, audio.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		t.result.Audio = append(t.result.Audio, path)
+		t.emit(ProgressEvent{Event: "speech", Output: path, Chunk: n + 1, Chunks: len(chapters), Bytes: audio.Len()})
+	}
+	return nil
+}
+
+// synthesize returns the MP3 audio of text.
+func (t *Translator) synthesize(ctx context.Context, text string) ([]byte, error) {
+	url := t.config.SpeechURL
+	if url == "" {
+		url = defaultSpeechURL
+		if t.config.BaseURL != "" {
+			url = t.baseURL() + "/audio/speech"
+		}
+	}
+	voice := t.config.SpeechVoice
+	if voice == "" {
+		voice = defaultSpeechVoice
+	}
+	key := t.config.SpeechAPIKey
+	if key == "" {
+		key = t.config.APIKey
+	}
+
+	requestBody, err := json.Marshal(speechRequest{Model: t.config.SpeechModel, Input: text, Voice: voice, ResponseFormat: "mp3"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal speech request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create speech request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if auditErr := t.recordRequest(url, t.config.SpeechModel, "", requestBody, 0, nil, err); auditErr != nil {
+			return nil, auditErr
+		}
+		if ctx.Err() != nil {
+			return nil, canceled(ctx.Err())
+		}
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to send speech request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	// The audio itself is not worth keeping in the audit log.
+	logged := body
+	if resp.StatusCode == http.StatusOK {
+		logged = nil
+	}
+	if auditErr := t.recordRequest(url, t.config.SpeechModel, "", requestBody, resp.StatusCode, logged, err); auditErr != nil {
+		return nil, auditErr
+	}
+	if err != nil {
+		return nil, classify(ErrorClassNetwork, fmt.Errorf("failed to read speech response: %w", err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, classify(ErrorClassAPI, fmt.Errorf("speech request failed with status %d: %s", resp.StatusCode, string(body)))
+	}
+	return body, nil
+}
+
+// chapterAudioPath names the audio of chapter n of total after the output
+// file: book.de.md gives book.de.mp3 for a single chapter and book.de.01.mp3,
+// book.de.02.mp3, ... otherwise.
+func chapterAudioPath(outputPath string, n, total int) string {
+	base := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+	if total == 1 {
+		return base + ".mp3"
+	}
+	width := len(fmt.Sprint(total))
+	if width < 2 {
+		width = 2
+	}
+	return fmt.Sprintf("%s.%0*d.mp3", base, width, n)
+}
+
+// markdownChapters splits text before every heading of its top level,
+// ignoring code blocks. Text before the first heading is a chapter of its
+// own.
+func markdownChapters(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	level := 7
+	var headings []int
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		if m := headingRe.FindStringSubmatch(strings.TrimRight(line, "\r\n")); m != nil && m[1][0] == '#' {
+			if len(m[1]) < level {
+				level, headings = len(m[1]), nil
+			}
+			if len(m[1]) == level {
+				headings = append(headings, i)
+			}
+		}
+	}
+	return cutChapters(lines, headings)
+}
+
+// textChapters splits plain text before lines like "Chapter 3" or "Глава 3".
+func textChapters(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	var titles []int
+	for i, line := range lines {
+		if chapterLineRe.MatchString(strings.TrimRight(line, "\r\n")) {
+			titles = append(titles, i)
+		}
+	}
+	return cutChapters(lines, titles)
+}
+
+// cutChapters joins lines into chapters starting at the given line
+// indexes, dropping chapters with nothing to read.
+func cutChapters(lines []string, starts []int) []string {
+	var chapters []string
+	add := func(from, to int) {
+		if chapter := strings.Join(lines[from:to], ""); strings.TrimSpace(chapter) != "" {
+			chapters = append(chapters, strings.TrimSpace(chapter))
+		}
+	}
+	from := 0
+	for _, start := range starts {
+		add(from, start)
+		from = start
+	}
+	add(from, len(lines))
+	return chapters
+}
+
+// speakableMarkdown reduces Markdown to the text to read aloud: code
+// blocks, images, tags and markup are dropped, links keep their text.
+func speakableMarkdown(text string) string {
+	text = backtickFenceRe.ReplaceAllString(text, "")
+	text = tildeFenceRe.ReplaceAllString(text, "")
+	text = speechLinkRe.ReplaceAllStringFunc(text, func(link string) string {
+		if strings.HasPrefix(link, "!") {
+			return ""
+		}
+		return speechLinkRe.FindStringSubmatch(link)[1]
+	})
+	text = speechTagRe.ReplaceAllString(text, "")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if tableDividerRe.MatchString(line) && strings.Contains(line, "-") {
+			lines[i] = ""
+		} else if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "|") {
+			cells := strings.Split(strings.Trim(trimmed, "|"), "|")
+			for c := range cells {
+				cells[c] = strings.TrimSpace(cells[c])
+			}
+			lines[i] = strings.Join(cells, ", ")
+		}
+	}
+	text = strings.Join(lines, "\n")
+	text = speechRuleRe.ReplaceAllString(text, "")
+	text = speechHeadRe.ReplaceAllString(text, "")
+	text = speechListRe.ReplaceAllString(text, "")
+	text = speechQuoteRe.ReplaceAllString(text, "")
+	text = speechMarkRe.ReplaceAllString(text, "")
+	return strings.TrimSpace(speechBlanksRe.ReplaceAllString(text, "\n\n"))
+}
+
+// speechPieces cuts text into pieces of at most max characters, at
+// paragraphs where possible and else at sentences.
+func speechPieces(text string, max int, abbreviations map[string]bool) []string {
+	var units []string
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		if utf8.RuneCountInString(paragraph) <= max {
+			units = append(units, paragraph+"\n\n")
+			continue
+		}
+		for _, sentence := range splitSentences(paragraph, abbreviations) {
+			for utf8.RuneCountInString(sentence) > max {
+				cut := len(string([]rune(sentence)[:max]))
+				units = append(units, sentence[:cut])
+				sentence = sentence[cut:]
+			}
+			units = append(units, sentence)
+		}
+		units = append(units, "\n\n")
+	}
+
+	var pieces []string
+	var piece strings.Builder
+	for _, unit := range units {
+		if piece.Len() > 0 && utf8.RuneCountInString(piece.String())+utf8.RuneCountInString(unit) > max {
+			pieces = append(pieces, strings.TrimSpace(piece.String()))
+			piece.Reset()
+		}
+		piece.WriteString(unit)
+	}
+	if strings.TrimSpace(piece.String()) != "" {
+		pieces = append(pieces, strings.TrimSpace(piece.String()))
+	}
+	return pieces
+}
@@ -0,0 +1,78 @@
+package translator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSpeech(t *testing.T) {
+	var mu sync.Mutex
+	var inputs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req speechRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("Authorization") != "Bearer speech-key" || req.Model != "tts-1" || req.Voice != "nova" || req.ResponseFormat != "mp3" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		inputs = append(inputs, req.Input)
+		mu.Unlock()
+		w.Write([]byte("MP3:" + req.Input))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "book.md")
+	out := filepath.Join(dir, "book.de.md")
+	os.WriteFile(in, []byte("# One\n\nFirst [link](http://example.com) *chapter*.\n\n```\ncode\n```\n\n![](figure.png)\n\n# Two\n\nSecond chapter.\n"), 0644)
+
+	tr := NewTranslator(Config{Provider: &promptRecorder{}, ChunkSize: 100, NoDelay: true, ToLang: "german",
+		SpeechModel: "tts-1", SpeechVoice: "nova", SpeechURL: server.URL, SpeechAPIKey: "speech-key"})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"ONE\n\nFIRST LINK CHAPTER.", "TWO\n\nSECOND CHAPTER."}
+	if !reflect.DeepEqual(inputs, want) {
+		t.Errorf("Expected the chapters read without markup, got %q", inputs)
+	}
+	audio := []string{filepath.Join(dir, "book.de.01.mp3"), filepath.Join(dir, "book.de.02.mp3")}
+	if !reflect.DeepEqual(tr.Result().Audio, audio) {
+		t.Errorf("Expected an MP3 per chapter, got %q", tr.Result().Audio)
+	}
+	if got, _ := os.ReadFile(audio[1]); string(got) != "MP3:"+want[1] {
+		t.Errorf("Expected the audio of the second chapter, got %q", got)
+	}
+}
+
+func TestTextChapters(t *testing.T) {
+	text := "The Book\n\nCHAPTER I. Home\n\nPart of it.\n\nChapter 2\n\nThe end.\n"
+	want := []string{"The Book", "CHAPTER I. Home\n\nPart of it.", "Chapter 2\n\nThe end."}
+	if got := textChapters(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestSpeechPieces(t *testing.T) {
+	text := strings.Repeat("A sentence of some length. ", 10) + "\n\nShort."
+	pieces := speechPieces(text, 60, nil)
+	for _, piece := range pieces {
+		if len(piece) > 60 {
+			t.Errorf("Expected pieces of at most 60 characters, got %q", piece)
+		}
+	}
+	if last := pieces[len(pieces)-1]; !strings.HasSuffix(last, "Short.") {
+		t.Errorf("Expected the last paragraph in the last piece, got %q", last)
+	}
+	if joined := strings.Join(strings.Fields(strings.Join(pieces, " ")), " "); joined != strings.Join(strings.Fields(text), " ") {
+		t.Errorf("Expected the pieces to hold all the text, got %q", pieces)
+	}
+}
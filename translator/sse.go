@@ -0,0 +1,86 @@
+package translator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// streamEvent is one server-sent event of a streamed chat completion.
+type streamEvent struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+	Usage *struct {
+		PromptTokens     int     `json:"prompt_tokens"`
+		CompletionTokens int     `json:"completion_tokens"`
+		Cost             float64 `json:"cost"`
+	} `json:"usage,omitempty"`
+}
+
+// readStream reads a streamed chat completion, passing each piece of the
+// answer to delta as it arrives. It also returns the raw stream for the
+// audit log. A stream that ends before its [DONE] event was cut off and
+// fails as a network error, so the chunk is retried.
+func readStream(r io.Reader, delta func(text string)) (*Completion, []byte, error) {
+	var raw bytes.Buffer
+	var text strings.Builder
+	completion := &Completion{}
+
+	scanner := bufio.NewScanner(io.TeeReader(r, &raw))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	done := false
+	for !done && scanner.Scan() {
+		// Lines without data are comments such as OpenRouter's keep-alive
+		// ": OPENROUTER PROCESSING" or event separators.
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+
+		var event streamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, raw.Bytes(), classify(ErrorClassAPI, fmt.Errorf("invalid stream event %q: %w", data, err))
+		}
+		if event.Error != nil {
+			return nil, raw.Bytes(), classify(ErrorClassAPI, fmt.Errorf("API error: %s", event.Error.Message))
+		}
+		for _, choice := range event.Choices {
+			if choice.Delta.Content != "" {
+				text.WriteString(choice.Delta.Content)
+				delta(choice.Delta.Content)
+			}
+		}
+		if event.Usage != nil {
+			completion.PromptTokens = event.Usage.PromptTokens
+			completion.CompletionTokens = event.Usage.CompletionTokens
+			completion.Cost = event.Usage.Cost
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, raw.Bytes(), classify(ErrorClassNetwork, fmt.Errorf("failed to read response stream: %w", err))
+	}
+	if !done {
+		return nil, raw.Bytes(), classify(ErrorClassNetwork, fmt.Errorf("response stream ended after %d bytes without [DONE]", text.Len()))
+	}
+	if text.Len() == 0 {
+		return nil, raw.Bytes(), classify(ErrorClassAPI, fmt.Errorf("no translation returned from API"))
+	}
+
+	completion.Text = text.String()
+	return completion, raw.Bytes(), nil
+}
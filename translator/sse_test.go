@@ -0,0 +1,139 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sseServer streams "<result>HALLO WELT</result>" in small pieces. The first
+// cutOff responses end before the [DONE] event.
+func sseServer(t *testing.T, cutOff int32) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Errorf("Expected a streamed request")
+		}
+		n := atomic.AddInt32(&requests, 1)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": OPENROUTER PROCESSING\n\n")
+		for _, piece := range []string{"<result>", "HALLO", " WELT", "</result>"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", piece)
+			w.(http.Flusher).Flush()
+			if n <= cutOff {
+				return
+			}
+		}
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":5}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	return server, &requests
+}
+
+func TestStreamedAnswers(t *testing.T) {
+	server, _ := sseServer(t, 0)
+	defer server.Close()
+
+	var deltas []string
+	tr := NewTranslator(Config{BaseURL: server.URL, ChunkSize: 100, NoDelay: true, Stream: true,
+		Progress: func(ev ProgressEvent) {
+			if ev.Event == "chunk_delta" {
+				if ev.Chunk != 1 {
+					t.Errorf("Expected deltas of chunk 1, got %d", ev.Chunk)
+				}
+				deltas = append(deltas, ev.Text)
+			}
+		}})
+
+	out, err := tr.TranslateText(context.Background(), "Hello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "HALLO WELT" {
+		t.Errorf("Unexpected translation %q", out)
+	}
+	if strings.Join(deltas, "|") != "<result>|HALLO| WELT|</result>" {
+		t.Errorf("Expected every piece as a delta, got %q", deltas)
+	}
+	if r := tr.Result(); r.PromptTokens != 12 || r.CompletionTokens != 5 {
+		t.Errorf("Expected usage from the stream, got %d/%d", r.PromptTokens, r.CompletionTokens)
+	}
+}
+
+func TestCutOffStreamIsRetried(t *testing.T) {
+	server, requests := sseServer(t, 1)
+	defer server.Close()
+
+	tr := NewTranslator(Config{BaseURL: server.URL, ChunkSize: 100, NoDelay: true, Stream: true,
+		Retry: RetryPolicy{Backoff: time.Millisecond}})
+	out, err := tr.TranslateText(context.Background(), "Hello world")
+	if err != nil || out != "HALLO WELT" {
+		t.Fatalf("Expected the retry to translate, got %q: %v", out, err)
+	}
+	if *requests != 2 {
+		t.Errorf("Expected one retry, got %d requests", *requests)
+	}
+}
+
+func TestStreamIntoOutputFile(t *testing.T) {
+	server, _ := sseServer(t, 1)
+	defer server.Close()
+
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.txt"), filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(input, []byte("Hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file holds each piece when its event arrives; the cut-off first
+	// answer is gone when the retry streams.
+	var seen []string
+	tr := NewTranslator(Config{BaseURL: server.URL, ChunkSize: 100, NoDelay: true, Stream: true,
+		Retry: RetryPolicy{Backoff: time.Millisecond},
+		Progress: func(ev ProgressEvent) {
+			if ev.Event == "chunk_delta" {
+				data, _ := os.ReadFile(output)
+				seen = append(seen, string(data))
+			}
+		}})
+	if err := tr.TranslateFileContext(context.Background(), input, output); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"<result>", "<result>", "<result>HALLO", "<result>HALLO WELT", "<result>HALLO WELT</result>"}
+	if strings.Join(seen, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected the answer streamed into the file and cut off before the retry, got %q", seen)
+	}
+	if data, _ := os.ReadFile(output); string(data) != "HALLO WELT" {
+		t.Errorf("Expected the streamed answer replaced by the chunk, got %q", data)
+	}
+}
+
+func TestFailedStreamLeavesNoOutput(t *testing.T) {
+	server, _ := sseServer(t, 100)
+	defer server.Close()
+
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.txt"), filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(input, []byte("Hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tr := NewTranslator(Config{BaseURL: server.URL, ChunkSize: 100, NoDelay: true, Stream: true,
+		Retry: RetryPolicy{Network: 1, Backoff: time.Millisecond}})
+	if err := tr.TranslateFileContext(context.Background(), input, output); err == nil {
+		t.Fatal("Expected the cut-off stream to fail")
+	}
+	if data, _ := os.ReadFile(output); len(data) != 0 {
+		t.Errorf("Expected the failed chunk cut off the output, got %q", data)
+	}
+}
@@ -0,0 +1,81 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTranslateStream(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+
+	input := "title: Hello\ndescription: A friendly greeting\n"
+	var out bytes.Buffer
+
+	translator := NewTranslator(Config{BaseURL: server.URL, ChunkSize: 100, Format: "yaml", NoDelay: true})
+	if err := translator.Translate(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != input {
+		t.Errorf("Expected %q, got %q", input, out.String())
+	}
+	if r := translator.Result(); r.Format != "yaml" || r.ChunksTranslated != r.Chunks || r.Chunks == 0 {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestTranslateStreamReadError(t *testing.T) {
+	translator := NewTranslator(Config{})
+	err := translator.Translate(context.Background(), failingReader{}, &bytes.Buffer{})
+	if ErrorClass(err) != ErrorClassInput {
+		t.Errorf("Expected an input error, got %v", err)
+	}
+}
+
+func TestTranslateText(t *testing.T) {
+	source := "First paragraph.\n\nSecond paragraph.\n"
+	translated, err := NewTranslator(Config{Provider: slowUpperProvider{}, ChunkSize: 5, NoDelay: true}).
+		TranslateText(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if translated != "FIRST PARAGRAPH.\n\nSECOND PARAGRAPH.\n" {
+		t.Errorf("Unexpected translation %q", translated)
+	}
+}
+
+func TestTranslateFileLanguages(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	os.WriteFile(in, []byte("hello world\n"), 0644)
+
+	provider := &systemRecorder{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "russian"})
+	results, err := tr.TranslateFileLanguages(context.Background(), in, []string{"german", "french"}, func(lang string) string {
+		return filepath.Join(dir, "out."+lang+".txt")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Output != filepath.Join(dir, "out.german.txt") || results[1].ChunksTranslated != 1 {
+		t.Errorf("Expected a result per language, got %+v", results)
+	}
+	if !strings.Contains(provider.system, "to french language") {
+		t.Errorf("Expected the last run to translate to french, got %q", provider.system)
+	}
+	for _, lang := range []string{"german", "french"} {
+		if got, _ := os.ReadFile(filepath.Join(dir, "out."+lang+".txt")); string(got) != "ok\n" {
+			t.Errorf("Expected the %s output written, got %q", lang, got)
+		}
+	}
+}
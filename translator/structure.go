@@ -0,0 +1,211 @@
+package translator
+
+import (
+	"html/template"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	listItemRe = regexp.MustCompile(`^[ \t]*([-*+]|\d+[.)])[ \t]+\S`)
+	linkRe     = regexp.MustCompile(`\]\(([^)\s]+)[^)]*\)|<(https?://[^>\s]+)>|#link\("([^"]+)"\)`)
+)
+
+// Heading is a Markdown, Quarto or Typst heading.
+type Heading struct {
+	Level int
+	Title string
+}
+
+// HeadingRow pairs the n-th heading of the source with the n-th heading of
+// the translation; either is nil when one side has fewer headings.
+type HeadingRow struct {
+	Source *Heading
+	Target *Heading
+}
+
+// Broken reports whether the row shows a lost, extra or re-leveled heading.
+func (r HeadingRow) Broken() bool {
+	return r.Source == nil || r.Target == nil || r.Source.Level != r.Target.Level
+}
+
+// StructureReport compares the structure of a document and its translation:
+// the headings tree, list items, code blocks, which must come through
+// unchanged, and link targets. Titles and text are not compared, so it can
+// be read without knowing both languages.
+type StructureReport struct {
+	Headings          []HeadingRow
+	SourceListItems   int
+	TargetListItems   int
+	SourceCodeBlocks  int
+	TargetCodeBlocks  int
+	ChangedCodeBlocks []int
+	// MissingLinks are link targets of the source absent from the
+	// translation, ExtraLinks the other way round.
+	MissingLinks []string
+	ExtraLinks   []string
+}
+
+type documentStructure struct {
+	headings   []Heading
+	listItems  int
+	codeBlocks []string
+	links      []string
+}
+
+func parseStructure(text string) documentStructure {
+	var s documentStructure
+	var code *strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		fence := strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+		if code != nil {
+			if fence {
+				s.codeBlocks = append(s.codeBlocks, code.String())
+				code = nil
+			} else {
+				code.WriteString(line + "\n")
+			}
+			continue
+		}
+		if fence {
+			code = &strings.Builder{}
+			continue
+		}
+
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			s.headings = append(s.headings, Heading{Level: len(m[1]), Title: strings.TrimRight(m[2], " \t#=")})
+		} else if listItemRe.MatchString(line) {
+			s.listItems++
+		}
+		for _, m := range linkRe.FindAllStringSubmatch(line, -1) {
+			s.links = append(s.links, m[1]+m[2]+m[3])
+		}
+	}
+	if code != nil {
+		s.codeBlocks = append(s.codeBlocks, code.String())
+	}
+	return s
+}
+
+// CompareStructure builds the StructureReport of source and its translation.
+func CompareStructure(source, translation string) *StructureReport {
+	src, dst := parseStructure(source), parseStructure(translation)
+	r := &StructureReport{
+		SourceListItems:  src.listItems,
+		TargetListItems:  dst.listItems,
+		SourceCodeBlocks: len(src.codeBlocks),
+		TargetCodeBlocks: len(dst.codeBlocks),
+	}
+
+	for i := 0; i < len(src.headings) || i < len(dst.headings); i++ {
+		var row HeadingRow
+		if i < len(src.headings) {
+			row.Source = &src.headings[i]
+		}
+		if i < len(dst.headings) {
+			row.Target = &dst.headings[i]
+		}
+		r.Headings = append(r.Headings, row)
+	}
+
+	for i, block := range src.codeBlocks {
+		if i < len(dst.codeBlocks) && dst.codeBlocks[i] != block {
+			r.ChangedCodeBlocks = append(r.ChangedCodeBlocks, i+1)
+		}
+	}
+
+	r.MissingLinks = missingItems(src.links, dst.links)
+	r.ExtraLinks = missingItems(dst.links, src.links)
+	return r
+}
+
+// missingItems returns the items of want that got lacks, counting repeats.
+func missingItems(want, got []string) []string {
+	counts := map[string]int{}
+	for _, item := range got {
+		counts[item]++
+	}
+	var missing []string
+	for _, item := range want {
+		if counts[item] > 0 {
+			counts[item]--
+		} else {
+			missing = append(missing, item)
+		}
+	}
+	return missing
+}
+
+// Problems counts the structural differences found.
+func (r *StructureReport) Problems() int {
+	n := len(r.ChangedCodeBlocks) + len(r.MissingLinks) + len(r.ExtraLinks)
+	for _, row := range r.Headings {
+		if row.Broken() {
+			n++
+		}
+	}
+	if r.SourceListItems != r.TargetListItems {
+		n++
+	}
+	if r.SourceCodeBlocks != r.TargetCodeBlocks {
+		n++
+	}
+	return n
+}
+
+var structureTemplate = template.Must(template.New("structure").Funcs(template.FuncMap{
+	"indent": func(h *Heading) string { return strings.Repeat("  ", h.Level-1) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Structure report: {{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+td, th { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+.bad { background: #fdd; }
+.ok { color: #070; }
+</style>
+</head>
+<body>
+<h1>Structure report: {{.Title}}</h1>
+{{with .Report}}<p>{{if .Problems}}{{.Problems}} structural differences found.{{else}}<span class="ok">No structural differences found.</span>{{end}}</p>
+
+<h2>Headings</h2>
+<table>
+<tr><th>Source</th><th>Translation</th></tr>
+{{range .Headings}}<tr{{if .Broken}} class="bad"{{end}}>
+<td>{{with .Source}}{{indent .}}H{{.Level}} {{.Title}}{{else}}missing{{end}}</td>
+<td>{{with .Target}}{{indent .}}H{{.Level}} {{.Title}}{{else}}missing{{end}}</td>
+</tr>
+{{end}}</table>
+
+<h2>Lists and code</h2>
+<table>
+<tr><th></th><th>Source</th><th>Translation</th></tr>
+<tr{{if ne .SourceListItems .TargetListItems}} class="bad"{{end}}><td>List items</td><td>{{.SourceListItems}}</td><td>{{.TargetListItems}}</td></tr>
+<tr{{if ne .SourceCodeBlocks .TargetCodeBlocks}} class="bad"{{end}}><td>Code blocks</td><td>{{.SourceCodeBlocks}}</td><td>{{.TargetCodeBlocks}}</td></tr>
+</table>
+{{if .ChangedCodeBlocks}}<p class="bad">Code blocks changed by the translation: {{range $i, $n := .ChangedCodeBlocks}}{{if $i}}, {{end}}#{{$n}}{{end}}</p>{{end}}
+
+<h2>Links</h2>
+{{if or .MissingLinks .ExtraLinks}}<table>
+<tr><th>Link target</th><th>Problem</th></tr>
+{{range .MissingLinks}}<tr class="bad"><td>{{.}}</td><td>missing from the translation</td></tr>
+{{end}}{{range .ExtraLinks}}<tr class="bad"><td>{{.}}</td><td>not in the source</td></tr>
+{{end}}</table>{{else}}<p class="ok">All link targets match.</p>{{end}}
+{{end}}</body>
+</html>
+`))
+
+// WriteHTML renders the report as a standalone HTML page titled title,
+// usually the name of the document.
+func (r *StructureReport) WriteHTML(w io.Writer, title string) error {
+	return structureTemplate.Execute(w, struct {
+		Title  string
+		Report *StructureReport
+	}{title, r})
+}
@@ -0,0 +1,43 @@
+package translator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareStructure(t *testing.T) {
+	source := "# Guide\n\nSee [the docs](https://example.com/docs) and <https://example.com>.\n\n## Install\n\n- one\n- two\n\n```sh\nmake install\n```\n\n## Usage\n\n1. run\n"
+	translation := "# Anleitung\n\nSiehe [die Doku](https://example.com/docs).\n\n### Installation\n\n- eins\n- zwei\n\n```sh\nmake installieren\n```\n\n## Verwendung\n\n1. starten\n"
+
+	r := CompareStructure(source, translation)
+	if len(r.Headings) != 3 || r.Headings[0].Broken() || !r.Headings[1].Broken() || r.Headings[2].Broken() {
+		t.Errorf("Expected only the second heading re-leveled, got %+v", r.Headings)
+	}
+	if r.SourceListItems != 3 || r.TargetListItems != 3 {
+		t.Errorf("Expected 3 list items on both sides, got %d and %d", r.SourceListItems, r.TargetListItems)
+	}
+	if !reflect.DeepEqual(r.ChangedCodeBlocks, []int{1}) {
+		t.Errorf("Expected the translated code block reported, got %v", r.ChangedCodeBlocks)
+	}
+	if !reflect.DeepEqual(r.MissingLinks, []string{"https://example.com"}) || len(r.ExtraLinks) != 0 {
+		t.Errorf("Expected the dropped autolink reported, got %v and %v", r.MissingLinks, r.ExtraLinks)
+	}
+	if r.Problems() != 3 {
+		t.Errorf("Expected 3 problems, got %d", r.Problems())
+	}
+
+	var html strings.Builder
+	if err := r.WriteHTML(&html, "guide.md"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"3 structural differences", `<tr class="bad">`, "H3 Installation", "https://example.com</td><td>missing"} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("Expected %q in the report", want)
+		}
+	}
+
+	if r := CompareStructure(source, source); r.Problems() != 0 {
+		t.Errorf("Expected no problems comparing a document with itself, got %+v", r)
+	}
+}
@@ -0,0 +1,29 @@
+package translator
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// structuredAnswer is the JSON object asked for in structured output mode,
+// see Config.NoStructuredOutput.
+type structuredAnswer struct {
+	Translation *string `json:"translation"`
+}
+
+// extractStructured takes the translation out of an answer asked for as a
+// JSON object. Backends that do not enforce the format may wrap the object
+// in a code fence or keep to the <result> tag, both of which are accepted.
+func (t *Translator) extractStructured(answer string) (string, error) {
+	answer = thinkBlockRe.ReplaceAllString(answer, "")
+	object := wrappingFenceRe.ReplaceAllString(strings.TrimSpace(answer), "$1")
+
+	var parsed structuredAnswer
+	if err := json.Unmarshal([]byte(object), &parsed); err == nil && parsed.Translation != nil {
+		return *parsed.Translation, nil
+	}
+	if translation, err := t.extractResultTag(answer); err == nil {
+		return translation, nil
+	}
+	return "", classify(ErrorClassExtraction, &missingTagError{answer: answer, structured: true})
+}
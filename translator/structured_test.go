@@ -0,0 +1,85 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractStructured(t *testing.T) {
+	tr := NewTranslator(Config{})
+	testCases := []struct{ answer, expected string }{
+		{`{"translation": "Hallo \"Welt\".\n"}`, "Hallo \"Welt\".\n"},
+		{"```json\n{\"translation\": \"Hallo Welt.\"}\n```", "Hallo Welt."},
+		{`<think>Easy.</think> {"translation": "Hallo Welt."}`, "Hallo Welt."},
+		{"<result>Hallo Welt.</result>", "Hallo Welt."},
+	}
+	for _, tc := range testCases {
+		if got, err := tr.extractStructured(tc.answer); err != nil || got != tc.expected {
+			t.Errorf("%q: expected %q, got %q (%v)", tc.answer, tc.expected, got, err)
+		}
+	}
+
+	for _, answer := range []string{`{"translation": "Hallo`, `{"text": "Hallo Welt."}`, "Hallo Welt."} {
+		_, err := tr.extractStructured(answer)
+		if !isMissingTag(err) || ErrorClass(err) != ErrorClassExtraction {
+			t.Errorf("%q: expected an extraction error, got %v", answer, err)
+		}
+		if _, ok := salvage(err); ok {
+			t.Errorf("%q: expected no salvage of a broken JSON answer", answer)
+		}
+	}
+}
+
+func TestStructuredOutput(t *testing.T) {
+	var requests []OpenRouterRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		answer := `{"translation": "Hallo Welt."}`
+		if len(requests) == 1 {
+			answer = `{"translation": "Hallo`
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": answer}}},
+		})
+	}))
+	defer server.Close()
+
+	config := Config{BaseURL: server.URL, APIKey: "key", Model: "openai/gpt-4o-mini", ToLang: "german",
+		ChunkSize: 100, NoDelay: true, Retry: RetryPolicy{Backoff: time.Millisecond}}
+	tr := NewTranslator(config)
+	out, err := tr.TranslateText(context.Background(), "Hello world.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "Hallo Welt." {
+		t.Errorf("Expected the translation of the JSON answer, got %q", out)
+	}
+	// The truncated object is asked for again.
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	if f := requests[0].ResponseFormat; f == nil || f.Type != "json_object" {
+		t.Errorf("Expected response_format json_object, got %+v", f)
+	}
+	if system := requests[1].Messages[0].Content; !strings.Contains(system, `{"translation": "..."}`) || !strings.Contains(system, "was not a JSON object") {
+		t.Errorf("Expected the retry to insist on the JSON object, got %q", system)
+	}
+
+	// Models without JSON mode, and runs with structured output off, keep
+	// the tag.
+	for _, c := range []Config{{Model: "anthropic/claude-3.5-sonnet"}, {Model: "openai/gpt-4o-mini", NoStructuredOutput: true}} {
+		requests = nil
+		config.Model, config.NoStructuredOutput = c.Model, c.NoStructuredOutput
+		NewTranslator(config).TranslateText(context.Background(), "Hello world.")
+		if len(requests) == 0 || requests[0].ResponseFormat != nil || !strings.Contains(requests[0].Messages[0].Content, "<result>") {
+			t.Errorf("%s: expected the <result> tag, got %+v", c.Model, requests)
+		}
+	}
+}
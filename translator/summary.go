@@ -0,0 +1,56 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// summaryMaxWords bounds the running summary, which goes into every prompt.
+const summaryMaxWords = 200
+
+// currentSummary returns the running summary of the document so far, see
+// Config.Summary.
+func (t *Translator) currentSummary() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.summary
+}
+
+// updateSummary folds a translated chunk into the running summary. A failed
+// update is reported as a warning and keeps the summary as it was.
+func (t *Translator) updateSummary(ctx context.Context, chunk int, source, translation string) {
+	if !t.config.Summary || strings.TrimSpace(translation) == "" {
+		return
+	}
+	previous := t.currentSummary()
+	if previous == "" {
+		previous = "(none yet)"
+	}
+
+	model := t.activeModel()
+	completion, err := t.provider.Complete(ctx, CompletionRequest{
+		Model: model,
+		System: fmt.Sprintf("You keep a running summary of a document that is being translated into %s language. "+
+			"Update the summary with the new passage of the user message. First list the characters, people and places with their names as translated, their gender and role, and key terms with their translation; then sum up the content so far in brief. "+
+			"Write it in %s language, in at most %d words. Place the answer in the tag <result>",
+			t.config.ToLang, t.config.ToLang, summaryMaxWords),
+		Prompt: fmt.Sprintf("Summary so far:\n\n<summary>\n%s\n</summary>\n\nNew passage:\n\n<source>%s</source>\n<translation>%s</translation>",
+			previous, source, translation),
+		ToLang: t.config.ToLang,
+		RunID:  t.runID,
+	})
+	var summary string
+	if err == nil {
+		t.addCompletionUsage(completion)
+		summary, err = t.extractTranslation(model, completion.Text)
+	}
+	if err != nil {
+		t.warn(Warning{Kind: WarningSummary, Chunk: chunk, Message: fmt.Sprintf("summary not updated after chunk %d: %v", chunk, err)})
+		return
+	}
+
+	t.mu.Lock()
+	t.summary = strings.TrimSpace(summary)
+	t.mu.Unlock()
+}
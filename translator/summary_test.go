@@ -0,0 +1,66 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// summaryProvider answers summary updates with a fixed summary, or fails
+// them, and records the translation prompts.
+type summaryProvider struct {
+	promptRecorder
+	fail bool
+}
+
+func (p *summaryProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	if strings.Contains(cr.System, "running summary") {
+		if p.fail {
+			return nil, errors.New("no summary today")
+		}
+		return &Completion{Text: "<result>Anna: she, the baker.</result>"}, nil
+	}
+	return p.promptRecorder.Complete(ctx, cr)
+}
+
+func TestSummary(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	out := filepath.Join(dir, "out.txt")
+	first := "Anna baked bread every morning before the sun rose over the hills and the quiet village, long before anyone else in the valley was awake."
+	second := "She sold it at the market in the square, where everybody knew her name, her loaves and the little bell she rang at noon every day."
+	os.WriteFile(in, []byte(first+"\n\n"+second+"\n"), 0644)
+
+	provider := &summaryProvider{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 50, NoDelay: true, ToLang: "german", Summary: true})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.prompts) != 2 {
+		t.Fatalf("Expected a translation request per paragraph, got %q", provider.prompts)
+	}
+	if strings.Contains(provider.prompts[0], "<summary>") {
+		t.Errorf("Expected no summary before the first chunk, got %q", provider.prompts[0])
+	}
+	if !strings.Contains(provider.prompts[1], "<summary>\nAnna: she, the baker.\n</summary>") {
+		t.Errorf("Expected the summary in the second prompt, got %q", provider.prompts[1])
+	}
+	if got, _ := os.ReadFile(out); strings.Contains(string(got), "BAKER") {
+		t.Errorf("Expected the summary left out of the output, got %q", got)
+	}
+
+	provider = &summaryProvider{fail: true}
+	tr = NewTranslator(Config{Provider: provider, ChunkSize: 50, NoDelay: true, ToLang: "german", Summary: true})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if warnings := tr.Result().Warnings; len(warnings) != 2 || warnings[0].Kind != WarningSummary {
+		t.Errorf("Expected a summary warning per chunk, got %v", warnings)
+	}
+	if strings.Contains(provider.prompts[1], "<summary>") {
+		t.Errorf("Expected no summary after failed updates, got %q", provider.prompts[1])
+	}
+}
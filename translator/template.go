@@ -0,0 +1,21 @@
+package translator
+
+import "regexp"
+
+// templateRes match the syntax of template engines, so tutorials about
+// them keep their examples: Jinja and Liquid raw blocks, comments and tags,
+// Go, Handlebars, Jinja and Liquid expressions, and JavaScript and shell
+// interpolation. None of them spans a blank line.
+var templateRes = []*regexp.Regexp{
+	regexp.MustCompile(`\{%-?\s*raw\s*-?%\}(?:[^\n]|\n[^\n])*?\{%-?\s*endraw\s*-?%\}`),
+	regexp.MustCompile(`\{#(?:[^\n]|\n[^\n])*?#\}`),
+	regexp.MustCompile(`\{%(?:[^\n]|\n[^\n])*?%\}`),
+	regexp.MustCompile(`\{\{(?:[^\n]|\n[^\n])*?\}\}`),
+	regexp.MustCompile(`\$\{[^{}\n]*\}`),
+}
+
+// maskTemplates protects the template syntax of text, appending it to
+// spans.
+func maskTemplates(text string, spans *[]string) string {
+	return maskMore(text, spans, templateRes...)
+}
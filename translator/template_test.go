@@ -0,0 +1,35 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaskTemplates(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "liquid.md")
+	out := filepath.Join(dir, "liquid.de.md")
+	source := "Print the title with {{ page.title | upcase }}.\n\n" +
+		"{% for post in site.posts %}\n  List {{ post.url }}\n{% endfor %}\n\n" +
+		"{# a Jinja comment #} Show {% raw %}{{ literally }}{% endraw %} and ${user.name} as they are.\n"
+	os.WriteFile(in, []byte(source), 0644)
+
+	provider := &promptRecorder{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "german"})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	for _, prompt := range provider.prompts {
+		if strings.ContainsAny(prompt, "{}$") {
+			t.Errorf("Expected template syntax kept from the model, got %q", prompt)
+		}
+	}
+	want := "PRINT THE TITLE WITH {{ page.title | upcase }}.\n\n" +
+		"{% for post in site.posts %}\n  LIST {{ post.url }}\n{% endfor %}\n\n" +
+		"{# a Jinja comment #} SHOW {% raw %}{{ literally }}{% endraw %} AND ${user.name} AS THEY ARE.\n"
+	if got, _ := os.ReadFile(out); string(got) != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,11 @@
+#set page(paper: "a4")
+#import "template.typ": conf
+
+= Introduction <intro>
+
+Typst documents mix markup with code. The sum $a + b$ and the call #emph[emphasis]
+must survive translation, see @intro.
+
+= Method
+
+We measured everything twice [truncate] to be sure.
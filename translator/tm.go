@@ -0,0 +1,342 @@
+package translator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTMThreshold     = 0.85
+	defaultTMMaxReferences = 3
+)
+
+// tmEntry is one translation memory segment: a source paragraph, its
+// translation and the embedding of the source.
+type tmEntry struct {
+	Lang      string    `json:"lang"`
+	Source    string    `json:"source"`
+	Target    string    `json:"target"`
+	Model     string    `json:"model,omitempty"`
+	Embedding []float64 `json:"embedding"`
+	// Document is the input file the segment came from.
+	Document string    `json:"document,omitempty"`
+	Created  time.Time `json:"created"`
+}
+
+type tmMatch struct {
+	entry tmEntry
+	score float64
+}
+
+// translationMemory is an append-only JSON lines file of tmEntry records.
+// With a key, new records are encrypted one line at a time.
+type translationMemory struct {
+	mu      sync.Mutex
+	path    string
+	key     []byte
+	entries []tmEntry
+}
+
+func openTranslationMemory(path string, key []byte) (*translationMemory, error) {
+	tm := &translationMemory{path: path, key: key}
+
+	if err := dropTornTail(path); err != nil {
+		return nil, fmt.Errorf("failed to open translation memory: %w", err)
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return tm, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open translation memory: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		record, err := OpenRecord(key, scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("translation memory %s line %d: %w", path, line, err)
+		}
+		var e tmEntry
+		if err := json.Unmarshal(record, &e); err != nil {
+			return nil, fmt.Errorf("translation memory %s line %d: %w", path, line, err)
+		}
+		tm.entries = append(tm.entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read translation memory: %w", err)
+	}
+
+	return tm, nil
+}
+
+func (tm *translationMemory) add(entries []tmEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var lines [][]byte
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if tm.key != nil {
+			if line, err = SealRecord(tm.key, line); err != nil {
+				return err
+			}
+		}
+		lines = append(lines, line)
+	}
+	if err := appendRecords(tm.path, 0644, lines...); err != nil {
+		return fmt.Errorf("failed to write translation memory: %w", err)
+	}
+
+	tm.mu.Lock()
+	tm.entries = append(tm.entries, entries...)
+	tm.mu.Unlock()
+	return nil
+}
+
+// has reports whether the memory holds a translation of source, a trimmed
+// paragraph or chunk, into lang.
+func (tm *translationMemory) has(lang, source string) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	for _, e := range tm.entries {
+		if e.Lang == lang && e.Source == source {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether the memory holds source translated into lang,
+// whole or paragraph by paragraph, as rememberTranslation stores it.
+func (tm *translationMemory) covers(lang, source string) bool {
+	if tm.has(lang, strings.TrimSpace(source)) {
+		return true
+	}
+	segments := splitSegments(source)
+	for _, segment := range segments {
+		if !tm.has(lang, segment) {
+			return false
+		}
+	}
+	return len(segments) > 0
+}
+
+func (tm *translationMemory) size() int {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return len(tm.entries)
+}
+
+// search returns up to limit entries for lang whose source embedding is at
+// least threshold similar to vector, best first.
+func (tm *translationMemory) search(lang string, vector []float64, threshold float64, limit int) []tmMatch {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	var matches []tmMatch
+	for _, e := range tm.entries {
+		if e.Lang != lang {
+			continue
+		}
+		if score := cosineSimilarity(vector, e.Embedding); score >= threshold {
+			matches = append(matches, tmMatch{entry: e, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// splitSegments splits text into non-empty paragraphs.
+func splitSegments(text string) []string {
+	var segments []string
+	for _, p := range strings.Split(text, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			segments = append(segments, p)
+		}
+	}
+	return segments
+}
+
+// tmReferences looks up prior translations similar to the paragraphs of
+// source. Lookup problems are logged and yield no references.
+func (t *Translator) tmReferences(ctx context.Context, source string) []tmEntry {
+	if t.tm == nil || t.tm.size() == 0 {
+		return nil
+	}
+
+	segments := splitSegments(source)
+	vectors, err := t.embed(ctx, segments)
+	if err != nil {
+		if t.config.Verbose {
+			fmt.Printf("Translation memory lookup failed: %v\n", err)
+		}
+		return nil
+	}
+
+	threshold := t.config.TMThreshold
+	if threshold <= 0 {
+		threshold = defaultTMThreshold
+	}
+	limit := t.config.TMMaxReferences
+	if limit <= 0 {
+		limit = defaultTMMaxReferences
+	}
+
+	seen := map[string]bool{}
+	var best []tmMatch
+	for _, v := range vectors {
+		for _, m := range t.tm.search(t.config.ToLang, v, threshold, limit) {
+			if !seen[m.entry.Source] {
+				seen[m.entry.Source] = true
+				best = append(best, m)
+			}
+		}
+	}
+
+	sort.SliceStable(best, func(i, j int) bool {
+		return best[i].score > best[j].score
+	})
+	if len(best) > limit {
+		best = best[:limit]
+	}
+
+	refs := make([]tmEntry, len(best))
+	for i, m := range best {
+		refs[i] = m.entry
+	}
+	return refs
+}
+
+// rememberTranslation stores the paragraphs of a translated chunk in the
+// translation memory. Paragraphs are paired when both sides have the same
+// number of them, otherwise the chunk is stored whole.
+func (t *Translator) rememberTranslation(ctx context.Context, source, target string) {
+	if t.tm == nil || t.config.NoPersist {
+		return
+	}
+
+	sources, targets := splitSegments(source), splitSegments(target)
+	if len(sources) != len(targets) {
+		sources, targets = []string{strings.TrimSpace(source)}, []string{strings.TrimSpace(target)}
+	}
+
+	vectors, err := t.embed(ctx, sources)
+	if err != nil {
+		if t.config.Verbose {
+			fmt.Printf("Could not add chunk to translation memory: %v\n", err)
+		}
+		return
+	}
+
+	var document string
+	if t.result.Input != "" {
+		document, _ = filepath.Abs(t.result.Input)
+	}
+	entries := make([]tmEntry, len(sources))
+	for i := range sources {
+		entries[i] = tmEntry{
+			Lang:      t.config.ToLang,
+			Source:    sources[i],
+			Target:    targets[i],
+			Model:     t.activeModel(),
+			Embedding: vectors[i],
+			Document:  document,
+			Created:   time.Now().UTC(),
+		}
+	}
+
+	if err := t.tm.add(entries); err != nil && t.config.Verbose {
+		fmt.Printf("Could not add chunk to translation memory: %v\n", err)
+	}
+}
+
+// ScrubTranslationMemory rewrites the memory at path without the entries for
+// which remove returns true, and reports how many were removed. remove gets
+// the entry's document and creation time, which is zero for entries written
+// before it was recorded. Kept entries are copied unchanged, encrypted ones
+// stay encrypted.
+func ScrubTranslationMemory(path string, key []byte, remove func(document string, created time.Time) bool) (int, error) {
+	if err := dropTornTail(path); err != nil {
+		return 0, fmt.Errorf("failed to read translation memory: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read translation memory: %w", err)
+	}
+
+	var kept strings.Builder
+	removed := 0
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		record, err := OpenRecord(key, []byte(line))
+		if err != nil {
+			return 0, fmt.Errorf("translation memory %s line %d: %w", path, i+1, err)
+		}
+		var e tmEntry
+		if err := json.Unmarshal(record, &e); err != nil {
+			return 0, fmt.Errorf("translation memory %s line %d: %w", path, i+1, err)
+		}
+
+		if remove(e.Document, e.Created) {
+			removed++
+			continue
+		}
+		kept.WriteString(line)
+		kept.WriteByte('\n')
+	}
+
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := WriteFileAtomic(path, []byte(kept.String()), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write translation memory: %w", err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,111 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEmbeddings serves embeddings that only depend on whether the input
+// mentions cats, so "cat" sentences are similar to each other.
+func fakeEmbeddings(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req embeddingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Invalid embeddings request: %v", err)
+		}
+
+		var resp embeddingsResponse
+		for i, in := range req.Input {
+			v := []float64{0, 1}
+			if strings.Contains(strings.ToLower(in), "cat") {
+				v = []float64{1, 0.1}
+			}
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float64 `json:"embedding"`
+			}{i, v})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestTranslationMemory(t *testing.T) {
+	server := fakeEmbeddings(t)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "tm.jsonl")
+	translator := NewTranslator(Config{ToLang: "russian", TMPath: path, EmbeddingsURL: server.URL})
+
+	tm, err := openTranslationMemory(path, nil)
+	if err != nil {
+		t.Fatalf("openTranslationMemory failed: %v", err)
+	}
+	translator.tm = tm
+
+	translator.rememberTranslation(context.Background(), "The cat sleeps.\n\nThe sun rises.", "Кошка спит.\n\nСолнце встаёт.")
+
+	reopened, err := openTranslationMemory(path, nil)
+	if err != nil {
+		t.Fatalf("Reopening translation memory failed: %v", err)
+	}
+	if len(reopened.entries) != 2 {
+		t.Fatalf("Expected 2 paired entries, got %d", len(reopened.entries))
+	}
+	translator.tm = reopened
+
+	refs := translator.tmReferences(context.Background(), "A cat sleeps on the sofa.")
+	if len(refs) != 1 || refs[0].Target != "Кошка спит." {
+		t.Errorf("Expected the cat paragraph as reference, got %+v", refs)
+	}
+
+	translator.config.ToLang = "german"
+	if refs := translator.tmReferences(context.Background(), "A cat sleeps on the sofa."); len(refs) != 0 {
+		t.Errorf("Expected no references for another language, got %+v", refs)
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	if s := cosineSimilarity([]float64{1, 0}, []float64{2, 0}); s < 0.999 {
+		t.Errorf("Expected parallel vectors to be similar, got %f", s)
+	}
+	if s := cosineSimilarity([]float64{1, 0}, []float64{0, 1}); s != 0 {
+		t.Errorf("Expected orthogonal vectors to score 0, got %f", s)
+	}
+	if s := cosineSimilarity([]float64{1}, []float64{1, 0}); s != 0 {
+		t.Errorf("Expected mismatched dimensions to score 0, got %f", s)
+	}
+}
+
+func TestScrubTranslationMemory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tm.jsonl")
+	tm, err := openTranslationMemory(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().AddDate(0, 0, -40)
+	tm.add([]tmEntry{
+		{Source: "old", Document: "/docs/a.md", Created: old},
+		{Source: "private", Document: "/private/b.md", Created: time.Now()},
+		{Source: "fresh", Document: "/docs/c.md", Created: time.Now()},
+	})
+
+	cutoff := time.Now().AddDate(0, 0, -30)
+	removed, err := ScrubTranslationMemory(path, nil, func(document string, created time.Time) bool {
+		return created.Before(cutoff) || strings.HasPrefix(document, "/private/")
+	})
+	if err != nil || removed != 2 {
+		t.Fatalf("Expected 2 entries removed, got %d, %v", removed, err)
+	}
+
+	reopened, err := openTranslationMemory(path, nil)
+	if err != nil || reopened.size() != 1 || reopened.entries[0].Source != "fresh" {
+		t.Errorf("Expected only the fresh entry to remain, got %+v, %v", reopened.entries, err)
+	}
+}
@@ -0,0 +1,125 @@
+package translator
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer counts the tokens a model sees in text. Chunk sizes, estimates
+// and verbose output use it. Adapters for exact BPE tokenizers such as
+// tiktoken-go plug in through Config.Tokenizers.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// TokenizerFunc adapts a function to the Tokenizer interface.
+type TokenizerFunc func(text string) int
+
+func (f TokenizerFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// EstimateTokens approximates the token count of text by script: four ASCII
+// characters make a token, accented Latin letters half a token, letters of
+// alphabets such as Cyrillic, Greek or Arabic 0.7 and each CJK, Kana, Hangul
+// or other character a whole token, in line with common BPE vocabularies.
+// It is the default Tokenizer.
+var EstimateTokens Tokenizer = TokenizerFunc(estimateTokens)
+
+func estimateTokens(text string) int {
+	tokens := 0.0
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf:
+			tokens += 0.25
+		case unicode.Is(unicode.Latin, r):
+			tokens += 0.5
+		case unicode.In(r, unicode.Cyrillic, unicode.Greek, unicode.Armenian, unicode.Georgian, unicode.Arabic, unicode.Hebrew):
+			tokens += 0.7
+		default:
+			tokens += 1
+		}
+	}
+	return int(tokens)
+}
+
+// tokenizer returns the Tokenizer of the active model, the one registered
+// in Config.Tokenizers under the longest matching model ID prefix, or
+// EstimateTokens.
+func (t *Translator) tokenizer() Tokenizer {
+	model := t.activeModel()
+	var best Tokenizer = EstimateTokens
+	bestLen := -1
+	for prefix, tok := range t.config.Tokenizers {
+		if strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			best, bestLen = tok, len(prefix)
+		}
+	}
+	return best
+}
+
+// maskBrackets stands in ASCII for the brackets of protected span
+// placeholders while counting tokens.
+var maskBrackets = strings.NewReplacer(maskOpen, "[[[", maskClose, "]]]")
+
+// countTokens counts the tokens of text for the active model. The brackets
+// of placeholders count as the three bytes of ASCII they take, so masking
+// protected spans does not move a document's chunk boundaries.
+func (t *Translator) countTokens(text string) int {
+	return t.tokenizer().CountTokens(maskBrackets.Replace(text))
+}
+
+// splitByTokens cuts text without sentence breaks into pieces of about size
+// tokens. Pieces end at a space when there is one in their last fifth and
+// never inside a character or a grapheme cluster, so combining accents and
+// emoji sequences stay whole.
+func (t *Translator) splitByTokens(text string, size int) []string {
+	tokens := t.countTokens(text)
+	if tokens < 1 {
+		tokens = 1
+	}
+	step := len(text) * size / tokens
+	if step < utf8.UTFMax {
+		step = utf8.UTFMax
+	}
+
+	var pieces []string
+	for len(text) > step {
+		end := graphemeBoundary(text, step)
+		if space := strings.LastIndexAny(text[:end], " \t\n"); space >= end*4/5 {
+			end = space + 1
+		}
+		if end == 0 {
+			end = step
+			for !utf8.RuneStart(text[end]) {
+				end++
+			}
+		}
+		pieces = append(pieces, text[:end])
+		text = text[end:]
+	}
+	return append(pieces, text)
+}
+
+// graphemeBoundary returns the last position at or before i where text can
+// be cut without splitting a character, separating a combining mark or
+// variation selector from its base, or breaking a zero width joiner sequence.
+func graphemeBoundary(text string, i int) int {
+	for i > 0 {
+		if !utf8.RuneStart(text[i]) {
+			i--
+			continue
+		}
+		next, _ := utf8.DecodeRuneInString(text[i:])
+		prev, _ := utf8.DecodeLastRuneInString(text[:i])
+		if unicode.In(next, unicode.Mn, unicode.Me, unicode.Mc, unicode.Variation_Selector) || next == zeroWidthJoiner || prev == zeroWidthJoiner {
+			i--
+			continue
+		}
+		return i
+	}
+	return 0
+}
+
+const zeroWidthJoiner = '\u200d'
@@ -0,0 +1,94 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{strings.Repeat("abcd", 10), 10},
+		{strings.Repeat("привет", 10), 42},
+		{strings.Repeat("翻译", 10), 20},
+		{strings.Repeat("é", 10), 5},
+	}
+	for _, tc := range tests {
+		if got := EstimateTokens.CountTokens(tc.text); got != tc.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestTokenizerPerModel(t *testing.T) {
+	words := TokenizerFunc(func(text string) int { return len(strings.Fields(text)) })
+	tr := NewTranslator(Config{Model: "openai/gpt-4o", ChunkSize: 100, Tokenizers: map[string]Tokenizer{
+		"openai/":       TokenizerFunc(func(string) int { return 0 }),
+		"openai/gpt-4o": words,
+	}})
+	if got := tr.countTokens("one two three"); got != 3 {
+		t.Errorf("Expected the longest matching prefix to count, got %d", got)
+	}
+	tr = NewTranslator(Config{Model: "deepseek/deepseek-chat", Tokenizers: map[string]Tokenizer{"openai/": words}})
+	if got := tr.countTokens("one two three"); got != 3 {
+		t.Errorf("Expected the estimate for other models, got %d", got)
+	}
+}
+
+func TestSplitCJKByTokens(t *testing.T) {
+	// Without sentence breaks CJK text is cut by token count, never inside
+	// a character.
+	text := strings.Repeat("翻译文本", 100)
+	tr := NewTranslator(Config{ChunkSize: 100})
+	chunks := tr.splitIntoChunks(context.Background(), text)
+	if len(chunks) < 4 {
+		t.Fatalf("Expected 400 CJK tokens in several chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if !utf8.ValidString(c) {
+			t.Errorf("Chunk %d is cut inside a character", i+1)
+		}
+		if n := tr.countTokens(c); n > 100 {
+			t.Errorf("Chunk %d has %d tokens, more than the chunk size", i+1, n)
+		}
+	}
+	if strings.Join(chunks, "") != text {
+		t.Error("Expected the chunks to add up to the text")
+	}
+}
+
+func TestSplitKeepsGraphemes(t *testing.T) {
+	tr := NewTranslator(Config{ChunkSize: 100})
+	for name, text := range map[string]string{
+		"cyrillic":  strings.Repeat("съешьже", 200),
+		"combining": strings.Repeat("e\u0301a\u0308", 300),
+		"emoji":     strings.Repeat("\U0001F469\u200d\U0001F469\u200d\U0001F467", 200),
+		"words":     strings.Repeat("слово ", 300),
+	} {
+		chunks := tr.splitByTokens(text, 80)
+		if len(chunks) < 2 {
+			t.Errorf("%s: expected several chunks, got %d", name, len(chunks))
+		}
+		if strings.Join(chunks, "") != text {
+			t.Errorf("%s: expected the chunks to add up to the text", name)
+		}
+		for i, c := range chunks {
+			first, _ := utf8.DecodeRuneInString(c)
+			last, _ := utf8.DecodeLastRuneInString(c)
+			if !utf8.ValidString(c) || first == '\u0301' || first == '\u0308' || first == '\u200d' || last == '\u200d' {
+				t.Errorf("%s: chunk %d splits a character or grapheme: %q", name, i+1, c)
+			}
+		}
+		if name == "words" {
+			for i, c := range chunks[:len(chunks)-1] {
+				if !strings.HasSuffix(c, " ") {
+					t.Errorf("words: expected chunk %d to end at a space, got %q", i+1, c[len(c)-10:])
+				}
+			}
+		}
+	}
+}
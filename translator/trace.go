@@ -0,0 +1,21 @@
+package translator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// newRunID returns a random ID for one translation run. It is sent with every
+// request and appears in progress events, the audit log and the Result, so a
+// failed request can be matched with the provider's logs.
+func newRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// chunkID identifies chunk i of the current run.
+func (t *Translator) chunkID(i int) string {
+	return fmt.Sprintf("%s-%d", t.runID, i+1)
+}
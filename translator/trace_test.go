@@ -0,0 +1,54 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRequestIDs(t *testing.T) {
+	var mu sync.Mutex
+	var requestIDs []string
+	handler := echoHandler(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requestIDs = append(requestIDs, r.Header.Get("X-Run-Id")+" "+r.Header.Get("X-Request-Id"))
+		mu.Unlock()
+		handler(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	audit := filepath.Join(dir, "audit.jsonl")
+	var progress bytes.Buffer
+	translator := NewTranslator(Config{BaseURL: server.URL, ChunkSize: 100, NoDelay: true, AuditPath: audit, ProgressOutput: &progress})
+	if err := translator.Translate(context.Background(), strings.NewReader("hello"), &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	run := translator.Result().RunID
+	if run == "" {
+		t.Fatal("Expected the result to carry a run ID")
+	}
+	if len(requestIDs) != 1 || requestIDs[0] != run+" "+run+"-1" {
+		t.Errorf("Expected run and chunk ID headers, got %q for run %s", requestIDs, run)
+	}
+
+	records, err := VerifyAuditLog(audit, nil)
+	if err != nil || len(records) != 1 || records[0].RunID != run || records[0].ChunkID != run+"-1" {
+		t.Errorf("Expected the audit record to carry the IDs, got %+v, %v", records, err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(progress.String()), "\n") {
+		var ev ProgressEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil || ev.Run != run {
+			t.Errorf("Expected progress event for run %s, got %s", run, line)
+		}
+	}
+}
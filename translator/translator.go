@@ -2,7 +2,7 @@ package translator
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,140 +10,1228 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Config struct {
-	APIKey     string
-	ToLang     string
-	ChunkSize  int
-	Model      string
-	Verbose    bool
+	APIKey    string
+	ToLang    string
+	ChunkSize int
+	Model     string
+	Verbose   bool
+	// MaxRetries is the default number of retries per failure kind, see
+	// RetryPolicy.
 	MaxRetries int
+	Retry      RetryPolicy
+	Format     string
+	// BaseURL is the OpenRouter compatible API root, by default
+	// https://openrouter.ai/api/v1.
+	BaseURL    string
+	PreferFree bool
+	// RaceModel, when set, receives every chunk alongside the active model;
+	// the first valid answer wins and the other request is cancelled.
+	RaceModel string
+	// FallbackModels are tried in order for a chunk whose retries with the
+	// active model are used up.
+	FallbackModels []string
+	// Temperature, TopP, MaxTokens and Seed override the sampling of every
+	// request; nil and zero keep the model profile's or the provider's
+	// default. Retries after unusable answers still lower the temperature
+	// and add to the seed, and Deterministic takes precedence over both.
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+	Seed        *int
+	// BatchAPI submits all chunks through a provider's asynchronous batch
+	// endpoint instead of one request each: BatchAPIOpenAI or
+	// BatchAPIAnthropic. The API key and BaseURL must be the provider's own.
+	BatchAPI          string
+	BatchPollInterval time.Duration
+	// Backend picks the built-in Provider: BackendOpenRouter (the default),
+	// BackendOllama, a local Ollama server that needs no API key, or
+	// BackendDeepL, the DeepL translation API.
+	Backend string
+	// Provider sends chunks to a model; nil uses the built-in Backend.
+	Provider Provider `json:"-"`
+	// HedgeDelay, when set, sends a chunk request a second time if no
+	// response bytes arrived within it and uses whichever answer comes first.
+	HedgeDelay time.Duration
+	// WarmUp opens the API connection before the first chunk.
+	WarmUp bool
+	// Concurrency is the number of chunks translated at the same time.
+	Concurrency int
+	// Schedule is the order chunks are handed to concurrent workers:
+	// ScheduleFIFO or ScheduleLargestFirst.
+	Schedule string
+	// Delay is the pause before the next chunk request once a chunk is done.
+	// Zero picks a delay from the chunk size; NoDelay disables pausing, which
+	// is what local models want.
+	Delay   time.Duration
+	NoDelay bool
+	// RateLimit, when set, is the number of chunk requests a minute the
+	// run sends at most, retries and hedged requests included, spaced
+	// evenly across its workers. It replaces the delay picked from the
+	// chunk size; NoDelay does not lift it.
+	RateLimit float64
+	// Until, when set, pauses the job at the first chunk boundary after this
+	// time with an error of class ErrorClassPaused; Result.Checkpoint then
+	// resumes it with TranslatePrepared.
+	Until time.Time
+	// MaxChunks and MaxCost, when set, stop a job with an error of class
+	// ErrorClassBudget before the first request if it has more chunks or
+	// its estimated cost in USD is higher, see Estimate. A cost that cannot
+	// be estimated counts as too high. ConfirmBudget, when set, is asked
+	// instead and lets the job go ahead by returning true.
+	MaxChunks     int
+	MaxCost       float64
+	ConfirmBudget func(est *Estimate, problem error) bool `json:"-"`
+	// Deterministic makes runs reproducible where the provider honors seeds:
+	// requests use temperature 0 and a fixed seed, chunks are translated one
+	// at a time, racing, hedging and free model selection are off, and
+	// Result.Inputs records what the output depends on.
+	Deterministic bool
+	// Gate, when set, is shared with other translators and decides which
+	// chunk request goes next; Priority is this translator's tier.
+	Gate     *PriorityGate `json:"-"`
+	Priority Priority
+	// ModelProfiles overrides the built-in request profiles, keyed by model
+	// ID prefix.
+	ModelProfiles map[string]ModelProfile
+	// TMPath enables the embedding based translation memory stored in this
+	// JSON lines file.
+	TMPath          string
+	TMThreshold     float64
+	TMMaxReferences int
+	EmbeddingsModel string
+	EmbeddingsURL   string
+	YAMLKeys        []string
+	// FrontMatterKeys are the keys, e.g. title and description, whose
+	// values are translated in the YAML or TOML front matter of Markdown
+	// and Quarto documents. The rest of the front matter, and all of it
+	// when FrontMatterKeys is empty, passes through untouched.
+	FrontMatterKeys []string
+	// FromLang is the language of the input, named in the prompt. When it
+	// is empty the language is detected from the first chunks, see
+	// DetectLanguage.
+	FromLang string
+	// Select, "heading:<title>", and SelectRegex, matched against heading
+	// titles, limit a run to the matching sections of a Markdown, Quarto or
+	// Typst document; everything else is written through unchanged.
+	Select      string
+	SelectRegex string
+	// ICU marks the input as ICU MessageFormat strings: answers with
+	// broken syntax or dropped or renamed arguments are retried, and kept
+	// untranslated when retries do not help. TranslateSegments always
+	// checks segments that are ICU messages.
+	ICU bool
+	// Tokenizers count tokens for the models whose ID starts with the key,
+	// the longest match winning; other models use EstimateTokens.
+	Tokenizers map[string]Tokenizer `json:"-"`
+	// SystemPrompt opens the system message of every request, e.g. with the
+	// audience or tone of the document; {to} stands for ToLang. The built-in
+	// instructions, such as answering in the <result> tag, follow it.
+	SystemPrompt string
+	// Glossary maps source terms to the translation the model must use;
+	// terms found in a chunk are listed in its prompt.
+	Glossary map[string]string
+	// EstimatesPath, when set, stores how far estimates were off per model
+	// and target language; every finished run refines the correction.
+	EstimatesPath string
+	// AuditPath, when set, records a hash chain of every API request in this
+	// JSON lines file, see AuditRecord.
+	AuditPath string
+	// Manifest writes a ChunkManifest next to the output file, named after
+	// it with ManifestSuffix, with the offsets, hashes and status of every
+	// chunk. It is not written for stream output or Config.Via, and is
+	// rewritten after every chunk. A run into an output file that has a
+	// manifest keeps the chunks done by the earlier run, see skipDone, even
+	// when that run was killed.
+	Manifest bool
+	// NoPersist keeps document text off disk: the translation memory is
+	// only read, never added to.
+	NoPersist bool
+	// Cache, when set, returns chunks translated by earlier runs instead of
+	// sending them again, see OpenFileCache. NoPersist turns it off.
+	Cache Cache `json:"-"`
+	// StorageKey, when set, encrypts what the translator stores locally,
+	// such as the translation memory. Derive it with the StorageKey function.
+	StorageKey []byte
+	// ReadKey opens records sealed by earlier runs without sealing new ones.
+	// It is not needed when StorageKey is set.
+	ReadKey []byte `json:"-"`
+	// ProgressOutput receives newline-delimited JSON progress events.
+	ProgressOutput io.Writer `json:"-"`
+	// Progress, when set, is called with every progress event, e.g.
+	// "chunk_start", "chunk_done" with the bytes written, or "retry". It may
+	// be called from several goroutines, but never concurrently.
+	Progress func(ev ProgressEvent) `json:"-"`
+	// Stream asks the backend for streamed answers and reports them as they
+	// arrive in "chunk_delta" progress events, so long chunks show signs of
+	// life. With a file output, the answer of the chunk due next is also
+	// written into the file as it arrives, at the chunk's offset; it is cut
+	// off again when the chunk is retried or fails, and replaced by the
+	// validated chunk once complete.
+	Stream bool
+	// Annotate wraps every translated chunk of the output in review markers,
+	// <!-- seg:42 conf:0.92 --> before it and <!-- /seg:42 --> after, where
+	// conf estimates how much the translation can be trusted. See
+	// StripAnnotations.
+	Annotate bool
+	// KeyContext describes UI strings by key, see LoadKeyContext.
+	// TranslateSegments adds the context of a segment's "key" Meta to its
+	// prompt.
+	KeyContext map[string]KeyContext
+	// Rewrap joins hard-wrapped lines into paragraphs before chunking, so
+	// sentences are not cut at line ends, see rewrap.
+	Rewrap bool
+	// ImageText, ImageTextAlt or ImageTextAppendix, sends the images of a
+	// Markdown or HTML document to VisionModel, by default the active
+	// model, to translate the text in them.
+	ImageText   string
+	VisionModel string
+	// SplitModel, when set, is asked where to cut a paragraph that has no
+	// sentence or line break and is longer than a chunk, so it is cut
+	// between clauses rather than by length. A cheap model will do; answers
+	// that change the text are ignored.
+	SplitModel string
+	// OCR, OCRTesseract or OCRVision, reads PDF and image inputs, which are
+	// then translated as Markdown with an anchor per page. OCRLanguages is
+	// passed to tesseract as -l, e.g. "eng+deu".
+	OCR          string
+	OCRLanguages string
+	// ContextSentences gives the model the last sentences of the chunk
+	// before, and of its translation once written, so pronouns, tense and
+	// terms carry over chunk boundaries. 0 sends no context.
+	ContextSentences int
+	// FinalNewline ends output files with exactly one line break.
+	// PostCommands run on every output file once it is complete, e.g.
+	// "prettier --write {output}" or "markdownlint --fix"; the file is
+	// appended as the last argument when {output} is missing. A failing
+	// command gives a WarningPostCommand.
+	FinalNewline bool
+	PostCommands []string
+	// SpeechModel, e.g. "tts-1", reads the translated file aloud after the
+	// run and writes an MP3 per chapter next to it, see speak. The audio
+	// comes from the OpenAI compatible endpoint at SpeechURL, by default
+	// BaseURL + "/audio/speech" or else OpenAI's, in SpeechVoice, by default
+	// "alloy", authorized with SpeechAPIKey or else APIKey.
+	SpeechModel  string
+	SpeechVoice  string
+	SpeechURL    string
+	SpeechAPIKey string
+	// Summary keeps a short running summary of the document, updated by the
+	// model after every chunk, and adds it to the prompts of the chunks
+	// after, so names and gendered pronouns stay the same throughout a
+	// novel. It costs an extra request per chunk.
+	Summary bool
+	// Via, ViaPandoc, has TranslateFile convert the input to Markdown, e.g.
+	// from DOCX, EPUB or reStructuredText, and the translation back to the
+	// format of outputPath. ViaToMarkdown and ViaFromMarkdown replace the
+	// conversion commands, see translateVia.
+	Via             string
+	ViaToMarkdown   string
+	ViaFromMarkdown string
+	// MaskCode keeps fenced code blocks and inline code spans away from the
+	// model in every format, not only in those that protect code already,
+	// such as markdown, so identifiers and comments in samples stay as
+	// they are.
+	MaskCode bool
+	// ProtectPatterns are regular expressions for text the model must copy
+	// verbatim, such as URLs, product names, file paths or ticket IDs.
+	// Matches are masked like code.
+	ProtectPatterns []string
+	// Typography rewrites the quotation marks, ellipses and the spaces
+	// before percent signs and punctuation of translations the way the
+	// target language sets them, e.g. „…“ for German and « … » for French.
+	// TypographyOverrides, keyed by language name or code, replace fields
+	// of the built-in tables.
+	Typography          bool
+	TypographyOverrides map[string]Typography
+	// Abbreviations, keyed by language name or code, add to the built-in
+	// abbreviations after which a full stop does not end a sentence, e.g.
+	// {"de": {"Abs.", "Bd."}}. Chunks are split at sentences of FromLang,
+	// or of every language when it is not set.
+	Abbreviations map[string][]string
+	// PostEdit, when set, has a second model revise the translation of
+	// every chunk, see Pipeline.
+	PostEdit *PostEdit
+	// NoSanityCheck turns off the check of translations for signs of a
+	// bad answer: an empty translation, one far shorter or longer than its
+	// source, or sentences of the source left untranslated. Such answers
+	// are asked for again with the problem named, and kept with a
+	// WarningSuspectOutput when retries do not help.
+	NoSanityCheck bool
+	// NoStructuredOutput keeps the <result> tag for models whose profile
+	// has JSONMode. By default they are asked for a {"translation": "..."}
+	// JSON object with response_format json_object, which does not depend
+	// on the model remembering a tag.
+	NoStructuredOutput bool
+	// NoExternalMetadata leaves out what requests carry beyond the
+	// translation itself: the HTTP-Referer and X-Title headers naming this
+	// tool to OpenRouter, the X-Run-Id and X-Request-Id headers and the run
+	// ID in batch metadata.
+	NoExternalMetadata bool
+	// Chunking is how text is split into chunks: ChunkingParagraph or
+	// ChunkingMarkdown. Empty picks ChunkingMarkdown for the markdown format
+	// and ChunkingParagraph otherwise.
+	Chunking string
+	// ChunkMargin is the share of ChunkSize held back when filling chunks,
+	// for token counts that come out low; nil holds back
+	// DefaultChunkMargin, except for chunk sizes too small to spare it.
+	// Chunks shrink further if their translation would not fit MaxTokens,
+	// see ChunkSizing.
+	ChunkMargin *float64
+	// Chunker, when set, splits documents instead of the built-in splitter
+	// of Chunking, e.g. by subtitle cue or by JSON key. ParagraphChunker
+	// and MarkdownChunker return the built-in ones.
+	Chunker Chunker `json:"-"`
 }
 
 type Translator struct {
 	config Config
+	client *http.Client
+	format *formatHandler
+	result Result
+
+	model       string
+	rateLimited int
+	tm          *translationMemory
+	dedupe      *dedupeIndex
+	pace        pacer
+	runID       string
+	audit       *auditLog
+	provider    Provider
+
+	// mu guards result and model state shared by concurrent chunk workers.
+	mu         sync.Mutex
+	progressMu sync.Mutex
+	job        *Job
+	// retries counts the retries of each chunk of the run, by chunk number.
+	retries map[int]int
+	// sourceLang is the language of the document, from Config.FromLang or
+	// detected; empty when unknown.
+	sourceLang string
+	// hints holds extra instructions by chunk index, see TranslateSegments.
+	hints []string
+	// screenshots holds image URLs by chunk index, see KeyContext.
+	screenshots []string
+	// maxLengths holds length limits in characters by chunk index, see
+	// Segment.MaxLength.
+	maxLengths []int
+	// imageTexts is what the vision model read from the document's images.
+	imageTexts []imageText
+	// icu checks answers as ICU MessageFormat, see icuProblem.
+	icu bool
+	// running is the file being translated, see carryOver.
+	running *fileJob
+	// abbreviationSets caches the abbreviations per language, guarded by mu.
+	abbreviationSets map[string]map[string]bool
+	// summary is the running summary of the document, see Config.Summary.
+	summary string
+	// postEditor revises drafts, see Config.PostEdit.
+	postEditor *Translator
+	// delimiters holds the delimiter each model is asked for and
+	// delimiterMisses its answers in a row without it, see noteDelimiter.
+	delimiters      map[string]delimiter
+	delimiterMisses map[string]int
+}
+
+// promptContext carries per-chunk material that is added to the prompt.
+type promptContext struct {
+	references []tmEntry
+	chunkID    string
+	// variation counts the unusable answers so far, see sampling.
+	variation int
+	// model replaces the active model, see Config.FallbackModels.
+	model string
+	// chunk is the 1-based number of the chunk, for progress events.
+	chunk int
+	// hint is added to the instructions of this chunk only.
+	hint string
+	// screenshot is an image URL attached for multimodal models.
+	screenshot string
+	// tooLong is the length of the previous answer when it went over the
+	// chunk's length limit, so the retry asks for a shorter one.
+	tooLong int
+	// correction names what was wrong with the previous answer, see
+	// sanityProblem.
+	correction string
+	// missingTag is set after an answer without the <result> tag, so the
+	// retry insists on it.
+	missingTag bool
+	// delimiter marks the translation in the answer, see noteDelimiter.
+	delimiter delimiter
+	// structured is set when the answer is asked for as a JSON object
+	// rather than in the <result> tag, see Config.NoStructuredOutput.
+	structured bool
+	// previousSource and previousTranslation are the end of the chunk
+	// before, given as context only, see Config.ContextSentences.
+	previousSource      string
+	previousTranslation string
+	// summary is the running summary of the document, see Config.Summary.
+	summary string
+}
+
+func (t *Translator) chunkHint(i int) string {
+	if i < len(t.hints) {
+		return t.hints[i]
+	}
+	return ""
+}
+
+func (t *Translator) chunkScreenshot(i int) string {
+	if i < len(t.screenshots) {
+		return t.screenshots[i]
+	}
+	return ""
+}
+
+// modelFor is the model a chunk is sent to.
+func (t *Translator) modelFor(pc promptContext) string {
+	if pc.model != "" {
+		return pc.model
+	}
+	return t.activeModel()
 }
 
 func NewTranslator(config Config) *Translator {
-	return &Translator{
-		config: config,
+	if config.Deterministic {
+		config.Concurrency = 1
+		config.RaceModel = ""
+		config.HedgeDelay = 0
+		config.PreferFree = false
+	}
+
+	t := &Translator{
+		config:   config,
+		client:   newHTTPClient(config),
+		provider: config.Provider,
+	}
+	if t.provider == nil {
+		switch config.Backend {
+		case BackendOllama:
+			t.provider = ollamaProvider{t: t}
+		case BackendDeepL:
+			t.provider = deeplProvider{t: t}
+		default:
+			t.provider = openRouterProvider{t: t}
+		}
 	}
+	if config.PostEdit != nil {
+		t.postEditor = newPostEditor(config)
+	}
+	return t
 }
 
 func (t *Translator) TranslateFile(inputPath, outputPath string) error {
+	return t.TranslateFileContext(context.Background(), inputPath, outputPath)
+}
+
+// TranslateFileContext is TranslateFile with a context. Cancelling ctx or
+// reaching its deadline aborts in-flight requests and stops the job with an
+// error of class ErrorClassCanceled; chunks finished so far stay written.
+func (t *Translator) TranslateFileContext(ctx context.Context, inputPath, outputPath string) error {
+	t.begin(ctx, inputPath, outputPath)
+	if t.config.Via != "" {
+		return t.finish(outputPath, t.translateVia(ctx, inputPath, outputPath))
+	}
+
+	prepared, err := t.prepareFile(ctx, inputPath)
+	if err == nil {
+		prepared, err = t.skipDone(prepared, outputPath)
+	}
+	if err == nil {
+		openOutput := createOutput(outputPath)
+		if prepared.Done > 0 {
+			openOutput = appendOutput(outputPath)
+		}
+		err = t.translatePrepared(ctx, prepared, openOutput)
+	}
+	if err == nil {
+		err = t.polish(ctx, outputPath)
+	}
+	if err == nil {
+		err = t.speak(ctx, outputPath, outputPath)
+	}
+	return t.finish(outputPath, err)
+}
+
+// Translate reads a whole document from r and writes its translation to w,
+// chunk by chunk as chunks complete. With Format "auto" the format is
+// detected from r's Name, if it has one like *os.File. Translate is the
+// stream counterpart of TranslateFileContext and goes through the same
+// pipeline.
+func (t *Translator) Translate(ctx context.Context, r io.Reader, w io.Writer) error {
+	t.begin(ctx, "", "")
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return t.finish("", classify(ErrorClassInput, fmt.Errorf("failed to read input: %w", err)))
+	}
+
+	name := ""
+	if named, ok := r.(interface{ Name() string }); ok {
+		name = named.Name()
+	}
+
+	prepared, err := t.prepare(ctx, name, content)
+	if err == nil {
+		err = t.translatePrepared(ctx, prepared, func() (io.WriteCloser, error) {
+			return nopWriteCloser{w}, nil
+		})
+	}
+	return t.finish("", err)
+}
+
+// TranslateText translates text in memory and returns the translation. It
+// goes through the same chunking and reassembly as Translate; Format "auto"
+// treats text as plain text.
+func (t *Translator) TranslateText(ctx context.Context, text string) (string, error) {
+	var out strings.Builder
+	if err := t.Translate(ctx, strings.NewReader(text), &out); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
 
+// createOutput opens outputPath when the translation is about to be written,
+// so a job that fails early leaves no empty file behind.
+func createOutput(outputPath string) func() (io.WriteCloser, error) {
+	return func() (io.WriteCloser, error) {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			return nil, classify(ErrorClassOutput, fmt.Errorf("failed to create output file: %w", err))
+		}
+		return f, nil
+	}
+}
+
+// appendOutput opens outputPath to continue a paused job.
+func appendOutput(outputPath string) func() (io.WriteCloser, error) {
+	return func() (io.WriteCloser, error) {
+		f, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return nil, classify(ErrorClassOutput, fmt.Errorf("failed to open output file to resume: %w", err))
+		}
+		return f, nil
+	}
+}
+
+// TranslateFileLanguages translates inputPath into each of langs, reading and
+// splitting it only once; outputPath names the output file of a language.
+// It returns the Result of every language translated, stopping at the first
+// one that fails.
+func (t *Translator) TranslateFileLanguages(ctx context.Context, inputPath string, langs []string, outputPath func(lang string) string) ([]Result, error) {
+	prepared, err := t.prepareFile(ctx, inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, lang := range langs {
+		config := t.config
+		config.ToLang = lang
+		d := t.Derive(config)
+		err := d.TranslatePreparedContext(ctx, prepared, outputPath(lang))
+		results = append(results, d.Result())
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// TranslatePrepared translates a file prepared earlier with Prepare, possibly
+// by another process, and writes the result to outputPath.
+func (t *Translator) TranslatePrepared(prepared *PreparedFile, outputPath string) error {
+	return t.TranslatePreparedContext(context.Background(), prepared, outputPath)
+}
+
+// TranslatePreparedContext is TranslatePrepared with a context.
+func (t *Translator) TranslatePreparedContext(ctx context.Context, prepared *PreparedFile, outputPath string) error {
+	t.begin(ctx, prepared.Input, outputPath)
+	prepared, err := t.skipDone(prepared, outputPath)
+	if err != nil {
+		return t.finish(outputPath, err)
+	}
+	openOutput := createOutput(outputPath)
+	if prepared.Done > 0 {
+		openOutput = appendOutput(outputPath)
+	}
+	err = t.translatePrepared(ctx, prepared, openOutput)
+	if err == nil {
+		err = t.polish(ctx, outputPath)
+	}
+	if err == nil {
+		err = t.speak(ctx, outputPath, outputPath)
+	}
+	return t.finish(outputPath, err)
+}
+
+func (t *Translator) begin(ctx context.Context, inputPath, outputPath string) {
+	t.runID = newRunID()
+	t.result = Result{RunID: t.runID, Input: inputPath, Output: outputPath, Format: "text"}
+	t.retries = nil
+	t.hints = nil
+	t.screenshots = nil
+	t.maxLengths = nil
+	t.icu = false
+	t.running = nil
+	t.summary = ""
+	t.selectModel(ctx)
+	if t.config.WarmUp {
+		t.warmUp(ctx)
+	}
+	t.emit(ProgressEvent{Event: "start", Input: inputPath, Output: outputPath})
+}
+
+func (t *Translator) finish(outputPath string, err error) error {
+	t.result.Model = t.activeModel()
+	if err != nil {
+		t.emit(ProgressEvent{Event: "error", Error: err.Error()})
+		return err
+	}
+
+	if err := t.reconcileEstimate(); err != nil && t.config.Verbose {
+		fmt.Printf("Could not update estimates: %v\n", err)
+	}
+	t.emit(ProgressEvent{Event: "done", Output: outputPath})
+	return nil
+}
+
+// PreparedFile is a document that has been masked and split into chunks but
+// not translated yet. It can be stored and translated later.
+type PreparedFile struct {
+	Input  string   `json:"input"`
+	Format string   `json:"format,omitempty"`
+	Chunks []string `json:"chunks"`
+	// Chunking is the chunking mode the chunks were split with.
+	Chunking string `json:"chunking,omitempty"`
+	// Spans restore protected markers in the output, SourceSpans restore
+	// them in the source text.
+	Spans       []string `json:"spans,omitempty"`
+	SourceSpans []string `json:"source_spans,omitempty"`
+	// Separators holds the text between each chunk and the next in the
+	// source, and after the last one, which the output repeats exactly.
+	// Without them, as in files prepared by earlier versions, chunks are
+	// joined with line breaks.
+	Separators []string `json:"separators,omitempty"`
+	// SourceSHA256 is the hash of the input file.
+	SourceSHA256 string `json:"source_sha256,omitempty"`
+	// Done is the number of leading chunks already written to the output by
+	// a paused run, NextLine the output line the next chunk starts at.
+	// TranslatePrepared appends the remaining chunks.
+	Done     int `json:"done,omitempty"`
+	NextLine int `json:"next_line,omitempty"`
+}
+
+// Prepare reads inputPath, protects the parts that must not be translated
+// and splits the rest into chunks, without calling the API but for
+// Config.SplitModel. PDF and image inputs are read with OCR first, see
+// Config.OCR, which calls the API for OCRVision.
+func (t *Translator) Prepare(inputPath string) (*PreparedFile, error) {
+	return t.prepareFile(context.Background(), inputPath)
+}
+
+func (t *Translator) prepareFile(ctx context.Context, inputPath string) (*PreparedFile, error) {
+	if scannedInput(inputPath) {
+		content, err := t.ocr(ctx, inputPath)
+		if err != nil {
+			return nil, err
+		}
+		return t.prepare(ctx, inputPath, content)
+	}
 	content, err := os.ReadFile(inputPath)
 	if err != nil {
-		return fmt.Errorf("failed to read input file: %w", err)
+		return nil, classify(ErrorClassInput, fmt.Errorf("failed to read input file: %w", err))
 	}
+	return t.prepare(ctx, inputPath, content)
+}
 
-	chunks := t.splitIntoChunks(string(content))
+func (t *Translator) prepare(ctx context.Context, inputPath string, content []byte) (*PreparedFile, error) {
+	format, err := lookupFormat(t.config.Format, inputPath)
+	if err != nil {
+		return nil, classify(ErrorClassConfig, err)
+	}
+	if format == nil && scannedInput(inputPath) && (t.config.Format == "" || t.config.Format == "auto") {
+		// OCR yields Markdown.
+		format, _ = lookupFormat("markdown", "")
+	}
+
+	prepared := &PreparedFile{Input: inputPath, SourceSHA256: sha256Hex(content)}
+
+	text := string(content)
+	if t.config.Rewrap {
+		text = rewrap(text)
+	}
+	var spans []string
+	if format != nil {
+		prepared.Format = format.name
+		text, spans = format.maskText(text, t.config)
+		if format.name == "markdown" || format.name == "quarto" {
+			text = t.maskFrontMatter(text, &spans)
+		}
+		if t.config.Verbose {
+			fmt.Printf("Using %s format, protected %d spans\n", format.name, len(spans))
+		}
+	}
+
+	prepared.Chunking = t.chunking(format)
+	if prepared.Chunking == ChunkingMarkdown {
+		text = maskCodeFences(text, &spans)
+	}
+	if t.config.MaskCode {
+		text = maskCode(text, &spans)
+	}
+	protect, err := protectPatterns(t.config.ProtectPatterns)
+	if err != nil {
+		return nil, classify(ErrorClassConfig, err)
+	}
+	text = maskMore(text, &spans, protect...)
+	text = maskTemplates(text, &spans)
+
+	if sel, err := parseSelector(t.config.Select, t.config.SelectRegex); err != nil {
+		return nil, classify(ErrorClassConfig, err)
+	} else if sel != nil {
+		if text, spans, err = selectSections(text, spans, sel); err != nil {
+			return nil, classify(ErrorClassInput, err)
+		}
+	}
+
+	sourceSpans := append([]string(nil), spans...)
+	text, spans, sourceSpans = t.substituteDuplicates(inputPath, text, spans, sourceSpans)
+
+	chunks, err := t.chunk(ctx, text, prepared.Chunking)
+	if err != nil {
+		return nil, classify(ErrorClassConfig, err)
+	}
+	if s := t.chunkSizing(); s.LimitModel != "" && len(chunks) > 1 {
+		t.warn(Warning{Kind: WarningChunkSize, Message: fmt.Sprintf("chunks are cut to %s, rather than the chunk size of %d", s, t.config.ChunkSize)})
+	}
+	prepared.Chunks = make([]string, len(chunks))
+	prepared.Separators = make([]string, len(chunks))
+	for i, c := range chunks {
+		prepared.Chunks[i], prepared.Separators[i] = c.Text, c.Separator
+	}
+	prepared.Spans = spans
+	prepared.SourceSpans = sourceSpans
 	if t.config.Verbose {
-		fmt.Printf("Split content into %d chunks\n", len(chunks))
+		fmt.Printf("Split content into %d chunks\n", len(prepared.Chunks))
 	}
 
-	outputFile, err := os.Create(outputPath)
+	return prepared, nil
+}
+
+func (t *Translator) translatePrepared(ctx context.Context, prepared *PreparedFile, openOutput func() (io.WriteCloser, error)) error {
+	format, err := lookupFormat(prepared.Format, "")
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return classify(ErrorClassConfig, err)
+	}
+	t.format = format
+	if format != nil {
+		t.result.Format = format.name
+	}
+
+	if err := t.openAudit(); err != nil {
+		return err
+	}
+
+	t.tm = nil
+	if t.config.TMPath != "" {
+		if t.tm, err = openTranslationMemory(t.config.TMPath, t.readKey()); err != nil {
+			return classify(ErrorClassConfig, err)
+		}
+		t.tm.key = t.config.StorageKey
+	}
+
+	chunks := prepared.Chunks
+	t.result.Chunks = len(chunks)
+	t.result.Estimate = t.estimate(chunks[prepared.Done:])
+	if err := t.checkBudget(ctx, t.result.Estimate, len(chunks)-prepared.Done); err != nil {
+		return err
+	}
+	if t.config.Deterministic {
+		t.result.Inputs = t.runInputs(prepared)
+	}
+	t.emit(ProgressEvent{Event: "split", Chunks: len(chunks)})
+
+	outputFile, err := openOutput()
+	if err != nil {
+		return err
 	}
 	defer outputFile.Close()
 
 	writer := bufio.NewWriter(outputFile)
 	defer writer.Flush()
+	file, _ := outputFile.(*os.File)
+
+	job := &fileJob{
+		ctx:         ctx,
+		chunks:      chunks,
+		spans:       prepared.Spans,
+		sourceSpans: prepared.SourceSpans,
+		chunking:    prepared.Chunking,
+		writer:      writer,
+		first:       prepared.Done,
+		file:        file,
+		next:        prepared.Done,
+		outputLine:  1,
+	}
+	if prepared.NextLine > 0 {
+		job.outputLine = prepared.NextLine
+	}
+	if len(prepared.Separators) == len(chunks) {
+		job.separators = prepared.Separators
+	}
+	t.startManifest(job, prepared)
+	t.running = job
+	t.sourceLang = t.sourceLanguage(job)
+	t.result.SourceLang = t.sourceLang
+	if t.config.Verbose && t.config.FromLang == "" && t.sourceLang != "" {
+		fmt.Printf("Detected source language: %s\n", t.sourceLang)
+	}
+	if err := t.readImages(ctx, job, prepared.Input); err != nil {
+		return err
+	}
 
-	for i, chunk := range chunks {
-		if t.config.Verbose {
-			fmt.Printf("Translating chunk %d of %d (size: %d characters, ~%d tokens)\n",
-				i+1, len(chunks), len(chunk), len(chunk)/4)
+	if job.first == len(chunks) {
+		// Every chunk was done by an earlier run, see skipDone.
+	} else if t.config.BatchAPI != "" {
+		err = t.translateBatch(ctx, job)
+	} else if t.config.Concurrency > 1 && len(chunks)-job.first > 1 {
+		err = t.translateConcurrently(job)
+	} else {
+		for i := job.first; i < len(chunks) && err == nil; i++ {
+			var translatedChunk string
+			if translatedChunk, err = t.translateChunkWithRetries(ctx, i, len(chunks), chunks[i], job.source(i)); err != nil {
+				t.markUntranslated(job, i)
+			} else {
+				err = t.writeChunk(job, i, translatedChunk)
+			}
 		}
+	}
+	if ErrorClass(err) == ErrorClassPaused {
+		checkpoint := *prepared
+		checkpoint.Done = job.next
+		checkpoint.NextLine = job.outputLine
+		t.result.Checkpoint = &checkpoint
+	}
+	if manifestErr := t.writeManifest(job); err == nil {
+		err = manifestErr
+	}
+	if err != nil {
+		return err
+	}
 
-		var translatedChunk string
-		var chunkErr error
-		maxRetries := t.config.MaxRetries
-		if maxRetries <= 0 {
-			maxRetries = 3
-		}
-		retryDelay := 2 * time.Second
-
-		for attempt := 0; attempt < maxRetries; attempt++ {
-			if attempt > 0 {
-				if t.config.Verbose {
-					fmt.Printf("Retrying chunk %d translation (attempt %d/%d) after error: %v\n",
-						i+1, attempt+1, maxRetries, chunkErr)
-				}
-				time.Sleep(retryDelay)
+	if t.config.Verbose {
+		fmt.Printf("Translation completed successfully\n")
+	}
 
-				retryDelay *= 2
+	return nil
+}
+
+// fileJob holds the state of one file being translated. Chunks are written in
+// order, whatever order they are translated in.
+type fileJob struct {
+	ctx         context.Context
+	chunks      []string
+	spans       []string
+	sourceSpans []string
+	chunking    string
+	separators  []string
+	writer      *bufio.Writer
+	// first is the first chunk to translate, next the next one to write.
+	first      int
+	next       int
+	outputLine int
+	// outputBytes is the output offset of the next chunk, manifest the
+	// chunk manifest if Config.Manifest is set.
+	outputBytes int
+	manifest    *ChunkManifest
+	// targets holds the written translations by chunk index, guarded by
+	// Translator.mu, see carryOver.
+	targets map[int]string
+	// file is the output file when Config.Stream writes answers into it as
+	// they arrive. streamMu guards the writes and next; streaming is the
+	// 1-based number of the chunk being streamed at offset streamStart, or
+	// -1 while a chunk is written, and streamOwner the request streaming it.
+	file        *os.File
+	streamMu    sync.Mutex
+	streaming   int
+	streamStart int64
+	streamOwner *int
+}
+
+func (j *fileJob) source(i int) string {
+	return unmaskSpans(j.chunks[i], j.sourceSpans)
+}
+
+func (t *Translator) translateChunkWithRetries(ctx context.Context, i, total int, chunk, source string) (string, error) {
+	if !t.config.Until.IsZero() && !time.Now().Before(t.config.Until) {
+		return "", classify(ErrorClassPaused, fmt.Errorf("paused before chunk %d of %d: the run window ended at %s",
+			i+1, total, t.config.Until.Format("15:04")))
+	}
+	if t.config.Verbose {
+		fmt.Printf("Translating chunk %d of %d (size: %d characters, ~%d tokens)\n",
+			i+1, total, len(chunk), t.countTokens(chunk))
+	}
+	if err := t.pace.wait(ctx, t.config.Verbose); err != nil {
+		return "", canceled(err)
+	}
+	if onlyMarkers(chunk) {
+		return chunk, nil
+	}
+	hint := t.chunkHint(i)
+	if translation, ok := t.cached(chunk, hint); ok {
+		t.mu.Lock()
+		t.result.ChunksCached++
+		t.mu.Unlock()
+		t.emit(ProgressEvent{Event: "chunk_cached", Chunk: i + 1, Chunks: total, Bytes: len(translation)})
+		return translation, nil
+	}
+	if err := t.job.wait(ctx); err != nil {
+		return "", canceled(err)
+	}
+	if gate := t.config.Gate; gate != nil {
+		if err := gate.acquire(ctx, t.config.Priority); err != nil {
+			return "", canceled(err)
+		}
+		defer gate.release()
+	}
+	t.emit(ProgressEvent{Event: "chunk_start", Chunk: i + 1, Chunks: total, Bytes: len(chunk)})
+
+	pc := promptContext{references: t.tmReferences(ctx, source), chunkID: t.chunkID(i), chunk: i + 1, hint: hint, screenshot: t.chunkScreenshot(i)}
+	t.carryOver(i, &pc)
+	if t.config.Summary {
+		pc.summary = t.currentSummary()
+	}
+	if t.config.Verbose && len(pc.references) > 0 {
+		fmt.Printf("Using %d translation memory references for chunk %d\n", len(pc.references), i+1)
+	}
+
+	// Once a model has used up the retries of a chunk, the chunk is tried
+	// again with the next fallback model and a fresh budget.
+	models := append([]string{""}, t.config.FallbackModels...)
+	var err error
+	for m, model := range models {
+		if m > 0 {
+			t.warn(Warning{Kind: WarningModelFallback, Chunk: i + 1,
+				Message: fmt.Sprintf("chunk %d (%s) failed with %s, falling back to %s: %v",
+					i+1, pc.chunkID, t.modelFor(pc), model, err)})
+		}
+		pc.model, pc.variation = model, 0
+
+		var translatedChunk string
+		translatedChunk, err = t.retryChunk(ctx, i, total, chunk, pc)
+		switch ErrorClass(err) {
+		case ErrorClassNetwork, ErrorClassAPI, ErrorClassRateLimit, ErrorClassExtraction:
+		default:
+			if err == nil && t.postEditor != nil {
+				translatedChunk = t.postEdit(ctx, i, chunk, translatedChunk)
+			}
+			// Answers with broken spans are kept but not cached, so the next
+			// run tries again.
+			if err == nil && missingMarkers(chunk, translatedChunk) == 0 {
+				t.cache(chunk, hint, translatedChunk)
 			}
+			return translatedChunk, err
+		}
+	}
+	// An answer that still lacks the <result> tag is kept without its
+	// commentary rather than failing the file.
+	if answer, ok := salvage(err); ok {
+		t.warn(Warning{Kind: WarningResultTag, Chunk: i + 1,
+			Message: fmt.Sprintf("chunk %d (%s): the model answered without the <result> tag, kept the answer without its commentary", i+1, pc.chunkID)})
+		return answer, nil
+	}
+	return "", err
+}
 
-			translatedChunk, chunkErr = t.translateChunk(chunk)
-			if chunkErr == nil {
-				break
+// retryChunk translates chunk i with the model of pc, retrying failures
+// while the retry budget lasts.
+func (t *Translator) retryChunk(ctx context.Context, i, total int, chunk string, pc promptContext) (string, error) {
+	budget := retryBudget{policy: t.retryPolicy()}
+
+	for attempt := 1; ; attempt++ {
+		translatedChunk, err := t.translateChunk(ctx, chunk, pc)
+		t.noteChunkResult(err)
+		if err == nil {
+			// An answer that breaks protected spans is retried while the
+			// extraction budget lasts, then kept and reported as a warning.
+			missing := missingMarkers(chunk, translatedChunk)
+			if missing > 0 {
+				err = classify(ErrorClassExtraction, fmt.Errorf("the model dropped or altered %d protected spans", missing))
+			} else if broken := missingPlaceholders(chunk, translatedChunk); len(broken) > 0 {
+				err = classify(ErrorClassExtraction, fmt.Errorf("the model dropped or altered the placeholders %s", strings.Join(broken, " ")))
+			} else if icuErr := t.icuProblem(chunk, translatedChunk); icuErr != nil {
+				err = classify(ErrorClassExtraction, icuErr)
+			} else if lengthErr := t.lengthProblem(i, translatedChunk); lengthErr != nil {
+				err = classify(ErrorClassExtraction, lengthErr)
+				pc.tooLong = textLength(translatedChunk)
+			} else if sanityErr := t.sanityProblem(chunk, translatedChunk); sanityErr != nil {
+				err = classify(ErrorClassExtraction, sanityErr)
+				pc.correction = sanityErr.Error()
+			}
+			if err == nil || !budget.allow(err) {
+				t.pace.after(t.chunkDelay(chunk))
+				return translatedChunk, nil
 			}
+		} else if !budget.allow(err) {
+			if cutErr := t.unstream(i); cutErr != nil {
+				return "", cutErr
+			}
+			return "", classify(ErrorClass(err), fmt.Errorf("failed to translate chunk %d (%s) after %d attempts: %w",
+				i+1, pc.chunkID, attempt, err))
+		}
+		if cutErr := t.unstream(i); cutErr != nil {
+			return "", cutErr
 		}
+		pc.variation = budget.extraction
+		pc.missingTag = isMissingTag(err)
 
-		if chunkErr != nil {
-			return fmt.Errorf("failed to translate chunk %d after %d attempts: %w",
-				i+1, maxRetries, chunkErr)
+		if t.config.Verbose {
+			fmt.Printf("Retrying chunk %d (%s) translation (attempt %d) after %s error: %v\n",
+				i+1, pc.chunkID, attempt+1, ErrorClass(err), err)
+		}
+		t.mu.Lock()
+		if t.retries == nil {
+			t.retries = map[int]int{}
 		}
+		t.retries[i+1]++
+		t.mu.Unlock()
+		t.emit(ProgressEvent{Event: "retry", Chunk: i + 1, Chunks: total, Attempt: attempt + 1, Error: err.Error()})
+		select {
+		case <-time.After(budget.policy.pause(attempt, err)):
+		case <-ctx.Done():
+			return "", canceled(ctx.Err())
+		}
+	}
+}
+
+// writeChunk validates translated chunk i, restores its protected spans and
+// appends it to the output.
+func (t *Translator) writeChunk(job *fileJob, i int, translatedChunk string) error {
+	// The streamed answer is replaced by the validated chunk, and nothing
+	// is streamed while it is written.
+	job.streamMu.Lock()
+	err := job.cutStream(i)
+	job.streaming = -1
+	job.streamMu.Unlock()
+	if err != nil {
+		return err
+	}
+	chunk := job.chunks[i]
+	last := i == len(job.chunks)-1
+
+	// A string that breaks ICU syntax would crash the app showing it, so
+	// the source is kept instead.
+	state := SegmentMachineTranslated
+	if icuErr := t.icuProblem(chunk, translatedChunk); icuErr != nil {
+		t.warn(Warning{Kind: WarningICUSyntax, Chunk: i + 1, Line: job.outputLine,
+			Message: fmt.Sprintf("%v, keeping the source text", icuErr)})
+		translatedChunk, state = chunk, SegmentUntranslated
+	}
 
-		if _, err := writer.WriteString(translatedChunk); err != nil {
-			return fmt.Errorf("failed to write translated chunk to output file: %w", err)
+	if job.separators != nil {
+		translatedChunk = keepEdges(chunk, translatedChunk)
+	}
+
+	t.validateChunk(i+1, job.outputLine, chunk, translatedChunk)
+	if lengthErr := t.lengthProblem(i, translatedChunk); lengthErr != nil {
+		t.warn(Warning{Kind: WarningTooLong, Chunk: i + 1, Line: job.outputLine, Message: lengthErr.Error()})
+	}
+	if state == SegmentMachineTranslated {
+		if sanityErr := t.sanityProblem(chunk, translatedChunk); sanityErr != nil {
+			t.warn(Warning{Kind: WarningSuspectOutput, Chunk: i + 1, Line: job.outputLine, Message: sanityErr.Error()})
 		}
+	}
 
-		if i < len(chunks)-1 && !strings.HasSuffix(translatedChunk, "\n") {
-			writer.WriteString("\n")
+	if t.config.Typography && state == SegmentMachineTranslated {
+		translatedChunk = t.typography().apply(translatedChunk)
+	}
+	if len(job.spans) > 0 {
+		translatedChunk = unmaskSpans(translatedChunk, job.spans)
+	}
+	translatedChunk = t.replaceAltText(translatedChunk)
+
+	source := job.source(i)
+	t.result.Segments = append(t.result.Segments, Segment{
+		ID:     i + 1,
+		Source: source,
+		Target: translatedChunk,
+		State:  state,
+	})
+	if t.config.ContextSentences > 0 && state == SegmentMachineTranslated {
+		t.mu.Lock()
+		if job.targets == nil {
+			job.targets = map[int]string{}
 		}
+		job.targets[i] = translatedChunk
+		t.mu.Unlock()
+	}
+	t.rememberTranslation(job.ctx, source, translatedChunk)
+	t.rememberCanonical(source, translatedChunk)
+	if state == SegmentMachineTranslated && !onlyMarkers(chunk) {
+		t.updateSummary(job.ctx, i+1, source, translatedChunk)
+	}
 
-		writer.Flush()
+	output := translatedChunk
+	if t.config.Annotate && !onlyMarkers(chunk) {
+		output = annotate(i+1, t.chunkConfidence(i+1, source, translatedChunk), translatedChunk)
+	}
+	if appendix := t.imageAppendix(); last && appendix != "" {
+		output = strings.TrimRight(output, "\n") + appendix
+	}
 
-		if i < len(chunks)-1 {
-			delay := 10 * time.Millisecond
-			if len(chunk) > 1000 {
+	job.noteManifest(i, output, state)
+	if _, err := job.writer.WriteString(output); err != nil {
+		return classify(ErrorClassOutput, fmt.Errorf("failed to write translated chunk to output file: %w", err))
+	}
 
-				additionalDelay := time.Duration(len(chunk)/1000) * 300 * time.Millisecond
-				if additionalDelay > 1500*time.Millisecond {
-					additionalDelay = 1500 * time.Millisecond
-				}
-				delay += additionalDelay
-			}
+	job.outputLine += strings.Count(output, "\n")
+	written := len(output)
 
-			if t.config.Verbose {
-				fmt.Printf("Waiting %v before next chunk...\n", delay)
-			}
-			time.Sleep(delay)
+	switch {
+	case job.separators != nil:
+		if separator := job.separators[i]; separator != "" {
+			job.writer.WriteString(separator)
+			job.outputLine += strings.Count(separator, "\n")
+			written += len(separator)
+		}
+	case !last:
+		// Markdown blocks in different chunks need a blank line between them.
+		breaks := 1
+		if job.chunking == ChunkingMarkdown {
+			breaks = 2
+		}
+		breaks -= len(translatedChunk) - len(strings.TrimRight(translatedChunk, "\n"))
+		if breaks > 0 {
+			job.writer.WriteString(strings.Repeat("\n", breaks))
+			job.outputLine += breaks
+			written += breaks
 		}
 	}
 
-	if t.config.Verbose {
-		fmt.Printf("Translation completed successfully\n")
+	job.writer.Flush()
+	job.outputBytes += written
+	job.streamMu.Lock()
+	job.next = i + 1
+	job.streaming = 0
+	job.streamMu.Unlock()
+	// A run that is killed keeps the chunks written so far for the next.
+	if err := t.writeManifest(job); err != nil {
+		return err
+	}
+	t.result.ChunksTranslated++
+	t.emit(ProgressEvent{Event: "chunk_done", Chunk: i + 1, Chunks: len(job.chunks), Bytes: written})
+
+	return nil
+}
+
+// streamDelta writes text, a piece of the streamed answer of chunk n by
+// the request owner, into the output file at the chunk's offset. Only the
+// chunk due next is streamed, by the request whose first piece arrived
+// last, so a retry or a hedged request starts over.
+func (t *Translator) streamDelta(n int, owner *int, text string) {
+	job := t.running
+	if job == nil || job.file == nil {
+		return
 	}
+	job.streamMu.Lock()
+	defer job.streamMu.Unlock()
+	if job.streamOwner != owner {
+		if *owner != len(text) || job.next != n-1 || job.streaming < 0 || job.cutStream(n-1) != nil {
+			return
+		}
+		start, err := job.file.Seek(0, io.SeekEnd)
+		if err != nil {
+			return
+		}
+		job.streaming, job.streamStart, job.streamOwner = n, start, owner
+	}
+	job.writer.WriteString(text)
+	job.writer.Flush()
+}
 
+// unstream cuts the streamed answer of chunk i off the output file, before
+// it is retried or when it failed.
+func (t *Translator) unstream(i int) error {
+	job := t.running
+	if job == nil || job.file == nil {
+		return nil
+	}
+	job.streamMu.Lock()
+	defer job.streamMu.Unlock()
+	return job.cutStream(i)
+}
+
+// cutStream truncates the output file back to the offset of chunk i if it
+// is being streamed. job.streamMu must be held.
+func (j *fileJob) cutStream(i int) error {
+	if j.streaming != i+1 {
+		return nil
+	}
+	j.streaming, j.streamOwner = 0, nil
+	j.writer.Flush()
+	if err := j.file.Truncate(j.streamStart); err != nil {
+		return classify(ErrorClassOutput, fmt.Errorf("failed to cut off the streamed answer of chunk %d: %w", i+1, err))
+	}
+	if _, err := j.file.Seek(j.streamStart, io.SeekStart); err != nil {
+		return classify(ErrorClassOutput, fmt.Errorf("failed to cut off the streamed answer of chunk %d: %w", i+1, err))
+	}
 	return nil
 }
 
-func (t *Translator) splitIntoChunks(text string) []string {
+// markUntranslated records chunks from i on as untranslated segments.
+func (t *Translator) markUntranslated(job *fileJob, i int) {
+	for j := i; j < len(job.chunks); j++ {
+		t.result.Segments = append(t.result.Segments, Segment{
+			ID:     j + 1,
+			Source: job.source(j),
+			State:  SegmentUntranslated,
+		})
+	}
+}
+
+// effectiveChunkSize is the size chunks are filled up to, see ChunkSizing.
+func (t *Translator) effectiveChunkSize() int {
+	return t.chunkSizing().Tokens
+}
+
+// fitsOneChunk reports whether a text of tokens is sent whole. The margin
+// is for chunks filled up to ChunkSize, so a text within it is, unless it
+// or its translation would not fit the limits of Config.MaxTokens or the
+// model.
+func (t *Translator) fitsOneChunk(tokens int) bool {
+	if s := t.chunkSizing(); s.MaxTokensBound || s.ContextBound {
+		return tokens <= s.Tokens
+	}
+	return tokens <= t.config.ChunkSize
+}
+
+func (t *Translator) splitIntoChunks(ctx context.Context, text string) []string {
 
 	if text == "" {
 		return []string{}
 	}
 
-	estimatedTokens := len(text) / 4
+	estimatedTokens := t.countTokens(text)
 
-	if estimatedTokens <= t.config.ChunkSize {
+	if t.fitsOneChunk(estimatedTokens) {
 		return []string{text}
 	}
 
-	effectiveChunkSize := int(float64(t.config.ChunkSize) * 0.8)
-	if effectiveChunkSize < 100 {
-		effectiveChunkSize = t.config.ChunkSize
-	}
+	effectiveChunkSize := t.effectiveChunkSize()
 
 	if t.config.Verbose {
-		fmt.Printf("Using effective chunk size of %d tokens (original: %d)\n",
-			effectiveChunkSize, t.config.ChunkSize)
+		fmt.Printf("Filling chunks up to %s\n", t.chunkSizing())
 	}
 
 	paragraphs := strings.Split(text, "\n\n")
@@ -154,7 +1242,7 @@ func (t *Translator) splitIntoChunks(text string) []string {
 
 	for _, paragraph := range paragraphs {
 
-		paragraphTokens := len(paragraph) / 4
+		paragraphTokens := t.countTokens(paragraph)
 
 		if paragraphTokens > effectiveChunkSize {
 			if currentChunk != "" {
@@ -167,7 +1255,7 @@ func (t *Translator) splitIntoChunks(text string) []string {
 
 			if len(lines) > 1 {
 				for _, line := range lines {
-					lineTokens := len(line) / 4
+					lineTokens := t.countTokens(line)
 
 					if currentTokens > 0 && (currentTokens+lineTokens+1) > effectiveChunkSize {
 						chunks = append(chunks, currentChunk)
@@ -184,36 +1272,14 @@ func (t *Translator) splitIntoChunks(text string) []string {
 				}
 			} else {
 
-				sentenceSplitters := []string{". ", "! ", "? ", "; "}
-				text := paragraph
-				var sentences []string
-
-				for _, splitter := range sentenceSplitters {
-					parts := strings.Split(text, splitter)
-					if len(parts) > 1 {
-						for i := 0; i < len(parts)-1; i++ {
-							sentences = append(sentences, parts[i]+splitter[:1])
-						}
-						text = parts[len(parts)-1]
-					}
-				}
-
-				if text != "" {
-					sentences = append(sentences, text)
-				}
+				sentences := splitSentences(paragraph, t.abbreviations(t.config.FromLang))
 
 				if len(sentences) <= 1 {
-					for i := 0; i < len(paragraph); i += effectiveChunkSize * 4 {
-						end := i + effectiveChunkSize*4
-						if end > len(paragraph) {
-							end = len(paragraph)
-						}
-						chunks = append(chunks, paragraph[i:end])
-					}
+					chunks = append(chunks, t.splitLongParagraph(ctx, paragraph, effectiveChunkSize)...)
 				} else {
 
 					for _, sentence := range sentences {
-						sentenceTokens := len(sentence) / 4
+						sentenceTokens := t.countTokens(sentence)
 
 						if currentTokens > 0 && (currentTokens+sentenceTokens) > effectiveChunkSize {
 							chunks = append(chunks, currentChunk)
@@ -259,7 +1325,7 @@ func (t *Translator) splitIntoChunks(text string) []string {
 	if t.config.Verbose {
 		for i, chunk := range chunks {
 			fmt.Printf("Chunk %d: ~%d tokens (%d characters)\n",
-				i+1, len(chunk)/4, len(chunk))
+				i+1, t.countTokens(chunk), len(chunk))
 		}
 	}
 
@@ -267,13 +1333,62 @@ func (t *Translator) splitIntoChunks(text string) []string {
 }
 
 type OpenRouterRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
+	Model          string            `json:"model"`
+	Messages       []Message         `json:"messages"`
+	Temperature    *float64          `json:"temperature,omitempty"`
+	Seed           *int              `json:"seed,omitempty"`
+	TopP           *float64          `json:"top_p,omitempty"`
+	MaxTokens      int               `json:"max_tokens,omitempty"`
+	Reasoning      *ReasoningRequest `json:"reasoning,omitempty"`
+	Usage          *UsageRequest     `json:"usage,omitempty"`
+	Stream         bool              `json:"stream,omitempty"`
+	ResponseFormat *ResponseFormat   `json:"response_format,omitempty"`
+}
+
+// ResponseFormat asks for an answer in a JSON object.
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type ReasoningRequest struct {
+	Exclude bool `json:"exclude"`
+}
+
+type UsageRequest struct {
+	Include bool `json:"include"`
 }
 
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// Images are URLs of images sent along with Content to multimodal
+	// models, as content parts.
+	Images []string `json:"-"`
+}
+
+// MarshalJSON writes Content as a plain string, or as text and image_url
+// content parts when there are Images.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message struct {
+		Role    string      `json:"role"`
+		Content interface{} `json:"content"`
+	}
+	if len(m.Images) == 0 {
+		return json.Marshal(message{m.Role, m.Content})
+	}
+	type imageURL struct {
+		URL string `json:"url"`
+	}
+	type contentPart struct {
+		Type     string    `json:"type"`
+		Text     string    `json:"text,omitempty"`
+		ImageURL *imageURL `json:"image_url,omitempty"`
+	}
+	parts := []contentPart{{Type: "text", Text: m.Content}}
+	for _, url := range m.Images {
+		parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{url}})
+	}
+	return json.Marshal(message{m.Role, parts})
 }
 
 type OpenRouterResponse struct {
@@ -285,132 +1400,240 @@ type OpenRouterResponse struct {
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error,omitempty"`
+	Usage *struct {
+		PromptTokens     int     `json:"prompt_tokens"`
+		CompletionTokens int     `json:"completion_tokens"`
+		Cost             float64 `json:"cost"`
+	} `json:"usage,omitempty"`
 }
 
-func (t *Translator) translateChunk(text string) (string, error) {
-
-	prompt := fmt.Sprintf("Translate the following text to %s language, but save formatting, the answer place in the tag <result>:\n\n%s",
-		t.config.ToLang, text)
-
-	request := OpenRouterRequest{
-		Model: t.config.Model,
-		Messages: []Message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
+func (t *Translator) translateChunk(ctx context.Context, text string, pc promptContext) (string, error) {
+	if t.config.RaceModel != "" {
+		return t.raceChunk(ctx, text, pc)
 	}
+	return t.hedgedRequest(ctx, t.modelFor(pc), text, pc)
+}
 
-	requestBody, err := json.Marshal(request)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+// requestTranslation sends one chunk to model and extracts the translation.
+func (t *Translator) requestTranslation(ctx context.Context, model, text string, pc promptContext) (string, error) {
+	if err := t.pace.limit(ctx, t.config.RateLimit, t.config.Verbose); err != nil {
+		return "", canceled(err)
+	}
+	pc.delimiter = t.delimiterFor(model)
+	cr := t.completionRequest(model, text, pc)
+	if t.config.Stream {
+		received := 0
+		cr.Delta = func(text string) {
+			received += len(text)
+			t.streamDelta(pc.chunk, &received, text)
+			t.emit(ProgressEvent{Event: "chunk_delta", Chunk: pc.chunk, Bytes: received, Text: text})
+		}
 	}
 
-	req, err := http.NewRequest("POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(requestBody))
+	completion, err := t.provider.Complete(ctx, cr)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+t.config.APIKey)
-	req.Header.Set("HTTP-Referer", "https://github.com/hightemp/go_ai_translate")
-	req.Header.Set("X-Title", "Go AI Translate")
+	t.addCompletionUsage(completion)
 
-	client := &http.Client{Timeout: 5 * time.Minute}
+	if completion.Plain {
+		return completion.Text, nil
+	}
+	if cr.JSON {
+		return t.extractStructured(completion.Text)
+	}
+	translation, err := t.extractDelimited(model, pc.delimiter, completion.Text)
+	t.noteDelimiter(model, pc.delimiter, isMissingTag(err))
+	return translation, err
+}
 
-	var resp *http.Response
-	maxRetries := t.config.MaxRetries
-	if maxRetries <= 0 {
-		maxRetries = 3
+// completionRequest is the request for the translation of one chunk by
+// model.
+func (t *Translator) completionRequest(model, text string, pc promptContext) CompletionRequest {
+	profile := t.profileFor(model)
+	temperature, seed := t.generation(profile, pc.variation)
+	pc.structured = profile.JSONMode && !t.config.NoStructuredOutput
+
+	system, prompt := t.buildPrompt(text, pc)
+	cr := CompletionRequest{
+		Model:            model,
+		System:           system,
+		Prompt:           prompt,
+		Text:             text,
+		ToLang:           t.config.ToLang,
+		Temperature:      temperature,
+		Seed:             seed,
+		TopP:             t.config.TopP,
+		MaxTokens:        t.config.MaxTokens,
+		ExcludeReasoning: profile.Reasoning,
+		RunID:            t.runID,
+		ChunkID:          pc.chunkID,
+		JSON:             pc.structured,
 	}
-	retryDelay := 2 * time.Second
+	if pc.screenshot != "" {
+		cr.Images = []string{pc.screenshot}
+	}
+	return cr
+}
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		if attempt > 0 {
-			if t.config.Verbose {
-				fmt.Printf("Retrying API call (attempt %d/%d) after error: %v\n",
-					attempt+1, maxRetries, err)
-			}
-			time.Sleep(retryDelay)
+// addCompletionUsage adds the tokens and cost of a completion to the result.
+func (t *Translator) addCompletionUsage(completion *Completion) {
+	t.mu.Lock()
+	t.result.PromptTokens += completion.PromptTokens
+	t.result.CompletionTokens += completion.CompletionTokens
+	t.result.Cost += completion.Cost
+	t.mu.Unlock()
+}
 
-			retryDelay *= 2
-		}
+// buildPrompt returns the system message with the instructions for one
+// chunk and the user message carrying the chunk. Keeping the instructions out
+// of the user message makes models less inclined to answer the text instead
+// of translating it.
+func (t *Translator) buildPrompt(text string, pc promptContext) (string, string) {
+	from := ""
+	if t.sourceLang != "" {
+		from = " from " + t.sourceLang
+	}
+	answer := pc.delimiter.instruction()
+	if pc.structured {
+		answer = `answer with a JSON object {"translation": "..."} holding the translation`
+	}
+	instruction := fmt.Sprintf("Translate the text of the user message%s to %s language, but save formatting, %s",
+		from, t.config.ToLang, answer)
 
-		resp, err = client.Do(req)
-		if err == nil {
-			break
-		}
+	if t.format != nil && t.format.hint != "" {
+		instruction += ". " + t.format.hint
 	}
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+
+	if pc.hint != "" {
+		instruction += ". " + pc.hint
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	if pc.tooLong > 0 {
+		instruction += fmt.Sprintf(". Your previous translation was %d characters long, over the limit of %d: write a shorter rendering, using common abbreviations if needed",
+			pc.tooLong, t.chunkMaxLength(pc.chunk-1))
+	}
+
+	switch {
+	case pc.missingTag && pc.structured:
+		instruction += `. Your previous answer was not a JSON object with the translation: answer with nothing but {"translation": "..."}, without any comment`
+	case pc.missingTag:
+		instruction += ". " + pc.delimiter.insist()
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		errorMsg := fmt.Sprintf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	if pc.correction != "" {
+		instruction += fmt.Sprintf(". Your previous answer was unusable, %s: translate all of the text to %s language, and nothing else",
+			pc.correction, t.config.ToLang)
+	}
 
-		var errorResponse struct {
-			Error struct {
-				Message string `json:"message"`
-				Type    string `json:"type"`
-				Code    string `json:"code"`
-			} `json:"error"`
-		}
+	if maskTokenRe.MatchString(text) {
+		instruction += fmt.Sprintf(". Markers like %s0%s stand for protected content, keep every marker exactly as it is",
+			maskOpen, maskClose)
+	}
 
-		if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse.Error.Message != "" {
-			errorMsg = fmt.Sprintf("API request failed: %s (Type: %s, Code: %s)",
-				errorResponse.Error.Message,
-				errorResponse.Error.Type,
-				errorResponse.Error.Code)
-		}
+	if hint := placeholderHint(text); hint != "" {
+		instruction += ". " + hint
+	}
 
-		return "", fmt.Errorf("%s", errorMsg)
+	if terms := t.glossaryTerms(text); len(terms) > 0 {
+		instruction += ". Translate these terms exactly as given: " + strings.Join(terms, "; ")
 	}
 
-	var response OpenRouterResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	if custom := strings.TrimSpace(t.config.SystemPrompt); custom != "" {
+		instruction = strings.Replace(custom, "{to}", t.config.ToLang, -1) + "\n\n" + instruction
 	}
 
-	if response.Error != nil {
-		errorMsg := fmt.Sprintf("API error: %s", response.Error.Message)
+	prompt := "Text to translate:\n\n" + text
 
-		if t.config.Verbose {
-			fmt.Printf("API error details: %s\n", errorMsg)
-			fmt.Printf("Request body: %s\n", string(requestBody))
+	if len(pc.references) > 0 {
+		var refs strings.Builder
+		refs.WriteString("Earlier translations of similar passages, reuse their wording and terminology where the source matches. Do not translate them again:\n\n")
+		for _, r := range pc.references {
+			fmt.Fprintf(&refs, "<reference>\n<source>%s</source>\n<translation>%s</translation>\n</reference>\n", r.Source, r.Target)
 		}
+		prompt = refs.String() + "\n" + prompt
+	}
 
-		return "", fmt.Errorf("%s", errorMsg)
+	if pc.previousSource != "" {
+		var prev strings.Builder
+		prev.WriteString("The text comes right after this passage, given for context only. Do not translate it again:\n\n<context>\n")
+		fmt.Fprintf(&prev, "<source>%s</source>\n", pc.previousSource)
+		if pc.previousTranslation != "" {
+			fmt.Fprintf(&prev, "<translation>%s</translation>\n", pc.previousTranslation)
+		}
+		prev.WriteString("</context>\n")
+		prompt = prev.String() + "\n" + prompt
 	}
 
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no translation returned from API")
+	if pc.summary != "" {
+		prompt = "Summary of the document so far, keep its names, genders and terms consistent. Do not translate it:\n\n<summary>\n" +
+			pc.summary + "\n</summary>\n\n" + prompt
 	}
 
-	translation := response.Choices[0].Message.Content
+	return instruction, prompt
+}
+
+// messages is the conversation asking for the translation of one chunk.
+func (t *Translator) messages(text string, pc promptContext) []Message {
+	system, prompt := t.buildPrompt(text, pc)
+	return []Message{{Role: "system", Content: system}, {Role: "user", Content: prompt}}
+}
+
+// extractTranslation takes the translation out of a model answer, applying
+// the model's profile.
+func (t *Translator) extractTranslation(model, translation string) (string, error) {
+	profile := t.profileFor(model)
+	if profile.Reasoning {
+		translation = thinkBlockRe.ReplaceAllString(translation, "")
+	}
 
 	result, err := t.extractResultTag(translation)
 
 	if err != nil {
-		return "", err
+		if !profile.IgnoresResultTag || strings.TrimSpace(translation) == "" {
+			return "", err
+		}
+		result = strings.TrimSpace(translation)
 	}
 
 	return result, nil
 }
 
-func (t *Translator) extractResultTag(input string) (string, error) {
-	re := regexp.MustCompile(`(?s)<result>(.*?)</result>`)
+func (t *Translator) baseURL() string {
+	if t.config.BaseURL != "" {
+		return strings.TrimSuffix(t.config.BaseURL, "/")
+	}
+	switch t.config.Backend {
+	case BackendOllama:
+		return ollamaBaseURL
+	case BackendDeepL:
+		// Keys of the free plan end in ":fx" and only work on its endpoint.
+		if strings.HasSuffix(t.config.APIKey, ":fx") {
+			return deeplFreeBaseURL
+		}
+		return deeplBaseURL
+	}
+	return openRouterBaseURL
+}
 
-	matches := re.FindStringSubmatch(input)
+func (t *Translator) activeModel() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.model != "" {
+		return t.model
+	}
+	return t.config.Model
+}
+
+var resultTagRe = regexp.MustCompile(`(?s)<result>(.*?)</result>`)
+
+func (t *Translator) extractResultTag(input string) (string, error) {
+	matches := resultTagRe.FindStringSubmatch(input)
 
 	if len(matches) < 2 {
-		return "", fmt.Errorf("tag <result> not found")
+		return "", classify(ErrorClassExtraction, &missingTagError{answer: input})
 	}
 
 	return matches[1], nil
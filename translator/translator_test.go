@@ -1,6 +1,7 @@
 package translator
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
@@ -50,7 +51,7 @@ func TestSplitIntoChunks(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			chunks := translator.splitIntoChunks(tc.input)
+			chunks := translator.splitIntoChunks(context.Background(), tc.input)
 
 			t.Logf("Input length: %d characters, approx %d tokens", len(tc.input), len(tc.input)/4)
 			t.Logf("Got %d chunks", len(chunks))
@@ -140,7 +141,7 @@ func TestTranslateChunk(t *testing.T) {
 	translator := NewTranslator(config)
 
 	input := "Hello, world!"
-	translated, err := translator.translateChunk(input)
+	translated, err := translator.translateChunk(context.Background(), input, promptContext{})
 	if err != nil {
 		t.Fatalf("Translation failed: %v", err)
 	}
@@ -0,0 +1,213 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Typography is how a language writes quotation marks, ellipses and the
+// spaces around some punctuation. Empty fields leave the text as the model
+// wrote it.
+type Typography struct {
+	// Quotes are the opening and closing quotation marks, InnerQuotes those
+	// of a quotation within a quotation. French puts a space inside them.
+	Quotes      [2]string `json:"quotes,omitempty"`
+	InnerQuotes [2]string `json:"inner_quotes,omitempty"`
+	// Ellipsis replaces three full stops.
+	Ellipsis string `json:"ellipsis,omitempty"`
+	// PercentSpace goes between a number and the percent sign.
+	PercentSpace string `json:"percent_space,omitempty"`
+	// PunctuationSpace goes before ; : ! and ?, as in French.
+	PunctuationSpace string `json:"punctuation_space,omitempty"`
+}
+
+const (
+	nbsp       = " "
+	narrowNbsp = " "
+)
+
+// typographies are the built-in tables by language name.
+var typographies = map[string]Typography{
+	"english":    {Quotes: [2]string{"“", "”"}, InnerQuotes: [2]string{"‘", "’"}, Ellipsis: "…"},
+	"german":     {Quotes: [2]string{"„", "“"}, InnerQuotes: [2]string{"‚", "‘"}, Ellipsis: "…", PercentSpace: nbsp},
+	"french":     {Quotes: [2]string{"«" + nbsp, nbsp + "»"}, InnerQuotes: [2]string{"“", "”"}, Ellipsis: "…", PercentSpace: narrowNbsp, PunctuationSpace: narrowNbsp},
+	"spanish":    {Quotes: [2]string{"«", "»"}, InnerQuotes: [2]string{"“", "”"}, Ellipsis: "…", PercentSpace: nbsp},
+	"italian":    {Quotes: [2]string{"«", "»"}, InnerQuotes: [2]string{"“", "”"}, Ellipsis: "…"},
+	"portuguese": {Quotes: [2]string{"«", "»"}, InnerQuotes: [2]string{"“", "”"}, Ellipsis: "…"},
+	"dutch":      {Quotes: [2]string{"“", "”"}, InnerQuotes: [2]string{"‘", "’"}, Ellipsis: "…"},
+	"russian":    {Quotes: [2]string{"«", "»"}, InnerQuotes: [2]string{"„", "“"}, Ellipsis: "…"},
+	"ukrainian":  {Quotes: [2]string{"«", "»"}, InnerQuotes: [2]string{"„", "“"}, Ellipsis: "…"},
+	"belarusian": {Quotes: [2]string{"«", "»"}, InnerQuotes: [2]string{"„", "“"}, Ellipsis: "…"},
+	"bulgarian":  {Quotes: [2]string{"„", "“"}, InnerQuotes: [2]string{"‚", "‘"}, Ellipsis: "…"},
+	"polish":     {Quotes: [2]string{"„", "”"}, InnerQuotes: [2]string{"«", "»"}, Ellipsis: "…"},
+	"czech":      {Quotes: [2]string{"„", "“"}, InnerQuotes: [2]string{"‚", "‘"}, Ellipsis: "…", PercentSpace: nbsp},
+	"slovak":     {Quotes: [2]string{"„", "“"}, InnerQuotes: [2]string{"‚", "‘"}, Ellipsis: "…", PercentSpace: nbsp},
+	"slovenian":  {Quotes: [2]string{"„", "“"}, InnerQuotes: [2]string{"‚", "‘"}, Ellipsis: "…"},
+	"hungarian":  {Quotes: [2]string{"„", "”"}, InnerQuotes: [2]string{"»", "«"}, Ellipsis: "…"},
+	"romanian":   {Quotes: [2]string{"„", "”"}, InnerQuotes: [2]string{"«", "»"}, Ellipsis: "…"},
+	"greek":      {Quotes: [2]string{"«", "»"}, InnerQuotes: [2]string{"“", "”"}, Ellipsis: "…"},
+	"lithuanian": {Quotes: [2]string{"„", "“"}, InnerQuotes: [2]string{"‚", "‘"}, Ellipsis: "…"},
+	"latvian":    {Quotes: [2]string{"«", "»"}, InnerQuotes: [2]string{"„", "“"}, Ellipsis: "…"},
+	"estonian":   {Quotes: [2]string{"„", "“"}, Ellipsis: "…"},
+	"danish":     {Quotes: [2]string{"»", "«"}, InnerQuotes: [2]string{"›", "‹"}, Ellipsis: "…"},
+	"norwegian":  {Quotes: [2]string{"«", "»"}, InnerQuotes: [2]string{"‘", "’"}, Ellipsis: "…", PercentSpace: nbsp},
+	"swedish":    {Quotes: [2]string{"”", "”"}, InnerQuotes: [2]string{"’", "’"}, Ellipsis: "…", PercentSpace: nbsp},
+	"finnish":    {Quotes: [2]string{"”", "”"}, InnerQuotes: [2]string{"’", "’"}, Ellipsis: "…", PercentSpace: nbsp},
+	"turkish":    {Quotes: [2]string{"“", "”"}, InnerQuotes: [2]string{"‘", "’"}, Ellipsis: "…"},
+	"indonesian": {Quotes: [2]string{"“", "”"}, InnerQuotes: [2]string{"‘", "’"}, Ellipsis: "…"},
+	"japanese":   {Quotes: [2]string{"「", "」"}, InnerQuotes: [2]string{"『", "』"}, Ellipsis: "…"},
+	"chinese":    {Quotes: [2]string{"“", "”"}, InnerQuotes: [2]string{"‘", "’"}, Ellipsis: "……"},
+	"korean":     {Quotes: [2]string{"“", "”"}, InnerQuotes: [2]string{"‘", "’"}, Ellipsis: "…"},
+}
+
+// doubleQuotes are the quotation marks models write that typography
+// replaces. Single quotes are left alone, as most are apostrophes.
+const doubleQuotes = "\"“”„‟«»"
+
+var (
+	ellipsisRe    = regexp.MustCompile(`\.{3,}`)
+	percentRe     = regexp.MustCompile(`(\d)[ \x{00a0}\x{202f}]?%`)
+	punctuationRe = regexp.MustCompile(`([\p{L}\p{N}»)\]])[ \x{00a0}\x{202f}]?([;:!?]+)([\s\x{00a0}]|$)`)
+)
+
+// LoadTypography reads typography overrides from a JSON file keyed by
+// language name or code, e.g. {"de": {"quotes": ["»", "«"]}}. Fields that
+// are set replace those of the built-in table.
+func LoadTypography(path string) (map[string]Typography, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read typography: %w", err)
+	}
+	var overrides map[string]Typography
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("typography %s: %w", path, err)
+	}
+	return overrides, nil
+}
+
+// typography returns the table of the target language with the overrides
+// of Config.TypographyOverrides applied.
+func (t *Translator) typography() Typography {
+	lang := languageName(t.config.ToLang)
+	table := typographies[lang]
+	for key, o := range t.config.TypographyOverrides {
+		if languageName(key) != lang {
+			continue
+		}
+		if o.Quotes[0] != "" || o.Quotes[1] != "" {
+			table.Quotes = o.Quotes
+		}
+		if o.InnerQuotes[0] != "" || o.InnerQuotes[1] != "" {
+			table.InnerQuotes = o.InnerQuotes
+		}
+		if o.Ellipsis != "" {
+			table.Ellipsis = o.Ellipsis
+		}
+		if o.PercentSpace != "" {
+			table.PercentSpace = o.PercentSpace
+		}
+		if o.PunctuationSpace != "" {
+			table.PunctuationSpace = o.PunctuationSpace
+		}
+	}
+	return table
+}
+
+// apply rewrites the typography of a translated chunk. Protected spans are
+// still markers at this point and are left alone.
+func (ty Typography) apply(text string) string {
+	if ty.Quotes[0] != "" {
+		text = ty.replaceQuotes(text)
+	}
+	if ty.Ellipsis != "" {
+		text = ellipsisRe.ReplaceAllStringFunc(text, func(dots string) string {
+			if len(dots) != 3 {
+				return dots
+			}
+			return ty.Ellipsis
+		})
+	}
+	if ty.PercentSpace != "" {
+		text = percentRe.ReplaceAllString(text, "${1}"+ty.PercentSpace+"%")
+	}
+	if ty.PunctuationSpace != "" {
+		text = punctuationRe.ReplaceAllString(text, "${1}"+ty.PunctuationSpace+"${2}${3}")
+	}
+	return text
+}
+
+// replaceQuotes replaces double quotation marks with those of the table.
+// A mark opens a quotation when it follows a space or an opening bracket
+// and closes one when a space or punctuation follows it; when that is
+// unclear, as in text without spaces, it closes the quotation that is
+// open. A quote within a quote gets the inner marks. Quotations end with
+// the paragraph, and a straight quote after a digit with no quotation
+// open, as in 12", is a unit.
+func (ty Typography) replaceQuotes(text string) string {
+	inner := ty.InnerQuotes
+	if inner[0] == "" {
+		inner = ty.Quotes
+	}
+
+	var b strings.Builder
+	depth := 0
+	prev, opened := rune(-1), false
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if r == '\n' && strings.HasPrefix(text[i:], "\n") {
+			depth = 0
+		}
+		if !strings.ContainsRune(doubleQuotes, r) {
+			b.WriteRune(r)
+			prev = r
+			continue
+		}
+
+		next, _ := utf8.DecodeRuneInString(text[i:])
+		left := prev == -1 || unicode.IsSpace(prev) || strings.ContainsRune("([{—–-/", prev) ||
+			opened && strings.ContainsRune(doubleQuotes, prev)
+		right := i == len(text) || unicode.IsSpace(next) || strings.ContainsRune(".,;:!?)]}…—–"+doubleQuotes, next)
+		switch {
+		case depth == 0 && r == '"' && unicode.IsDigit(prev):
+			b.WriteRune(r)
+			opened = false
+		case left && !right || left == right && depth == 0:
+			depth++
+			marks := ty.Quotes
+			if depth > 1 {
+				marks = inner
+			}
+			b.WriteString(marks[0])
+			if strings.HasSuffix(marks[0], nbsp) {
+				for i < len(text) && (text[i] == ' ' || strings.HasPrefix(text[i:], nbsp)) {
+					_, n := utf8.DecodeRuneInString(text[i:])
+					i += n
+				}
+			}
+			opened = true
+		default:
+			marks := ty.Quotes
+			if depth > 1 {
+				marks = inner
+			}
+			if depth > 0 {
+				depth--
+			}
+			if strings.HasPrefix(marks[1], nbsp) {
+				trimmed := strings.TrimRight(b.String(), " "+nbsp)
+				b.Reset()
+				b.WriteString(trimmed)
+			}
+			b.WriteString(marks[1])
+			opened = false
+		}
+		prev = r
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTypographyApply(t *testing.T) {
+	testCases := []struct {
+		lang, input, expected string
+	}{
+		{"german", `Er sagte: "Das ist 'gut' und "sehr" schön..."`, "Er sagte: „Das ist 'gut' und ‚sehr‘ schön…“"},
+		{"de", `"Eins."` + "\n\n" + `"Zwei.`, "„Eins.“\n\n„Zwei."},
+		{"german", `Ein 12" Rohr kostet 50 %, oder 50%ig.`, "Ein 12\" Rohr kostet 50 %, oder 50 %ig."},
+		{"french", `Il dit : "Bonjour !" Vraiment? Oui; 20 % .`, "Il dit : « Bonjour ! » Vraiment ? Oui ; 20 % ."},
+		{"french", `« Déjà »`, "« Déjà »"},
+		{"english", `«Hi» and „so“.`, "“Hi” and “so”."},
+		{"japanese", `彼は"はい"と言った`, "彼は「はい」と言った"},
+		{"japanese", `"はい"`, "「はい」"},
+		{"chinese", `等等...`, "等等……"},
+		{"klingon", `"Qapla'..."`, `"Qapla'..."`},
+		{"german", `Keep "⟦0⟧" and ⟦1⟧....`, "Keep „⟦0⟧“ and ⟦1⟧...."},
+	}
+	for _, tc := range testCases {
+		tr := NewTranslator(Config{ToLang: tc.lang})
+		if got := tr.typography().apply(tc.input); got != tc.expected {
+			t.Errorf("%s %q: expected %q, got %q", tc.lang, tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestTypographyOverrides(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "typography.json")
+	os.WriteFile(path, []byte(`{"de": {"quotes": ["»", "«"]}, "french": {"ellipsis": "..."}}`), 0644)
+	overrides, err := LoadTypography(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewTranslator(Config{ToLang: "German", TypographyOverrides: overrides})
+	if got, want := tr.typography().apply(`"a "b" c..."`), "»a ‚b‘ c…«"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	tr = NewTranslator(Config{ToLang: "fr", TypographyOverrides: overrides})
+	if got := tr.typography().Ellipsis; got != "..." {
+		t.Errorf("Expected the ellipsis override, got %q", got)
+	}
+
+	os.WriteFile(path, []byte(`{"de": {"quotes": "»«"}}`), 0644)
+	if _, err := LoadTypography(path); err == nil {
+		t.Error("Expected an error for a malformed table")
+	}
+}
+
+func TestTypographyTranslation(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "notes.md")
+	out := filepath.Join(dir, "notes.de.md")
+	os.WriteFile(in, []byte("She said \"wait...\" and ran `echo \"x\"`.\n"), 0644)
+
+	tr := NewTranslator(Config{Provider: &promptRecorder{}, ChunkSize: 100, NoDelay: true, ToLang: "german", Typography: true})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	want := "SHE SAID „WAIT…“ AND RAN `echo \"x\"`.\n"
+	if got, _ := os.ReadFile(out); string(got) != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,129 @@
+package translator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MinChunkSize is the smallest chunk size Validate accepts. Smaller chunks
+// cut sentences apart and cost more in prompt overhead than they translate.
+const MinChunkSize = 50
+
+// Validate reports the first setting that would make a run fail or produce
+// nonsense, with an error of class ErrorClassConfig saying how to fix it. New
+// calls it; NewTranslator does not, so callers of it should.
+func (c Config) Validate() error {
+	if c.ChunkSize < MinChunkSize {
+		return configError("chunk size %d is too small, use at least %d tokens", c.ChunkSize, MinChunkSize)
+	}
+	if c.ChunkMargin != nil && (*c.ChunkMargin < 0 || *c.ChunkMargin >= 1) {
+		return configError("chunk margin %g must be at least 0 and below 1, e.g. 0.2 to fill chunks up to 80%% of the chunk size", *c.ChunkMargin)
+	}
+	if strings.TrimSpace(c.ToLang) == "" {
+		return configError("no target language set, e.g. \"german\" or \"de\"")
+	}
+
+	switch c.Backend {
+	case "", BackendOpenRouter:
+		// Other OpenAI compatible servers at BaseURL name models freely.
+		if c.Provider == nil && c.BaseURL == "" {
+			for _, model := range append([]string{c.Model}, c.FallbackModels...) {
+				if !strings.Contains(model, "/") {
+					return configError("unknown model %q: OpenRouter model IDs look like vendor/model, e.g. %s", model, DefaultModel)
+				}
+			}
+		}
+	case BackendOllama:
+		if c.Model == "" {
+			return configError("no model set, use the name of a model pulled into Ollama, e.g. llama3.1")
+		}
+	case BackendDeepL:
+		if _, err := deeplTargetLang(c.ToLang); err != nil {
+			return configError("%v", err)
+		}
+	default:
+		return configError("unknown backend %q, use %s, %s or %s", c.Backend, BackendOpenRouter, BackendOllama, BackendDeepL)
+	}
+
+	if _, err := lookupFormat(c.Format, ""); err != nil {
+		return classify(ErrorClassConfig, err)
+	}
+	switch c.Schedule {
+	case "", ScheduleFIFO, ScheduleLargestFirst:
+	default:
+		return configError("unknown schedule %q, use %s or %s", c.Schedule, ScheduleFIFO, ScheduleLargestFirst)
+	}
+	if c.ContextSentences < 0 {
+		return configError("context sentences %d must not be negative", c.ContextSentences)
+	}
+	switch c.Chunking {
+	case "", ChunkingParagraph, ChunkingMarkdown:
+	default:
+		return configError("unknown chunking %q, use %s or %s", c.Chunking, ChunkingParagraph, ChunkingMarkdown)
+	}
+	if c.Summary && c.Backend == BackendDeepL {
+		return configError("the running summary needs a language model, DeepL cannot write one")
+	}
+	switch c.Via {
+	case "":
+	case ViaPandoc:
+		if c.Format != "" && c.Format != "auto" && c.Format != "markdown" {
+			return configError("conversion via %s translates Markdown, not the %s format", c.Via, c.Format)
+		}
+	default:
+		return configError("unknown conversion %q, use %s", c.Via, ViaPandoc)
+	}
+	switch c.ImageText {
+	case "":
+	case ImageTextAlt, ImageTextAppendix:
+		if c.Backend == BackendDeepL {
+			return configError("image text needs a vision model, DeepL cannot read images")
+		}
+	default:
+		return configError("unknown image text mode %q, use %s or %s", c.ImageText, ImageTextAlt, ImageTextAppendix)
+	}
+	switch c.OCR {
+	case "", OCRTesseract, OCRVision:
+	default:
+		return configError("unknown OCR engine %q, use %s or %s", c.OCR, OCRTesseract, OCRVision)
+	}
+	switch c.BatchAPI {
+	case "", BatchAPIOpenAI, BatchAPIAnthropic:
+	default:
+		return configError("unknown batch API %q, use %s or %s", c.BatchAPI, BatchAPIOpenAI, BatchAPIAnthropic)
+	}
+
+	if _, err := parseSelector(c.Select, c.SelectRegex); err != nil {
+		return classify(ErrorClassConfig, err)
+	}
+	if pe := c.PostEdit; pe != nil {
+		switch pe.Backend {
+		case "", BackendOpenRouter, BackendOllama:
+		default:
+			return configError("post-editing needs a language model, use %s or %s, not %q", BackendOpenRouter, BackendOllama, pe.Backend)
+		}
+		if strings.TrimSpace(pe.Model) == "" {
+			return configError("no post-editing model set")
+		}
+	}
+	if _, err := protectPatterns(c.ProtectPatterns); err != nil {
+		return classify(ErrorClassConfig, err)
+	}
+	if c.Concurrency < 0 {
+		return configError("concurrency %d is negative, use 1 or more", c.Concurrency)
+	}
+	if c.Temperature != nil && (*c.Temperature < 0 || *c.Temperature > 2) {
+		return configError("temperature %g is out of range, use 0 to 2", *c.Temperature)
+	}
+	if c.TopP != nil && (*c.TopP <= 0 || *c.TopP > 1) {
+		return configError("top_p %g is out of range, use more than 0 and at most 1", *c.TopP)
+	}
+	if c.MaxTokens < 0 {
+		return configError("max tokens %d is negative, leave it at 0 for the provider's default", c.MaxTokens)
+	}
+	return nil
+}
+
+func configError(format string, args ...interface{}) error {
+	return classify(ErrorClassConfig, fmt.Errorf(format, args...))
+}
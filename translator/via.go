@@ -0,0 +1,71 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ViaPandoc converts documents with pandoc, see Config.Via.
+	ViaPandoc = "pandoc"
+
+	defaultViaToMarkdown   = "pandoc {input} -t gfm --wrap=none -o {output}"
+	defaultViaFromMarkdown = "pandoc {input} -f gfm -o {output}"
+)
+
+// translateVia translates inputPath by way of Markdown, see Config.Via.
+func (t *Translator) translateVia(ctx context.Context, inputPath, outputPath string) error {
+	dir, err := os.MkdirTemp("", "go_ai_translate-via-")
+	if err != nil {
+		return fmt.Errorf("failed to create a directory for conversion: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source.md")
+	target := filepath.Join(dir, "target.md")
+
+	toMarkdown := t.config.ViaToMarkdown
+	if toMarkdown == "" {
+		toMarkdown = defaultViaToMarkdown
+	}
+	if err := runCommandLine(ctx, toMarkdown, inputPath, source); err != nil {
+		return err
+	}
+
+	prepared, err := t.prepareFile(ctx, source)
+	if err != nil {
+		return err
+	}
+	if err := t.translatePrepared(ctx, prepared, createOutput(target)); err != nil {
+		return err
+	}
+	if err := t.speak(ctx, target, outputPath); err != nil {
+		return err
+	}
+
+	fromMarkdown := t.config.ViaFromMarkdown
+	if fromMarkdown == "" {
+		fromMarkdown = defaultViaFromMarkdown
+	}
+	if err := runCommandLine(ctx, fromMarkdown, target, outputPath); err != nil {
+		return err
+	}
+	return t.polish(ctx, outputPath)
+}
+
+// runCommandLine runs a command of Config.Via or Config.PostCommands.
+// The command is split into arguments at spaces and run without a shell;
+// {input} and {output} in it stand for the files.
+func runCommandLine(ctx context.Context, command, input, output string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return configError("empty command")
+	}
+	for i, arg := range args {
+		args[i] = strings.NewReplacer("{input}", input, "{output}", output).Replace(arg)
+	}
+	return runTool(ctx, args[0], args[1:]...)
+}
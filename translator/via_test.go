@@ -0,0 +1,51 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestTranslateVia(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a stand-in for pandoc")
+	}
+	bin := t.TempDir()
+	// pandoc <input> ... -o <output> turns AsciiDoc titles into Markdown
+	// headings and back.
+	os.WriteFile(filepath.Join(bin, "pandoc"), []byte(`#!/bin/sh
+in=$1
+while [ "$1" != "-o" ]; do shift; done
+case "$in" in
+*.md) sed 's/^# /= /' "$in" > "$2" ;;
+*) sed 's/^= /# /' "$in" > "$2" ;;
+esac
+`), 0755)
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "doc.adoc")
+	out := filepath.Join(dir, "doc.de.adoc")
+	os.WriteFile(in, []byte("= Title\n\nHello world.\n"), 0644)
+
+	tr := NewTranslator(Config{Provider: &promptRecorder{}, ChunkSize: 100, NoDelay: true, ToLang: "german", Via: ViaPandoc})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "= TITLE\n\nHELLO WORLD.\n" {
+		t.Errorf("Expected the translation converted back, got %q", got)
+	}
+	if tr.Result().Format != "markdown" || tr.Result().Input != in {
+		t.Errorf("Expected a Markdown translation of %s, got %+v", in, tr.Result())
+	}
+
+	tr = NewTranslator(Config{Provider: &promptRecorder{}, ChunkSize: 100, NoDelay: true, ToLang: "german", Via: ViaPandoc,
+		ViaToMarkdown: "missing-converter {input} {output}"})
+	if err := tr.TranslateFile(in, out); ErrorClass(err) != ErrorClassConfig || !strings.Contains(err.Error(), "missing-converter") {
+		t.Errorf("Expected a config error naming the missing command, got %v", err)
+	}
+}
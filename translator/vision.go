@@ -0,0 +1,194 @@
+package translator
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ImageTextAlt replaces the alt text of images with a description in
+	// the target language, written by a vision model from the image itself.
+	ImageTextAlt = "alt"
+	// ImageTextAppendix appends the text found in images, translated, to
+	// the end of the output.
+	ImageTextAppendix = "appendix"
+)
+
+var (
+	markdownImageRe = regexp.MustCompile(`!\[([^\]\n]*)\]\(([^)\s]+)((?:[ \t]+"[^"\n]*")?\))`)
+	htmlImageRe     = regexp.MustCompile(`<img\b[^>]*>`)
+	htmlSrcRe       = regexp.MustCompile(`\bsrc\s*=\s*"([^"]*)"`)
+	htmlAltRe       = regexp.MustCompile(`\balt\s*=\s*"[^"]*"`)
+)
+
+// imageText is what a vision model read from one image of the document.
+type imageText struct {
+	src  string
+	text string
+}
+
+// documentImages lists the sources of the Markdown and HTML images in text,
+// each once, in order of appearance.
+func documentImages(text string) []string {
+	var srcs []string
+	seen := map[string]bool{}
+	add := func(src string) {
+		if src != "" && !seen[src] {
+			seen[src] = true
+			srcs = append(srcs, src)
+		}
+	}
+	for _, m := range markdownImageRe.FindAllStringSubmatch(text, -1) {
+		add(m[2])
+	}
+	for _, tag := range htmlImageRe.FindAllString(text, -1) {
+		if m := htmlSrcRe.FindStringSubmatch(tag); m != nil {
+			add(m[1])
+		}
+	}
+	return srcs
+}
+
+// imageURL returns src as a URL a vision model can fetch: web and data URLs
+// as they are, local files, relative to the document, as data URLs.
+func imageURL(src, document string) (string, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "data:") {
+		return src, nil
+	}
+	path := src
+	if !filepath.IsAbs(path) && document != "" {
+		path = filepath.Join(filepath.Dir(document), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	mediaType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if !strings.HasPrefix(mediaType, "image/") {
+		return "", fmt.Errorf("%s is not an image", src)
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// readImages sends the images of the document to the vision model, see
+// Config.ImageText. An image that cannot be read is reported as a warning
+// and left as it is.
+func (t *Translator) readImages(ctx context.Context, job *fileJob, document string) error {
+	t.imageTexts = nil
+	if t.config.ImageText == "" {
+		return nil
+	}
+	var source strings.Builder
+	for i := range job.chunks {
+		source.WriteString(job.source(i) + "\n")
+	}
+
+	model := t.config.VisionModel
+	if model == "" {
+		model = t.activeModel()
+	}
+	instruction := fmt.Sprintf("Write a short alt text in %s language for the image of the user message, giving the gist of what it shows and a translation of any text in it. Place the answer in the tag <result>", t.config.ToLang)
+	if t.config.ImageText == ImageTextAppendix {
+		instruction = fmt.Sprintf("Transcribe the text in the image of the user message, translated into %s language, one line per label or paragraph. Place the answer in the tag <result>, and leave the tag empty if the image has no text", t.config.ToLang)
+	}
+
+	for _, src := range documentImages(source.String()) {
+		url, err := imageURL(src, document)
+		if err == nil {
+			var completion *Completion
+			completion, err = t.provider.Complete(ctx, CompletionRequest{
+				Model:  model,
+				System: instruction,
+				Prompt: "Image: " + src,
+				ToLang: t.config.ToLang,
+				Images: []string{url},
+				RunID:  t.runID,
+			})
+			if err == nil {
+				t.addCompletionUsage(completion)
+				var text string
+				if text, err = t.extractTranslation(model, completion.Text); err == nil {
+					t.imageTexts = append(t.imageTexts, imageText{src: src, text: strings.TrimSpace(text)})
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return canceled(ctx.Err())
+		}
+		if err != nil {
+			t.warn(Warning{Kind: WarningImageText, Message: fmt.Sprintf("image %s: %v", src, err)})
+		}
+	}
+	return nil
+}
+
+func (t *Translator) imageTextOf(src string) (string, bool) {
+	for _, it := range t.imageTexts {
+		if it.src == src {
+			return it.text, it.text != ""
+		}
+	}
+	return "", false
+}
+
+// replaceAltText sets the alt text of the images in translation to what the
+// vision model wrote for them.
+func (t *Translator) replaceAltText(translation string) string {
+	if t.config.ImageText != ImageTextAlt || len(t.imageTexts) == 0 {
+		return translation
+	}
+	alt := func(src string) (string, bool) {
+		text, ok := t.imageTextOf(src)
+		return strings.Join(strings.Fields(text), " "), ok
+	}
+
+	translation = markdownImageRe.ReplaceAllStringFunc(translation, func(image string) string {
+		m := markdownImageRe.FindStringSubmatch(image)
+		text, ok := alt(m[2])
+		if !ok {
+			return image
+		}
+		text = strings.NewReplacer("[", "(", "]", ")").Replace(text)
+		return "![" + text + "](" + m[2] + m[3]
+	})
+	return htmlImageRe.ReplaceAllStringFunc(translation, func(tag string) string {
+		m := htmlSrcRe.FindStringSubmatch(tag)
+		if m == nil {
+			return tag
+		}
+		text, ok := alt(m[1])
+		if !ok {
+			return tag
+		}
+		attr := `alt="` + strings.Replace(text, `"`, "&quot;", -1) + `"`
+		if htmlAltRe.MatchString(tag) {
+			return htmlAltRe.ReplaceAllLiteralString(tag, attr)
+		}
+		return strings.Replace(tag, "<img", "<img "+attr, 1)
+	})
+}
+
+// imageAppendix lists the translated text of the images that have some,
+// for the end of the output.
+func (t *Translator) imageAppendix() string {
+	if t.config.ImageText != ImageTextAppendix {
+		return ""
+	}
+	var b strings.Builder
+	for _, it := range t.imageTexts {
+		if it.text == "" {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("\n\n## Image text\n")
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n%s\n", it.src, it.text)
+	}
+	return b.String()
+}
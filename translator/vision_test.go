@@ -0,0 +1,70 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// visionProvider reads "Diagramm" from every image and translates "See".
+type visionProvider struct {
+	mu     sync.Mutex
+	images []string
+}
+
+func (p *visionProvider) Complete(ctx context.Context, cr CompletionRequest) (*Completion, error) {
+	if len(cr.Images) > 0 {
+		p.mu.Lock()
+		p.images = append(p.images, cr.Images...)
+		p.mu.Unlock()
+		return &Completion{Text: "<result>Diagramm [Eingabe]</result>"}, nil
+	}
+	text := cr.Prompt[strings.Index(cr.Prompt, ":\n\n")+3:]
+	return &Completion{Text: "<result>" + strings.Replace(text, "See", "Siehe", -1) + "</result>"}, nil
+}
+
+func TestImageText(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "doc.md")
+	out := filepath.Join(dir, "out.md")
+	doc := "# Flow\n\n![flow chart](img/flow.png)\n\nSee <img src=\"https://example.com/a.png\" alt=\"arch\"> too.\n\n![gone](missing.png)\n"
+	os.MkdirAll(filepath.Join(dir, "img"), 0755)
+	os.WriteFile(filepath.Join(dir, "img", "flow.png"), []byte("\x89PNG"), 0644)
+	os.WriteFile(in, []byte(doc), 0644)
+
+	provider := &visionProvider{}
+	tr := NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "german", ImageText: ImageTextAlt})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(out)
+	want := "# Flow\n\n![Diagramm (Eingabe)](img/flow.png)\n\nSiehe <img src=\"https://example.com/a.png\" alt=\"Diagramm [Eingabe]\"> too.\n\n![gone](missing.png)\n"
+	if string(got) != want {
+		t.Errorf("Expected alt texts from the vision model, got %q", got)
+	}
+	if len(provider.images) != 2 || !strings.HasPrefix(provider.images[0], "data:image/png;base64,") || provider.images[1] != "https://example.com/a.png" {
+		t.Errorf("Expected the local image as a data URL and the remote one as is, got %q", provider.images)
+	}
+	if w := tr.Result().Warnings; len(w) != 1 || w[0].Kind != WarningImageText || !strings.Contains(w[0].Message, "missing.png") {
+		t.Errorf("Expected a warning for the missing image, got %+v", w)
+	}
+
+	tr = NewTranslator(Config{Provider: provider, ChunkSize: 100, NoDelay: true, ToLang: "german", ImageText: ImageTextAppendix})
+	if err := tr.TranslateFile(in, out); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = os.ReadFile(out)
+	if !strings.HasSuffix(string(got), "![gone](missing.png)\n\n## Image text\n\n### img/flow.png\n\nDiagramm [Eingabe]\n\n### https://example.com/a.png\n\nDiagramm [Eingabe]\n") {
+		t.Errorf("Expected an appendix of image texts, got %q", got)
+	}
+	if !strings.Contains(string(got), "![flow chart](img/flow.png)") {
+		t.Errorf("Expected alt texts left to the translation in appendix mode, got %q", got)
+	}
+
+	if err := (Config{ChunkSize: 100, ToLang: "german", Backend: BackendDeepL, ImageText: ImageTextAlt}).Validate(); ErrorClass(err) != ErrorClassConfig {
+		t.Errorf("Expected DeepL to be rejected for image text, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package translator
+
+import (
+	"regexp"
+	"strings"
+)
+
+var defaultYAMLKeys = []string{"description", "summary", "message"}
+
+var (
+	yamlCommentRe     = regexp.MustCompile(`^(\s*#\s*)(.*)$`)
+	yamlKeyValueRe    = regexp.MustCompile(`^(\s*(?:-\s+)?)("[^"]*"|'[^']*'|[^\s:#][^:#]*?)(\s*:)(\s+.*|)$`)
+	yamlBlockScalarRe = regexp.MustCompile(`^[|>][-+0-9]*$`)
+	yamlValuePrefixRe = regexp.MustCompile(`^((?:[&!]\S+\s+)*)`)
+)
+
+// maskYAML leaves only comments and the values of selected keys visible to
+// the model. Everything else, including indentation, anchors and the contents
+// of unselected block scalars, is protected so the manifest keeps its shape.
+func maskYAML(text string, config Config) (string, []string) {
+	keys := config.YAMLKeys
+	if len(keys) == 0 {
+		keys = defaultYAMLKeys
+	}
+
+	var b maskBuilder
+	blockIndent := -1
+	blockKeep := false
+
+	lines := strings.SplitAfter(text, "\n")
+	for _, line := range lines {
+		body := strings.TrimSuffix(line, "\n")
+		newline := line[len(body):]
+
+		if blockIndent >= 0 {
+			if strings.TrimSpace(body) == "" || yamlIndent(body) > blockIndent {
+				if blockKeep {
+					indent := yamlIndent(body)
+					b.protect(body[:indent])
+					b.keep(body[indent:])
+				} else {
+					b.protect(body)
+				}
+				b.keep(newline)
+				continue
+			}
+			blockIndent = -1
+		}
+
+		if m := yamlCommentRe.FindStringSubmatch(body); m != nil {
+			b.protect(m[1])
+			b.keep(m[2])
+			b.keep(newline)
+			continue
+		}
+
+		m := yamlKeyValueRe.FindStringSubmatch(body)
+		if m == nil {
+			value, comment := splitYAMLComment(body)
+			b.protect(value)
+			maskYAMLComment(&b, comment)
+			b.keep(newline)
+			continue
+		}
+
+		selected := yamlKeySelected(m[2], keys)
+		value, comment := splitYAMLComment(m[4])
+		b.protect(m[1] + m[2] + m[3])
+
+		trimmed := strings.TrimSpace(value)
+		if yamlBlockScalarRe.MatchString(trimmed) {
+			blockIndent = yamlIndent(body)
+			blockKeep = selected
+			b.protect(value)
+		} else if selected && trimmed != "" {
+			maskYAMLScalar(&b, value)
+		} else {
+			b.protect(value)
+		}
+
+		maskYAMLComment(&b, comment)
+		b.keep(newline)
+	}
+
+	return b.result()
+}
+
+func maskYAMLScalar(b *maskBuilder, value string) {
+	lead := len(value) - len(strings.TrimLeft(value, " \t"))
+	trail := len(value) - len(strings.TrimRight(value, " \t"))
+	inner := value[lead : len(value)-trail]
+
+	prefix := yamlValuePrefixRe.FindString(inner)
+	inner = inner[len(prefix):]
+
+	switch {
+	case inner == "" || strings.ContainsAny(inner[:1], "*{[@`"):
+		b.protect(value)
+		return
+	case len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0]:
+		b.protect(value[:lead] + prefix + inner[:1])
+		b.keep(inner[1 : len(inner)-1])
+		b.protect(inner[len(inner)-1:] + value[len(value)-trail:])
+	default:
+		b.protect(value[:lead] + prefix)
+		b.keep(inner)
+		b.protect(value[len(value)-trail:])
+	}
+}
+
+func maskYAMLComment(b *maskBuilder, comment string) {
+	if comment == "" {
+		return
+	}
+	m := yamlCommentRe.FindStringSubmatch(comment)
+	b.protect(m[1])
+	b.keep(m[2])
+}
+
+// splitYAMLComment separates a trailing " #" comment from a value, ignoring
+// hash signs inside quoted strings.
+func splitYAMLComment(s string) (string, string) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			j := i
+			for j > 0 && (s[j-1] == ' ' || s[j-1] == '\t') {
+				j--
+			}
+			return s[:j], s[j:]
+		}
+	}
+	return s, ""
+}
+
+func yamlKeySelected(key string, keys []string) bool {
+	key = strings.Trim(key, `"'`)
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		key = key[i+1:]
+	}
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func yamlIndent(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
@@ -0,0 +1,69 @@
+package translator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaskYAML(t *testing.T) {
+	input := `# Deployment for the web frontend
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web # public name
+  annotations:
+    example.com/description: "Serves the landing page"
+    checksum: abc123
+defaults: &defaults
+  summary: &sum Shared settings
+  script: |
+    # not a comment
+    echo hello
+  description: >-
+    Long text that
+    spans lines.
+spec:
+  replicas: 2
+`
+
+	masked, spans := maskYAML(input, Config{})
+
+	visible := []string{
+		"Deployment for the web frontend",
+		"public name",
+		"Serves the landing page",
+		"Shared settings",
+		"Long text that",
+		"spans lines.",
+	}
+	for _, v := range visible {
+		if !strings.Contains(masked, v) {
+			t.Errorf("Expected %q to be translatable, got %q", v, masked)
+		}
+	}
+
+	hidden := []string{"apiVersion", "name: web", "abc123", "&defaults", "&sum", "not a comment", "echo hello", "replicas", "example.com"}
+	for _, h := range hidden {
+		if strings.Contains(masked, h) {
+			t.Errorf("Expected %q to be protected, got %q", h, masked)
+		}
+	}
+
+	if strings.Count(masked, "\n") != strings.Count(input, "\n") {
+		t.Errorf("Masking changed the line structure: %q", masked)
+	}
+
+	if restored := unmaskSpans(masked, spans); restored != input {
+		t.Errorf("Unmasking did not restore the input\nExpected: %q\nGot: %q", input, restored)
+	}
+}
+
+func TestMaskYAMLCustomKeys(t *testing.T) {
+	input := "title: Hello\ndescription: World\n"
+
+	masked, _ := maskYAML(input, Config{YAMLKeys: []string{"title"}})
+
+	if !strings.Contains(masked, "Hello") || strings.Contains(masked, "World") {
+		t.Errorf("Expected only title to be translatable, got %q", masked)
+	}
+}